@@ -10,6 +10,7 @@ import (
 	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/config"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/payment"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/redis"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/s3"
@@ -27,6 +28,8 @@ type App struct {
 	cache  *redisClient.Client
 	prod   *kafka.Producer
 	log    *zap.SugaredLogger
+
+	stopWorkers context.CancelFunc
 }
 
 func New(cfg *config.Config, log *zap.SugaredLogger) (*App, error) {
@@ -61,19 +64,70 @@ func New(cfg *config.Config, log *zap.SugaredLogger) (*App, error) {
 	}
 
 	// Сервисы и репозитории
-	jwtManager := auth.NewJWTManager(cfg.JWTSecret, 24*time.Hour)
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.AccessTTL)
+	rbac := auth.NewRBAC(cfg.RolePermissions)
 	imageService := service.NewImageService(s3Storage, cfg)
 
 	userRepo := postgres.NewUserRepo(db)
+	sessionRepo := postgres.NewSessionRepo(db)
 	productRepo := postgres.NewProductRepo(db)
+	categoryRepo := postgres.NewCategoryRepo(db)
+	productImageRepo := postgres.NewProductImageRepo(db)
+	attributeRepo := postgres.NewAttributeRepo(db)
+	importJobRepo := postgres.NewImportJobRepo(db)
+	stockRepo := postgres.NewStockRepo(db)
+	warehouseRepo := postgres.NewWarehouseRepo(db)
+	stockAlertRepo := postgres.NewStockAlertRepo(db)
+	orderRepo := postgres.NewOrderRepo(db)
+	paymentRepo := postgres.NewPaymentRepo(db)
+	returnRepo := postgres.NewReturnRepo(db)
+	invoiceRepo := postgres.NewInvoiceRepo(db)
+	cartRepo := postgres.NewCartRepo(db)
+	promotionRepo := postgres.NewPromotionRepo(db)
 	cacheRepo := redis.NewCache(cfg.RedisAddr, 30*time.Minute)
+	cartStore := redis.NewCartStore(rdb, cfg.CartTTL)
+	idempotencyStore := redis.NewIdempotencyStore(rdb)
+
+	userService := service.NewUserService(userRepo, sessionRepo, jwtManager, producer, rbac, cfg.RefreshTTL)
+	stockAlertService := service.NewStockAlertService(stockAlertRepo, productRepo, producer, cfg.LowStockThreshold)
+	productService := service.NewProductService(productRepo, categoryRepo, productImageRepo, attributeRepo, imageService, cacheRepo,
+		stockAlertService, cfg.PriceFacetBuckets)
+	categoryService := service.NewCategoryService(categoryRepo)
+	attributeService := service.NewAttributeService(attributeRepo, categoryRepo)
+	importService := service.NewImportService(importJobRepo, productRepo, categoryRepo, productService)
+	exportService := service.NewExportService(productRepo, attributeRepo, orderRepo)
+	stockService := service.NewStockService(stockRepo, productService)
+	warehouseService := service.NewWarehouseService(warehouseRepo)
+	orderService := service.NewOrderService(orderRepo, productService, producer, cfg.ReservationTTL)
+	var paymentProviders []service.PaymentProvider
+	if cfg.Payments.FakeEnabled {
+		fakeProvider, err := payment.NewFakeProvider(cfg.Payments.FakeSecret, cfg.Payments.ReturnURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Warnw("Fake payment provider is enabled: orders can be paid without money, do not use in production")
+		paymentProviders = append(paymentProviders, fakeProvider)
+	}
+	paymentService := service.NewPaymentService(paymentRepo, orderService, producer, cfg.Payments.DefaultProvider,
+		paymentProviders...)
+	returnService := service.NewReturnService(returnRepo, orderService, paymentService, productService, producer)
+	promotionService := service.NewPromotionService(promotionRepo)
+	cartService := service.NewCartService(cartRepo, cartStore, productRepo, orderService, promotionService)
+	pdfService := service.NewPDFService(cfg.PDF)
+	invoiceService := service.NewInvoiceService(invoiceRepo, orderRepo, userRepo, pdfService, s3Storage)
+
+	if err := importService.FailInterrupted(context.Background()); err != nil {
+		log.Warnw("Failed to close interrupted import jobs", "error", err)
+	}
 
-	userService := service.NewUserService(userRepo, jwtManager, producer)
-	productService := service.NewProductService(productRepo, imageService, cacheRepo)
-	pdfService := service.NewPDFService("http://localhost:8080")
+	// Фоновые задачи
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	go orderService.RunReservationSweeper(workersCtx, cfg.ReservationSweepInterval)
 
 	// HTTP маршрутизатор
-	mux := router.New(cfg, db, rdb, jwtManager, userService, productService, pdfService)
+	mux := router.New(cfg, db, rdb, jwtManager, sessionRepo, rbac, idempotencyStore, userService, productService, pdfService,
+		orderService, cartService, categoryService, attributeService, importService, exportService, stockService,
+		warehouseService, stockAlertService, paymentService, returnService, invoiceService, promotionService)
 
 	server := &http.Server{
 		Addr:         cfg.HTTPPort,
@@ -84,11 +138,12 @@ func New(cfg *config.Config, log *zap.SugaredLogger) (*App, error) {
 	}
 
 	return &App{
-		server: server,
-		db:     db,
-		cache:  rdb,
-		prod:   producer,
-		log:    log,
+		server:      server,
+		db:          db,
+		cache:       rdb,
+		prod:        producer,
+		log:         log,
+		stopWorkers: stopWorkers,
 	}, nil
 }
 
@@ -98,6 +153,7 @@ func (a *App) Run() error {
 
 func (a *App) Stop(ctx context.Context) error {
 	a.log.Infow("closing resources...")
+	a.stopWorkers()
 	if err := a.server.Shutdown(ctx); err != nil {
 		return err
 	}
@@ -8,9 +8,10 @@ import (
 )
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -26,11 +27,17 @@ func NewJWTManager(secretKey string, ttl time.Duration) *JWTManager {
 	}
 }
 
-func (m *JWTManager) Generate(userID int, email, role string) (string, error) {
+// TTL возвращает время жизни access токена
+func (m *JWTManager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *JWTManager) Generate(userID int, email, role, sessionID string) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"strings"
 )
@@ -12,7 +13,12 @@ const (
 	UserContextKey contextKey = "user"
 )
 
-func AuthMiddleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
+// SessionChecker проверяет, что сессия, к которой привязан токен, не отозвана
+type SessionChecker interface {
+	IsActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+func AuthMiddleware(jwtManager *JWTManager, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -33,12 +39,43 @@ func AuthMiddleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
 				return
 			}
 
+			if claims.SessionID == "" {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
+			active, err := sessions.IsActive(r.Context(), claims.SessionID)
+			if err != nil {
+				log.Printf("Session check error: %v", err)
+				http.Error(w, "Failed to verify session", http.StatusInternalServerError)
+				return
+			}
+			if !active {
+				http.Error(w, "Session revoked", http.StatusUnauthorized)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// OptionalAuthMiddleware пропускает запросы без токена (гостей), но отклоняет запросы с невалидным токеном
+func OptionalAuthMiddleware(jwtManager *JWTManager, sessions SessionChecker) func(http.Handler) http.Handler {
+	required := AuthMiddleware(jwtManager, sessions)
+	return func(next http.Handler) http.Handler {
+		withAuth := required(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withAuth.ServeHTTP(w, r)
+		})
+	}
+}
+
 func GetUserFromContext(ctx context.Context) *Claims {
 	if claims, ok := ctx.Value(UserContextKey).(*Claims); ok {
 		return claims
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSessionID = "6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5f"
+
+type fakeSessions struct {
+	active map[string]bool
+	err    error
+}
+
+func (f fakeSessions) IsActive(_ context.Context, sessionID string) (bool, error) {
+	return f.active[sessionID], f.err
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	manager := NewJWTManager("test-secret", time.Hour)
+	token := func(m *JWTManager, sessionID string) string {
+		t.Helper()
+		s, err := m.Generate(7, "user@example.com", "customer", sessionID)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		return "Bearer " + s
+	}
+	active := fakeSessions{active: map[string]bool{testSessionID: true}}
+
+	tests := []struct {
+		name       string
+		header     string
+		sessions   fakeSessions
+		wantStatus int
+		wantUserID int
+	}{
+		{name: "guest without header", sessions: active, wantStatus: http.StatusOK},
+		{name: "valid token", header: token(manager, testSessionID), sessions: active, wantStatus: http.StatusOK, wantUserID: 7},
+		{name: "token signed with another key", header: token(NewJWTManager("other-secret", time.Hour), testSessionID), sessions: active, wantStatus: http.StatusUnauthorized},
+		{name: "malformed header", header: "Token " + token(manager, testSessionID)[len("Bearer "):], sessions: active, wantStatus: http.StatusUnauthorized},
+		{name: "token without session", header: token(manager, ""), sessions: active, wantStatus: http.StatusUnauthorized},
+		{name: "revoked session", header: token(manager, testSessionID), sessions: fakeSessions{}, wantStatus: http.StatusUnauthorized},
+		{name: "session check failure", header: token(manager, testSessionID), sessions: fakeSessions{err: errors.New("db down")}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID int
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if claims := GetUserFromContext(r.Context()); claims != nil {
+					gotUserID = claims.UserID
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/cart", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			OptionalAuthMiddleware(manager, tt.sessions)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("user id = %d, want %d", gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
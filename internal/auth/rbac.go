@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+// Permission — право вида "<ресурс>:<действие>"
+type Permission string
+
+const (
+	PermProductsRead   Permission = "products:read"
+	PermProductsWrite  Permission = "products:write"
+	PermStockWrite     Permission = "stock:write"
+	PermOrdersRead     Permission = "orders:read"
+	PermOrdersWrite    Permission = "orders:write"
+	PermOrdersCancel   Permission = "orders:cancel"
+	PermPaymentsRefund Permission = "payments:refund"
+	PermReturnsWrite   Permission = "returns:write"
+	PermUsersRead      Permission = "users:read"
+	PermUsersWrite     Permission = "users:write"
+
+	// PermAll выдает все права (в том числе "products:*" выдает все права на продукты)
+	PermAll Permission = "*"
+)
+
+// DefaultRolePermissions — права ролей по умолчанию. Конфиг (role_permissions) может переопределить любую роль.
+var DefaultRolePermissions = map[string][]Permission{
+	entity.RoleAdmin: {PermAll},
+	entity.RoleManager: {PermProductsRead, PermProductsWrite, PermStockWrite, PermOrdersRead, PermOrdersWrite,
+		PermOrdersCancel, PermPaymentsRefund, PermReturnsWrite},
+	// Склад ведет остатки и двигает заказы по статусам, но не возвращает деньги
+	entity.RoleWarehouse: {PermProductsRead, PermStockWrite, PermOrdersRead, PermOrdersWrite},
+	// Поддержка отменяет заказы и рассматривает возвраты, но не меняет остальные статусы
+	entity.RoleSupport:  {PermProductsRead, PermOrdersRead, PermOrdersCancel, PermReturnsWrite, PermUsersRead},
+	entity.RoleCustomer: {},
+}
+
+// RBAC сопоставляет роли пользователей с правами
+type RBAC struct {
+	roles map[string]map[Permission]bool
+}
+
+// NewRBAC строит таблицу прав из значений по умолчанию и переопределений из конфига
+func NewRBAC(overrides map[string][]string) *RBAC {
+	roles := make(map[string]map[Permission]bool, len(DefaultRolePermissions)+len(overrides))
+
+	for role, perms := range DefaultRolePermissions {
+		roles[role] = make(map[Permission]bool, len(perms))
+		for _, p := range perms {
+			roles[role][p] = true
+		}
+	}
+
+	for role, perms := range overrides {
+		roles[role] = make(map[Permission]bool, len(perms))
+		for _, p := range perms {
+			roles[role][Permission(p)] = true
+		}
+	}
+
+	return &RBAC{roles: roles}
+}
+
+// HasRole проверяет, что роль известна системе
+func (r *RBAC) HasRole(role string) bool {
+	_, ok := r.roles[role]
+	return ok
+}
+
+// HasPermission проверяет право роли с учетом "*" и "<ресурс>:*"
+func (r *RBAC) HasPermission(role string, perm Permission) bool {
+	perms := r.roles[role]
+	if perms[perm] || perms[PermAll] {
+		return true
+	}
+
+	resource, _, _ := strings.Cut(string(perm), ":")
+	return perms[Permission(resource+":*")]
+}
+
+// RequirePermission пропускает запрос, только если у роли пользователя есть право perm.
+// Должен стоять после AuthMiddleware.
+func (r *RBAC) RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			claims := GetUserFromContext(req.Context())
+			if claims == nil {
+				http.Error(w, "Authorization required", http.StatusUnauthorized)
+				return
+			}
+
+			if !r.HasPermission(claims.Role, perm) {
+				http.Error(w, "Permission denied: "+string(perm), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestRBACHasPermission(t *testing.T) {
+	rbac := NewRBAC(map[string][]string{
+		"catalog":          {"products:*"},
+		entity.RoleSupport: {"orders:read"},
+	})
+
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{entity.RoleAdmin, PermProductsWrite, true},
+		{entity.RoleAdmin, PermUsersWrite, true},
+		{entity.RoleAdmin, Permission("reports:read"), true},
+		{entity.RoleManager, PermOrdersWrite, true},
+		{entity.RoleManager, PermUsersRead, false},
+		{entity.RoleWarehouse, PermProductsRead, true},
+		{entity.RoleWarehouse, PermProductsWrite, false},
+		{entity.RoleCustomer, PermProductsRead, false},
+		{"catalog", PermProductsRead, true},
+		{"catalog", PermProductsWrite, true},
+		{"catalog", Permission("products:delete"), true},
+		{"catalog", PermOrdersRead, false},
+		// Переопределение заменяет права роли целиком
+		{entity.RoleSupport, PermOrdersRead, true},
+		{entity.RoleSupport, PermUsersRead, false},
+		{"unknown", PermProductsRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.perm), func(t *testing.T) {
+			if got := rbac.HasPermission(tt.role, tt.perm); got != tt.want {
+				t.Errorf("HasPermission(%q, %q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRolePermissions(t *testing.T) {
+	rbac := NewRBAC(nil)
+
+	tests := []struct {
+		role string
+		perm Permission
+		want bool
+	}{
+		{entity.RoleManager, PermStockWrite, true},
+		{entity.RoleManager, PermPaymentsRefund, true},
+		{entity.RoleManager, PermReturnsWrite, true},
+		{entity.RoleManager, PermOrdersCancel, true},
+		{entity.RoleWarehouse, PermStockWrite, true},
+		{entity.RoleWarehouse, PermOrdersWrite, true},
+		{entity.RoleWarehouse, PermPaymentsRefund, false},
+		{entity.RoleWarehouse, PermReturnsWrite, false},
+		{entity.RoleSupport, PermOrdersCancel, true},
+		{entity.RoleSupport, PermReturnsWrite, true},
+		{entity.RoleSupport, PermOrdersWrite, false},
+		{entity.RoleSupport, PermPaymentsRefund, false},
+		{entity.RoleSupport, PermStockWrite, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.perm), func(t *testing.T) {
+			if got := rbac.HasPermission(tt.role, tt.perm); got != tt.want {
+				t.Errorf("HasPermission(%q, %q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRBACHasRole(t *testing.T) {
+	rbac := NewRBAC(map[string][]string{"catalog": {"products:read"}})
+
+	for _, role := range []string{entity.RoleAdmin, entity.RoleCustomer, "catalog"} {
+		if !rbac.HasRole(role) {
+			t.Errorf("HasRole(%q) = false, want true", role)
+		}
+	}
+	if rbac.HasRole("root") {
+		t.Error(`HasRole("root") = true, want false`)
+	}
+}
+
+func TestRBACRequirePermission(t *testing.T) {
+	rbac := NewRBAC(nil)
+	handler := rbac.RequirePermission(PermProductsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		want   int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"customer", &Claims{UserID: 1, Role: entity.RoleCustomer}, http.StatusForbidden},
+		{"manager", &Claims{UserID: 2, Role: entity.RoleManager}, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/products", nil)
+			if tt.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
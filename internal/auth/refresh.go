@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+// NewRefreshSecret генерирует случайный секрет refresh токена
+func NewRefreshSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// FormatRefreshToken собирает refresh токен вида "<session_id>.<secret>"
+func FormatRefreshToken(sessionID, secret string) string {
+	return sessionID + "." + secret
+}
+
+// ParseRefreshToken разбирает refresh токен на ID сессии и секрет
+func ParseRefreshToken(token string) (sessionID, secret string, err error) {
+	sessionID, secret, ok := strings.Cut(token, ".")
+	if !ok || !isUUID(sessionID) || secret == "" {
+		return "", "", errors.ErrInvalidToken
+	}
+	return sessionID, secret, nil
+}
+
+// isUUID проверяет формат 8-4-4-4-12, чтобы мусор не доходил до колонки UUID в БД
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HashRefreshSecret возвращает хэш секрета для хранения в БД
+func HashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+func TestRefreshTokenRoundTrip(t *testing.T) {
+	secret, err := NewRefreshSecret()
+	if err != nil {
+		t.Fatalf("NewRefreshSecret() error = %v", err)
+	}
+	other, err := NewRefreshSecret()
+	if err != nil {
+		t.Fatalf("NewRefreshSecret() error = %v", err)
+	}
+	if secret == other {
+		t.Fatal("NewRefreshSecret() returned the same secret twice")
+	}
+
+	sessionID, gotSecret, err := ParseRefreshToken(FormatRefreshToken(testSessionID, secret))
+	if err != nil {
+		t.Fatalf("ParseRefreshToken() error = %v", err)
+	}
+	if sessionID != testSessionID || gotSecret != secret {
+		t.Errorf("ParseRefreshToken() = %q, %q, want %q, %q", sessionID, gotSecret, testSessionID, secret)
+	}
+}
+
+func TestParseRefreshTokenInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "no separator", token: testSessionID},
+		{name: "empty secret", token: testSessionID + "."},
+		{name: "session is not uuid", token: "42.secret"},
+		{name: "uuid with bad character", token: "6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5z.secret"},
+		{name: "uuid without dashes", token: "6f1c2d3e04a5b04c6d08e9f00a1b2c3d4e5f.secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseRefreshToken(tt.token); !errors.Is(err, errors.ErrInvalidToken) {
+				t.Errorf("ParseRefreshToken(%q) error = %v, want %v", tt.token, err, errors.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestHashRefreshSecret(t *testing.T) {
+	hash := HashRefreshSecret("secret")
+	if hash != HashRefreshSecret("secret") {
+		t.Error("HashRefreshSecret() is not deterministic")
+	}
+	if hash == HashRefreshSecret("secret2") {
+		t.Error("HashRefreshSecret() returned the same hash for different secrets")
+	}
+	if len(hash) != 64 {
+		t.Errorf("len(HashRefreshSecret()) = %d, want 64", len(hash))
+	}
+}
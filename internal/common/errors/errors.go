@@ -10,5 +10,78 @@ var (
 	ErrFileUploadFailed   = errors.New("file upload failed")
 	ErrFileDeleteFailed   = errors.New("file delete failed")
 	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidSearchQuery = errors.New("invalid search query")
+	ErrInvalidCursor      = errors.New("invalid cursor")
 	ErrProductNotFound    = errors.New("product not found")
+	ErrVariantNotFound    = errors.New("product variant not found")
+	ErrVariantRequired    = errors.New("product has variants, variant must be specified")
+	ErrSKUExists          = errors.New("sku already exists")
+	ErrExternalIDExists   = errors.New("external id already exists")
+	ErrInvalidVariant     = errors.New("invalid product variant")
+	ErrImageNotFound      = errors.New("product image not found")
+	ErrInvalidImageOrder  = errors.New("image order must list every product image once")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrCategoryExists     = errors.New("category already exists")
+	ErrCategoryNotEmpty   = errors.New("category has subcategories or products")
+	ErrInvalidCategory    = errors.New("invalid category")
+	ErrAttributeNotFound  = errors.New("attribute not found")
+	ErrAttributeExists    = errors.New("attribute already exists")
+	ErrAttributeInUse     = errors.New("attribute has product values")
+	ErrInvalidAttribute   = errors.New("invalid attribute")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrEmptyOrder         = errors.New("order has no items")
+	ErrInvalidQuantity    = errors.New("invalid quantity")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+
+	ErrInvalidStockMovement = errors.New("invalid stock movement")
+	ErrWarehouseNotFound    = errors.New("warehouse not found")
+	ErrWarehouseExists      = errors.New("warehouse code already exists")
+	ErrWarehouseInUse       = errors.New("warehouse has stock or orders")
+	ErrInvalidWarehouse     = errors.New("invalid warehouse")
+	ErrInvalidDelivery      = errors.New("invalid delivery method or pickup point")
+	ErrProductInStock       = errors.New("product is in stock")
+
+	ErrInvalidOrderStatus      = errors.New("invalid order status")
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrOrderNotPayable         = errors.New("order cannot be paid")
+	ErrOrderNotCancellable     = errors.New("order can only be cancelled before payment")
+	ErrInvoiceNotAvailable     = errors.New("invoice is not available for cancelled orders")
+	ErrInvalidOrderNote        = errors.New("order note must be 1-2000 characters")
+	ErrInvalidBulkOrders       = errors.New("bulk operation requires 1-100 order ids")
+
+	ErrReturnNotFound          = errors.New("return not found")
+	ErrInvalidReturn           = errors.New("invalid return")
+	ErrOrderNotReturnable      = errors.New("only delivered orders can be returned")
+	ErrInvalidReturnTransition = errors.New("return has already been resolved")
+
+	ErrPaymentNotFound         = errors.New("payment not found")
+	ErrPaymentProviderNotFound = errors.New("payment provider not found")
+	ErrPaymentProviderFailed   = errors.New("payment provider request failed")
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+	ErrInvalidPaymentEvent     = errors.New("invalid payment event")
+	ErrInvalidRefund           = errors.New("invalid refund amount")
+
+	ErrUserNotFound     = errors.New("user not found")
+	ErrUserDisabled     = errors.New("user is disabled")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrCannotModifySelf = errors.New("cannot modify own account")
+
+	ErrCartEmpty     = errors.New("cart is empty")
+	ErrInvalidCartID = errors.New("invalid cart id")
+
+	ErrPromotionNotFound      = errors.New("promotion not found")
+	ErrInvalidPromotion       = errors.New("invalid promotion")
+	ErrPromoCodeExists        = errors.New("promo code already exists")
+	ErrInvalidPromoCode       = errors.New("invalid promo code")
+	ErrPromoCodeNotFound      = errors.New("promo code not found or expired")
+	ErrPromoCodeExhausted     = errors.New("promo code usage limit reached")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to the order")
+
+	ErrImportJobNotFound = errors.New("import job not found")
+	ErrInvalidImportFile = errors.New("invalid import file")
 )
+
+// Is — обертка над errors.Is, чтобы не импортировать два пакета errors в одном файле
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
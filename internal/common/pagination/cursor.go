@@ -0,0 +1,106 @@
+// Package pagination реализует непрозрачные курсоры для keyset-пагинации.
+// Курсор хранит значение ключа сортировки и ID последней строки страницы,
+// следующая страница начинается строго после этой пары.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// TimeLayout — формат времени в курсоре, совпадает с текстовым видом TIMESTAMP в Postgres (микросекунды)
+const TimeLayout = "2006-01-02 15:04:05.999999"
+
+// ValueKind — тип ключа сортировки, который хранится в Cursor.Value
+type ValueKind int
+
+const (
+	// ValueNone — курсор хранит только ID
+	ValueNone ValueKind = iota
+	// ValueTime — время в формате TimeLayout
+	ValueTime
+	// ValueInt — целое число
+	ValueInt
+	// ValueDecimal — десятичное число без экспоненты, как numeric в Postgres
+	ValueDecimal
+	// ValueText — произвольная строка
+	ValueText
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]{1,20}(\.[0-9]{1,10})?$`)
+
+// Cursor — позиция в выдаче. Sort фиксирует сортировку, для которой курсор выдан.
+type Cursor struct {
+	Sort  string `json:"s,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// Encode упаковывает курсор в строку для query-параметра
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode распаковывает курсор, выданный для сортировки sort, со значением вида kind;
+// для пустой строки возвращает nil (первая страница). Курсор приходит от клиента, поэтому значение
+// проверяется здесь: иначе подделанный курсор дошел бы до приведения типа в SQL.
+func Decode(s, sort string, kind ValueKind) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, errors.ErrInvalidCursor
+	}
+	// Значение курсора имеет смысл только для той сортировки, для которой он выдан
+	if c.Sort != sort || !validValue(c.Value, kind) {
+		return nil, errors.ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// validValue проверяет, что значение курсора разбирается как kind
+func validValue(v string, kind ValueKind) bool {
+	switch kind {
+	case ValueNone:
+		return v == ""
+	case ValueTime:
+		_, err := time.Parse(TimeLayout, v)
+		return err == nil
+	case ValueInt:
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	case ValueDecimal:
+		return decimalPattern.MatchString(v)
+	case ValueText:
+		return utf8.ValidString(v) && !strings.ContainsRune(v, 0)
+	}
+	return false
+}
+
+// ClampLimit приводит размер страницы к допустимому диапазону
+func ClampLimit(limit int) int {
+	if limit < 1 || limit > MaxLimit {
+		return DefaultLimit
+	}
+	return limit
+}
@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+		kind   ValueKind
+	}{
+		{"id only", Cursor{ID: 42}, ValueNone},
+		{"time", Cursor{Value: "2025-06-02 10:15:30.123456", ID: 7}, ValueTime},
+		{"time without fraction", Cursor{Value: "2025-06-02 10:15:30", ID: 7}, ValueTime},
+		{"decimal", Cursor{Sort: "price_asc", Value: "15990.00", ID: 3}, ValueDecimal},
+		{"int", Cursor{Sort: "popularity", Value: "120", ID: 3}, ValueInt},
+		{"text", Cursor{Sort: "name", Value: "Диван \"Честер\"", ID: 9}, ValueText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.cursor.Encode(), tt.cursor.Sort, tt.kind)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if *got != tt.cursor {
+				t.Errorf("Decode() = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	got, err := Decode("", "", ValueTime)
+	if err != nil || got != nil {
+		t.Errorf("Decode(\"\") = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		kind   ValueKind
+	}{
+		{"not base64", "!!!", "", ValueNone},
+		{"not json", raw("cursor"), "", ValueNone},
+		{"missing id", raw(`{"v":"1"}`), "", ValueInt},
+		{"negative id", raw(`{"id":-1}`), "", ValueNone},
+		{"other sort", Cursor{Sort: "price_asc", Value: "10", ID: 1}.Encode(), "name", ValueText},
+		{"sort missing", Cursor{Value: "10", ID: 1}.Encode(), "price_asc", ValueDecimal},
+		{"value where none expected", Cursor{Value: "x", ID: 1}.Encode(), "", ValueNone},
+		{"bad time", Cursor{Value: "yesterday", ID: 1}.Encode(), "", ValueTime},
+		{"time with zone", Cursor{Value: "2025-06-02T10:15:30Z", ID: 1}.Encode(), "", ValueTime},
+		{"bad int", Cursor{Value: "1.5", ID: 1}.Encode(), "", ValueInt},
+		{"int overflow", Cursor{Value: "99999999999999999999", ID: 1}.Encode(), "", ValueInt},
+		{"decimal exponent", Cursor{Value: "1e5", ID: 1}.Encode(), "", ValueDecimal},
+		{"decimal nan", Cursor{Value: "NaN", ID: 1}.Encode(), "", ValueDecimal},
+		{"decimal hex", Cursor{Value: "0x10", ID: 1}.Encode(), "", ValueDecimal},
+		{"text with nul", Cursor{Value: "a\x00b", ID: 1}.Encode(), "", ValueText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.cursor, tt.sort, tt.kind)
+			if !errors.Is(err, errors.ErrInvalidCursor) {
+				t.Errorf("Decode() = %+v, %v, want ErrInvalidCursor", got, err)
+			}
+		})
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultLimit},
+		{-5, DefaultLimit},
+		{1, 1},
+		{MaxLimit, MaxLimit},
+		{MaxLimit + 1, DefaultLimit},
+	}
+
+	for _, tt := range tests {
+		if got := ClampLimit(tt.limit); got != tt.want {
+			t.Errorf("ClampLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
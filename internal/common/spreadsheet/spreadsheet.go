@@ -0,0 +1,76 @@
+// Package spreadsheet читает и пишет табличные файлы каталога (CSV и XLSX).
+// XLSX разбирается стандартной библиотекой: читается первый лист книги,
+// формулы не вычисляются — берется сохраненное в файле значение.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+// Format — формат табличного файла
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// FormatFromFilename определяет формат по расширению файла
+func FormatFromFilename(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".xlsx":
+		return FormatXLSX, true
+	}
+	return "", false
+}
+
+// Read возвращает строки файла вместе с заголовком. Короткие строки не дополняются.
+func Read(data []byte, format Format) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatXLSX:
+		return readXLSX(data)
+	}
+	return nil, fmt.Errorf("%w: unsupported format %q", errors.ErrInvalidImportFile, format)
+}
+
+// readCSV читает CSV с разделителем «,», «;» или табуляцией: Excel с русской локалью сохраняет CSV через «;»
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidImportFile, err)
+	}
+	return rows, nil
+}
+
+// detectDelimiter выбирает разделитель, который чаще всего встречается в строке заголовка
+func detectDelimiter(data []byte) rune {
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+
+	best, bestCount := ',', bytes.Count(header, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(header, []byte(string(candidate))); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,212 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+func TestFormatFromFilename(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Format
+		wantOK bool
+	}{
+		{"catalog.csv", FormatCSV, true},
+		{"Каталог.XLSX", FormatXLSX, true},
+		{"catalog.xls", "", false},
+		{"catalog", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := FormatFromFilename(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("FormatFromFilename(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want [][]string
+	}{
+		{
+			name: "comma",
+			data: "sku,name,price\nA-1,Стул,100\n",
+			want: [][]string{{"sku", "name", "price"}, {"A-1", "Стул", "100"}},
+		},
+		{
+			name: "semicolon from excel with bom",
+			data: "\xef\xbb\xbfsku;name;price\nA-1;Стул, мягкий;1 500,50\n",
+			want: [][]string{{"sku", "name", "price"}, {"A-1", "Стул, мягкий", "1 500,50"}},
+		},
+		{
+			name: "tab",
+			data: "sku\tname\nA-1\t\"Диван \"\"Честер\"\"\"\n",
+			want: [][]string{{"sku", "name"}, {"A-1", `Диван "Честер"`}},
+		},
+		{
+			name: "short rows are kept as is",
+			data: "sku,name,price\nA-1\n",
+			want: [][]string{{"sku", "name", "price"}, {"A-1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read([]byte(tt.data), FormatCSV)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Товары" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId7" Target="worksheets/products.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>sku</t></si><si><t>name</t></si><si><r><t>Диван </t></r><r><t>угловой</t></r></si></sst>`,
+		"xl/worksheets/products.xml": `<worksheet><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>price</t></is></c></row>
+			<row r="3"><c r="A3"><v>101</v></c><c r="C3"><v>15990.5</v></c></row>
+			<row r="4"><c r="B4" t="s"><v>2</v></c></row>
+		</sheetData></worksheet>`,
+	})
+
+	got, err := Read(data, FormatXLSX)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := [][]string{
+		{"sku", "name", "price"},
+		nil, // пустая строка 2 сохраняется, чтобы номера строк совпадали с Excel
+		{"101", "", "15990.5"},
+		{"", "Диван угловой"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}
+
+func TestReadXLSXFallbackSheet(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c><v>sku</v></c><c><v>name</v></c></row></sheetData></worksheet>`,
+	})
+
+	got, err := Read(data, FormatXLSX)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if want := [][]string{{"sku", "name"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	sheet := func(rows string) []byte {
+		return buildXLSX(t, map[string]string{
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` + rows + `</sheetData></worksheet>`,
+		})
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not a zip", data: []byte("sku,name\n")},
+		{name: "no sheets", data: buildXLSX(t, map[string]string{"xl/styles.xml": `<styleSheet/>`})},
+		{name: "broken xml", data: sheet(`<row r="1"><c><v>1</v>`)},
+		{name: "bad cell reference", data: sheet(`<row r="1"><c r="1A"><v>1</v></c></row>`)},
+		{name: "column beyond XFD", data: sheet(`<row r="1"><c r="ABCD1"><v>1</v></c></row>`)},
+		{name: "row beyond Excel limit", data: sheet(`<row r="1048577"><c><v>1</v></c></row>`)},
+		{name: "missing shared string", data: sheet(`<row r="1"><c r="A1" t="s"><v>5</v></c></row>`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(tt.data, FormatXLSX); !errors.Is(err, errors.ErrInvalidImportFile) {
+				t.Errorf("Read() error = %v, want ErrInvalidImportFile", err)
+			}
+		})
+	}
+}
+
+func TestReadUnsupportedFormat(t *testing.T) {
+	if _, err := Read([]byte("x"), Format("ods")); !errors.Is(err, errors.ErrInvalidImportFile) {
+		t.Errorf("Read() error = %v, want ErrInvalidImportFile", err)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref    string
+		want   int
+		wantOK bool
+	}{
+		{"A1", 0, true},
+		{"Z10", 25, true},
+		{"AA3", 26, true},
+		{"XFD1048576", 16383, true},
+		{"1", 0, false},
+		{"ABCD1", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := columnIndex(tt.ref)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d, %v", tt.ref, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// buildXLSX собирает zip-архив книги из частей
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// readZipPart возвращает содержимое файла из zip-архива
+func readZipPart(t *testing.T, data []byte, name string) string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(content)
+}
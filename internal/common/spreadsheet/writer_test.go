@@ -0,0 +1,97 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	rows := [][]interface{}{
+		{"sku", "name", "price", "stock", "note"},
+		{"00123", `Диван "Честер", <угловой> & мягкий`, 15990.5, 3, nil},
+		{"A-2", "  пробелы сохраняются  ", 0.1, 0, "строка\nс переносом"},
+	}
+	want := [][]string{
+		{"sku", "name", "price", "stock", "note"},
+		{"00123", `Диван "Честер", <угловой> & мягкий`, "15990.5", "3", ""},
+		{"A-2", "  пробелы сохраняются  ", "0.1", "0", "строка\nс переносом"},
+	}
+
+	for _, format := range []Format{FormatCSV, FormatXLSX} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			for _, row := range rows {
+				if err := w.WriteRow(row...); err != nil {
+					t.Fatalf("WriteRow() error = %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			got, err := Read(buf.Bytes(), format)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Read() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestXLSXWriterCellTypes(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatXLSX)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WriteRow("00123", 42, 1.5, ""); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	sheet := readZipPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	// Артикул остается текстом, числа — числовыми ячейками
+	for _, part := range []string{
+		`<c t="inlineStr"><is><t xml:space="preserve">00123</t></is></c>`,
+		`<c><v>42</v></c>`,
+		`<c><v>1.5</v></c>`,
+		`<c/>`,
+	} {
+		if !strings.Contains(sheet, part) {
+			t.Errorf("sheet %q does not contain %q", sheet, part)
+		}
+	}
+}
+
+func TestCSVWriterBOM(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatCSV)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WriteRow("sku"); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := buf.String(); got != "\xef\xbb\xbfsku\n" {
+		t.Errorf("csv = %q, want BOM and header", got)
+	}
+}
+
+func TestNewWriterUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, Format("ods")); err == nil {
+		t.Error("NewWriter(ods) error = nil, want error")
+	}
+}
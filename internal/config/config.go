@@ -14,16 +14,37 @@ type Config struct {
 	RedisAddr    string        `mapstructure:"redis_addr"`
 	KafkaBrokers []string      `mapstructure:"kafka_brokers"`
 	JWTSecret    string        `mapstructure:"jwt_secret"`
+	AccessTTL    time.Duration `mapstructure:"access_token_ttl"`
+	RefreshTTL   time.Duration `mapstructure:"refresh_token_ttl"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
 	CorsDebug    bool          `mapstructure:"cors_debug"`
+	CartTTL      time.Duration `mapstructure:"cart_ttl"`
+
+	// IdempotencyTTL — сколько хранится ответ на запрос с заголовком Idempotency-Key
+	IdempotencyTTL time.Duration `mapstructure:"idempotency_ttl"`
+
+	// ReservationTTL — сколько товар неоплаченного заказа удерживается за покупателем;
+	// ReservationSweepInterval — как часто отменяются заказы с истекшим резервом
+	ReservationTTL           time.Duration `mapstructure:"reservation_ttl"`
+	ReservationSweepInterval time.Duration `mapstructure:"reservation_sweep_interval"`
+
+	// LowStockThreshold — порог низкого остатка для товаров без собственного порога
+	LowStockThreshold int `mapstructure:"low_stock_threshold"`
+
+	// PriceFacetBuckets — границы ценовых диапазонов фасета цены в каталоге, в рублях
+	PriceFacetBuckets []float64 `mapstructure:"price_facet_buckets"`
 
 	MaxUploadSize     int64    `mapstructure:"max_upload_size"`
 	AllowedImageTypes []string `mapstructure:"allowed_image_types"`
 
-	AWS AWS `mapstructure:"aws"`
-	PDF PDF `mapstructure:"pdf"`
+	// RolePermissions переопределяет права ролей, например manager: ["products:*", "orders:read"]
+	RolePermissions map[string][]string `mapstructure:"role_permissions"`
+
+	AWS      AWS      `mapstructure:"aws"`
+	PDF      PDF      `mapstructure:"pdf"`
+	Payments Payments `mapstructure:"payments"`
 }
 
 type AWS struct {
@@ -39,6 +60,22 @@ type PDF struct {
 	FontPath    string `mapstructure:"font_path"`
 	LogoPath    string `mapstructure:"logo_path"`
 	CompanyName string `mapstructure:"company_name"`
+	// CompanyDetails — реквизиты продавца в шапке счета (ИНН, КПП, адрес, банк)
+	CompanyDetails string `mapstructure:"company_details"`
+	// VATRate — ставка НДС в процентах, включенного в цены; 0 — счет выписывается без НДС
+	VATRate float64 `mapstructure:"vat_rate"`
+}
+
+type Payments struct {
+	// DefaultProvider — провайдер для оплаты, если покупатель не выбрал другой
+	DefaultProvider string `mapstructure:"default_provider"`
+	// ReturnURL — куда провайдер возвращает покупателя после оплаты
+	ReturnURL string `mapstructure:"return_url"`
+	// FakeEnabled включает встроенный фейковый провайдер — только для разработки и тестов:
+	// с ним заказ оплачивается подписанным вебхуком без реальных денег
+	FakeEnabled bool `mapstructure:"fake_enabled"`
+	// FakeSecret — ключ подписи вебхуков фейкового провайдера; обязателен, если он включен
+	FakeSecret string `mapstructure:"fake_secret"`
 }
 
 func Load() *Config {
@@ -47,10 +84,18 @@ func Load() *Config {
 	viper.SetDefault("redis_addr", "localhost:6379")
 	viper.SetDefault("kafka_brokers", []string{"localhost:9092"})
 	viper.SetDefault("jwt_secret", "talesofrussianglubinka")
+	viper.SetDefault("access_token_ttl", 15*time.Minute)
+	viper.SetDefault("refresh_token_ttl", 30*24*time.Hour)
 	viper.SetDefault("read_timeout", 15*time.Second)
 	viper.SetDefault("write_timeout", 15*time.Second)
 	viper.SetDefault("idle_timeout", 60*time.Second)
 	viper.SetDefault("cors_debug", true)
+	viper.SetDefault("cart_ttl", 7*24*time.Hour)
+	viper.SetDefault("idempotency_ttl", 24*time.Hour)
+	viper.SetDefault("reservation_ttl", 15*time.Minute)
+	viper.SetDefault("reservation_sweep_interval", time.Minute)
+	viper.SetDefault("low_stock_threshold", 3)
+	viper.SetDefault("price_facet_buckets", []float64{5000, 15000, 30000, 60000, 100000})
 	viper.SetDefault("max_upload_size", 10485760) // 10MB
 	viper.SetDefault("allowed_image_types", []string{"image/jpeg", "image/png", "image/webp"})
 	viper.SetDefault("aws.region", "us-east-1")
@@ -60,6 +105,9 @@ func Load() *Config {
 	viper.SetDefault("aws.s3_host", "furniture-s3")
 	viper.SetDefault("pdf.base_url", "http://localhost:8080")
 	viper.SetDefault("pdf.company_name", "Furniture Shop")
+	viper.SetDefault("pdf.vat_rate", 20)
+	viper.SetDefault("payments.return_url", "http://localhost:3000/orders")
+	viper.SetDefault("payments.fake_enabled", false)
 
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -75,10 +123,18 @@ func Load() *Config {
 	viper.BindEnv("redis_addr", "APP_REDIS_ADDR")
 	viper.BindEnv("kafka_brokers", "APP_KAFKA_BROKERS")
 	viper.BindEnv("jwt_secret", "APP_JWT_SECRET")
+	viper.BindEnv("access_token_ttl", "APP_ACCESS_TOKEN_TTL")
+	viper.BindEnv("refresh_token_ttl", "APP_REFRESH_TOKEN_TTL")
 	viper.BindEnv("read_timeout", "APP_READ_TIMEOUT")
 	viper.BindEnv("write_timeout", "APP_WRITE_TIMEOUT")
 	viper.BindEnv("idle_timeout", "APP_IDLE_TIMEOUT")
 	viper.BindEnv("cors_debug", "APP_CORS_DEBUG")
+	viper.BindEnv("cart_ttl", "APP_CART_TTL")
+	viper.BindEnv("idempotency_ttl", "APP_IDEMPOTENCY_TTL")
+	viper.BindEnv("reservation_ttl", "APP_RESERVATION_TTL")
+	viper.BindEnv("reservation_sweep_interval", "APP_RESERVATION_SWEEP_INTERVAL")
+	viper.BindEnv("low_stock_threshold", "APP_LOW_STOCK_THRESHOLD")
+	viper.BindEnv("price_facet_buckets", "APP_PRICE_FACET_BUCKETS")
 	viper.BindEnv("max_upload_size", "APP_MAX_UPLOAD_SIZE")
 	viper.BindEnv("allowed_image_types", "APP_ALLOWED_IMAGE_TYPES")
 	viper.BindEnv("aws.region", "APP_AWS_REGION")
@@ -86,6 +142,10 @@ func Load() *Config {
 	viper.BindEnv("aws.secret_access_key", "APP_AWS_SECRET_ACCESS_KEY")
 	viper.BindEnv("aws.s3_bucket", "APP_AWS_S3_BUCKET")
 	viper.BindEnv("aws.s3_host", "APP_AWS_S3_HOST")
+	viper.BindEnv("payments.default_provider", "APP_PAYMENTS_DEFAULT_PROVIDER")
+	viper.BindEnv("payments.return_url", "APP_PAYMENTS_RETURN_URL")
+	viper.BindEnv("payments.fake_enabled", "APP_PAYMENTS_FAKE_ENABLED")
+	viper.BindEnv("payments.fake_secret", "APP_PAYMENTS_FAKE_SECRET")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
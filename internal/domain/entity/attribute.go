@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+// AttributeType определяет, как хранится и фильтруется значение характеристики
+type AttributeType string
+
+const (
+	AttributeTypeNumber AttributeType = "number" // число в единицах Unit, фильтр по диапазону
+	AttributeTypeEnum   AttributeType = "enum"   // одно значение из Options, фильтр по списку значений
+)
+
+// IsValid проверяет, что тип характеристики поддерживается
+func (t AttributeType) IsValid() bool {
+	return t == AttributeTypeNumber || t == AttributeTypeEnum
+}
+
+// Attribute — определение характеристики товара (ширина, материал каркаса и т.п.)
+type Attribute struct {
+	ID        int           `json:"id" db:"id"`
+	Code      string        `json:"code" db:"code"`
+	Name      string        `json:"name" db:"name"`
+	Type      AttributeType `json:"type" db:"type"`
+	Unit      string        `json:"unit,omitempty" db:"unit"`
+	Min       *float64      `json:"min,omitempty" db:"min_value"`
+	Max       *float64      `json:"max,omitempty" db:"max_value"`
+	Options   []string      `json:"options,omitempty" db:"options"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// CategoryAttribute — характеристика, привязанная к категории. CategoryID указывает,
+// на какой категории сделана привязка: для подкатегорий она наследуется.
+type CategoryAttribute struct {
+	Attribute
+	CategoryID int  `json:"category_id" db:"category_id"`
+	Required   bool `json:"required" db:"required"`
+	SortOrder  int  `json:"sort_order" db:"sort_order"`
+}
+
+// ProductAttribute — значение характеристики товара. Для number заполнено Number, для enum — Value.
+type ProductAttribute struct {
+	AttributeID int           `json:"attribute_id" db:"attribute_id"`
+	Code        string        `json:"code" db:"code"`
+	Name        string        `json:"name" db:"name"`
+	Type        AttributeType `json:"type" db:"type"`
+	Unit        string        `json:"unit,omitempty" db:"unit"`
+	Number      *float64      `json:"number,omitempty" db:"num_value"`
+	Value       string        `json:"value,omitempty" db:"text_value"`
+}
+
+// AttributeFilter — условие фильтра каталога по характеристике: диапазон для number, список значений для enum
+type AttributeFilter struct {
+	Code   string
+	Min    *float64
+	Max    *float64
+	Values []string
+}
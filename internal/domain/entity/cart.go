@@ -0,0 +1,34 @@
+package entity
+
+// Cart — корзина с актуальными ценами и скидками. CartID заполнен для гостевой корзины, UserID — для корзины пользователя.
+// Subtotal — сумма позиций без скидок, Total — к оплате. PromoWarning объясняет, почему промокод не дал скидку.
+type Cart struct {
+	CartID        string          `json:"cart_id,omitempty"`
+	UserID        int             `json:"user_id,omitempty"`
+	Items         []CartItem      `json:"items"`
+	ItemsCount    int             `json:"items_count"`
+	Subtotal      float64         `json:"subtotal"`
+	PromoCode     string          `json:"promo_code,omitempty"`
+	PromoWarning  string          `json:"promo_warning,omitempty"`
+	Discounts     []OrderDiscount `json:"discounts"`
+	DiscountTotal float64         `json:"discount_total"`
+	Total         float64         `json:"total"`
+}
+
+// CartItem — позиция корзины. Цена и остаток берутся из каталога в момент просмотра.
+type CartItem struct {
+	ProductID int               `json:"product_id"`
+	VariantID int               `json:"variant_id,omitempty"`
+	Quantity  int               `json:"quantity"`
+	Name      string            `json:"name,omitempty"`
+	SKU       string            `json:"sku,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+	Price     float64           `json:"price"`
+	ImageURL  string            `json:"image_url,omitempty"`
+	Stock     int               `json:"stock"`
+	Subtotal  float64           `json:"subtotal"`
+	Discount  float64           `json:"discount,omitempty"`
+	Warning   string            `json:"warning,omitempty"`
+
+	CategoryID int `json:"-"`
+}
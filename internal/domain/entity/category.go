@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// Category — узел дерева категорий каталога
+type Category struct {
+	ID        int       `json:"id" db:"id"`
+	ParentID  *int      `json:"parent_id" db:"parent_id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// ProductCount — количество товаров в категории вместе с подкатегориями
+	ProductCount int         `json:"product_count" db:"-"`
+	Children     []*Category `json:"children,omitempty" db:"-"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// ProductImage — изображение из галереи товара. Главное изображение дублируется в Product.ImageURL.
+type ProductImage struct {
+	ID        int       `json:"id" db:"id"`
+	ProductID int       `json:"product_id" db:"product_id"`
+	URL       string    `json:"url" db:"url"`
+	AltText   string    `json:"alt_text" db:"alt_text"`
+	Position  int       `json:"position" db:"position"`
+	IsPrimary bool      `json:"is_primary" db:"is_primary"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
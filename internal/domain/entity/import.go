@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// ImportJobStatus — состояние задачи импорта каталога
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+// ImportRowAction — что импорт сделал (или сделал бы в dry-run) со строкой файла
+type ImportRowAction string
+
+const (
+	ImportRowCreate ImportRowAction = "create"
+	ImportRowUpdate ImportRowAction = "update"
+	ImportRowError  ImportRowAction = "error"
+)
+
+// ImportJob — асинхронная задача импорта товаров из CSV или XLSX
+type ImportJob struct {
+	ID            int             `json:"id" db:"id"`
+	Status        ImportJobStatus `json:"status" db:"status"`
+	Format        string          `json:"format" db:"format"`
+	Filename      string          `json:"filename" db:"filename"`
+	DryRun        bool            `json:"dry_run" db:"dry_run"`
+	TotalRows     int             `json:"total_rows" db:"total_rows"`
+	ProcessedRows int             `json:"processed_rows" db:"processed_rows"`
+	Created       int             `json:"created" db:"created_count"`
+	Updated       int             `json:"updated" db:"updated_count"`
+	Failed        int             `json:"failed" db:"failed_count"`
+	Rows          []ImportRow     `json:"rows,omitempty" db:"report"`
+	Error         string          `json:"error,omitempty" db:"error"`
+	CreatedBy     int             `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	StartedAt     *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ImportRow — результат обработки одной строки файла. Row — номер строки в файле, считая заголовок.
+type ImportRow struct {
+	Row        int             `json:"row"`
+	SKU        string          `json:"sku,omitempty"`
+	ExternalID string          `json:"external_id,omitempty"`
+	Action     ImportRowAction `json:"action"`
+	ProductID  int             `json:"product_id,omitempty"`
+	Errors     []string        `json:"errors,omitempty"`
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// OrderInvoice — счет по заказу. Номер и дата выписки не меняются, поэтому повторная генерация дает тот же документ.
+type OrderInvoice struct {
+	OrderID  int       `json:"order_id" db:"order_id"`
+	Number   string    `json:"number" db:"number"`
+	Token    string    `json:"-" db:"token"`
+	FileURL  string    `json:"-" db:"file_url"`
+	IssuedAt time.Time `json:"issued_at" db:"issued_at"`
+}
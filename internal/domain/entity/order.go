@@ -13,21 +13,139 @@ const (
 	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
+// orderTransitions описывает допустимые переходы жизненного цикла заказа
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:    {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped: {OrderStatusDelivered},
+}
+
+// IsValid проверяет, что статус известен системе
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusShipped, OrderStatusDelivered, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo проверяет, разрешен ли переход в статус next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryMethod — способ получения заказа
+type DeliveryMethod string
+
+const (
+	DeliveryCourier DeliveryMethod = "delivery" // доставка со склада
+	DeliveryPickup  DeliveryMethod = "pickup"   // самовывоз из пункта выдачи
+)
+
+// Delivery — способ получения заказа. Для самовывоза товар списывается только из выбранной точки.
+type Delivery struct {
+	Method            DeliveryMethod `json:"delivery_method" db:"delivery_method"`
+	PickupWarehouseID int            `json:"pickup_warehouse_id,omitempty" db:"pickup_warehouse_id"`
+}
+
+// Order — заказ. Subtotal — сумма позиций по ценам каталога, DiscountTotal — скидки акций (разбивка в Discounts),
+// Total — сумма к оплате.
 type Order struct {
-	ID        int         `json:"id" db:"id"`
-	UserID    int         `json:"user_id" db:"user_id"`
-	Total     float64     `json:"total" db:"total"`
-	Status    OrderStatus `json:"status" db:"status"`
-	CreatedAt time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+	ID            int         `json:"id" db:"id"`
+	UserID        int         `json:"user_id" db:"user_id"`
+	Subtotal      float64     `json:"subtotal" db:"subtotal"`
+	DiscountTotal float64     `json:"discount_total" db:"discount_total"`
+	Total         float64     `json:"total" db:"total"`
+	PromoCode     string      `json:"promo_code,omitempty" db:"promo_code"`
+	Status        OrderStatus `json:"status" db:"status"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+	Delivery
 
-	Items []OrderItem `json:"items,omitempty"`
+	// ReservedUntil — до какого момента товар неоплаченного заказа зарезервирован; после заказ отменяется
+	ReservedUntil *time.Time `json:"reserved_until,omitempty" db:"reserved_until"`
+
+	Items     []OrderItem     `json:"items,omitempty"`
+	Discounts []OrderDiscount `json:"discounts,omitempty"`
 }
 
 type OrderItem struct {
 	ID        int     `json:"id" db:"id"`
 	OrderID   int     `json:"order_id" db:"order_id"`
 	ProductID int     `json:"product_id" db:"product_id"`
+	VariantID int     `json:"variant_id,omitempty" db:"variant_id"`
+	SKU       string  `json:"sku,omitempty" db:"sku"`
 	Quantity  int     `json:"quantity" db:"quantity"`
 	Price     float64 `json:"price" db:"price"`
+	// Discount — часть скидок заказа, приходящаяся на позицию (на все количество)
+	Discount float64 `json:"discount,omitempty" db:"discount"`
+
+	ProductName string `json:"product_name,omitempty" db:"-"`
+
+	Allocations []OrderAllocation `json:"allocations,omitempty" db:"-"`
+}
+
+// OrderAllocation — сколько штук позиции списано (или, до оплаты, зарезервировано) на конкретном складе
+type OrderAllocation struct {
+	WarehouseID int `json:"warehouse_id" db:"warehouse_id"`
+	Quantity    int `json:"quantity" db:"quantity"`
+}
+
+// OrderStatusHistory — запись о смене статуса заказа.
+// FromStatus пустой для первой записи, ChangedBy пустой для системных переходов.
+type OrderStatusHistory struct {
+	ID         int         `json:"id" db:"id"`
+	OrderID    int         `json:"order_id" db:"order_id"`
+	FromStatus OrderStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   OrderStatus `json:"to_status" db:"to_status"`
+	ChangedBy  *int        `json:"changed_by,omitempty" db:"changed_by"`
+	Comment    string      `json:"comment,omitempty" db:"comment"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+}
+
+// OrderFilter — параметры поиска заказов в админке. Пустые поля не фильтруют.
+type OrderFilter struct {
+	Statuses    []OrderStatus
+	CreatedFrom *time.Time // включительно
+	CreatedTo   *time.Time // не включительно
+	Email       string     // подстрока email покупателя
+	TotalMin    *float64
+	TotalMax    *float64
+	ProductID   int // заказы, в которых есть товар
+}
+
+// AdminOrder — заказ в админке вместе с контактами покупателя
+type AdminOrder struct {
+	Order
+	CustomerEmail string `json:"customer_email" db:"customer_email"`
+	CustomerName  string `json:"customer_name" db:"customer_name"`
+}
+
+// OrderDetails — карточка заказа для оператора: заказ с покупателем, история статусов и внутренние заметки
+type OrderDetails struct {
+	AdminOrder
+	History []*OrderStatusHistory `json:"history"`
+	Notes   []*OrderNote          `json:"notes"`
+}
+
+// OrderNote — внутренняя заметка сотрудника к заказу
+type OrderNote struct {
+	ID         int       `json:"id" db:"id"`
+	OrderID    int       `json:"order_id" db:"order_id"`
+	AuthorID   *int      `json:"author_id,omitempty" db:"author_id"`
+	AuthorName string    `json:"author_name,omitempty" db:"author_name"`
+	Text       string    `json:"text" db:"text"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// BulkStatusResult — результат смены статуса одного заказа в массовой операции; Error пустой при успехе
+type BulkStatusResult struct {
+	OrderID int         `json:"order_id"`
+	Status  OrderStatus `json:"status,omitempty"`
+	Error   string      `json:"error,omitempty"`
 }
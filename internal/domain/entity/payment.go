@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// PaymentStatus — статус платежа у провайдера
+type PaymentStatus string
+
+const (
+	PaymentPending    PaymentStatus = "pending"    // создан, покупатель еще не оплатил
+	PaymentAuthorized PaymentStatus = "authorized" // деньги заблокированы и ждут списания (capture)
+	PaymentSucceeded  PaymentStatus = "succeeded"  // деньги списаны, заказ оплачен
+	PaymentCancelled  PaymentStatus = "cancelled"  // платеж отменен или отклонен
+	PaymentRefunded   PaymentStatus = "refunded"   // деньги возвращены полностью
+)
+
+// IsValid проверяет, что статус известен системе
+func (s PaymentStatus) IsValid() bool {
+	switch s {
+	case PaymentPending, PaymentAuthorized, PaymentSucceeded, PaymentCancelled, PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// PaymentIntent — платеж по заказу у конкретного провайдера.
+// Частичный возврат оставляет статус succeeded и увеличивает RefundedAmount.
+type PaymentIntent struct {
+	ID                int           `json:"id" db:"id"`
+	OrderID           int           `json:"order_id" db:"order_id"`
+	Provider          string        `json:"provider" db:"provider"`
+	ProviderPaymentID string        `json:"provider_payment_id,omitempty" db:"provider_payment_id"`
+	Amount            float64       `json:"amount" db:"amount"`
+	RefundedAmount    float64       `json:"refunded_amount" db:"refunded_amount"`
+	Currency          string        `json:"currency" db:"currency"`
+	Status            PaymentStatus `json:"status" db:"status"`
+	// ConfirmationURL — страница провайдера, на которой покупатель подтверждает оплату
+	ConfirmationURL string    `json:"confirmation_url,omitempty" db:"confirmation_url"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PaymentEvent — уведомление провайдера о смене статуса платежа с уже проверенной подписью
+type PaymentEvent struct {
+	ProviderPaymentID string
+	Status            PaymentStatus
+	Amount            float64
+}
@@ -4,12 +4,96 @@ import "time"
 
 type Product struct {
 	ID          int       `json:"id" db:"id"`
+	SKU         string    `json:"sku,omitempty" db:"sku"`
+	ExternalID  string    `json:"external_id,omitempty" db:"external_id"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
 	Price       float64   `json:"price" db:"price"`
 	Category    string    `json:"category" db:"category"`
+	CategoryID  int       `json:"category_id" db:"category_id"`
 	Stock       int       `json:"stock" db:"stock"`
+	Available   int       `json:"available" db:"-"` // остаток на активных точках без резервов неоплаченных заказов
 	ImageURL    string    `json:"image_url" db:"image_url"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	Variants []ProductVariant `json:"variants,omitempty" db:"-"`
+	Images   []ProductImage   `json:"images,omitempty" db:"-"`
+
+	Attributes []ProductAttribute `json:"attributes,omitempty" db:"-"`
+
+	// Availability — свободный остаток по активным складам и шоурумам; Stock — сумма по всем точкам
+	Availability []StockAvailability `json:"availability,omitempty" db:"-"`
+}
+
+// Variant возвращает вариант товара по ID
+func (p *Product) Variant(id int) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// ProductSearchResult — продукт, найденный полнотекстовым поиском.
+// NameHighlight и Snippet — HTML: текст экранирован, совпадения обернуты в <mark>.
+type ProductSearchResult struct {
+	Product
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+// ProductSort — вариант сортировки каталога
+type ProductSort string
+
+const (
+	ProductSortNewest     ProductSort = "newest"
+	ProductSortPriceAsc   ProductSort = "price_asc"
+	ProductSortPriceDesc  ProductSort = "price_desc"
+	ProductSortName       ProductSort = "name"
+	ProductSortPopularity ProductSort = "popularity"
+)
+
+// IsValid проверяет, что сортировка поддерживается
+func (s ProductSort) IsValid() bool {
+	switch s {
+	case ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortName, ProductSortPopularity:
+		return true
+	}
+	return false
+}
+
+// ProductFilter — параметры фильтрации и сортировки каталога
+type ProductFilter struct {
+	Categories  []string // slug или название категории; подкатегории включаются
+	CategoryIDs []int
+	MinPrice    *float64
+	MaxPrice    *float64
+	InStock     bool
+	Attributes  []AttributeFilter
+	UpdatedFrom *time.Time // изменены не раньше (включительно)
+	UpdatedTo   *time.Time // изменены раньше (не включительно)
+	Sort        ProductSort
+}
+
+// ProductFacets — количество товаров по значениям фильтров для боковой панели витрины.
+// Каждый фасет считается без учета собственного фильтра, чтобы можно было выбрать несколько значений.
+type ProductFacets struct {
+	Categories  []CategoryFacet   `json:"categories"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+}
+
+type CategoryFacet struct {
+	CategoryID int    `json:"category_id"`
+	Category   string `json:"category"`
+	Count      int    `json:"count"`
+}
+
+// PriceRangeFacet — ценовой диапазон [Min, Max); Max = nil для последнего диапазона
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
 }
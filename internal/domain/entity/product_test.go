@@ -0,0 +1,16 @@
+package entity
+
+import "testing"
+
+func TestProductSortIsValid(t *testing.T) {
+	for _, s := range []ProductSort{ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc, ProductSortName, ProductSortPopularity} {
+		if !s.IsValid() {
+			t.Errorf("ProductSort(%q).IsValid() = false, want true", s)
+		}
+	}
+	for _, s := range []ProductSort{"", "price", "NEWEST"} {
+		if s.IsValid() {
+			t.Errorf("ProductSort(%q).IsValid() = true, want false", s)
+		}
+	}
+}
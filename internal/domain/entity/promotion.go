@@ -0,0 +1,199 @@
+package entity
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DiscountType — вид скидки акции
+type DiscountType string
+
+const (
+	DiscountPercent DiscountType = "percent" // процент от суммы подходящих позиций
+	DiscountFixed   DiscountType = "fixed"   // фиксированная сумма на заказ
+)
+
+// IsValid проверяет, что вид скидки поддерживается
+func (t DiscountType) IsValid() bool {
+	return t == DiscountPercent || t == DiscountFixed
+}
+
+// PromotionScope — к каким позициям применяется акция
+type PromotionScope string
+
+const (
+	PromotionScopeCart     PromotionScope = "cart"     // вся корзина
+	PromotionScopeCategory PromotionScope = "category" // товары категории и ее подкатегорий
+	PromotionScopeProduct  PromotionScope = "product"  // один товар (все его варианты)
+)
+
+// IsValid проверяет, что область действия поддерживается
+func (s PromotionScope) IsValid() bool {
+	switch s {
+	case PromotionScopeCart, PromotionScopeCategory, PromotionScopeProduct:
+		return true
+	}
+	return false
+}
+
+// Promotion — акция. Без RequiresCode применяется автоматически, иначе — только по промокоду.
+// Пустые StartsAt/EndsAt не ограничивают срок, нулевые лимиты не ограничивают использование.
+// MinTotal — минимальная сумма подходящих позиций (с учетом скидок других акций).
+type Promotion struct {
+	ID           int            `json:"id" db:"id"`
+	Name         string         `json:"name" db:"name"`
+	Type         DiscountType   `json:"type" db:"discount_type"`
+	Value        float64        `json:"value" db:"value"`
+	Scope        PromotionScope `json:"scope" db:"scope"`
+	CategoryID   int            `json:"category_id,omitempty" db:"category_id"`
+	ProductID    int            `json:"product_id,omitempty" db:"product_id"`
+	MinTotal     float64        `json:"min_total" db:"min_total"`
+	StartsAt     *time.Time     `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt       *time.Time     `json:"ends_at,omitempty" db:"ends_at"`
+	UsageLimit   int            `json:"usage_limit,omitempty" db:"usage_limit"`
+	PerUserLimit int            `json:"per_user_limit,omitempty" db:"per_user_limit"`
+	RequiresCode bool           `json:"requires_code" db:"requires_code"`
+	Active       bool           `json:"active" db:"active"`
+	UsedCount    int            `json:"used_count" db:"-"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+
+	// Categories — категория акции со всеми подкатегориями; заполняется при расчете скидок
+	Categories []int `json:"-" db:"-"`
+	// UserUsedCount — сколько раз акцией воспользовался покупатель, для которого считаются скидки
+	UserUsedCount int `json:"-" db:"-"`
+	// Code — промокод, по которому применяется акция
+	Code *PromoCode `json:"-" db:"-"`
+}
+
+// LimitReached проверяет, исчерпаны ли общий лимит акции, лимит покупателя или лимит промокода
+func (p *Promotion) LimitReached() bool {
+	switch {
+	case p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit:
+		return true
+	case p.PerUserLimit > 0 && p.UserUsedCount >= p.PerUserLimit:
+		return true
+	case p.Code != nil && p.Code.UsageLimit > 0 && p.Code.UsedCount >= p.Code.UsageLimit:
+		return true
+	}
+	return false
+}
+
+// appliesTo проверяет, подходит ли позиция под область действия акции
+func (p *Promotion) appliesTo(line DiscountLine) bool {
+	switch p.Scope {
+	case PromotionScopeProduct:
+		return line.ProductID == p.ProductID
+	case PromotionScopeCategory:
+		return line.CategoryID == p.CategoryID || slices.Contains(p.Categories, line.CategoryID)
+	}
+	return true
+}
+
+// PromoCode — промокод акции; UsageLimit = 1 — одноразовый код
+type PromoCode struct {
+	ID          int       `json:"id" db:"id"`
+	PromotionID int       `json:"promotion_id" db:"promotion_id"`
+	Code        string    `json:"code" db:"code"`
+	UsageLimit  int       `json:"usage_limit,omitempty" db:"usage_limit"`
+	UsedCount   int       `json:"used_count" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// NormalizePromoCode приводит введенный покупателем код к виду, в котором коды хранятся
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// OrderDiscount — скидка одной акции в корзине или заказе
+type OrderDiscount struct {
+	PromotionID int     `json:"promotion_id,omitempty" db:"promotion_id"`
+	PromoCodeID int     `json:"-" db:"promo_code_id"`
+	Name        string  `json:"name" db:"name"`
+	Code        string  `json:"code,omitempty" db:"code"`
+	Amount      float64 `json:"amount" db:"amount"`
+}
+
+// DiscountLine — позиция корзины или заказа при расчете скидок
+type DiscountLine struct {
+	ProductID  int
+	CategoryID int
+	Amount     float64 // стоимость позиции без скидок
+	Discount   float64 // скидка, распределенная на позицию
+}
+
+// ApplyPromotions рассчитывает скидки акций и распределяет их по позициям (lines[i].Discount).
+// Сначала применяются акции на товары и категории, затем на корзину — к сумме, оставшейся после них.
+// Фиксированная скидка дается один раз и делится между подходящими позициями пропорционально их сумме.
+// Скидка не превышает стоимость позиций. Акции, которые ничего не дали (нет подходящих позиций
+// или не набран MinTotal), в результат не попадают.
+func ApplyPromotions(lines []DiscountLine, promotions []*Promotion) []OrderDiscount {
+	ordered := make([]*Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		if p.Scope != PromotionScopeCart {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range promotions {
+		if p.Scope == PromotionScopeCart {
+			ordered = append(ordered, p)
+		}
+	}
+
+	discounts := []OrderDiscount{}
+	for _, p := range ordered {
+		var eligible []int
+		var base float64
+		for i := range lines {
+			if p.appliesTo(lines[i]) {
+				eligible = append(eligible, i)
+				base += lines[i].Amount - lines[i].Discount
+			}
+		}
+		base = roundMoney(base)
+		if base <= 0 || base < p.MinTotal {
+			continue
+		}
+
+		amount := p.Value
+		if p.Type == DiscountPercent {
+			amount = base * p.Value / 100
+		}
+		amount = roundMoney(min(amount, base))
+		if amount <= 0 {
+			continue
+		}
+
+		left := amount
+		for _, i := range eligible {
+			rest := roundMoney(lines[i].Amount - lines[i].Discount)
+			share := min(roundMoney(amount*rest/base), rest, left)
+			lines[i].Discount = roundMoney(lines[i].Discount + share)
+			left = roundMoney(left - share)
+		}
+		// Копейки, потерянные при округлении долей, достаются позициям, с которых еще есть что скинуть
+		for _, i := range eligible {
+			if left <= 0 {
+				break
+			}
+			share := min(roundMoney(lines[i].Amount-lines[i].Discount), left)
+			lines[i].Discount = roundMoney(lines[i].Discount + share)
+			left = roundMoney(left - share)
+		}
+
+		discount := OrderDiscount{PromotionID: p.ID, Name: p.Name, Amount: amount}
+		if p.Code != nil {
+			discount.PromoCodeID = p.Code.ID
+			discount.Code = p.Code.Code
+		}
+		discounts = append(discounts, discount)
+	}
+	return discounts
+}
+
+// roundMoney округляет сумму до копеек
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,121 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyPromotions(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []DiscountLine
+		promotions    []*Promotion
+		wantDiscounts []OrderDiscount
+		wantLines     []float64
+	}{
+		{
+			name:  "percent on cart is split by line amount",
+			lines: []DiscountLine{{ProductID: 1, Amount: 100}, {ProductID: 2, Amount: 50}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "-10%", Type: DiscountPercent, Value: 10, Scope: PromotionScopeCart},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, Name: "-10%", Amount: 15}},
+			wantLines:     []float64{10, 5},
+		},
+		{
+			name:  "fixed discount gives rounding remainder to the first line",
+			lines: []DiscountLine{{ProductID: 1, Amount: 10}, {ProductID: 2, Amount: 10}, {ProductID: 3, Amount: 10}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "-10", Type: DiscountFixed, Value: 10, Scope: PromotionScopeCart},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, Name: "-10", Amount: 10}},
+			wantLines:     []float64{3.34, 3.33, 3.33},
+		},
+		{
+			name:  "fixed discount does not exceed the eligible amount",
+			lines: []DiscountLine{{ProductID: 1, Amount: 5}, {ProductID: 2, Amount: 40}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "-10", Type: DiscountFixed, Value: 10, Scope: PromotionScopeProduct, ProductID: 1},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, Name: "-10", Amount: 5}},
+			wantLines:     []float64{5, 0},
+		},
+		{
+			name: "category scope includes subcategories only",
+			lines: []DiscountLine{
+				{ProductID: 1, CategoryID: 3, Amount: 100},
+				{ProductID: 2, CategoryID: 4, Amount: 200},
+				{ProductID: 3, CategoryID: 5, Amount: 300},
+			},
+			promotions: []*Promotion{
+				{ID: 1, Name: "sofas", Type: DiscountPercent, Value: 10, Scope: PromotionScopeCategory, CategoryID: 3, Categories: []int{3, 4}},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, Name: "sofas", Amount: 30}},
+			wantLines:     []float64{10, 20, 0},
+		},
+		{
+			name:  "product promotions apply before cart promotions",
+			lines: []DiscountLine{{ProductID: 1, Amount: 100}, {ProductID: 2, Amount: 100}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "cart", Type: DiscountPercent, Value: 10, Scope: PromotionScopeCart},
+				{ID: 2, Name: "product", Type: DiscountFixed, Value: 20, Scope: PromotionScopeProduct, ProductID: 1},
+			},
+			wantDiscounts: []OrderDiscount{
+				{PromotionID: 2, Name: "product", Amount: 20},
+				{PromotionID: 1, Name: "cart", Amount: 18},
+			},
+			wantLines: []float64{28, 10},
+		},
+		{
+			name:  "min total is checked against the amount left after earlier promotions",
+			lines: []DiscountLine{{ProductID: 1, Amount: 100}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "product", Type: DiscountFixed, Value: 20, Scope: PromotionScopeProduct, ProductID: 1},
+				{ID: 2, Name: "cart", Type: DiscountFixed, Value: 5, Scope: PromotionScopeCart, MinTotal: 90},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, Name: "product", Amount: 20}},
+			wantLines:     []float64{20},
+		},
+		{
+			name:  "promotion without eligible lines is skipped",
+			lines: []DiscountLine{{ProductID: 1, CategoryID: 3, Amount: 100}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "other", Type: DiscountPercent, Value: 50, Scope: PromotionScopeProduct, ProductID: 2},
+			},
+			wantDiscounts: []OrderDiscount{},
+			wantLines:     []float64{0},
+		},
+		{
+			name:  "promo code is recorded in the discount",
+			lines: []DiscountLine{{ProductID: 1, Amount: 33.33}},
+			promotions: []*Promotion{
+				{ID: 1, Name: "code", Type: DiscountPercent, Value: 15, Scope: PromotionScopeCart, Code: &PromoCode{ID: 7, Code: "SOFA15"}},
+			},
+			wantDiscounts: []OrderDiscount{{PromotionID: 1, PromoCodeID: 7, Name: "code", Code: "SOFA15", Amount: 5}},
+			wantLines:     []float64{5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discounts := ApplyPromotions(tt.lines, tt.promotions)
+			if !reflect.DeepEqual(discounts, tt.wantDiscounts) {
+				t.Errorf("discounts = %+v, want %+v", discounts, tt.wantDiscounts)
+			}
+
+			lineDiscounts := make([]float64, len(tt.lines))
+			for i, line := range tt.lines {
+				lineDiscounts[i] = line.Discount
+			}
+			if !reflect.DeepEqual(lineDiscounts, tt.wantLines) {
+				t.Errorf("line discounts = %v, want %v", lineDiscounts, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestNormalizePromoCode(t *testing.T) {
+	if got := NormalizePromoCode("  sofa15 "); got != "SOFA15" {
+		t.Errorf("NormalizePromoCode() = %q, want %q", got, "SOFA15")
+	}
+}
@@ -0,0 +1,84 @@
+package entity
+
+import "time"
+
+// ReturnStatus — этап возврата
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested" // покупатель запросил возврат
+	ReturnApproved  ReturnStatus = "approved"  // одобрен, товар оприходован, деньги еще возвращаются
+	ReturnRejected  ReturnStatus = "rejected"  // отклонен поддержкой
+	ReturnCompleted ReturnStatus = "completed" // деньги возвращены, возврат закрыт
+)
+
+// IsValid проверяет, что статус известен системе
+func (s ReturnStatus) IsValid() bool {
+	switch s {
+	case ReturnRequested, ReturnApproved, ReturnRejected, ReturnCompleted:
+		return true
+	}
+	return false
+}
+
+// ReturnReason — причина возврата
+type ReturnReason string
+
+const (
+	ReturnDefective      ReturnReason = "defective"        // брак
+	ReturnDamaged        ReturnReason = "damaged"          // повреждено при доставке
+	ReturnWrongItem      ReturnReason = "wrong_item"       // привезли не тот товар
+	ReturnNotAsDescribed ReturnReason = "not_as_described" // не соответствует описанию
+	ReturnChangedMind    ReturnReason = "changed_mind"     // передумал
+	ReturnOther          ReturnReason = "other"
+)
+
+// IsValid проверяет, что причина поддерживается
+func (r ReturnReason) IsValid() bool {
+	switch r {
+	case ReturnDefective, ReturnDamaged, ReturnWrongItem, ReturnNotAsDescribed, ReturnChangedMind, ReturnOther:
+		return true
+	}
+	return false
+}
+
+// OrderReturn — возврат (RMA) доставленного заказа.
+// RefundAmount до одобрения — стоимость возвращаемых позиций за вычетом их скидок, после — сумма, решенная поддержкой.
+type OrderReturn struct {
+	ID                 int          `json:"id" db:"id"`
+	OrderID            int          `json:"order_id" db:"order_id"`
+	UserID             int          `json:"user_id" db:"user_id"`
+	Status             ReturnStatus `json:"status" db:"status"`
+	Reason             ReturnReason `json:"reason" db:"reason"`
+	Comment            string       `json:"comment,omitempty" db:"comment"`
+	RefundAmount       float64      `json:"refund_amount" db:"refund_amount"`
+	RestockWarehouseID int          `json:"restock_warehouse_id,omitempty" db:"restock_warehouse_id"`
+	ResolvedBy         *int         `json:"resolved_by,omitempty" db:"resolved_by"`
+	ResolutionComment  string       `json:"resolution_comment,omitempty" db:"resolution_comment"`
+	ResolvedAt         *time.Time   `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedAt          time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at" db:"updated_at"`
+
+	Items []OrderReturnItem `json:"items"`
+}
+
+// OrderReturnItem — возвращаемое количество позиции заказа. Restock — решение вернуть товар на склад
+// (брак и поврежденный товар обычно не возвращают).
+type OrderReturnItem struct {
+	ID          int     `json:"id" db:"id"`
+	OrderItemID int     `json:"order_item_id" db:"order_item_id"`
+	ProductID   int     `json:"product_id" db:"product_id"`
+	VariantID   int     `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity    int     `json:"quantity" db:"quantity"`
+	Price       float64 `json:"price" db:"price"`
+	Restock     bool    `json:"restock" db:"restock"`
+}
+
+// ReturnDecision — решение поддержки по возврату: сумма к возврату (nil — стоимость позиций),
+// позиции заказа (order_item_id), которые возвращаются на склад, и склад для них (0 — основной)
+type ReturnDecision struct {
+	RefundAmount *float64
+	RestockItems []int
+	WarehouseID  int
+	Comment      string
+}
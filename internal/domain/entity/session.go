@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// Session — сессия пользователя на одном устройстве, к ней привязаны access и refresh токены
+type Session struct {
+	ID               string    `json:"id" db:"id"`
+	UserID           int       `json:"user_id" db:"user_id"`
+	RefreshTokenHash string    `json:"-" db:"refresh_token_hash"`
+	UserAgent        string    `json:"user_agent,omitempty" db:"user_agent"`
+	ExpiresAt        time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
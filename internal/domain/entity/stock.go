@@ -0,0 +1,92 @@
+package entity
+
+import "time"
+
+// StockReason — причина движения остатка
+type StockReason string
+
+const (
+	StockReceipt    StockReason = "receipt"    // поступление на склад
+	StockSale       StockReason = "sale"       // продажа (списание под заказ)
+	StockReturn     StockReason = "return"     // возврат (в том числе отмена заказа)
+	StockAdjustment StockReason = "adjustment" // корректировка по инвентаризации
+	StockWriteOff   StockReason = "write_off"  // списание брака, потерь
+)
+
+// IsValid проверяет, что причина поддерживается
+func (r StockReason) IsValid() bool {
+	switch r {
+	case StockReceipt, StockSale, StockReturn, StockAdjustment, StockWriteOff:
+		return true
+	}
+	return false
+}
+
+// Sign возвращает знак изменения остатка для причины; 0 — знак задает сама корректировка
+func (r StockReason) Sign() int {
+	switch r {
+	case StockReceipt, StockReturn:
+		return 1
+	case StockSale, StockWriteOff:
+		return -1
+	}
+	return 0
+}
+
+// StockMovement — запись журнала остатков. Quantity — изменение со знаком, Balance — остаток на складе после движения.
+type StockMovement struct {
+	ID          int         `json:"id" db:"id"`
+	ProductID   int         `json:"product_id" db:"product_id"`
+	VariantID   int         `json:"variant_id,omitempty" db:"variant_id"`
+	WarehouseID int         `json:"warehouse_id" db:"warehouse_id"`
+	Quantity    int         `json:"quantity" db:"quantity"`
+	Reason      StockReason `json:"reason" db:"reason"`
+	Balance     int         `json:"balance" db:"balance"`
+	UserID      int         `json:"user_id,omitempty" db:"user_id"`
+	Reference   string      `json:"reference,omitempty" db:"reference"`
+	Comment     string      `json:"comment,omitempty" db:"comment"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+}
+
+// StockDiscrepancy — расхождение остатка товара (или варианта) на складе с суммой его движений в журнале
+type StockDiscrepancy struct {
+	WarehouseID int    `json:"warehouse_id"`
+	ProductID   int    `json:"product_id"`
+	VariantID   int    `json:"variant_id,omitempty"`
+	ProductName string `json:"product_name"`
+	SKU         string `json:"sku,omitempty"`
+	Stock       int    `json:"stock"`
+	Ledger      int    `json:"ledger"`
+}
+
+// ReservationStatus — состояние резерва остатка под заказ
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"    // товар удерживается до оплаты
+	ReservationConverted ReservationStatus = "converted" // заказ оплачен, резерв списан со склада
+	ReservationReleased  ReservationStatus = "released"  // резерв снят: заказ отменен или срок истек
+)
+
+// LowStockProduct — товар, чей остаток опустился ниже порога
+type LowStockProduct struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku,omitempty"`
+	Stock     int    `json:"stock"`
+	Threshold int    `json:"threshold"`
+}
+
+// StockSubscription — подписка пользователя на поступление товара (или варианта).
+// NotifiedAt пустой, пока товар не появился в наличии.
+type StockSubscription struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	ProductID  int        `json:"product_id" db:"product_id"`
+	VariantID  int        `json:"variant_id,omitempty" db:"variant_id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty" db:"notified_at"`
+
+	Email       string `json:"-" db:"-"`
+	ProductName string `json:"-" db:"-"`
+}
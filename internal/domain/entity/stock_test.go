@@ -0,0 +1,21 @@
+package entity
+
+import "testing"
+
+func TestStockReasonSign(t *testing.T) {
+	tests := map[StockReason]int{
+		StockReceipt:    1,
+		StockReturn:     1,
+		StockSale:       -1,
+		StockWriteOff:   -1,
+		StockAdjustment: 0,
+	}
+	for reason, want := range tests {
+		if !reason.IsValid() {
+			t.Errorf("StockReason(%q).IsValid() = false, want true", reason)
+		}
+		if got := reason.Sign(); got != want {
+			t.Errorf("StockReason(%q).Sign() = %d, want %d", reason, got, want)
+		}
+	}
+}
@@ -6,6 +6,15 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Роли пользователей. Права ролей описаны в auth.DefaultRolePermissions.
+const (
+	RoleCustomer  = "customer"
+	RoleAdmin     = "admin"
+	RoleManager   = "manager"
+	RoleWarehouse = "warehouse"
+	RoleSupport   = "support"
+)
+
 type User struct {
 	ID        int       `json:"id" db:"id"`
 	Email     string    `json:"email" db:"email"`
@@ -14,6 +23,16 @@ type User struct {
 	Role      string    `json:"role" db:"role"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Disabled   bool       `json:"disabled" db:"disabled"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+}
+
+// UserFilter — параметры поиска пользователей в админке
+type UserFilter struct {
+	Query    string // подстрока email или имени
+	Role     string
+	Disabled *bool
 }
 
 func (u *User) HashPassword() error {
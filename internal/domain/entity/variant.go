@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// ProductVariant — вариант исполнения товара (цвет, обивка, размер) со своим SKU, ценой и остатком
+type ProductVariant struct {
+	ID        int               `json:"id" db:"id"`
+	ProductID int               `json:"product_id" db:"product_id"`
+	SKU       string            `json:"sku" db:"sku"`
+	Options   map[string]string `json:"options" db:"options"`
+	Price     *float64          `json:"price,omitempty" db:"price"` // nil — действует цена товара
+	Stock     int               `json:"stock" db:"stock"`
+	Available int               `json:"available" db:"-"` // остаток на активных точках без резервов неоплаченных заказов
+	Images    []string          `json:"images" db:"images"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+
+	Availability []StockAvailability `json:"availability,omitempty" db:"-"`
+}
+
+// EffectivePrice возвращает цену варианта с учетом цены товара
+func (v *ProductVariant) EffectivePrice(productPrice float64) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return productPrice
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "testing"
+
+func TestProductVariantEffectivePrice(t *testing.T) {
+	price := 120.0
+	if got := (&ProductVariant{Price: &price}).EffectivePrice(100); got != 120 {
+		t.Errorf("EffectivePrice() with own price = %v, want 120", got)
+	}
+	if got := (&ProductVariant{}).EffectivePrice(100); got != 100 {
+		t.Errorf("EffectivePrice() without own price = %v, want 100", got)
+	}
+}
+
+func TestProductVariant(t *testing.T) {
+	product := &Product{Variants: []ProductVariant{{ID: 1, SKU: "A"}, {ID: 2, SKU: "B"}}}
+
+	if v := product.Variant(2); v == nil || v.SKU != "B" {
+		t.Errorf("Variant(2) = %+v, want SKU B", v)
+	}
+	if v := product.Variant(3); v != nil {
+		t.Errorf("Variant(3) = %+v, want nil", v)
+	}
+	// Возвращается указатель на элемент среза, а не копия
+	product.Variant(1).Stock = 5
+	if product.Variants[0].Stock != 5 {
+		t.Error("Variant() returned a copy")
+	}
+}
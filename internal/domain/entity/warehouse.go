@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// WarehouseType — вид точки хранения
+type WarehouseType string
+
+const (
+	WarehouseTypeWarehouse WarehouseType = "warehouse" // склад: отгружает заказы с доставкой
+	WarehouseTypeShowroom  WarehouseType = "showroom"  // шоурум: товар можно посмотреть и забрать самовывозом
+)
+
+// IsValid проверяет, что вид точки поддерживается
+func (t WarehouseType) IsValid() bool {
+	return t == WarehouseTypeWarehouse || t == WarehouseTypeShowroom
+}
+
+// Warehouse — склад или шоурум со своими остатками.
+// Pickup разрешает самовывоз; Priority — порядок списания под доставку (меньше — раньше).
+type Warehouse struct {
+	ID        int           `json:"id" db:"id"`
+	Code      string        `json:"code" db:"code"`
+	Name      string        `json:"name" db:"name"`
+	Type      WarehouseType `json:"type" db:"type"`
+	Address   string        `json:"address" db:"address"`
+	Pickup    bool          `json:"pickup" db:"pickup"`
+	Active    bool          `json:"active" db:"active"`
+	Priority  int           `json:"priority" db:"priority"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// StockAvailability — наличие товара (или варианта) в одной точке
+type StockAvailability struct {
+	WarehouseID int           `json:"warehouse_id"`
+	Name        string        `json:"name"`
+	Type        WarehouseType `json:"type"`
+	Pickup      bool          `json:"pickup"`
+	Stock       int           `json:"stock"`
+}
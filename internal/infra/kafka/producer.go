@@ -11,10 +11,16 @@ import (
 type EventType string
 
 const (
-	EventOrderCreated   EventType = "order.created"
-	EventOrderPaid      EventType = "order.paid"
-	EventOrderShipped   EventType = "order.shipped"
-	EventUserRegistered EventType = "user.registered"
+	EventOrderCreated       EventType = "order.created"
+	EventOrderPaid          EventType = "order.paid"
+	EventOrderShipped       EventType = "order.shipped"
+	EventOrderDelivered     EventType = "order.delivered"
+	EventOrderCancelled     EventType = "order.cancelled"
+	EventOrderReturned      EventType = "order.returned"
+	EventPaymentRefunded    EventType = "payment.refunded"
+	EventProductLowStock    EventType = "product.low_stock"
+	EventProductBackInStock EventType = "product.back_in_stock"
+	EventUserRegistered     EventType = "user.registered"
 )
 
 type Event struct {
@@ -0,0 +1,121 @@
+// Package payment содержит адаптеры платежных систем. Каждый адаптер реализует service.PaymentProvider
+// и принимает вебхуки по адресу /api/payments/webhook/{Name()}.
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+// FakeSignatureHeader — заголовок с подписью вебхука фейкового провайдера
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// minFakeSecretLength — минимальная длина ключа подписи фейкового провайдера
+const minFakeSecretLength = 32
+
+// knownFakeSecrets — ключи, которые когда-либо лежали в репозитории и потому известны всем
+var knownFakeSecrets = []string{"fake-webhook-secret"}
+
+// FakeProvider — встроенный провайдер для локальной разработки и тестов: во внешнюю систему ничего не уходит.
+// Платеж создается сразу, а оплату имитирует вебхук
+//
+//	{"payment_id": "fake_...", "status": "succeeded", "amount": 15990}
+//
+// с HMAC-SHA256 тела (hex) в заголовке X-Fake-Signature. Статус authorized имитирует двухстадийную
+// оплату: магазин сам вызывает Capture, и фейковый провайдер списывает деньги сразу.
+//
+// Любой, кто знает ключ, может отметить заказ оплаченным, поэтому провайдер подключается только
+// явным флагом payments.fake_enabled и не запускается с пустым, коротким или известным ключом.
+type FakeProvider struct {
+	secret    []byte
+	returnURL string
+}
+
+func NewFakeProvider(secret, returnURL string) (*FakeProvider, error) {
+	if len(secret) < minFakeSecretLength {
+		return nil, fmt.Errorf("fake payment provider: secret must be at least %d characters", minFakeSecretLength)
+	}
+	for _, known := range knownFakeSecrets {
+		if secret == known {
+			return nil, fmt.Errorf("fake payment provider: secret is publicly known, set a random one")
+		}
+	}
+	return &FakeProvider{secret: []byte(secret), returnURL: returnURL}, nil
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// CreatePayment выдает платежу идентификатор; ConfirmationURL сразу ведет на страницу возврата
+func (p *FakeProvider) CreatePayment(ctx context.Context, intent *entity.PaymentIntent) error {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("generate fake payment id: %w", err)
+	}
+
+	intent.ProviderPaymentID = "fake_" + hex.EncodeToString(buf)
+	intent.Status = entity.PaymentPending
+	if p.returnURL != "" {
+		intent.ConfirmationURL = p.returnURL + "?payment_id=" + url.QueryEscape(intent.ProviderPaymentID)
+	}
+	return nil
+}
+
+// Capture списывает заблокированные деньги без ожидания следующего вебхука
+func (p *FakeProvider) Capture(ctx context.Context, intent *entity.PaymentIntent) error {
+	intent.Status = entity.PaymentSucceeded
+	return nil
+}
+
+// Refund ничего не делает: сумму возврата проверяет сервис платежей
+func (p *FakeProvider) Refund(ctx context.Context, intent *entity.PaymentIntent, amount float64) error {
+	return nil
+}
+
+// ParseWebhook проверяет подпись X-Fake-Signature и разбирает уведомление
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*entity.PaymentEvent, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return nil, apperrors.ErrInvalidSignature
+	}
+
+	var payload struct {
+		PaymentID string               `json:"payment_id"`
+		Status    entity.PaymentStatus `json:"status"`
+		Amount    float64              `json:"amount"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidPaymentEvent, err)
+	}
+	if payload.PaymentID == "" || !payload.Status.IsValid() {
+		return nil, fmt.Errorf("%w: payment_id and known status are required", apperrors.ErrInvalidPaymentEvent)
+	}
+
+	return &entity.PaymentEvent{
+		ProviderPaymentID: payload.PaymentID,
+		Status:            payload.Status,
+		Amount:            payload.Amount,
+	}, nil
+}
+
+// Sign возвращает подпись тела вебхука для заголовка X-Fake-Signature
+func (p *FakeProvider) Sign(body []byte) string {
+	return hex.EncodeToString(p.sign(body))
+}
+
+func (p *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
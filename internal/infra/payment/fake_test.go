@@ -0,0 +1,90 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestNewFakeProviderRejectsWeakSecret(t *testing.T) {
+	for _, secret := range []string{"", "short", "fake-webhook-secret"} {
+		if _, err := NewFakeProvider(secret, ""); err == nil {
+			t.Errorf("NewFakeProvider(%q) error = nil, want error", secret)
+		}
+	}
+	if _, err := NewFakeProvider(testSecret, ""); err != nil {
+		t.Errorf("NewFakeProvider() error = %v", err)
+	}
+}
+
+func TestFakeProviderParseWebhook(t *testing.T) {
+	provider, err := NewFakeProvider(testSecret, "")
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+	body := []byte(`{"payment_id": "fake_1", "status": "succeeded", "amount": 15990}`)
+	signed := func(body []byte) http.Header {
+		return http.Header{FakeSignatureHeader: []string{provider.Sign(body)}}
+	}
+
+	event, err := provider.ParseWebhook(signed(body), body)
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	want := entity.PaymentEvent{ProviderPaymentID: "fake_1", Status: entity.PaymentSucceeded, Amount: 15990}
+	if *event != want {
+		t.Errorf("ParseWebhook() = %+v, want %+v", *event, want)
+	}
+
+	other, err := NewFakeProvider(strings.Repeat("x", minFakeSecretLength), "")
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+	tampered := []byte(`{"payment_id": "fake_1", "status": "succeeded", "amount": 1}`)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{name: "no signature", header: http.Header{}, body: body, want: apperrors.ErrInvalidSignature},
+		{name: "signature is not hex", header: http.Header{FakeSignatureHeader: []string{"zz"}}, body: body, want: apperrors.ErrInvalidSignature},
+		{name: "tampered body", header: signed(body), body: tampered, want: apperrors.ErrInvalidSignature},
+		{name: "signed with other secret", header: http.Header{FakeSignatureHeader: []string{other.Sign(body)}}, body: body, want: apperrors.ErrInvalidSignature},
+		{name: "invalid json", header: signed([]byte(`{`)), body: []byte(`{`), want: apperrors.ErrInvalidPaymentEvent},
+		{name: "unknown status", header: signed([]byte(`{"payment_id": "fake_1", "status": "paid"}`)), body: []byte(`{"payment_id": "fake_1", "status": "paid"}`), want: apperrors.ErrInvalidPaymentEvent},
+		{name: "no payment id", header: signed([]byte(`{"status": "succeeded"}`)), body: []byte(`{"status": "succeeded"}`), want: apperrors.ErrInvalidPaymentEvent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := provider.ParseWebhook(tt.header, tt.body); !apperrors.Is(err, tt.want) {
+				t.Errorf("ParseWebhook() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeProviderCreatePayment(t *testing.T) {
+	provider, err := NewFakeProvider(testSecret, "http://localhost:3000/orders")
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+	intent := &entity.PaymentIntent{OrderID: 1, Amount: 100}
+	if err := provider.CreatePayment(context.Background(), intent); err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	if !strings.HasPrefix(intent.ProviderPaymentID, "fake_") || intent.Status != entity.PaymentPending {
+		t.Errorf("CreatePayment() = %+v, want pending fake_ payment", intent)
+	}
+	if want := "http://localhost:3000/orders?payment_id=" + intent.ProviderPaymentID; intent.ConfirmationURL != want {
+		t.Errorf("ConfirmationURL = %q, want %q", intent.ConfirmationURL, want)
+	}
+}
@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+type AttributeRepo struct {
+	db *sql.DB
+}
+
+func NewAttributeRepo(db *sql.DB) *AttributeRepo {
+	return &AttributeRepo{db: db}
+}
+
+// List возвращает все определения характеристик
+func (r *AttributeRepo) List(ctx context.Context) ([]*entity.Attribute, error) {
+	query := `
+		SELECT id, code, name, type, unit, min_value, max_value, options, created_at, updated_at
+		FROM attributes
+		ORDER BY name, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list attributes: %w", err)
+	}
+	defer rows.Close()
+
+	attributes := make([]*entity.Attribute, 0)
+	for rows.Next() {
+		var a entity.Attribute
+		err := rows.Scan(&a.ID, &a.Code, &a.Name, &a.Type, &a.Unit, &a.Min, &a.Max,
+			pq.Array(&a.Options), &a.CreatedAt, &a.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan attribute: %w", err)
+		}
+		attributes = append(attributes, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return attributes, nil
+}
+
+// Create создает определение характеристики
+func (r *AttributeRepo) Create(ctx context.Context, a *entity.Attribute) error {
+	query := `
+		INSERT INTO attributes (code, name, type, unit, min_value, max_value, options)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		a.Code, a.Name, a.Type, a.Unit, a.Min, a.Max, pq.Array(a.Options),
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return attributeError("create attribute", err)
+	}
+	return nil
+}
+
+// Update обновляет определение характеристики. Тип и код не меняются,
+// чтобы не переписывать уже сохраненные значения товаров.
+func (r *AttributeRepo) Update(ctx context.Context, a *entity.Attribute) error {
+	query := `
+		UPDATE attributes
+		SET name = $1, unit = $2, min_value = $3, max_value = $4, options = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING code, type, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		a.Name, a.Unit, a.Min, a.Max, pq.Array(a.Options), a.ID,
+	).Scan(&a.Code, &a.Type, &a.CreatedAt, &a.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrAttributeNotFound
+	}
+	if err != nil {
+		return attributeError("update attribute", err)
+	}
+	return nil
+}
+
+// Delete удаляет характеристику, если ни у одного товара нет ее значения
+func (r *AttributeRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM attributes WHERE id = $1`, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+			return apperrors.ErrAttributeInUse
+		}
+		return fmt.Errorf("delete attribute: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete attribute - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.ErrAttributeNotFound
+	}
+	return nil
+}
+
+// ForCategory возвращает характеристики категории вместе с унаследованными от родителей.
+// Если характеристика привязана на нескольких уровнях, действует ближайшая привязка.
+func (r *AttributeRepo) ForCategory(ctx context.Context, categoryID int) ([]entity.CategoryAttribute, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 0 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, a.depth + 1 FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id, code, name, type, unit, min_value, max_value, options, created_at, updated_at,
+		       category_id, required, sort_order
+		FROM (
+			SELECT DISTINCT ON (at.id)
+			       at.id, at.code, at.name, at.type, at.unit, at.min_value, at.max_value, at.options,
+			       at.created_at, at.updated_at, ca.category_id, ca.required, ca.sort_order
+			FROM category_attributes ca
+			JOIN ancestors an ON an.id = ca.category_id
+			JOIN attributes at ON at.id = ca.attribute_id
+			ORDER BY at.id, an.depth
+		) bound
+		ORDER BY sort_order, name, id`
+
+	rows, err := r.db.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("list category attributes: %w", err)
+	}
+	defer rows.Close()
+
+	attributes := []entity.CategoryAttribute{}
+	for rows.Next() {
+		var a entity.CategoryAttribute
+		err := rows.Scan(&a.ID, &a.Code, &a.Name, &a.Type, &a.Unit, &a.Min, &a.Max,
+			pq.Array(&a.Options), &a.CreatedAt, &a.UpdatedAt,
+			&a.CategoryID, &a.Required, &a.SortOrder)
+		if err != nil {
+			return nil, fmt.Errorf("scan category attribute: %w", err)
+		}
+		attributes = append(attributes, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return attributes, nil
+}
+
+// SetCategoryAttributes заменяет прямые привязки характеристик к категории
+func (r *AttributeRepo) SetCategoryAttributes(ctx context.Context, categoryID int, bindings []entity.CategoryAttribute) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("set category attributes - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM category_attributes WHERE category_id = $1`, categoryID); err != nil {
+		return fmt.Errorf("set category attributes - clear: %w", err)
+	}
+
+	for _, b := range bindings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO category_attributes (category_id, attribute_id, required, sort_order)
+			VALUES ($1, $2, $3, $4)`,
+			categoryID, b.ID, b.Required, b.SortOrder,
+		)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) {
+				switch {
+				case pqErr.Code == pgForeignKeyViolation && pqErr.Constraint == "category_attributes_category_id_fkey":
+					return apperrors.ErrCategoryNotFound
+				case pqErr.Code == pgForeignKeyViolation:
+					return fmt.Errorf("%w: %d", apperrors.ErrAttributeNotFound, b.ID)
+				case pqErr.Code == pgUniqueViolation:
+					return fmt.Errorf("%w: attribute %d listed twice", apperrors.ErrInvalidAttribute, b.ID)
+				}
+			}
+			return fmt.Errorf("set category attributes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("set category attributes - commit: %w", err)
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// productAttributeValues возвращает значения характеристик товара в порядке отображения его категории
+func productAttributeValues(ctx context.Context, db queryer, productID int) ([]entity.ProductAttribute, error) {
+	query := `
+		SELECT a.id, a.code, a.name, a.type, a.unit, v.num_value, COALESCE(v.text_value, '')
+		FROM product_attribute_values v
+		JOIN attributes a ON a.id = v.attribute_id
+		JOIN products p ON p.id = v.product_id
+		LEFT JOIN category_attributes ca ON ca.attribute_id = a.id AND ca.category_id = p.category_id
+		WHERE v.product_id = $1
+		ORDER BY COALESCE(ca.sort_order, 0), a.name, a.id`
+
+	rows, err := db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("list product attributes: %w", err)
+	}
+	defer rows.Close()
+
+	values := []entity.ProductAttribute{}
+	for rows.Next() {
+		var v entity.ProductAttribute
+		if err := rows.Scan(&v.AttributeID, &v.Code, &v.Name, &v.Type, &v.Unit, &v.Number, &v.Value); err != nil {
+			return nil, fmt.Errorf("scan product attribute: %w", err)
+		}
+		values = append(values, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return values, nil
+}
+
+// replaceAttributeValues заменяет значения характеристик товара; значения уже проверены сервисом
+func replaceAttributeValues(ctx context.Context, tx *sql.Tx, productID int, values []entity.ProductAttribute) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_attribute_values WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("clear product attributes: %w", err)
+	}
+
+	for _, v := range values {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO product_attribute_values (product_id, attribute_id, num_value, text_value)
+			VALUES ($1, $2, $3, NULLIF($4, ''))`,
+			productID, v.AttributeID, v.Number, v.Value,
+		)
+		if err != nil {
+			return fmt.Errorf("insert product attribute: %w", err)
+		}
+	}
+	return nil
+}
+
+// attributeError переводит нарушения ограничений при записи характеристики в доменные ошибки
+func attributeError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return apperrors.ErrAttributeExists
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestProductFilterClauseAttributes(t *testing.T) {
+	minWidth := 100.0
+	where, args := productFilterClause(entity.ProductFilter{Attributes: []entity.AttributeFilter{
+		{Code: "width", Min: &minWidth},
+		{Code: "material", Values: []string{"Дуб", "Бук"}},
+	}})
+
+	if len(args) != 4 {
+		t.Fatalf("productFilterClause() args = %d, want 4", len(args))
+	}
+	for _, part := range []string{"a.code = $1 AND v.num_value >= $2", "a.code = $3 AND v.text_value = ANY($4)"} {
+		if !strings.Contains(where, part) {
+			t.Errorf("productFilterClause() where = %q, want it to contain %q", where, part)
+		}
+	}
+	// Каждая характеристика проверяется своим EXISTS, условия разных характеристик не смешиваются
+	if got := strings.Count(where, "EXISTS ("); got != 2 {
+		t.Errorf("productFilterClause() has %d EXISTS, want 2", got)
+	}
+}
+
+func TestAttributeRepoCategoryInheritanceAndFilter(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	attributes := NewAttributeRepo(db)
+	categories := NewCategoryRepo(db)
+	products := NewProductRepo(db)
+
+	width := &entity.Attribute{Code: "width", Name: "Ширина", Type: entity.AttributeTypeNumber, Unit: "см"}
+	material := &entity.Attribute{Code: "material", Name: "Материал", Type: entity.AttributeTypeEnum, Options: []string{"Дуб", "Бук"}}
+	for _, a := range []*entity.Attribute{width, material} {
+		if err := attributes.Create(ctx, a); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	furniture := &entity.Category{Name: "Мебель", Slug: "mebel"}
+	if err := categories.Create(ctx, furniture); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tables := &entity.Category{ParentID: &furniture.ID, Name: "Столы", Slug: "stoly"}
+	if err := categories.Create(ctx, tables); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := attributes.SetCategoryAttributes(ctx, furniture.ID, []entity.CategoryAttribute{
+		{Attribute: entity.Attribute{ID: width.ID}, SortOrder: 2},
+		{Attribute: entity.Attribute{ID: material.ID}, SortOrder: 1},
+	}); err != nil {
+		t.Fatalf("SetCategoryAttributes(parent) error = %v", err)
+	}
+	// Подкатегория переопределяет привязку ширины: у столов она обязательна
+	if err := attributes.SetCategoryAttributes(ctx, tables.ID, []entity.CategoryAttribute{
+		{Attribute: entity.Attribute{ID: width.ID}, Required: true, SortOrder: 0},
+	}); err != nil {
+		t.Fatalf("SetCategoryAttributes(child) error = %v", err)
+	}
+
+	defs, err := attributes.ForCategory(ctx, tables.ID)
+	if err != nil {
+		t.Fatalf("ForCategory() error = %v", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("ForCategory() returned %d attributes, want 2", len(defs))
+	}
+	if defs[0].Code != "width" || !defs[0].Required || defs[0].CategoryID != tables.ID {
+		t.Errorf("ForCategory()[0] = %+v, want width bound on the child as required", defs[0])
+	}
+	if defs[1].Code != "material" || defs[1].CategoryID != furniture.ID {
+		t.Errorf("ForCategory()[1] = %+v, want material inherited from the parent", defs[1])
+	}
+
+	number := func(v float64) *float64 { return &v }
+	wide := &entity.Product{Name: "Большой стол", Price: 100, Category: tables.Name, CategoryID: tables.ID, Attributes: []entity.ProductAttribute{
+		{AttributeID: width.ID, Number: number(180)}, {AttributeID: material.ID, Value: "Дуб"},
+	}}
+	narrow := &entity.Product{Name: "Малый стол", Price: 100, Category: tables.Name, CategoryID: tables.ID, Attributes: []entity.ProductAttribute{
+		{AttributeID: width.ID, Number: number(80)}, {AttributeID: material.ID, Value: "Дуб"},
+	}}
+	for _, p := range []*entity.Product{wide, narrow} {
+		if err := products.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	list, err := products.List(ctx, entity.ProductFilter{Attributes: []entity.AttributeFilter{
+		{Code: "width", Min: number(100)},
+		{Code: "material", Values: []string{"Дуб"}},
+	}}, 10, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || list[0].ID != wide.ID {
+		t.Errorf("List() by attributes returned %d products, want only %d", len(list), wide.ID)
+	}
+
+	got, err := products.GetByID(ctx, wide.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if len(got.Attributes) != 2 || got.Attributes[0].Code != "material" || got.Attributes[1].Code != "width" {
+		t.Errorf("GetByID() attributes = %+v, want material then width", got.Attributes)
+	}
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+type CartRepo struct {
+	db *sql.DB
+}
+
+func NewCartRepo(db *sql.DB) *CartRepo {
+	return &CartRepo{db: db}
+}
+
+// Items возвращает позиции корзины пользователя в порядке добавления
+func (r *CartRepo) Items(ctx context.Context, userID int) ([]entity.CartItem, error) {
+	query := `
+		SELECT product_id, COALESCE(variant_id, 0), quantity
+		FROM cart_items
+		WHERE user_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []entity.CartItem{}
+	for rows.Next() {
+		var item entity.CartItem
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("scan cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return items, nil
+}
+
+// AddItem увеличивает количество товара (варианта) в корзине или добавляет его; variantID = 0 — товар без вариантов
+func (r *CartRepo) AddItem(ctx context.Context, userID, productID, variantID, quantity int) error {
+	return addCartItem(ctx, r.db, userID, productID, variantID, quantity)
+}
+
+// SetItem устанавливает количество товара (варианта) в корзине
+func (r *CartRepo) SetItem(ctx context.Context, userID, productID, variantID, quantity int) error {
+	query := `
+		INSERT INTO cart_items (user_id, product_id, variant_id, quantity)
+		VALUES ($1, $2, NULLIF($3, 0), $4)
+		ON CONFLICT (user_id, product_id, (COALESCE(variant_id, 0)))
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, productID, variantID, quantity); err != nil {
+		return fmt.Errorf("set cart item: %w", err)
+	}
+	return nil
+}
+
+// RemoveItem удаляет товар (вариант) из корзины
+func (r *CartRepo) RemoveItem(ctx context.Context, userID, productID, variantID int) error {
+	query := `DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, productID, variantID); err != nil {
+		return fmt.Errorf("remove cart item: %w", err)
+	}
+	return nil
+}
+
+// Clear очищает корзину пользователя вместе с промокодом
+func (r *CartRepo) Clear(ctx context.Context, userID int) error {
+	return clearCart(ctx, r.db, userID)
+}
+
+// clearCart очищает корзину пользователя; db может быть транзакцией оформления заказа
+func clearCart(ctx context.Context, db execer, userID int) error {
+	query := `
+		WITH codes AS (DELETE FROM cart_promo_codes WHERE user_id = $1)
+		DELETE FROM cart_items WHERE user_id = $1`
+
+	if _, err := db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("clear cart: %w", err)
+	}
+	return nil
+}
+
+// PromoCode возвращает промокод, примененный к корзине пользователя, или пустую строку
+func (r *CartRepo) PromoCode(ctx context.Context, userID int) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `SELECT code FROM cart_promo_codes WHERE user_id = $1`, userID).Scan(&code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get cart promo code: %w", err)
+	}
+	return code, nil
+}
+
+// SetPromoCode применяет промокод к корзине пользователя, заменяя предыдущий
+func (r *CartRepo) SetPromoCode(ctx context.Context, userID int, code string) error {
+	query := `
+		INSERT INTO cart_promo_codes (user_id, code)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET code = EXCLUDED.code, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, code); err != nil {
+		return fmt.Errorf("set cart promo code: %w", err)
+	}
+	return nil
+}
+
+// RemovePromoCode убирает промокод из корзины пользователя
+func (r *CartRepo) RemovePromoCode(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM cart_promo_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("remove cart promo code: %w", err)
+	}
+	return nil
+}
+
+// Merge добавляет позиции гостевой корзины к корзине пользователя одной транзакцией.
+// Товары и варианты, которых уже нет в каталоге, пропускаются.
+func (r *CartRepo) Merge(ctx context.Context, userID int, items []entity.CartItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("merge cart - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		if err := addCartItem(ctx, tx, userID, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("merge cart - commit: %w", err)
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func addCartItem(ctx context.Context, db execer, userID, productID, variantID, quantity int) error {
+	query := `
+		INSERT INTO cart_items (user_id, product_id, variant_id, quantity)
+		SELECT $1, p.id, NULLIF($3, 0), $4 FROM products p
+		WHERE p.id = $2
+		  AND ($3 = 0 OR EXISTS (SELECT 1 FROM product_variants v WHERE v.id = $3 AND v.product_id = p.id))
+		ON CONFLICT (user_id, product_id, (COALESCE(variant_id, 0)))
+		DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := db.ExecContext(ctx, query, userID, productID, variantID, quantity); err != nil {
+		return fmt.Errorf("add cart item: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+// Коды ошибок Postgres, которые переводятся в доменные ошибки
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type CategoryRepo struct {
+	db *sql.DB
+}
+
+func NewCategoryRepo(db *sql.DB) *CategoryRepo {
+	return &CategoryRepo{db: db}
+}
+
+// List возвращает все категории плоским списком в порядке отображения
+func (r *CategoryRepo) List(ctx context.Context) ([]*entity.Category, error) {
+	query := `
+		SELECT id, parent_id, name, slug, sort_order, created_at, updated_at
+		FROM categories
+		ORDER BY sort_order, name, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := make([]*entity.Category, 0)
+	for rows.Next() {
+		var c entity.Category
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.SortOrder, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		categories = append(categories, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return categories, nil
+}
+
+// GetByID возвращает категорию по ID или nil, если ее нет
+func (r *CategoryRepo) GetByID(ctx context.Context, id int) (*entity.Category, error) {
+	query := `
+		SELECT id, parent_id, name, slug, sort_order, created_at, updated_at
+		FROM categories WHERE id = $1`
+
+	var c entity.Category
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.SortOrder, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get category by id: %w", err)
+	}
+	return &c, nil
+}
+
+// ProductCounts возвращает количество товаров, привязанных непосредственно к каждой категории
+func (r *CategoryRepo) ProductCounts(ctx context.Context) (map[int]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT category_id, COUNT(*) FROM products GROUP BY category_id`)
+	if err != nil {
+		return nil, fmt.Errorf("count products by category: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var categoryID, count int
+		if err := rows.Scan(&categoryID, &count); err != nil {
+			return nil, fmt.Errorf("scan category count: %w", err)
+		}
+		counts[categoryID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return counts, nil
+}
+
+// Create создает категорию
+func (r *CategoryRepo) Create(ctx context.Context, category *entity.Category) error {
+	query := `
+		INSERT INTO categories (parent_id, name, slug, sort_order)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		category.ParentID, category.Name, category.Slug, category.SortOrder).
+		Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return categoryError("create category", err)
+	}
+	return nil
+}
+
+// Update обновляет категорию и денормализованное название категории у ее товаров
+func (r *CategoryRepo) Update(ctx context.Context, category *entity.Category) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE categories
+		SET parent_id = $1, name = $2, slug = $3, sort_order = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		category.ParentID, category.Name, category.Slug, category.SortOrder, category.ID).
+		Scan(&category.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrCategoryNotFound
+	}
+	if err != nil {
+		return categoryError("update category", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE products SET category = $1 WHERE category_id = $2 AND category <> $1`,
+		category.Name, category.ID); err != nil {
+		return fmt.Errorf("update products category name: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет категорию; категорию с подкатегориями или товарами удалить нельзя
+func (r *CategoryRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return apperrors.ErrCategoryNotEmpty
+	}
+	if err != nil {
+		return fmt.Errorf("delete category: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete category - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.ErrCategoryNotFound
+	}
+	return nil
+}
+
+// categoryError переводит нарушения ограничений при записи категории в доменные ошибки
+func categoryError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %s", apperrors.ErrCategoryExists, pqErr.Constraint)
+		case pgForeignKeyViolation:
+			// Родительскую категорию удалили между проверкой и записью
+			return fmt.Errorf("%w: parent", apperrors.ErrCategoryNotFound)
+		}
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
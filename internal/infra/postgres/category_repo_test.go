@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestProductFilterIncludesSubcategories(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	categories := NewCategoryRepo(db)
+	products := NewProductRepo(db)
+
+	living := &entity.Category{Name: "Гостиная", Slug: "gostinaya"}
+	if err := categories.Create(ctx, living); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sofas := &entity.Category{ParentID: &living.ID, Name: "Диваны", Slug: "divany"}
+	if err := categories.Create(ctx, sofas); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	kitchen := &entity.Category{Name: "Кухня", Slug: "kukhnya"}
+	if err := categories.Create(ctx, kitchen); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	sofa := &entity.Product{Name: "Диван", Price: 100, Category: sofas.Name, CategoryID: sofas.ID, Stock: 1}
+	table := &entity.Product{Name: "Стол", Price: 100, Category: kitchen.Name, CategoryID: kitchen.ID, Stock: 1}
+	for _, p := range []*entity.Product{sofa, table} {
+		if err := products.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter entity.ProductFilter
+		want   []int
+	}{
+		{name: "parent by slug", filter: entity.ProductFilter{Categories: []string{"gostinaya"}}, want: []int{sofa.ID}},
+		{name: "parent by name ignoring case", filter: entity.ProductFilter{Categories: []string{"гостиная"}}, want: []int{sofa.ID}},
+		{name: "parent by id", filter: entity.ProductFilter{CategoryIDs: []int{living.ID}}, want: []int{sofa.ID}},
+		{name: "child", filter: entity.ProductFilter{CategoryIDs: []int{sofas.ID}}, want: []int{sofa.ID}},
+		{name: "unknown category", filter: entity.ProductFilter{Categories: []string{"bedroom"}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := products.List(ctx, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(list) != len(tt.want) {
+				t.Fatalf("List() returned %d products, want %d", len(list), len(tt.want))
+			}
+			for i, id := range tt.want {
+				if list[i].ID != id {
+					t.Errorf("List()[%d].ID = %d, want %d", i, list[i].ID, id)
+				}
+			}
+		})
+	}
+
+	counts, err := categories.ProductCounts(ctx)
+	if err != nil {
+		t.Fatalf("ProductCounts() error = %v", err)
+	}
+	if counts[sofas.ID] != 1 || counts[kitchen.ID] != 1 || counts[living.ID] != 0 {
+		t.Errorf("ProductCounts() = %v, want only direct products", counts)
+	}
+}
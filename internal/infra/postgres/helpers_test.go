@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+// createTestCategories создает корневые категории, у которых slug совпадает с названием
+func createTestCategories(t *testing.T, db *sql.DB, names ...string) map[string]int {
+	t.Helper()
+	repo := NewCategoryRepo(db)
+	ids := make(map[string]int, len(names))
+	for _, name := range names {
+		category := &entity.Category{Name: name, Slug: name}
+		if err := repo.Create(context.Background(), category); err != nil {
+			t.Fatalf("create category %q: %v", name, err)
+		}
+		ids[name] = category.ID
+	}
+	return ids
+}
+
+// createTestProduct создает товар в отдельной категории
+func createTestProduct(t *testing.T, db *sql.DB, name string, price float64, stock int) *entity.Product {
+	t.Helper()
+	category := "category-" + name
+	product := &entity.Product{
+		Name:       name,
+		Price:      price,
+		Category:   category,
+		CategoryID: createTestCategories(t, db, category)[category],
+		Stock:      stock,
+	}
+	if err := NewProductRepo(db).Create(context.Background(), product, 0); err != nil {
+		t.Fatalf("create product %q: %v", name, err)
+	}
+	return product
+}
+
+// createTestUser создает покупателя для заказов и корзин
+func createTestUser(t *testing.T, db *sql.DB) *entity.User {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	user := &entity.User{
+		Email:    fmt.Sprintf("user%d@example.com", count+1),
+		Password: "hash",
+		Name:     "Test",
+		Role:     entity.RoleCustomer,
+	}
+	if err := NewUserRepo(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// productStock возвращает остаток товара в базе
+func productStock(t *testing.T, db *sql.DB, productID int) int {
+	t.Helper()
+	var stock int
+	if err := db.QueryRow(`SELECT stock FROM products WHERE id = $1`, productID).Scan(&stock); err != nil {
+		t.Fatalf("get product stock: %v", err)
+	}
+	return stock
+}
+
+// warehouseStockOf возвращает остаток товара без вариантов на складе
+func warehouseStockOf(t *testing.T, db *sql.DB, warehouseID, productID int) int {
+	t.Helper()
+	var stock int
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(stock), 0) FROM warehouse_stock
+		WHERE warehouse_id = $1 AND product_id = $2 AND variant_id IS NULL`,
+		warehouseID, productID,
+	).Scan(&stock)
+	if err != nil {
+		t.Fatalf("get warehouse stock: %v", err)
+	}
+	return stock
+}
+
+// availableStock возвращает свободный остаток товара: без резервов неоплаченных заказов
+func availableStock(t *testing.T, db *sql.DB, productID int) int {
+	t.Helper()
+	product, err := NewProductRepo(db).GetByID(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("get product: %v", err)
+	}
+	return product.Available
+}
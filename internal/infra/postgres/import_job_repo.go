@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+type ImportJobRepo struct {
+	db *sql.DB
+}
+
+func NewImportJobRepo(db *sql.DB) *ImportJobRepo {
+	return &ImportJobRepo{db: db}
+}
+
+// Create сохраняет новую задачу импорта в статусе pending
+func (r *ImportJobRepo) Create(ctx context.Context, job *entity.ImportJob) error {
+	query := `
+		INSERT INTO import_jobs (status, format, filename, dry_run, total_rows, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		entity.ImportJobPending, job.Format, job.Filename, job.DryRun, job.TotalRows, job.CreatedBy,
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create import job: %w", err)
+	}
+	job.Status = entity.ImportJobPending
+	return nil
+}
+
+// GetByID возвращает задачу вместе с построчным отчетом; nil, если задачи нет
+func (r *ImportJobRepo) GetByID(ctx context.Context, id int) (*entity.ImportJob, error) {
+	query := `
+		SELECT id, status, format, filename, dry_run, total_rows, processed_rows,
+		       created_count, updated_count, failed_count, report, error, COALESCE(created_by, 0),
+		       created_at, started_at, finished_at
+		FROM import_jobs WHERE id = $1`
+
+	var job entity.ImportJob
+	var report []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID, &job.Status, &job.Format, &job.Filename, &job.DryRun, &job.TotalRows, &job.ProcessedRows,
+		&job.Created, &job.Updated, &job.Failed, &report, &job.Error, &job.CreatedBy,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get import job: %w", err)
+	}
+
+	if err := json.Unmarshal(report, &job.Rows); err != nil {
+		return nil, fmt.Errorf("decode import report: %w", err)
+	}
+	return &job, nil
+}
+
+// List возвращает последние задачи без построчного отчета
+func (r *ImportJobRepo) List(ctx context.Context, limit int) ([]*entity.ImportJob, error) {
+	query := `
+		SELECT id, status, format, filename, dry_run, total_rows, processed_rows,
+		       created_count, updated_count, failed_count, error, COALESCE(created_by, 0),
+		       created_at, started_at, finished_at
+		FROM import_jobs
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list import jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*entity.ImportJob, 0)
+	for rows.Next() {
+		var job entity.ImportJob
+		err := rows.Scan(
+			&job.ID, &job.Status, &job.Format, &job.Filename, &job.DryRun, &job.TotalRows, &job.ProcessedRows,
+			&job.Created, &job.Updated, &job.Failed, &job.Error, &job.CreatedBy,
+			&job.CreatedAt, &job.StartedAt, &job.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan import job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return jobs, nil
+}
+
+// Start переводит задачу в статус running
+func (r *ImportJobRepo) Start(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE import_jobs SET status = $1, started_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		entity.ImportJobRunning, id,
+	)
+	if err != nil {
+		return fmt.Errorf("start import job: %w", err)
+	}
+	return nil
+}
+
+// Progress сохраняет счетчики обработанных строк
+func (r *ImportJobRepo) Progress(ctx context.Context, job *entity.ImportJob) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET processed_rows = $1, created_count = $2, updated_count = $3, failed_count = $4
+		WHERE id = $5`,
+		job.ProcessedRows, job.Created, job.Updated, job.Failed, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update import job progress: %w", err)
+	}
+	return nil
+}
+
+// Finish сохраняет итог задачи: статус, счетчики, построчный отчет и ошибку
+func (r *ImportJobRepo) Finish(ctx context.Context, job *entity.ImportJob) error {
+	rows := job.Rows
+	if rows == nil {
+		rows = []entity.ImportRow{}
+	}
+	report, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("encode import report: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET status = $1, processed_rows = $2, created_count = $3, updated_count = $4, failed_count = $5,
+		    report = $6, error = $7, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $8`,
+		job.Status, job.ProcessedRows, job.Created, job.Updated, job.Failed, report, job.Error, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("finish import job: %w", err)
+	}
+	return nil
+}
+
+// FailUnfinished помечает задачи, прерванные остановкой сервера, как failed
+func (r *ImportJobRepo) FailUnfinished(ctx context.Context, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE import_jobs
+		SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status IN ($3, $4)`,
+		entity.ImportJobFailed, reason, entity.ImportJobPending, entity.ImportJobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("fail unfinished import jobs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+type InvoiceRepo struct {
+	db *sql.DB
+}
+
+func NewInvoiceRepo(db *sql.DB) *InvoiceRepo {
+	return &InvoiceRepo{db: db}
+}
+
+// GetByOrder возвращает счет заказа или nil, если он еще не выписан
+func (r *InvoiceRepo) GetByOrder(ctx context.Context, orderID int) (*entity.OrderInvoice, error) {
+	inv := &entity.OrderInvoice{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT order_id, number, token, COALESCE(file_url, ''), issued_at
+		FROM order_invoices WHERE order_id = $1`,
+		orderID,
+	).Scan(&inv.OrderID, &inv.Number, &inv.Token, &inv.FileURL, &inv.IssuedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// Create выписывает счет. Если параллельный запрос уже выписал счет по заказу, возвращается он.
+func (r *InvoiceRepo) Create(ctx context.Context, inv *entity.OrderInvoice) (*entity.OrderInvoice, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO order_invoices (order_id, number, token)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (order_id) DO NOTHING`,
+		inv.OrderID, inv.Number, inv.Token,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create invoice: %w", err)
+	}
+
+	created, err := r.GetByOrder(ctx, inv.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if created == nil {
+		return nil, fmt.Errorf("create invoice: order %d invoice not found after insert", inv.OrderID)
+	}
+	return created, nil
+}
+
+// SetFileURL запоминает, где в хранилище лежит PDF счета
+func (r *InvoiceRepo) SetFileURL(ctx context.Context, orderID int, fileURL string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE order_invoices SET file_url = $1 WHERE order_id = $2`, fileURL, orderID)
+	if err != nil {
+		return fmt.Errorf("set invoice file url: %w", err)
+	}
+	return nil
+}
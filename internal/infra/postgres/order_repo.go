@@ -0,0 +1,1102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+type OrderRepo struct {
+	db *sql.DB
+}
+
+func NewOrderRepo(db *sql.DB) *OrderRepo {
+	return &OrderRepo{db: db}
+}
+
+// Create создает заказ и его позиции в одной транзакции.
+// Цена фиксируется на момент покупки, количество распределяется по складам и резервируется на reservationTTL:
+// товар остается на складе, но другим покупателям не продается. Списание со склада происходит при оплате.
+// К зафиксированным ценам применяются действующие акции и промокод order.PromoCode.
+// С fromCart заказ оформлен из корзины, и она очищается при фиксации транзакции.
+func (r *OrderRepo) Create(ctx context.Context, order *entity.Order, reservationTTL time.Duration, fromCart bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create order - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if order.Method == entity.DeliveryPickup {
+		var ok bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = $1 AND active AND pickup)`,
+			order.PickupWarehouseID,
+		).Scan(&ok)
+		if err != nil {
+			return fmt.Errorf("create order - check pickup point: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: pickup point %d", apperrors.ErrInvalidDelivery, order.PickupWarehouseID)
+		}
+	}
+
+	// Блокируем строки товаров (а за ними вариантов) всегда в одном порядке,
+	// чтобы параллельные заказы не ловили дедлок
+	sort.Slice(order.Items, func(i, j int) bool {
+		if order.Items[i].ProductID != order.Items[j].ProductID {
+			return order.Items[i].ProductID < order.Items[j].ProductID
+		}
+		return order.Items[i].VariantID < order.Items[j].VariantID
+	})
+
+	for i := range order.Items {
+		if err := allocateItemStock(ctx, tx, &order.Items[i], order.Delivery); err != nil {
+			return err
+		}
+	}
+
+	if err := applyOrderPromotions(ctx, tx, order); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO orders (user_id, subtotal, discount_total, total, promo_code, status, delivery_method, pickup_warehouse_id, reserved_until)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, 0), CURRENT_TIMESTAMP + make_interval(secs => $9))
+		RETURNING id, created_at, updated_at, reserved_until`,
+		order.UserID, order.Subtotal, order.DiscountTotal, order.Total, order.PromoCode, order.Status, order.Method,
+		order.PickupWarehouseID, reservationTTL.Seconds(),
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt, &order.ReservedUntil)
+	if err != nil {
+		return fmt.Errorf("create order: %w", err)
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO order_items (order_id, product_id, variant_id, sku, quantity, price, discount)
+			VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5, $6, $7)
+			RETURNING id`,
+			item.OrderID, item.ProductID, item.VariantID, item.SKU, item.Quantity, item.Price, item.Discount,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("create order item: %w", err)
+		}
+
+		for _, allocation := range item.Allocations {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO stock_reservations (order_id, order_item_id, warehouse_id, product_id, variant_id, quantity, expires_at)
+				VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7)`,
+				order.ID, item.ID, allocation.WarehouseID, item.ProductID, item.VariantID, allocation.Quantity, order.ReservedUntil,
+			)
+			if err != nil {
+				return fmt.Errorf("create stock reservation: %w", err)
+			}
+		}
+	}
+
+	for _, discount := range order.Discounts {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_discounts (order_id, promotion_id, promo_code_id, name, code, amount)
+			VALUES ($1, $2, NULLIF($3, 0), $4, NULLIF($5, ''), $6)`,
+			order.ID, discount.PromotionID, discount.PromoCodeID, discount.Name, discount.Code, discount.Amount,
+		)
+		if err != nil {
+			return fmt.Errorf("create order discount: %w", err)
+		}
+	}
+
+	if err := insertStatusHistory(ctx, tx, order.ID, "", order.Status, order.UserID, ""); err != nil {
+		return err
+	}
+
+	// Корзина очищается в той же транзакции: иначе при сбое очистки заказ уже создан,
+	// а покупатель видит ошибку и оформляет его повторно
+	if fromCart {
+		if err := clearCart(ctx, tx, order.UserID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create order - commit: %w", err)
+	}
+	return nil
+}
+
+// applyOrderPromotions считает скидки заказа по действующим акциям и промокоду order.PromoCode
+// и заполняет Subtotal, DiscountTotal, Total, Discounts и скидки позиций. Цены позиций уже должны быть зафиксированы.
+// Промокод, который не дал скидки (не набрана сумма, нет подходящих товаров), — ошибка, чтобы покупатель
+// не оплатил заказ дороже, чем ожидал.
+func applyOrderPromotions(ctx context.Context, tx *sql.Tx, order *entity.Order) error {
+	promotions, err := applicablePromotions(ctx, tx, order.UserID, order.PromoCode, true)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]int, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	categories, err := productCategories(ctx, tx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]entity.DiscountLine, len(order.Items))
+	var subtotal float64
+	for i, item := range order.Items {
+		lines[i] = entity.DiscountLine{
+			ProductID:  item.ProductID,
+			CategoryID: categories[item.ProductID],
+			Amount:     item.Price * float64(item.Quantity),
+		}
+		subtotal += lines[i].Amount
+	}
+
+	order.Discounts = entity.ApplyPromotions(lines, promotions)
+	for i := range order.Items {
+		order.Items[i].Discount = lines[i].Discount
+	}
+
+	var discountTotal float64
+	codeApplied := false
+	for _, discount := range order.Discounts {
+		discountTotal += discount.Amount
+		codeApplied = codeApplied || discount.Code != ""
+	}
+	if order.PromoCode != "" && !codeApplied {
+		return fmt.Errorf("%w: %s", apperrors.ErrPromoCodeNotApplicable, order.PromoCode)
+	}
+
+	order.Subtotal = math.Round(subtotal*100) / 100
+	order.DiscountTotal = math.Round(discountTotal*100) / 100
+	order.Total = math.Round((order.Subtotal-order.DiscountTotal)*100) / 100
+	return nil
+}
+
+// orderReference — ссылка на заказ в журнале остатков
+func orderReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// allocateItemStock фиксирует цену позиции и распределяет ее количество по свободному (без чужих резервов) остатку:
+// при доставке — по складам в порядке приоритета, при самовывозе — только из выбранной точки.
+// Распределение сохраняется в item.Allocations. Строка товара блокируется до конца транзакции,
+// поэтому параллельные заказы не зарезервируют один и тот же остаток.
+func allocateItemStock(ctx context.Context, tx *sql.Tx, item *entity.OrderItem, delivery entity.Delivery) error {
+	var hasVariants bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT price, name, EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1)
+		FROM products WHERE id = $1
+		FOR UPDATE`,
+		item.ProductID,
+	).Scan(&item.Price, &item.ProductName, &hasVariants)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", apperrors.ErrProductNotFound, item.ProductID)
+	}
+	if err != nil {
+		return fmt.Errorf("create order - lock product: %w", err)
+	}
+
+	item.SKU = ""
+	if item.VariantID == 0 && hasVariants {
+		return fmt.Errorf("%w: product %d", apperrors.ErrVariantRequired, item.ProductID)
+	}
+	if item.VariantID != 0 {
+		var price sql.NullFloat64
+		err := tx.QueryRowContext(ctx,
+			`SELECT price, sku FROM product_variants WHERE id = $1 AND product_id = $2`,
+			item.VariantID, item.ProductID,
+		).Scan(&price, &item.SKU)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", apperrors.ErrVariantNotFound, item.VariantID)
+		}
+		if err != nil {
+			return fmt.Errorf("create order - get variant: %w", err)
+		}
+		if price.Valid {
+			item.Price = price.Float64
+		}
+	}
+
+	query := `
+		SELECT ws.warehouse_id, ws.stock - ` + reservedStockExpr + `
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		WHERE ws.product_id = $1 AND COALESCE(ws.variant_id, 0) = $2 AND ws.stock > 0 AND w.active`
+	args := []interface{}{item.ProductID, item.VariantID}
+	if delivery.Method == entity.DeliveryPickup {
+		query += ` AND w.id = $3`
+		args = append(args, delivery.PickupWarehouseID)
+	} else {
+		// Доставка отгружается только со складов: товар в шоурумах — выставочный
+		query += ` AND w.type = $3`
+		args = append(args, entity.WarehouseTypeWarehouse)
+	}
+	query += ` ORDER BY w.priority, w.id`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("create order - find stock: %w", err)
+	}
+	var available []entity.OrderAllocation
+	for rows.Next() {
+		var allocation entity.OrderAllocation
+		if err := rows.Scan(&allocation.WarehouseID, &allocation.Quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("create order - scan stock: %w", err)
+		}
+		available = append(available, allocation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	var remaining int
+	item.Allocations, remaining = splitAllocation(available, item.Quantity)
+	if remaining > 0 {
+		if item.VariantID != 0 {
+			return fmt.Errorf("%w: variant %d", apperrors.ErrInsufficientStock, item.VariantID)
+		}
+		return fmt.Errorf("%w: product %d", apperrors.ErrInsufficientStock, item.ProductID)
+	}
+
+	return nil
+}
+
+// splitAllocation распределяет quantity по свободным остаткам складов в порядке available:
+// каждый склад отдает сколько может, пока количество не набрано. Склады без свободного остатка пропускаются.
+// Возвращает распределение и количество, которое набрать не удалось.
+func splitAllocation(available []entity.OrderAllocation, quantity int) ([]entity.OrderAllocation, int) {
+	var allocations []entity.OrderAllocation
+	for _, a := range available {
+		if quantity == 0 {
+			break
+		}
+		if a.Quantity <= 0 {
+			continue
+		}
+		a.Quantity = min(a.Quantity, quantity)
+		quantity -= a.Quantity
+		allocations = append(allocations, a)
+	}
+	return allocations, quantity
+}
+
+// UpdateStatus переводит заказ из статуса from в to и пишет запись в историю.
+// Если статус успели поменять параллельно, возвращает ErrInvalidStatusTransition.
+// При оплате резервы заказа списываются со складов продажами. При отмене резервы снимаются,
+// а уже списанные позиции возвращаются на склады, и в журнал остатков пишутся возвраты от имени changedBy.
+func (r *OrderRepo) UpdateStatus(ctx context.Context, id int, from, to entity.OrderStatus, changedBy int, comment string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("update order status - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE orders SET status = $1, reserved_until = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3`,
+		to, id, from,
+	)
+	if err != nil {
+		return fmt.Errorf("update order status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update order status - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: order %d is no longer %s", apperrors.ErrInvalidStatusTransition, id, from)
+	}
+
+	switch to {
+	case entity.OrderStatusPaid:
+		if err := convertReservations(ctx, tx, id); err != nil {
+			return err
+		}
+	case entity.OrderStatusCancelled:
+		if err := releaseReservations(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := restockOrder(ctx, tx, id, changedBy); err != nil {
+			return err
+		}
+	}
+
+	if err := insertStatusHistory(ctx, tx, id, from, to, changedBy, comment); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update order status - commit: %w", err)
+	}
+	return nil
+}
+
+// convertReservations превращает активные резервы оплаченного заказа в списания со складов:
+// по каждому резерву пишется продажа от имени покупателя и сохраняется распределение позиции по складам.
+// Резерв, чей срок истек, но который еще не снят фоновой задачей, тоже списывается.
+func convertReservations(ctx context.Context, tx *sql.Tx, orderID int) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sr.order_item_id, sr.product_id, COALESCE(sr.variant_id, 0), sr.warehouse_id, sr.quantity, o.user_id
+		FROM stock_reservations sr
+		JOIN orders o ON o.id = sr.order_id
+		WHERE sr.order_id = $1 AND sr.status = $2
+		ORDER BY sr.product_id, sr.variant_id, sr.id`,
+		orderID, entity.ReservationActive,
+	)
+	if err != nil {
+		return fmt.Errorf("convert reservations: %w", err)
+	}
+
+	type reservation struct {
+		itemID   int
+		movement *entity.StockMovement
+	}
+	var reservations []reservation
+	for rows.Next() {
+		m := &entity.StockMovement{Reason: entity.StockSale, Reference: orderReference(orderID)}
+		var res reservation
+		if err := rows.Scan(&res.itemID, &m.ProductID, &m.VariantID, &m.WarehouseID, &m.Quantity, &m.UserID); err != nil {
+			rows.Close()
+			return fmt.Errorf("convert reservations - scan: %w", err)
+		}
+		res.movement = m
+		reservations = append(reservations, res)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	// Товары блокируются по возрастанию ID, как при оформлении заказа
+	locked := 0
+	for _, res := range reservations {
+		m := res.movement
+		if m.ProductID != locked {
+			if err := lockProduct(ctx, tx, m.ProductID); err != nil {
+				return err
+			}
+			locked = m.ProductID
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_allocations (order_item_id, warehouse_id, quantity)
+			VALUES ($1, $2, $3)`,
+			res.itemID, m.WarehouseID, m.Quantity,
+		)
+		if err != nil {
+			return fmt.Errorf("create order allocation: %w", err)
+		}
+
+		// Остаток могли списать в обход резерва (инвентаризацией) — тогда оплату не провести
+		m.Quantity = -m.Quantity
+		if err := applyStockMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE stock_reservations SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2 AND status = $3`,
+		entity.ReservationConverted, orderID, entity.ReservationActive,
+	)
+	if err != nil {
+		return fmt.Errorf("convert reservations - update: %w", err)
+	}
+	return nil
+}
+
+// releaseReservations снимает активные резервы заказа
+func releaseReservations(ctx context.Context, tx *sql.Tx, orderID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE stock_reservations SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $2 AND status = $3`,
+		entity.ReservationReleased, orderID, entity.ReservationActive,
+	)
+	if err != nil {
+		return fmt.Errorf("release reservations: %w", err)
+	}
+	return nil
+}
+
+// ListExpiredReservations возвращает до limit неоплаченных заказов, чей резерв истек
+func (r *OrderRepo) ListExpiredReservations(ctx context.Context, limit int) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT sr.order_id
+		FROM stock_reservations sr
+		JOIN orders o ON o.id = sr.order_id
+		WHERE sr.status = $1 AND sr.expires_at <= CURRENT_TIMESTAMP AND o.status = $2
+		ORDER BY sr.order_id
+		LIMIT $3`,
+		entity.ReservationActive, entity.OrderStatusPending, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list expired reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan expired reservation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return ids, nil
+}
+
+// restockOrder возвращает списанные позиции отмененного заказа на склады, с которых они были списаны.
+// Позиции заказов, оформленных до появления складов, возвращаются на основной склад,
+// а позиции, которые были только зарезервированы, не возвращаются: со склада они не списывались.
+func restockOrder(ctx context.Context, tx *sql.Tx, orderID, changedBy int) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT oi.product_id, COALESCE(oi.variant_id, 0), COALESCE(a.warehouse_id, 0), COALESCE(a.quantity, oi.quantity)
+		FROM order_items oi
+		LEFT JOIN order_allocations a ON a.order_item_id = oi.id
+		WHERE oi.order_id = $1
+		  AND (a.id IS NOT NULL OR NOT EXISTS (SELECT 1 FROM stock_reservations sr WHERE sr.order_item_id = oi.id))
+		ORDER BY oi.product_id, oi.variant_id, a.id`,
+		orderID,
+	)
+	if err != nil {
+		return fmt.Errorf("restock order: %w", err)
+	}
+
+	var movements []*entity.StockMovement
+	for rows.Next() {
+		m := &entity.StockMovement{Reason: entity.StockReturn, UserID: changedBy, Reference: orderReference(orderID)}
+		if err := rows.Scan(&m.ProductID, &m.VariantID, &m.WarehouseID, &m.Quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("restock order - scan: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	// Товары блокируются по возрастанию ID, как при оформлении заказа
+	locked, defaultWarehouse := 0, 0
+	for _, m := range movements {
+		if m.ProductID != locked {
+			if err := lockProduct(ctx, tx, m.ProductID); err != nil {
+				return err
+			}
+			locked = m.ProductID
+		}
+		if m.WarehouseID == 0 {
+			if defaultWarehouse == 0 {
+				if defaultWarehouse, err = defaultWarehouseID(ctx, tx); err != nil {
+					return err
+				}
+			}
+			m.WarehouseID = defaultWarehouse
+		}
+
+		err := applyStockMovement(ctx, tx, m)
+		if errors.Is(err, apperrors.ErrVariantRequired) {
+			// Вариант позиции удален после оформления заказа — вернуть остаток некуда
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListStatusHistory возвращает историю смены статусов заказа в хронологическом порядке
+func (r *OrderRepo) ListStatusHistory(ctx context.Context, orderID int) ([]*entity.OrderStatusHistory, error) {
+	query := `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, changed_by, COALESCE(comment, ''), created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*entity.OrderStatusHistory{}
+	for rows.Next() {
+		var h entity.OrderStatusHistory
+		var changedBy sql.NullInt64
+		err := rows.Scan(
+			&h.ID,
+			&h.OrderID,
+			&h.FromStatus,
+			&h.ToStatus,
+			&changedBy,
+			&h.Comment,
+			&h.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan order status history: %w", err)
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			h.ChangedBy = &id
+		}
+		history = append(history, &h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return history, nil
+}
+
+// GetByID возвращает заказ вместе с позициями
+func (r *OrderRepo) GetByID(ctx context.Context, id int) (*entity.Order, error) {
+	query := `
+		SELECT id, user_id, subtotal, discount_total, total, COALESCE(promo_code, ''), status, delivery_method, COALESCE(pickup_warehouse_id, 0), reserved_until, created_at, updated_at
+		FROM orders WHERE id = $1`
+
+	order := &entity.Order{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.Subtotal,
+		&order.DiscountTotal,
+		&order.Total,
+		&order.PromoCode,
+		&order.Status,
+		&order.Method,
+		&order.PickupWarehouseID,
+		&order.ReservedUntil,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get order by id: %w", err)
+	}
+
+	if err := r.loadItems(ctx, []*entity.Order{order}); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ListByUser возвращает заказы пользователя, новые первыми
+func (r *OrderRepo) ListByUser(ctx context.Context, userID, limit, offset int) ([]*entity.Order, error) {
+	query := `
+		SELECT id, user_id, subtotal, discount_total, total, COALESCE(promo_code, ''), status, delivery_method, COALESCE(pickup_warehouse_id, 0), reserved_until, created_at, updated_at
+		FROM orders
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListByUserAfter возвращает заказы пользователя после курсора (keyset по created_at, id), новые первыми.
+// Курсор следующей страницы равен nil, если страница последняя.
+func (r *OrderRepo) ListByUserAfter(ctx context.Context, userID int, after *pagination.Cursor, limit int) ([]*entity.Order, *pagination.Cursor, error) {
+	query := `
+		SELECT id, user_id, subtotal, discount_total, total, COALESCE(promo_code, ''), status, delivery_method, COALESCE(pickup_warehouse_id, 0), reserved_until, created_at, updated_at
+		FROM orders
+		WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if after != nil {
+		query += ` AND (created_at, id) < ($2::timestamp, $3)`
+		args = append(args, after.Value, after.ID)
+	}
+
+	// Берем на одну строку больше, чтобы понять, есть ли следующая страница
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list orders after cursor: %w", err)
+	}
+	defer rows.Close()
+
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *pagination.Cursor
+	if len(orders) > limit {
+		orders = orders[:limit]
+		last := orders[limit-1]
+		next = &pagination.Cursor{Value: last.CreatedAt.Format(pagination.TimeLayout), ID: last.ID}
+	}
+
+	if err := r.loadItems(ctx, orders); err != nil {
+		return nil, nil, err
+	}
+	return orders, next, nil
+}
+
+// CountByUser возвращает количество заказов пользователя (для пагинации)
+func (r *OrderRepo) CountByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count orders: %w", err)
+	}
+	return count, nil
+}
+
+// loadItems подгружает позиции для списка заказов одним запросом
+func (r *OrderRepo) loadItems(ctx context.Context, orders []*entity.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(orders))
+	byID := make(map[int]*entity.Order, len(orders))
+	for _, o := range orders {
+		ids = append(ids, int64(o.ID))
+		byID[o.ID] = o
+		o.Items = []entity.OrderItem{}
+	}
+
+	query := `
+		SELECT oi.id, oi.order_id, oi.product_id, COALESCE(oi.variant_id, 0), COALESCE(oi.sku, ''),
+		       oi.quantity, oi.price, oi.discount, COALESCE(p.name, '')
+		FROM order_items oi
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = ANY($1)
+		ORDER BY oi.id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("load order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.OrderItem
+		err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.VariantID,
+			&item.SKU,
+			&item.Quantity,
+			&item.Price,
+			&item.Discount,
+			&item.ProductName,
+		)
+		if err != nil {
+			return fmt.Errorf("scan order item: %w", err)
+		}
+		if o, ok := byID[item.OrderID]; ok {
+			o.Items = append(o.Items, item)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	if err := r.loadAllocations(ctx, orders, ids); err != nil {
+		return err
+	}
+	return r.loadDiscounts(ctx, byID, ids)
+}
+
+// loadDiscounts подгружает скидки акций для списка заказов
+func (r *OrderRepo) loadDiscounts(ctx context.Context, byID map[int]*entity.Order, orderIDs []int64) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_id, COALESCE(promotion_id, 0), COALESCE(promo_code_id, 0), name, COALESCE(code, ''), amount
+		FROM order_discounts
+		WHERE order_id = ANY($1)
+		ORDER BY id`,
+		pq.Array(orderIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("load order discounts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID int
+		var discount entity.OrderDiscount
+		err := rows.Scan(&orderID, &discount.PromotionID, &discount.PromoCodeID, &discount.Name, &discount.Code, &discount.Amount)
+		if err != nil {
+			return fmt.Errorf("scan order discount: %w", err)
+		}
+		if o, ok := byID[orderID]; ok {
+			o.Discounts = append(o.Discounts, discount)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+// loadAllocations подгружает распределение позиций заказов по складам: списания оплаченных заказов
+// и активные резервы неоплаченных
+func (r *OrderRepo) loadAllocations(ctx context.Context, orders []*entity.Order, orderIDs []int64) error {
+	items := make(map[int]*entity.OrderItem)
+	for _, o := range orders {
+		for i := range o.Items {
+			items[o.Items[i].ID] = &o.Items[i]
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.order_item_id, a.warehouse_id, a.quantity
+		FROM order_allocations a
+		JOIN order_items oi ON oi.id = a.order_item_id
+		WHERE oi.order_id = ANY($1)
+		UNION ALL
+		SELECT sr.order_item_id, sr.warehouse_id, sr.quantity
+		FROM stock_reservations sr
+		WHERE sr.order_id = ANY($1) AND sr.status = $2`,
+		pq.Array(orderIDs), entity.ReservationActive,
+	)
+	if err != nil {
+		return fmt.Errorf("load order allocations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID int
+		var allocation entity.OrderAllocation
+		if err := rows.Scan(&itemID, &allocation.WarehouseID, &allocation.Quantity); err != nil {
+			return fmt.Errorf("scan order allocation: %w", err)
+		}
+		if item, ok := items[itemID]; ok {
+			item.Allocations = append(item.Allocations, allocation)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+// insertStatusHistory пишет переход в историю; changedBy = 0 означает системный переход
+func insertStatusHistory(ctx context.Context, tx *sql.Tx, orderID int, from, to entity.OrderStatus, changedBy int, comment string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, comment)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), NULLIF($5, ''))`,
+		orderID, from, to, changedBy, comment,
+	)
+	if err != nil {
+		return fmt.Errorf("insert order status history: %w", err)
+	}
+	return nil
+}
+
+func scanOrders(rows *sql.Rows) ([]*entity.Order, error) {
+	orders := []*entity.Order{}
+	for rows.Next() {
+		var o entity.Order
+		err := rows.Scan(
+			&o.ID,
+			&o.UserID,
+			&o.Subtotal,
+			&o.DiscountTotal,
+			&o.Total,
+			&o.PromoCode,
+			&o.Status,
+			&o.Method,
+			&o.PickupWarehouseID,
+			&o.ReservedUntil,
+			&o.CreatedAt,
+			&o.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan order: %w", err)
+		}
+		orders = append(orders, &o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return orders, nil
+}
+
+const adminOrderColumns = `o.id, o.user_id, o.subtotal, o.discount_total, o.total, COALESCE(o.promo_code, ''),
+	o.status, o.delivery_method, COALESCE(o.pickup_warehouse_id, 0),
+	o.reserved_until, o.created_at, o.updated_at, COALESCE(u.email, ''), COALESCE(u.name, '')`
+
+// List возвращает заказы всех покупателей по фильтру, новые первыми. Фильтры по статусу и дате
+// опираются на индексы idx_orders_status и idx_orders_created_at.
+func (r *OrderRepo) List(ctx context.Context, filter entity.OrderFilter, limit, offset int) ([]*entity.AdminOrder, error) {
+	where, args := orderFilterClause(filter)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.user_id%s
+		ORDER BY o.created_at DESC, o.id DESC
+		LIMIT $%d OFFSET $%d`, adminOrderColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list all orders: %w", err)
+	}
+	defer rows.Close()
+
+	result := []*entity.AdminOrder{}
+	for rows.Next() {
+		o, err := scanAdminOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	orders := make([]*entity.Order, 0, len(result))
+	for _, o := range result {
+		orders = append(orders, &o.Order)
+	}
+	if err := r.loadItems(ctx, orders); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Count возвращает количество заказов по фильтру (для пагинации)
+func (r *OrderRepo) Count(ctx context.Context, filter entity.OrderFilter) (int, error) {
+	where, args := orderFilterClause(filter)
+
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders o LEFT JOIN users u ON u.id = o.user_id`+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count all orders: %w", err)
+	}
+	return count, nil
+}
+
+// GetAdminOrder возвращает заказ с контактами покупателя и позициями или nil, если его нет
+func (r *OrderRepo) GetAdminOrder(ctx context.Context, id int) (*entity.AdminOrder, error) {
+	o, err := scanAdminOrder(r.db.QueryRowContext(ctx, `
+		SELECT `+adminOrderColumns+`
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.user_id
+		WHERE o.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, []*entity.Order{&o.Order}); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Export передает в fn заказы по фильтру с позициями, новые первыми, не загружая выборку в память целиком
+func (r *OrderRepo) Export(ctx context.Context, filter entity.OrderFilter, fn func(*entity.AdminOrder) error) error {
+	where, args := orderFilterClause(filter)
+	query := `
+		SELECT ` + adminOrderColumns + `,
+		       COALESCE(oi.id, 0), COALESCE(oi.product_id, 0), COALESCE(oi.variant_id, 0), COALESCE(oi.sku, ''),
+		       COALESCE(oi.quantity, 0), COALESCE(oi.price, 0), COALESCE(oi.discount, 0), COALESCE(p.name, '')
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.user_id
+		LEFT JOIN order_items oi ON oi.order_id = o.id
+		LEFT JOIN products p ON p.id = oi.product_id` + where + `
+		ORDER BY o.created_at DESC, o.id DESC, oi.id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("export orders: %w", err)
+	}
+	defer rows.Close()
+
+	var current *entity.AdminOrder
+	for rows.Next() {
+		var o entity.AdminOrder
+		var item entity.OrderItem
+		err := rows.Scan(
+			&o.ID, &o.UserID, &o.Subtotal, &o.DiscountTotal, &o.Total, &o.PromoCode, &o.Status, &o.Method, &o.PickupWarehouseID,
+			&o.ReservedUntil, &o.CreatedAt, &o.UpdatedAt, &o.CustomerEmail, &o.CustomerName,
+			&item.ID, &item.ProductID, &item.VariantID, &item.SKU, &item.Quantity, &item.Price, &item.Discount,
+			&item.ProductName,
+		)
+		if err != nil {
+			return fmt.Errorf("scan exported order: %w", err)
+		}
+
+		if current == nil || current.ID != o.ID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			o.Items = []entity.OrderItem{}
+			current = &o
+		}
+		if item.ID != 0 {
+			item.OrderID = o.ID
+			current.Items = append(current.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+	return nil
+}
+
+// orderFilterClause собирает WHERE для выборок заказов админки (таблицы orders o и users u)
+func orderFilterClause(filter entity.OrderFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, s := range filter.Statuses {
+			statuses = append(statuses, string(s))
+		}
+		args = append(args, pq.Array(statuses))
+		conditions = append(conditions, fmt.Sprintf("o.status = ANY($%d)", len(args)))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("o.created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("o.created_at < $%d", len(args)))
+	}
+	if filter.Email != "" {
+		args = append(args, "%"+escapeLike(filter.Email)+"%")
+		conditions = append(conditions, fmt.Sprintf("u.email ILIKE $%d", len(args)))
+	}
+	if filter.TotalMin != nil {
+		args = append(args, *filter.TotalMin)
+		conditions = append(conditions, fmt.Sprintf("o.total >= $%d", len(args)))
+	}
+	if filter.TotalMax != nil {
+		args = append(args, *filter.TotalMax)
+		conditions = append(conditions, fmt.Sprintf("o.total <= $%d", len(args)))
+	}
+	if filter.ProductID != 0 {
+		args = append(args, filter.ProductID)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM order_items fi WHERE fi.order_id = o.id AND fi.product_id = $%d)", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func scanAdminOrder(row interface{ Scan(...interface{}) error }) (*entity.AdminOrder, error) {
+	var o entity.AdminOrder
+	err := row.Scan(
+		&o.ID, &o.UserID, &o.Subtotal, &o.DiscountTotal, &o.Total, &o.PromoCode, &o.Status, &o.Method, &o.PickupWarehouseID,
+		&o.ReservedUntil, &o.CreatedAt, &o.UpdatedAt, &o.CustomerEmail, &o.CustomerName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scan order: %w", err)
+	}
+	return &o, nil
+}
+
+// AddNote сохраняет внутреннюю заметку к заказу
+func (r *OrderRepo) AddNote(ctx context.Context, note *entity.OrderNote) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO order_notes (order_id, author_id, text)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		note.OrderID, note.AuthorID, note.Text,
+	).Scan(&note.ID, &note.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return fmt.Errorf("%w: %d", apperrors.ErrOrderNotFound, note.OrderID)
+	}
+	if err != nil {
+		return fmt.Errorf("add order note: %w", err)
+	}
+	return nil
+}
+
+// ListNotes возвращает заметки к заказу в хронологическом порядке
+func (r *OrderRepo) ListNotes(ctx context.Context, orderID int) ([]*entity.OrderNote, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.id, n.order_id, n.author_id, COALESCE(u.name, ''), n.text, n.created_at
+		FROM order_notes n
+		LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.order_id = $1
+		ORDER BY n.created_at, n.id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []*entity.OrderNote{}
+	for rows.Next() {
+		var n entity.OrderNote
+		var authorID sql.NullInt64
+		if err := rows.Scan(&n.ID, &n.OrderID, &authorID, &n.AuthorName, &n.Text, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan order note: %w", err)
+		}
+		if authorID.Valid {
+			id := int(authorID.Int64)
+			n.AuthorID = &id
+		}
+		notes = append(notes, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return notes, nil
+}
@@ -0,0 +1,477 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestSplitAllocation(t *testing.T) {
+	tests := []struct {
+		name          string
+		available     []entity.OrderAllocation
+		quantity      int
+		want          []entity.OrderAllocation
+		wantRemaining int
+	}{
+		{
+			name:      "first warehouse is enough",
+			available: []entity.OrderAllocation{{WarehouseID: 1, Quantity: 5}, {WarehouseID: 2, Quantity: 5}},
+			quantity:  3,
+			want:      []entity.OrderAllocation{{WarehouseID: 1, Quantity: 3}},
+		},
+		{
+			name:      "split across warehouses in order",
+			available: []entity.OrderAllocation{{WarehouseID: 3, Quantity: 2}, {WarehouseID: 1, Quantity: 4}, {WarehouseID: 2, Quantity: 9}},
+			quantity:  7,
+			want:      []entity.OrderAllocation{{WarehouseID: 3, Quantity: 2}, {WarehouseID: 1, Quantity: 4}, {WarehouseID: 2, Quantity: 1}},
+		},
+		{
+			name:      "fully reserved warehouses are skipped",
+			available: []entity.OrderAllocation{{WarehouseID: 1, Quantity: 0}, {WarehouseID: 2, Quantity: -1}, {WarehouseID: 3, Quantity: 2}},
+			quantity:  2,
+			want:      []entity.OrderAllocation{{WarehouseID: 3, Quantity: 2}},
+		},
+		{
+			name:          "not enough stock",
+			available:     []entity.OrderAllocation{{WarehouseID: 1, Quantity: 1}, {WarehouseID: 2, Quantity: 2}},
+			quantity:      5,
+			want:          []entity.OrderAllocation{{WarehouseID: 1, Quantity: 1}, {WarehouseID: 2, Quantity: 2}},
+			wantRemaining: 2,
+		},
+		{name: "no stock", quantity: 1, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, remaining := splitAllocation(tt.available, tt.quantity)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAllocation() = %+v, want %+v", got, tt.want)
+			}
+			if remaining != tt.wantRemaining {
+				t.Errorf("splitAllocation() remaining = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestOrderRepoCreateWithVariants(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	products := NewProductRepo(db)
+	orders := NewOrderRepo(db)
+	user := createTestUser(t, db)
+
+	sofa := createTestProduct(t, db, "Диван", 50000, 0)
+	variantPrice := 55000.0
+	velvet := &entity.ProductVariant{ProductID: sofa.ID, SKU: "SOFA-VELVET", Options: map[string]string{"fabric": "velvet"}, Price: &variantPrice, Stock: 2, Images: []string{}}
+	linen := &entity.ProductVariant{ProductID: sofa.ID, SKU: "SOFA-LINEN", Options: map[string]string{"fabric": "linen"}, Stock: 5, Images: []string{}}
+	for _, v := range []*entity.ProductVariant{velvet, linen} {
+		if err := products.CreateVariant(ctx, v, 0); err != nil {
+			t.Fatalf("CreateVariant() error = %v", err)
+		}
+	}
+	table := createTestProduct(t, db, "Стол", 9000, 3)
+
+	order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending, Delivery: entity.Delivery{Method: entity.DeliveryCourier}, Items: []entity.OrderItem{
+		{ProductID: sofa.ID, VariantID: velvet.ID, Quantity: 2},
+		{ProductID: sofa.ID, VariantID: linen.ID, Quantity: 1},
+		{ProductID: table.ID, Quantity: 1},
+	}}
+	if err := orders.Create(ctx, order, time.Hour, false); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Цена варианта заменяет цену товара, без своей цены действует цена товара
+	if want := 2*55000.0 + 50000 + 9000; order.Total != want {
+		t.Errorf("order total = %v, want %v", order.Total, want)
+	}
+	for _, item := range order.Items {
+		if item.VariantID == velvet.ID && item.SKU != velvet.SKU {
+			t.Errorf("item SKU = %q, want %q", item.SKU, velvet.SKU)
+		}
+	}
+	// Резерв не списывает товар со склада, но убирает его из продажи
+	if got := productStock(t, db, sofa.ID); got != 7 {
+		t.Errorf("sofa stock = %d, want 7", got)
+	}
+	if got := availableStock(t, db, sofa.ID); got != 4 {
+		t.Errorf("sofa available = %d, want 4", got)
+	}
+	if got := availableStock(t, db, table.ID); got != 2 {
+		t.Errorf("table available = %d, want 2", got)
+	}
+
+	tests := []struct {
+		name string
+		item entity.OrderItem
+		want error
+	}{
+		{name: "variant required", item: entity.OrderItem{ProductID: sofa.ID, Quantity: 1}, want: apperrors.ErrVariantRequired},
+		{name: "variant reserved by other order", item: entity.OrderItem{ProductID: sofa.ID, VariantID: velvet.ID, Quantity: 1}, want: apperrors.ErrInsufficientStock},
+		{name: "variant of other product", item: entity.OrderItem{ProductID: table.ID, VariantID: linen.ID, Quantity: 1}, want: apperrors.ErrVariantNotFound},
+		{name: "unknown product", item: entity.OrderItem{ProductID: 999999, Quantity: 1}, want: apperrors.ErrProductNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending, Delivery: entity.Delivery{Method: entity.DeliveryCourier}, Items: []entity.OrderItem{tt.item}}
+			if err := orders.Create(ctx, order, time.Hour, false); !apperrors.Is(err, tt.want) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.want)
+			}
+			// Неудачный заказ не должен ничего зарезервировать
+			if got := availableStock(t, db, sofa.ID); got != 4 {
+				t.Errorf("sofa available = %d, want 4", got)
+			}
+			if got := availableStock(t, db, table.ID); got != 2 {
+				t.Errorf("table available = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestOrderRepoCreateAllocatesWarehouses(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	warehouses := NewWarehouseRepo(db)
+	stock := NewStockRepo(db)
+	orders := NewOrderRepo(db)
+	user := createTestUser(t, db)
+
+	// Основной склад из миграции имеет приоритет 0
+	var mainID int
+	if err := db.QueryRow(`SELECT id FROM warehouses WHERE code = 'main'`).Scan(&mainID); err != nil {
+		t.Fatalf("get main warehouse: %v", err)
+	}
+	reserve := &entity.Warehouse{Code: "reserve", Name: "Резервный склад", Type: entity.WarehouseTypeWarehouse, Active: true, Priority: 1}
+	showroom := &entity.Warehouse{Code: "showroom", Name: "Шоурум", Type: entity.WarehouseTypeShowroom, Pickup: true, Active: true, Priority: 2}
+	for _, w := range []*entity.Warehouse{reserve, showroom} {
+		if err := warehouses.Create(ctx, w); err != nil {
+			t.Fatalf("Create(warehouse) error = %v", err)
+		}
+	}
+
+	chair := createTestProduct(t, db, "Стул", 4990, 2)
+	for _, m := range []*entity.StockMovement{
+		{ProductID: chair.ID, WarehouseID: reserve.ID, Quantity: 3, Reason: entity.StockReceipt},
+		{ProductID: chair.ID, WarehouseID: showroom.ID, Quantity: 5, Reason: entity.StockReceipt},
+	} {
+		if err := stock.Post(ctx, m); err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+	}
+
+	// Доставка резервируется на складах по приоритету, шоурум не участвует
+	delivery := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 4}}}
+	if err := orders.Create(ctx, delivery, time.Hour, false); err != nil {
+		t.Fatalf("Create(delivery) error = %v", err)
+	}
+	want := []entity.OrderAllocation{{WarehouseID: mainID, Quantity: 2}, {WarehouseID: reserve.ID, Quantity: 2}}
+	if got := delivery.Items[0].Allocations; !reflect.DeepEqual(got, want) {
+		t.Errorf("delivery allocations = %+v, want %+v", got, want)
+	}
+
+	// Свободного остатка складов не хватает, а шоурум для доставки не используется
+	short := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 2}}}
+	if err := orders.Create(ctx, short, time.Hour, false); !apperrors.Is(err, apperrors.ErrInsufficientStock) {
+		t.Errorf("Create(short delivery) error = %v, want %v", err, apperrors.ErrInsufficientStock)
+	}
+
+	// Самовывоз резервируется только в выбранной точке
+	pickup := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryPickup, PickupWarehouseID: showroom.ID},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 2}}}
+	if err := orders.Create(ctx, pickup, time.Hour, false); err != nil {
+		t.Fatalf("Create(pickup) error = %v", err)
+	}
+	if want := []entity.OrderAllocation{{WarehouseID: showroom.ID, Quantity: 2}}; !reflect.DeepEqual(pickup.Items[0].Allocations, want) {
+		t.Errorf("pickup allocations = %+v, want %+v", pickup.Items[0].Allocations, want)
+	}
+
+	notPickup := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryPickup, PickupWarehouseID: reserve.ID},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 1}}}
+	if err := orders.Create(ctx, notPickup, time.Hour, false); !apperrors.Is(err, apperrors.ErrInvalidDelivery) {
+		t.Errorf("Create(pickup from warehouse) error = %v, want %v", err, apperrors.ErrInvalidDelivery)
+	}
+
+	// Оплата списывает резерв с тех складов, на которых он был
+	if err := orders.UpdateStatus(ctx, delivery.ID, entity.OrderStatusPending, entity.OrderStatusPaid, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus(paid) error = %v", err)
+	}
+	if got := warehouseStockOf(t, db, mainID, chair.ID); got != 0 {
+		t.Errorf("main stock after payment = %d, want 0", got)
+	}
+	if got := warehouseStockOf(t, db, reserve.ID, chair.ID); got != 1 {
+		t.Errorf("reserve stock after payment = %d, want 1", got)
+	}
+	paid, err := orders.GetByID(ctx, delivery.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got := paid.Items[0].Allocations; !reflect.DeepEqual(got, want) {
+		t.Errorf("paid allocations = %+v, want %+v", got, want)
+	}
+
+	// Отмена оплаченного заказа возвращает товар на те же склады
+	if err := orders.UpdateStatus(ctx, delivery.ID, entity.OrderStatusPaid, entity.OrderStatusCancelled, user.ID, ""); err != nil {
+		t.Fatalf("UpdateStatus(cancelled) error = %v", err)
+	}
+	if got := warehouseStockOf(t, db, mainID, chair.ID); got != 2 {
+		t.Errorf("main stock after cancel = %d, want 2", got)
+	}
+	if got := warehouseStockOf(t, db, reserve.ID, chair.ID); got != 3 {
+		t.Errorf("reserve stock after cancel = %d, want 3", got)
+	}
+}
+
+func TestOrderRepoExpiredReservations(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	orders := NewOrderRepo(db)
+	user := createTestUser(t, db)
+	lamp := createTestProduct(t, db, "Лампа", 2500, 3)
+
+	create := func(quantity int, ttl time.Duration) *entity.Order {
+		t.Helper()
+		order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+			Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+			Items:    []entity.OrderItem{{ProductID: lamp.ID, Quantity: quantity}}}
+		if err := orders.Create(ctx, order, ttl, false); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return order
+	}
+	expired := create(2, -time.Second)
+	active := create(1, time.Hour)
+	if got := availableStock(t, db, lamp.ID); got != 0 {
+		t.Fatalf("available before release = %d, want 0", got)
+	}
+
+	ids, err := orders.ListExpiredReservations(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListExpiredReservations() error = %v", err)
+	}
+	if want := []int{expired.ID}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ListExpiredReservations() = %v, want %v", ids, want)
+	}
+
+	// Снятие резерва возвращает товар в продажу, ничего не списывая и не возвращая на склад
+	if err := orders.UpdateStatus(ctx, expired.ID, entity.OrderStatusPending, entity.OrderStatusCancelled, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus(cancelled) error = %v", err)
+	}
+	if got := availableStock(t, db, lamp.ID); got != 2 {
+		t.Errorf("available after release = %d, want 2", got)
+	}
+	if got := productStock(t, db, lamp.ID); got != 3 {
+		t.Errorf("stock after release = %d, want 3", got)
+	}
+	ids, err = orders.ListExpiredReservations(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListExpiredReservations() error = %v", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("ListExpiredReservations() after release = %v, want none", ids)
+	}
+
+	// Оплаченный заказ больше не держит резерв, а товар списан со склада
+	if err := orders.UpdateStatus(ctx, active.ID, entity.OrderStatusPending, entity.OrderStatusPaid, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus(paid) error = %v", err)
+	}
+	if got := productStock(t, db, lamp.ID); got != 2 {
+		t.Errorf("stock after payment = %d, want 2", got)
+	}
+	if got := availableStock(t, db, lamp.ID); got != 2 {
+		t.Errorf("available after payment = %d, want 2", got)
+	}
+}
+
+func TestOrderFilterClause(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minTotal := 1000.0
+
+	tests := []struct {
+		name         string
+		filter       entity.OrderFilter
+		wantContains []string
+		wantArgs     int
+	}{
+		{name: "no filters", filter: entity.OrderFilter{}},
+		{
+			name:         "statuses and period",
+			filter:       entity.OrderFilter{Statuses: []entity.OrderStatus{entity.OrderStatusPaid}, CreatedFrom: &from},
+			wantContains: []string{" WHERE o.status = ANY($1) AND o.created_at >= $2"},
+			wantArgs:     2,
+		},
+		{
+			name:         "email and total",
+			filter:       entity.OrderFilter{Email: "ivan", TotalMin: &minTotal},
+			wantContains: []string{"u.email ILIKE $1 AND o.total >= $2"},
+			wantArgs:     2,
+		},
+		{
+			name:         "product",
+			filter:       entity.OrderFilter{ProductID: 5},
+			wantContains: []string{" WHERE EXISTS (SELECT 1 FROM order_items fi WHERE fi.order_id = o.id AND fi.product_id = $1)"},
+			wantArgs:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := orderFilterClause(tt.filter)
+			if len(args) != tt.wantArgs {
+				t.Errorf("orderFilterClause() args = %d, want %d", len(args), tt.wantArgs)
+			}
+			if len(tt.wantContains) == 0 && where != "" {
+				t.Errorf("orderFilterClause() where = %q, want empty", where)
+			}
+			for _, part := range tt.wantContains {
+				if !strings.Contains(where, part) {
+					t.Errorf("orderFilterClause() where = %q, want it to contain %q", where, part)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderRepoListFilter(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	orders := NewOrderRepo(db)
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	chair := createTestProduct(t, db, "Стул", 5000, 10)
+	table := createTestProduct(t, db, "Стол", 20000, 10)
+
+	create := func(user *entity.User, items ...entity.OrderItem) *entity.Order {
+		t.Helper()
+		order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+			Delivery: entity.Delivery{Method: entity.DeliveryCourier}, Items: items}
+		for _, item := range items {
+			order.Total += item.Price * float64(item.Quantity)
+		}
+		if err := orders.Create(ctx, order, time.Hour, false); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return order
+	}
+	chairs := create(alice, entity.OrderItem{ProductID: chair.ID, Quantity: 1, Price: chair.Price})
+	tables := create(bob, entity.OrderItem{ProductID: table.ID, Quantity: 1, Price: table.Price})
+	both := create(alice,
+		entity.OrderItem{ProductID: chair.ID, Quantity: 2, Price: chair.Price},
+		entity.OrderItem{ProductID: table.ID, Quantity: 1, Price: table.Price})
+	if err := orders.UpdateStatus(ctx, tables.ID, entity.OrderStatusPending, entity.OrderStatusPaid, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	minTotal := 20000.0
+	tests := []struct {
+		name   string
+		filter entity.OrderFilter
+		want   []int
+	}{
+		{name: "all newest first", want: []int{both.ID, tables.ID, chairs.ID}},
+		{name: "status", filter: entity.OrderFilter{Statuses: []entity.OrderStatus{entity.OrderStatusPaid}}, want: []int{tables.ID}},
+		{name: "email", filter: entity.OrderFilter{Email: alice.Email}, want: []int{both.ID, chairs.ID}},
+		{name: "total", filter: entity.OrderFilter{TotalMin: &minTotal}, want: []int{both.ID, tables.ID}},
+		{name: "product", filter: entity.OrderFilter{ProductID: chair.ID}, want: []int{both.ID, chairs.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orders.List(ctx, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			ids := make([]int, 0, len(got))
+			for _, o := range got {
+				ids = append(ids, o.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("List() ids = %v, want %v", ids, tt.want)
+			}
+			count, err := orders.Count(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != len(tt.want) {
+				t.Errorf("Count() = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+
+	details, err := orders.GetAdminOrder(ctx, both.ID)
+	if err != nil || details == nil {
+		t.Fatalf("GetAdminOrder() = %v, %v", details, err)
+	}
+	if details.CustomerEmail != alice.Email || len(details.Items) != 2 {
+		t.Errorf("GetAdminOrder() email %q items %d, want %q and 2 items", details.CustomerEmail, len(details.Items), alice.Email)
+	}
+
+	note := &entity.OrderNote{OrderID: both.ID, AuthorID: &bob.ID, Text: "Позвонить перед доставкой"}
+	if err := orders.AddNote(ctx, note); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	notes, err := orders.ListNotes(ctx, both.ID)
+	if err != nil {
+		t.Fatalf("ListNotes() error = %v", err)
+	}
+	if len(notes) != 1 || notes[0].Text != note.Text {
+		t.Errorf("ListNotes() = %+v, want the added note", notes)
+	}
+	if err := orders.AddNote(ctx, &entity.OrderNote{OrderID: both.ID + 100, Text: "нет заказа"}); !apperrors.Is(err, apperrors.ErrOrderNotFound) {
+		t.Errorf("AddNote(missing order) error = %v, want %v", err, apperrors.ErrOrderNotFound)
+	}
+}
+
+func TestOrderRepoCreateFromCart(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	carts := NewCartRepo(db)
+	orders := NewOrderRepo(db)
+	user := createTestUser(t, db)
+	chair := createTestProduct(t, db, "Стул", 4990, 1)
+
+	if err := carts.AddItem(ctx, user.ID, chair.ID, 0, 1); err != nil {
+		t.Fatalf("AddItem() error = %v", err)
+	}
+	cartItems := func() int {
+		t.Helper()
+		items, err := carts.Items(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("Items() error = %v", err)
+		}
+		return len(items)
+	}
+
+	// Неудачное оформление откатывается вместе с очисткой корзины
+	tooMany := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 2}}}
+	if err := orders.Create(ctx, tooMany, time.Hour, true); !apperrors.Is(err, apperrors.ErrInsufficientStock) {
+		t.Fatalf("Create(insufficient) error = %v, want %v", err, apperrors.ErrInsufficientStock)
+	}
+	if got := cartItems(); got != 1 {
+		t.Errorf("cart items after failed order = %d, want 1", got)
+	}
+
+	order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: chair.ID, Quantity: 1}}}
+	if err := orders.Create(ctx, order, time.Hour, true); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got := cartItems(); got != 0 {
+		t.Errorf("cart items after order = %d, want 0", got)
+	}
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+type PaymentRepo struct {
+	db *sql.DB
+}
+
+func NewPaymentRepo(db *sql.DB) *PaymentRepo {
+	return &PaymentRepo{db: db}
+}
+
+const paymentColumns = `id, order_id, provider, COALESCE(provider_payment_id, ''), amount, refunded_amount, currency, status,
+	COALESCE(confirmation_url, ''), created_at, updated_at`
+
+// Create сохраняет платеж до обращения к провайдеру: его ID служит ключом идемпотентности запроса
+func (r *PaymentRepo) Create(ctx context.Context, p *entity.PaymentIntent) error {
+	query := `
+		INSERT INTO payment_intents (order_id, provider, amount, currency, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, p.OrderID, p.Provider, p.Amount, p.Currency, p.Status).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create payment: %w", err)
+	}
+	return nil
+}
+
+// SaveProviderPayment сохраняет ответ провайдера на создание платежа
+func (r *PaymentRepo) SaveProviderPayment(ctx context.Context, p *entity.PaymentIntent) error {
+	query := `
+		UPDATE payment_intents
+		SET provider_payment_id = $1, status = $2, confirmation_url = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, p.ProviderPaymentID, p.Status, p.ConfirmationURL, p.ID).Scan(&p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrPaymentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("save provider payment: %w", err)
+	}
+	return nil
+}
+
+// GetByProviderPaymentID возвращает платеж по идентификатору провайдера или nil, если его нет
+func (r *PaymentRepo) GetByProviderPaymentID(ctx context.Context, provider, providerPaymentID string) (*entity.PaymentIntent, error) {
+	p, err := scanPayment(r.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payment_intents WHERE provider = $1 AND provider_payment_id = $2`,
+		provider, providerPaymentID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetLatestByOrder возвращает последний платеж заказа в статусе status или nil, если такого нет
+func (r *PaymentRepo) GetLatestByOrder(ctx context.Context, orderID int, status entity.PaymentStatus) (*entity.PaymentIntent, error) {
+	p, err := scanPayment(r.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payment_intents WHERE order_id = $1 AND status = $2 ORDER BY id DESC LIMIT 1`,
+		orderID, status,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListByOrder возвращает все платежи заказа, начиная с первого
+func (r *PaymentRepo) ListByOrder(ctx context.Context, orderID int) ([]*entity.PaymentIntent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+paymentColumns+` FROM payment_intents WHERE order_id = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list payments: %w", err)
+	}
+	defer rows.Close()
+
+	payments := make([]*entity.PaymentIntent, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return payments, nil
+}
+
+// UpdateStatus переводит платеж из статуса from в to. false означает, что платеж уже не в статусе from:
+// его обработал параллельный или повторный вебхук.
+func (r *PaymentRepo) UpdateStatus(ctx context.Context, p *entity.PaymentIntent, from, to entity.PaymentStatus) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE payment_intents SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3
+		RETURNING updated_at`,
+		to, p.ID, from,
+	).Scan(&p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update payment status: %w", err)
+	}
+
+	p.Status = to
+	return true, nil
+}
+
+// AddRefund учитывает возврат amount по проведенному платежу; отрицательная сумма отменяет неудавшийся возврат.
+// Когда возвращена вся сумма, платеж становится refunded. Возврат сверх суммы платежа — ErrInvalidRefund.
+func (r *PaymentRepo) AddRefund(ctx context.Context, p *entity.PaymentIntent, amount float64) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE payment_intents
+		SET refunded_amount = refunded_amount + $1,
+		    status = CASE WHEN refunded_amount + $1 >= amount THEN 'refunded' ELSE 'succeeded' END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status IN ('succeeded', 'refunded')
+		  AND refunded_amount + $1 BETWEEN 0 AND amount
+		RETURNING refunded_amount, status, updated_at`,
+		amount, p.ID,
+	).Scan(&p.RefundedAmount, &p.Status, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %.2f", apperrors.ErrInvalidRefund, amount)
+	}
+	if err != nil {
+		return fmt.Errorf("add refund: %w", err)
+	}
+	return nil
+}
+
+func scanPayment(row interface{ Scan(...interface{}) error }) (*entity.PaymentIntent, error) {
+	var p entity.PaymentIntent
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.ProviderPaymentID, &p.Amount, &p.RefundedAmount, &p.Currency,
+		&p.Status, &p.ConfirmationURL, &p.CreatedAt, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan payment: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,103 @@
+// Package pgtest поднимает тестовую базу PostgreSQL для интеграционных тестов.
+// Тесты пропускаются, если не задан TEST_DATABASE_URL.
+package pgtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/migrate"
+	_ "github.com/lib/pq"
+)
+
+// lockKey — ключ advisory lock, которым тесты разных пакетов сериализуют доступ к общей базе
+const lockKey = 7245001
+
+// Open подключается к TEST_DATABASE_URL, применяет миграции и очищает все таблицы,
+// оставляя только справочные записи, без которых не работает приложение (основной склад).
+// База принадлежит тесту до его завершения.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	lock, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("get lock connection: %v", err)
+	}
+	if _, err := lock.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		t.Fatalf("acquire test database lock: %v", err)
+	}
+	t.Cleanup(func() {
+		lock.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+		lock.Close()
+	})
+
+	if err := migrate.NewMigrator(migrationsPath()).Run(db); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	if err := truncate(ctx, db); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	if err := seed(ctx, db); err != nil {
+		t.Fatalf("seed tables: %v", err)
+	}
+	return db
+}
+
+// migrationsPath возвращает путь к migrations/ в корне репозитория независимо от пакета теста
+func migrationsPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "..", "migrations")
+}
+
+func truncate(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT quote_ident(tablename) FROM pg_tables
+		WHERE schemaname = 'public' AND tablename <> 'schema_migrations'`)
+	if err != nil {
+		return fmt.Errorf("list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate tables: %w", err)
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE")
+	return err
+}
+
+// seed возвращает записи, которые создают миграции и на которые опирается код:
+// без основного склада не проводятся остатки, заданные в карточке товара
+func seed(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO warehouses (code, name) VALUES ('main', 'Основной склад')`)
+	return err
+}
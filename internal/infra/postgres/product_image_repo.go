@@ -0,0 +1,345 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+// ProductImageRepo хранит галерею товара. Все изменения идут в транзакции под блокировкой товара
+// и заканчиваются синхронизацией products.image_url с главным изображением.
+type ProductImageRepo struct {
+	db *sql.DB
+}
+
+func NewProductImageRepo(db *sql.DB) *ProductImageRepo {
+	return &ProductImageRepo{db: db}
+}
+
+// ListByProduct возвращает изображения товара в порядке отображения
+func (r *ProductImageRepo) ListByProduct(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	query := `
+		SELECT id, product_id, url, alt_text, position, is_primary, created_at
+		FROM product_images
+		WHERE product_id = $1
+		ORDER BY position, id`
+
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("list product images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []entity.ProductImage{}
+	for rows.Next() {
+		var img entity.ProductImage
+		err := rows.Scan(
+			&img.ID,
+			&img.ProductID,
+			&img.URL,
+			&img.AltText,
+			&img.Position,
+			&img.IsPrimary,
+			&img.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan product image: %w", err)
+		}
+		images = append(images, img)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return images, nil
+}
+
+// Add добавляет изображение в конец галереи. Изображение становится главным,
+// если это запрошено (img.IsPrimary) или у товара еще нет главного изображения.
+func (r *ProductImageRepo) Add(ctx context.Context, img *entity.ProductImage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("add product image - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, img.ProductID); err != nil {
+		return err
+	}
+
+	var hasPrimary bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(position) + 1, 0), COALESCE(BOOL_OR(is_primary), FALSE)
+		FROM product_images WHERE product_id = $1`,
+		img.ProductID,
+	).Scan(&img.Position, &hasPrimary)
+	if err != nil {
+		return fmt.Errorf("add product image - next position: %w", err)
+	}
+
+	img.IsPrimary = img.IsPrimary || !hasPrimary
+	if img.IsPrimary && hasPrimary {
+		if err := clearPrimaryImage(ctx, tx, img.ProductID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO product_images (product_id, url, alt_text, position, is_primary)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		img.ProductID, img.URL, img.AltText, img.Position, img.IsPrimary,
+	).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("add product image: %w", err)
+	}
+
+	if err := syncPrimaryImage(ctx, tx, img.ProductID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("add product image - commit: %w", err)
+	}
+	return nil
+}
+
+// ReplacePrimary заменяет главное изображение новым на той же позиции
+// и возвращает URL старого (пустой, если главного не было), чтобы удалить файл из хранилища
+func (r *ProductImageRepo) ReplacePrimary(ctx context.Context, img *entity.ProductImage) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("replace primary image - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, img.ProductID); err != nil {
+		return "", err
+	}
+
+	var oldURL string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM product_images
+		WHERE product_id = $1 AND is_primary
+		RETURNING url, position`,
+		img.ProductID,
+	).Scan(&oldURL, &img.Position)
+	if errors.Is(err, sql.ErrNoRows) {
+		img.Position = 0
+	} else if err != nil {
+		return "", fmt.Errorf("replace primary image - delete old: %w", err)
+	}
+
+	img.IsPrimary = true
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO product_images (product_id, url, alt_text, position, is_primary)
+		VALUES ($1, $2, $3, $4, TRUE)
+		RETURNING id, created_at`,
+		img.ProductID, img.URL, img.AltText, img.Position,
+	).Scan(&img.ID, &img.CreatedAt)
+	if err != nil {
+		return "", fmt.Errorf("replace primary image: %w", err)
+	}
+
+	if err := syncPrimaryImage(ctx, tx, img.ProductID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("replace primary image - commit: %w", err)
+	}
+	return oldURL, nil
+}
+
+// UpdateAltText меняет альтернативный текст изображения
+func (r *ProductImageRepo) UpdateAltText(ctx context.Context, productID, imageID int, altText string) (*entity.ProductImage, error) {
+	query := `
+		UPDATE product_images SET alt_text = $1
+		WHERE id = $2 AND product_id = $3
+		RETURNING id, product_id, url, alt_text, position, is_primary, created_at`
+
+	img := &entity.ProductImage{}
+	err := r.db.QueryRowContext(ctx, query, altText, imageID, productID).Scan(
+		&img.ID,
+		&img.ProductID,
+		&img.URL,
+		&img.AltText,
+		&img.Position,
+		&img.IsPrimary,
+		&img.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", apperrors.ErrImageNotFound, imageID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update product image alt text: %w", err)
+	}
+	return img, nil
+}
+
+// SetPrimary делает изображение главным
+func (r *ProductImageRepo) SetPrimary(ctx context.Context, productID, imageID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("set primary image - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM product_images WHERE id = $1 AND product_id = $2)`,
+		imageID, productID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("set primary image - check image: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", apperrors.ErrImageNotFound, imageID)
+	}
+
+	if err := clearPrimaryImage(ctx, tx, productID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE product_images SET is_primary = TRUE WHERE id = $1`, imageID); err != nil {
+		return fmt.Errorf("set primary image: %w", err)
+	}
+
+	if err := syncPrimaryImage(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("set primary image - commit: %w", err)
+	}
+	return nil
+}
+
+// Reorder задает порядок галереи; imageIDs должен содержать каждое изображение товара ровно один раз
+func (r *ProductImageRepo) Reorder(ctx context.Context, productID int, imageIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("reorder product images - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	var total, matched int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE id = ANY($2))
+		FROM product_images WHERE product_id = $1`,
+		productID, pq.Array(imageIDs),
+	).Scan(&total, &matched)
+	if err != nil {
+		return fmt.Errorf("reorder product images - check images: %w", err)
+	}
+	if total != len(imageIDs) || matched != total || hasDuplicates(imageIDs) {
+		return apperrors.ErrInvalidImageOrder
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE product_images pi
+		SET position = o.ord - 1
+		FROM unnest($2::int[]) WITH ORDINALITY AS o(id, ord)
+		WHERE pi.id = o.id AND pi.product_id = $1`,
+		productID, pq.Array(imageIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("reorder product images: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("reorder product images - commit: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет изображение и возвращает его URL для удаления файла из хранилища.
+// Если удалено главное изображение, главным становится первое из оставшихся.
+func (r *ProductImageRepo) Delete(ctx context.Context, productID, imageID int) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("delete product image - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, productID); err != nil {
+		return "", err
+	}
+
+	var url string
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING url`,
+		imageID, productID,
+	).Scan(&url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %d", apperrors.ErrImageNotFound, imageID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("delete product image: %w", err)
+	}
+
+	if err := syncPrimaryImage(ctx, tx, productID); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("delete product image - commit: %w", err)
+	}
+	return url, nil
+}
+
+func clearPrimaryImage(ctx context.Context, tx *sql.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE product_images SET is_primary = FALSE WHERE product_id = $1 AND is_primary`, productID)
+	if err != nil {
+		return fmt.Errorf("clear primary image: %w", err)
+	}
+	return nil
+}
+
+// syncPrimaryImage назначает главным первое изображение, если главного нет,
+// и копирует URL главного изображения в products.image_url
+func syncPrimaryImage(ctx context.Context, tx *sql.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE product_images SET is_primary = TRUE
+		WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1)
+		  AND NOT EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND is_primary)`,
+		productID)
+	if err != nil {
+		return fmt.Errorf("promote primary image: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products
+		SET image_url = COALESCE((SELECT url FROM product_images WHERE product_id = $1 AND is_primary), ''),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		productID)
+	if err != nil {
+		return fmt.Errorf("sync product image url: %w", err)
+	}
+	return nil
+}
+
+func hasDuplicates(ids []int) bool {
+	seen := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			return true
+		}
+		seen[id] = struct{}{}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestHasDuplicates(t *testing.T) {
+	tests := []struct {
+		ids  []int
+		want bool
+	}{
+		{nil, false},
+		{[]int{1}, false},
+		{[]int{3, 1, 2}, false},
+		{[]int{3, 1, 3}, true},
+	}
+
+	for _, tt := range tests {
+		if got := hasDuplicates(tt.ids); got != tt.want {
+			t.Errorf("hasDuplicates(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+	}
+}
+
+func TestProductImageRepoGallery(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductImageRepo(db)
+	product := createTestProduct(t, db, "Диван", 50000, 1)
+	other := createTestProduct(t, db, "Стол", 9000, 1)
+
+	images := make([]*entity.ProductImage, 3)
+	for i, url := range []string{"/a.jpg", "/b.jpg", "/c.jpg"} {
+		images[i] = &entity.ProductImage{ProductID: product.ID, URL: url}
+		if err := repo.Add(ctx, images[i]); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	// Первое изображение товара без галереи становится главным
+	assertGallery(t, db, repo, product.ID, []int{images[0].ID, images[1].ID, images[2].ID}, images[0].ID, "/a.jpg")
+
+	if err := repo.SetPrimary(ctx, product.ID, images[2].ID); err != nil {
+		t.Fatalf("SetPrimary() error = %v", err)
+	}
+	assertGallery(t, db, repo, product.ID, []int{images[0].ID, images[1].ID, images[2].ID}, images[2].ID, "/c.jpg")
+
+	if err := repo.Reorder(ctx, product.ID, []int{images[1].ID, images[2].ID, images[0].ID}); err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	assertGallery(t, db, repo, product.ID, []int{images[1].ID, images[2].ID, images[0].ID}, images[2].ID, "/c.jpg")
+
+	foreign := &entity.ProductImage{ProductID: other.ID, URL: "/other.jpg"}
+	if err := repo.Add(ctx, foreign); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	invalidOrders := map[string][]int{
+		"missing image":   {images[1].ID, images[2].ID},
+		"duplicate image": {images[1].ID, images[2].ID, images[2].ID},
+		"foreign image":   {images[1].ID, images[2].ID, foreign.ID},
+	}
+	for name, ids := range invalidOrders {
+		if err := repo.Reorder(ctx, product.ID, ids); !apperrors.Is(err, apperrors.ErrInvalidImageOrder) {
+			t.Errorf("Reorder(%s) error = %v, want %v", name, err, apperrors.ErrInvalidImageOrder)
+		}
+	}
+
+	// После удаления главного главным становится первое по порядку
+	url, err := repo.Delete(ctx, product.ID, images[2].ID)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if url != "/c.jpg" {
+		t.Errorf("Delete() url = %q, want /c.jpg", url)
+	}
+	assertGallery(t, db, repo, product.ID, []int{images[1].ID, images[0].ID}, images[1].ID, "/b.jpg")
+
+	if _, err := repo.Delete(ctx, product.ID, foreign.ID); !apperrors.Is(err, apperrors.ErrImageNotFound) {
+		t.Errorf("Delete(foreign) error = %v, want %v", err, apperrors.ErrImageNotFound)
+	}
+
+	for _, id := range []int{images[0].ID, images[1].ID} {
+		if _, err := repo.Delete(ctx, product.ID, id); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	assertGallery(t, db, repo, product.ID, nil, 0, "")
+}
+
+// assertGallery проверяет порядок галереи, главное изображение и products.image_url
+func assertGallery(t *testing.T, db *sql.DB, repo *ProductImageRepo, productID int, wantOrder []int, wantPrimary int, wantURL string) {
+	t.Helper()
+
+	images, err := repo.ListByProduct(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("ListByProduct() error = %v", err)
+	}
+	if len(images) != len(wantOrder) {
+		t.Fatalf("gallery has %d images, want %d", len(images), len(wantOrder))
+	}
+	for i, img := range images {
+		if img.ID != wantOrder[i] {
+			t.Errorf("gallery[%d] = %d, want %d", i, img.ID, wantOrder[i])
+		}
+		if img.IsPrimary != (img.ID == wantPrimary) {
+			t.Errorf("image %d is_primary = %v, want %v", img.ID, img.IsPrimary, img.ID == wantPrimary)
+		}
+	}
+
+	var imageURL string
+	if err := db.QueryRow(`SELECT COALESCE(image_url, '') FROM products WHERE id = $1`, productID).Scan(&imageURL); err != nil {
+		t.Fatalf("get product image url: %v", err)
+	}
+	if imageURL != wantURL {
+		t.Errorf("products.image_url = %q, want %q", imageURL, wantURL)
+	}
+}
@@ -3,9 +3,16 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
 
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
 )
 
 type ProductRepo struct {
@@ -16,51 +23,118 @@ func NewProductRepo(db *sql.DB) *ProductRepo {
 	return &ProductRepo{db: db}
 }
 
-// Create создает новый продукт
-func (r *ProductRepo) Create(ctx context.Context, product *entity.Product) error {
+// Create создает новый продукт; изображение, если оно есть, становится главным в галерее.
+// Начальный остаток приходуется на основной склад и записывается в журнал как поступление от имени actorID.
+func (r *ProductRepo) Create(ctx context.Context, product *entity.Product, actorID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create product - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO products (name, description, price, category, stock, image_url) 
-		VALUES ($1, $2, $3, $4, $5, $6) 
+		INSERT INTO products (sku, external_id, name, description, price, category, category_id, stock, image_url)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), $3, $4, $5, $6, $7, 0, $8)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
+		product.SKU,
+		product.ExternalID,
 		product.Name,
 		product.Description,
 		product.Price,
 		product.Category,
-		product.Stock,
+		product.CategoryID,
 		product.ImageURL,
 	).Scan(&product.ID, &product.CreatedAt, &product.UpdatedAt)
 
 	if err != nil {
-		return fmt.Errorf("create product: %w", err)
+		return productKeyError("create product", err)
+	}
+
+	if product.ImageURL != "" {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO product_images (product_id, url, alt_text, position, is_primary)
+			VALUES ($1, $2, $3, 0, TRUE)`,
+			product.ID, product.ImageURL, product.Name,
+		)
+		if err != nil {
+			return fmt.Errorf("create product image: %w", err)
+		}
+	}
+
+	if err := replaceAttributeValues(ctx, tx, product.ID, product.Attributes); err != nil {
+		return err
+	}
+
+	if product.Stock != 0 {
+		warehouseID, err := defaultWarehouseID(ctx, tx)
+		if err != nil {
+			return err
+		}
+		movement := &entity.StockMovement{
+			ProductID:   product.ID,
+			WarehouseID: warehouseID,
+			Quantity:    product.Stock,
+			Reason:      entity.StockReceipt,
+			UserID:      actorID,
+			Comment:     "Начальный остаток",
+		}
+		if err := applyStockMovement(ctx, tx, movement); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create product - commit: %w", err)
 	}
 	return nil
 }
 
-// Update обновляет существующий продукт
+// Update обновляет существующий продукт.
+// Остаток не меняется: его ведет журнал движений (StockRepo), а product.Stock заполняется текущим значением.
+// image_url тоже не меняется: его ведет галерея (ProductImageRepo).
+// Характеристики заменяются целиком, если product.Attributes != nil.
 func (r *ProductRepo) Update(ctx context.Context, product *entity.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("update product - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE products 
-		SET name = $1, description = $2, price = $3, category = $4, stock = $5, image_url = $6, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $7
-		RETURNING updated_at`
+		SET name = $1, description = $2, price = $3, category = $4, category_id = $5,
+		    sku = NULLIF($7, ''), external_id = NULLIF($8, ''),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING stock, image_url, updated_at`
 
-	err := r.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		product.Name,
 		product.Description,
 		product.Price,
 		product.Category,
-		product.Stock,
-		product.ImageURL,
+		product.CategoryID,
 		product.ID,
-	).Scan(&product.UpdatedAt)
-
+		product.SKU,
+		product.ExternalID,
+	).Scan(&product.Stock, &product.ImageURL, &product.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", apperrors.ErrProductNotFound, product.ID)
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("product not found: %d", product.ID)
+		return productKeyError("update product", err)
+	}
+
+	if product.Attributes != nil {
+		if err := replaceAttributeValues(ctx, tx, product.ID, product.Attributes); err != nil {
+			return err
 		}
-		return fmt.Errorf("update product: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update product - commit: %w", err)
 	}
 	return nil
 }
@@ -86,22 +160,18 @@ func (r *ProductRepo) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-// List возвращает список продуктов с пагинацией и фильтрацией
-func (r *ProductRepo) List(ctx context.Context, category string, limit, offset int) ([]*entity.Product, error) {
-	baseQuery := `
-		SELECT id, name, description, price, category, stock, image_url, created_at, updated_at 
-		FROM products`
+// List возвращает список продуктов с пагинацией, фильтрацией и сортировкой
+func (r *ProductRepo) List(ctx context.Context, filter entity.ProductFilter, limit, offset int) ([]*entity.Product, error) {
+	where, args := productFilterClause(filter)
 
-	var query string
-	var args []interface{}
+	keyset := productKeysetFor(filter.Sort)
 
-	if category != "" {
-		query = baseQuery + " WHERE category = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
-		args = []interface{}{category, limit, offset}
-	} else {
-		query = baseQuery + " ORDER BY created_at DESC LIMIT $1 OFFSET $2"
-		args = []interface{}{limit, offset}
-	}
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT p.id, COALESCE(p.sku, ''), COALESCE(p.external_id, ''), p.name, p.description, p.price, p.category, p.category_id, p.stock, p.image_url, p.created_at, p.updated_at
+		FROM %s%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, productListFrom(filter.Sort), where, keyset.orderBy(), len(args)-1, len(args))
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -114,10 +184,13 @@ func (r *ProductRepo) List(ctx context.Context, category string, limit, offset i
 		var p entity.Product
 		err := rows.Scan(
 			&p.ID,
+			&p.SKU,
+			&p.ExternalID,
 			&p.Name,
 			&p.Description,
 			&p.Price,
 			&p.Category,
+			&p.CategoryID,
 			&p.Stock,
 			&p.ImageURL,
 			&p.CreatedAt,
@@ -133,22 +206,287 @@ func (r *ProductRepo) List(ctx context.Context, category string, limit, offset i
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
+	if err := r.loadVariants(ctx, products); err != nil {
+		return nil, err
+	}
+	if err := r.loadAvailability(ctx, products); err != nil {
+		return nil, err
+	}
+
 	return products, nil
 }
 
+// ListAfter возвращает страницу продуктов после курсора (keyset-пагинация) без подсчета общего количества.
+// Курсор следующей страницы равен nil, если страница последняя.
+func (r *ProductRepo) ListAfter(ctx context.Context, filter entity.ProductFilter, after *pagination.Cursor, limit int) ([]*entity.Product, *pagination.Cursor, error) {
+	where, args := productFilterClause(filter)
+	keyset := productKeysetFor(filter.Sort)
+
+	if after != nil {
+		op := ">"
+		if keyset.desc {
+			op = "<"
+		}
+		args = append(args, after.Value, after.ID)
+		condition := fmt.Sprintf("(%s, p.id) %s ($%d::%s, $%d)", keyset.expr, op, len(args)-1, keyset.cast, len(args))
+		if where == "" {
+			where = " WHERE " + condition
+		} else {
+			where += " AND " + condition
+		}
+	}
+
+	// Берем на одну строку больше, чтобы понять, есть ли следующая страница
+	args = append(args, limit+1)
+	query := fmt.Sprintf(`
+		SELECT p.id, COALESCE(p.sku, ''), COALESCE(p.external_id, ''), p.name, p.description, p.price, p.category, p.category_id, p.stock, p.image_url, p.created_at, p.updated_at,
+		       (%s)::text
+		FROM %s%s
+		ORDER BY %s
+		LIMIT $%d`, keyset.expr, productListFrom(filter.Sort), where, keyset.orderBy(), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list products after cursor: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]*entity.Product, 0, limit+1)
+	sortKeys := make([]string, 0, limit+1)
+	for rows.Next() {
+		var p entity.Product
+		var sortKey string
+		err := rows.Scan(
+			&p.ID,
+			&p.SKU,
+			&p.ExternalID,
+			&p.Name,
+			&p.Description,
+			&p.Price,
+			&p.Category,
+			&p.CategoryID,
+			&p.Stock,
+			&p.ImageURL,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+			&sortKey,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan product: %w", err)
+		}
+		products = append(products, &p)
+		sortKeys = append(sortKeys, sortKey)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	var next *pagination.Cursor
+	if len(products) > limit {
+		products = products[:limit]
+		next = &pagination.Cursor{Value: sortKeys[limit-1], ID: products[limit-1].ID}
+	}
+
+	if err := r.loadVariants(ctx, products); err != nil {
+		return nil, nil, err
+	}
+	if err := r.loadAvailability(ctx, products); err != nil {
+		return nil, nil, err
+	}
+	return products, next, nil
+}
+
+// Facets считает количество товаров по категориям и ценовым диапазонам с границами priceBuckets (по возрастанию).
+// Фасет категорий не учитывает фильтр по категориям, фасет цены — фильтр по цене.
+func (r *ProductRepo) Facets(ctx context.Context, filter entity.ProductFilter, priceBuckets []float64) (*entity.ProductFacets, error) {
+	facets := &entity.ProductFacets{
+		Categories:  make([]entity.CategoryFacet, 0),
+		PriceRanges: make([]entity.PriceRangeFacet, 0, len(priceBuckets)+1),
+	}
+
+	categoryFilter := filter
+	categoryFilter.Categories, categoryFilter.CategoryIDs = nil, nil
+	where, args := productFilterClause(categoryFilter)
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT p.category_id, p.category, COUNT(*) FROM products p"+where+
+			" GROUP BY p.category_id, p.category ORDER BY p.category", args...)
+	if err != nil {
+		return nil, fmt.Errorf("category facets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet entity.CategoryFacet
+		if err := rows.Scan(&facet.CategoryID, &facet.Category, &facet.Count); err != nil {
+			return nil, fmt.Errorf("scan category facet: %w", err)
+		}
+		facets.Categories = append(facets.Categories, facet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	priceFilter := filter
+	priceFilter.MinPrice, priceFilter.MaxPrice = nil, nil
+	where, args = productFilterClause(priceFilter)
+
+	columns := make([]string, 0, len(priceBuckets)+1)
+	lower := 0.0
+	for _, upper := range priceBuckets {
+		columns = append(columns, fmt.Sprintf("COUNT(*) FILTER (WHERE p.price >= %g AND p.price < %g)", lower, upper))
+		facets.PriceRanges = append(facets.PriceRanges, entity.PriceRangeFacet{Min: lower, Max: &upper})
+		lower = upper
+	}
+	columns = append(columns, fmt.Sprintf("COUNT(*) FILTER (WHERE p.price >= %g)", lower))
+	facets.PriceRanges = append(facets.PriceRanges, entity.PriceRangeFacet{Min: lower})
+
+	dest := make([]interface{}, len(facets.PriceRanges))
+	for i := range facets.PriceRanges {
+		dest[i] = &facets.PriceRanges[i].Count
+	}
+
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM products p" + where
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("price facets: %w", err)
+	}
+
+	return facets, nil
+}
+
+// productFilterClause собирает WHERE для фильтра каталога (таблица products с алиасом p)
+func productFilterClause(filter entity.ProductFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(filter.Categories) > 0 || len(filter.CategoryIDs) > 0 {
+		// Категория задается ID, slug или названием без учета регистра и включает все подкатегории
+		names := make([]string, 0, len(filter.Categories))
+		for _, category := range filter.Categories {
+			names = append(names, strings.ToLower(category))
+		}
+		args = append(args, pq.Array(filter.CategoryIDs), pq.Array(names))
+		conditions = append(conditions, fmt.Sprintf(`p.category_id IN (
+			WITH RECURSIVE tree AS (
+				SELECT id FROM categories WHERE id = ANY($%[1]d) OR slug = ANY($%[2]d) OR lower(name) = ANY($%[2]d)
+				UNION
+				SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+			)
+			SELECT id FROM tree)`, len(args)-1, len(args)))
+	}
+	if filter.MinPrice != nil {
+		args = append(args, *filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("p.price >= $%d", len(args)))
+	}
+	if filter.MaxPrice != nil {
+		args = append(args, *filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("p.price <= $%d", len(args)))
+	}
+	if filter.InStock {
+		// В наличии — есть свободный остаток на активном складе: резервы неоплаченных заказов не учитываются
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM warehouse_stock ws
+			JOIN warehouses w ON w.id = ws.warehouse_id
+			WHERE ws.product_id = p.id AND ws.stock > 0 AND w.active AND ws.stock - `+reservedStockExpr+` > 0)`)
+	}
+	if filter.UpdatedFrom != nil {
+		args = append(args, *filter.UpdatedFrom)
+		conditions = append(conditions, fmt.Sprintf("p.updated_at >= $%d", len(args)))
+	}
+	if filter.UpdatedTo != nil {
+		args = append(args, *filter.UpdatedTo)
+		conditions = append(conditions, fmt.Sprintf("p.updated_at < $%d", len(args)))
+	}
+	for _, attr := range filter.Attributes {
+		args = append(args, attr.Code)
+		valueConditions := []string{fmt.Sprintf("a.code = $%d", len(args))}
+		if attr.Min != nil {
+			args = append(args, *attr.Min)
+			valueConditions = append(valueConditions, fmt.Sprintf("v.num_value >= $%d", len(args)))
+		}
+		if attr.Max != nil {
+			args = append(args, *attr.Max)
+			valueConditions = append(valueConditions, fmt.Sprintf("v.num_value <= $%d", len(args)))
+		}
+		if len(attr.Values) > 0 {
+			args = append(args, pq.Array(attr.Values))
+			valueConditions = append(valueConditions, fmt.Sprintf("v.text_value = ANY($%d)", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM product_attribute_values v
+			JOIN attributes a ON a.id = v.attribute_id
+			WHERE v.product_id = p.id AND %s)`, strings.Join(valueConditions, " AND ")))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// productKeyset описывает ключ сортировки каталога: выражение, тип для приведения значения курсора и направление.
+// id добавляется вторым ключом и делает порядок детерминированным.
+type productKeyset struct {
+	expr string
+	cast string
+	desc bool
+}
+
+func (k productKeyset) orderBy() string {
+	if k.desc {
+		return k.expr + " DESC, p.id DESC"
+	}
+	return k.expr + " ASC, p.id ASC"
+}
+
+func productKeysetFor(sort entity.ProductSort) productKeyset {
+	switch sort {
+	case entity.ProductSortPriceAsc:
+		return productKeyset{expr: "p.price", cast: "numeric"}
+	case entity.ProductSortPriceDesc:
+		return productKeyset{expr: "p.price", cast: "numeric", desc: true}
+	case entity.ProductSortName:
+		return productKeyset{expr: "p.name", cast: "text"}
+	case entity.ProductSortPopularity:
+		return productKeyset{expr: "COALESCE(s.sold, 0)", cast: "bigint", desc: true}
+	default:
+		return productKeyset{expr: "p.created_at", cast: "timestamp", desc: true}
+	}
+}
+
+// productListFrom возвращает FROM для списка; для сортировки по популярности подключает продажи
+func productListFrom(sort entity.ProductSort) string {
+	if sort != entity.ProductSortPopularity {
+		return "products p"
+	}
+	// Популярность — количество проданных штук без учета отмененных заказов
+	return `products p
+		LEFT JOIN (
+			SELECT oi.product_id, SUM(oi.quantity) AS sold
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.status <> 'cancelled'
+			GROUP BY oi.product_id
+		) s ON s.product_id = p.id`
+}
+
 // GetByID возвращает продукт по ID
 func (r *ProductRepo) GetByID(ctx context.Context, id int) (*entity.Product, error) {
 	query := `
-		SELECT id, name, description, price, category, stock, image_url, created_at, updated_at 
+		SELECT id, COALESCE(sku, ''), COALESCE(external_id, ''), name, description, price, category, category_id, stock, image_url, created_at, updated_at
 		FROM products WHERE id = $1`
 
 	product := &entity.Product{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&product.ID,
+		&product.SKU,
+		&product.ExternalID,
 		&product.Name,
 		&product.Description,
 		&product.Price,
 		&product.Category,
+		&product.CategoryID,
 		&product.Stock,
 		&product.ImageURL,
 		&product.CreatedAt,
@@ -161,91 +499,658 @@ func (r *ProductRepo) GetByID(ctx context.Context, id int) (*entity.Product, err
 	if err != nil {
 		return nil, fmt.Errorf("get product by id: %w", err)
 	}
+
+	if err := r.loadVariants(ctx, []*entity.Product{product}); err != nil {
+		return nil, err
+	}
+	if err := r.loadAvailability(ctx, []*entity.Product{product}); err != nil {
+		return nil, err
+	}
+	if product.Attributes, err = productAttributeValues(ctx, r.db, product.ID); err != nil {
+		return nil, err
+	}
 	return product, nil
 }
 
-// Count возвращает общее количество продуктов (для пагинации)
-func (r *ProductRepo) Count(ctx context.Context, category string) (int, error) {
-	var query string
-	var args []interface{}
-
-	if category != "" {
-		query = `SELECT COUNT(*) FROM products WHERE category = $1`
-		args = []interface{}{category}
-	} else {
-		query = `SELECT COUNT(*) FROM products`
-		args = []interface{}{}
+// GetByIDs возвращает продукты по списку ID; отсутствующие ID в карте не появляются
+func (r *ProductRepo) GetByIDs(ctx context.Context, ids []int) (map[int]*entity.Product, error) {
+	products := make(map[int]*entity.Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
 	}
 
-	var count int
-	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	query := `
+		SELECT id, COALESCE(sku, ''), COALESCE(external_id, ''), name, description, price, category, category_id, stock, image_url, created_at, updated_at
+		FROM products WHERE id = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
-		return 0, fmt.Errorf("count products: %w", err)
+		return nil, fmt.Errorf("get products by ids: %w", err)
 	}
+	defer rows.Close()
 
-	return count, nil
-}
+	for rows.Next() {
+		var p entity.Product
+		err := rows.Scan(
+			&p.ID,
+			&p.SKU,
+			&p.ExternalID,
+			&p.Name,
+			&p.Description,
+			&p.Price,
+			&p.Category,
+			&p.CategoryID,
+			&p.Stock,
+			&p.ImageURL,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan product: %w", err)
+		}
+		products[p.ID] = &p
+	}
 
-// UpdateStock обновляет количество товара на складе
-func (r *ProductRepo) UpdateStock(ctx context.Context, id, stock int) error {
-	query := `UPDATE products SET stock = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
 
-	result, err := r.db.ExecContext(ctx, query, stock, id)
-	if err != nil {
-		return fmt.Errorf("update product stock: %w", err)
+	list := make([]*entity.Product, 0, len(products))
+	for _, p := range products {
+		list = append(list, p)
+	}
+	if err := r.loadVariants(ctx, list); err != nil {
+		return nil, err
+	}
+	if err := r.loadAvailability(ctx, list); err != nil {
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("update product stock - get rows affected: %w", err)
+	return products, nil
+}
+
+// Categories возвращает названия категорий продуктов без повторов
+func (r *ProductRepo) Categories(ctx context.Context, ids []int) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("product not found: %d", id)
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT category FROM products WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("get product categories: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	var categories []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("scan product category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return categories, nil
 }
 
-// Search выполняет поиск продуктов по названию и описанию
-func (r *ProductRepo) Search(ctx context.Context, query string, limit, offset int) ([]*entity.Product, error) {
-	sqlQuery := `
-		SELECT id, name, description, price, category, stock, image_url, created_at, updated_at 
-		FROM products 
-		WHERE name ILIKE $1 OR description ILIKE $1
-		ORDER BY created_at DESC 
-		LIMIT $2 OFFSET $3`
+// Export построчно передает в fn продукты по фильтру в порядке ID вместе с галереей и характеристиками.
+// Строки читаются из курсора по мере записи, поэтому каталог целиком в память не загружается.
+func (r *ProductRepo) Export(ctx context.Context, filter entity.ProductFilter, fn func(*entity.Product) error) error {
+	where, args := productFilterClause(filter)
+	query := `
+		SELECT p.id, COALESCE(p.sku, ''), COALESCE(p.external_id, ''), p.name, COALESCE(p.description, ''),
+		       p.price, p.category, p.category_id, p.stock, COALESCE(p.image_url, ''), p.created_at, p.updated_at,
+		       COALESCE((
+		           SELECT json_agg(json_build_object('id', i.id, 'product_id', i.product_id, 'url', i.url,
+		                  'alt_text', i.alt_text, 'position', i.position, 'is_primary', i.is_primary,
+		                  'created_at', i.created_at AT TIME ZONE 'UTC')
+		                  ORDER BY i.position, i.id)
+		           FROM product_images i WHERE i.product_id = p.id), '[]'),
+		       COALESCE((
+		           SELECT json_agg(json_build_object('attribute_id', a.id, 'code', a.code, 'name', a.name,
+		                  'type', a.type, 'unit', a.unit, 'number', v.num_value, 'value', COALESCE(v.text_value, ''))
+		                  ORDER BY a.code)
+		           FROM product_attribute_values v JOIN attributes a ON a.id = v.attribute_id
+		           WHERE v.product_id = p.id), '[]')
+		FROM products p` + where + `
+		ORDER BY p.id`
 
-	searchPattern := "%" + query + "%"
-	rows, err := r.db.QueryContext(ctx, sqlQuery, searchPattern, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("search products: %w", err)
+		return fmt.Errorf("export products: %w", err)
 	}
 	defer rows.Close()
 
-	var products []*entity.Product
 	for rows.Next() {
 		var p entity.Product
+		var images, attributes []byte
 		err := rows.Scan(
 			&p.ID,
+			&p.SKU,
+			&p.ExternalID,
 			&p.Name,
 			&p.Description,
 			&p.Price,
 			&p.Category,
+			&p.CategoryID,
 			&p.Stock,
 			&p.ImageURL,
 			&p.CreatedAt,
 			&p.UpdatedAt,
+			&images,
+			&attributes,
+		)
+		if err != nil {
+			return fmt.Errorf("scan product: %w", err)
+		}
+		if err := json.Unmarshal(images, &p.Images); err != nil {
+			return fmt.Errorf("decode product images: %w", err)
+		}
+		if err := json.Unmarshal(attributes, &p.Attributes); err != nil {
+			return fmt.Errorf("decode product attributes: %w", err)
+		}
+
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+// GetByKey возвращает ID продукта по внешнему ID или, если он пуст, по артикулу; 0 — продукт не найден
+func (r *ProductRepo) GetByKey(ctx context.Context, sku, externalID string) (int, error) {
+	column, key := "sku", sku
+	if externalID != "" {
+		column, key = "external_id", externalID
+	}
+	if key == "" {
+		return 0, nil
+	}
+
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM products WHERE "+column+" = $1", key).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get product by %s: %w", column, err)
+	}
+	return id, nil
+}
+
+// Count возвращает общее количество продуктов по фильтру (для пагинации)
+func (r *ProductRepo) Count(ctx context.Context, filter entity.ProductFilter) (int, error) {
+	where, args := productFilterClause(filter)
+
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products p"+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count products: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListVariants возвращает варианты товара
+func (r *ProductRepo) ListVariants(ctx context.Context, productID int) ([]entity.ProductVariant, error) {
+	byProduct, err := r.variantsByProduct(ctx, []int{productID})
+	if err != nil {
+		return nil, err
+	}
+	if variants, ok := byProduct[productID]; ok {
+		return variants, nil
+	}
+	return []entity.ProductVariant{}, nil
+}
+
+// CreateVariant добавляет вариант товара и пересчитывает остаток товара.
+// Остаток варианта приходуется на основной склад; у первого варианта собственные остатки товара
+// списываются со складов корректировкой, так как дальше остаток считается по вариантам.
+// Движения пишутся в журнал от имени actorID.
+func (r *ProductRepo) CreateVariant(ctx context.Context, variant *entity.ProductVariant, actorID int) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return fmt.Errorf("marshal variant options: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create variant - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, variant.ProductID); err != nil {
+		return err
+	}
+
+	var hasVariants bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1)`,
+		variant.ProductID,
+	).Scan(&hasVariants)
+	if err != nil {
+		return fmt.Errorf("create variant - check variants: %w", err)
+	}
+	if !hasVariants {
+		transfer := entity.StockMovement{Reason: entity.StockAdjustment, UserID: actorID, Comment: "Остаток товара перенесен на варианты"}
+		if err := clearStock(ctx, tx, variant.ProductID, 0, transfer); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO product_variants (product_id, sku, options, price, stock, images)
+		VALUES ($1, $2, $3, $4, 0, $5)
+		RETURNING id, created_at, updated_at`,
+		variant.ProductID, variant.SKU, options, variant.Price, pq.Array(variant.Images),
+	).Scan(&variant.ID, &variant.CreatedAt, &variant.UpdatedAt)
+	if err != nil {
+		return variantError("create variant", err)
+	}
+
+	if variant.Stock != 0 {
+		warehouseID, err := defaultWarehouseID(ctx, tx)
+		if err != nil {
+			return err
+		}
+		movement := &entity.StockMovement{
+			ProductID:   variant.ProductID,
+			VariantID:   variant.ID,
+			WarehouseID: warehouseID,
+			Quantity:    variant.Stock,
+			Reason:      entity.StockReceipt,
+			UserID:      actorID,
+			Comment:     "Начальный остаток варианта",
+		}
+		if err := applyStockMovement(ctx, tx, movement); err != nil {
+			return err
+		}
+	}
+
+	if err := syncStockTotals(ctx, tx, variant.ProductID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create variant - commit: %w", err)
+	}
+	return nil
+}
+
+// UpdateVariant обновляет вариант товара. Остаток не меняется: его ведет журнал движений (StockRepo),
+// а variant.Stock заполняется текущим значением.
+func (r *ProductRepo) UpdateVariant(ctx context.Context, variant *entity.ProductVariant) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return fmt.Errorf("marshal variant options: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("update variant - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, variant.ProductID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE product_variants
+		SET sku = $1, options = $2, price = $3, images = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND product_id = $6
+		RETURNING stock, created_at, updated_at`,
+		variant.SKU, options, variant.Price, pq.Array(variant.Images), variant.ID, variant.ProductID,
+	).Scan(&variant.Stock, &variant.CreatedAt, &variant.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", apperrors.ErrVariantNotFound, variant.ID)
+	}
+	if err != nil {
+		return variantError("update variant", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("update variant - commit: %w", err)
+	}
+	return nil
+}
+
+// DeleteVariant удаляет вариант товара и пересчитывает остаток товара.
+// Остатки варианта списываются со складов в журнале от имени actorID, история варианта сохраняется.
+func (r *ProductRepo) DeleteVariant(ctx context.Context, productID, variantID, actorID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete variant - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, productID); err != nil {
+		return err
+	}
+	if err := checkStockTarget(ctx, tx, productID, variantID); err != nil {
+		return err
+	}
+
+	writeOff := entity.StockMovement{Reason: entity.StockAdjustment, UserID: actorID, Comment: "Вариант удален"}
+	if err := clearStock(ctx, tx, productID, variantID, writeOff); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_variants WHERE id = $1 AND product_id = $2`, variantID, productID); err != nil {
+		return fmt.Errorf("delete variant: %w", err)
+	}
+
+	if err := syncStockTotals(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete variant - commit: %w", err)
+	}
+	return nil
+}
+
+// loadVariants подгружает варианты для списка товаров одним запросом
+func (r *ProductRepo) loadVariants(ctx context.Context, products []*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	byProduct, err := r.variantsByProduct(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		p.Variants = byProduct[p.ID]
+	}
+	return nil
+}
+
+// loadAvailability подгружает свободный остаток товаров и вариантов по активным складам и шоурумам одним запросом.
+// Резервы неоплаченных заказов вычитаются; Available товара и вариантов — сумма свободного остатка по точкам.
+func (r *ProductRepo) loadAvailability(ctx context.Context, products []*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT product_id, variant_id, warehouse_id, name, type, pickup, free
+		FROM (
+			SELECT ws.product_id, COALESCE(ws.variant_id, 0) AS variant_id, w.id AS warehouse_id,
+			       w.name, w.type, w.pickup, w.priority, ws.stock - `+reservedStockExpr+` AS free
+			FROM warehouse_stock ws
+			JOIN warehouses w ON w.id = ws.warehouse_id
+			WHERE ws.product_id = ANY($1) AND ws.stock > 0 AND w.active
+		) s
+		WHERE free > 0
+		ORDER BY priority, warehouse_id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("load availability: %w", err)
+	}
+	defer rows.Close()
+
+	type stockKey struct{ productID, variantID int }
+	byItem := make(map[stockKey][]entity.StockAvailability)
+	for rows.Next() {
+		var key stockKey
+		var a entity.StockAvailability
+		if err := rows.Scan(&key.productID, &key.variantID, &a.WarehouseID, &a.Name, &a.Type, &a.Pickup, &a.Stock); err != nil {
+			return fmt.Errorf("scan availability: %w", err)
+		}
+		byItem[key] = append(byItem[key], a)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for _, p := range products {
+		p.Availability = nil
+		for i := range p.Variants {
+			v := &p.Variants[i]
+			v.Availability = byItem[stockKey{p.ID, v.ID}]
+			v.Available = totalAvailable(v.Availability)
+			p.Availability = mergeAvailability(p.Availability, v.Availability)
+		}
+		p.Availability = mergeAvailability(p.Availability, byItem[stockKey{p.ID, 0}])
+		p.Available = totalAvailable(p.Availability)
+	}
+	return nil
+}
+
+// totalAvailable суммирует свободный остаток по точкам
+func totalAvailable(availability []entity.StockAvailability) int {
+	total := 0
+	for _, a := range availability {
+		total += a.Stock
+	}
+	return total
+}
+
+// mergeAvailability добавляет к наличию товара наличие его варианта, суммируя остатки одного склада
+func mergeAvailability(total, add []entity.StockAvailability) []entity.StockAvailability {
+	for _, a := range add {
+		merged := false
+		for i := range total {
+			if total[i].WarehouseID == a.WarehouseID {
+				total[i].Stock += a.Stock
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			total = append(total, a)
+		}
+	}
+	return total
+}
+
+func (r *ProductRepo) variantsByProduct(ctx context.Context, productIDs []int) (map[int][]entity.ProductVariant, error) {
+	query := `
+		SELECT id, product_id, sku, options, price, stock, images, created_at, updated_at
+		FROM product_variants
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list variants: %w", err)
+	}
+	defer rows.Close()
+
+	byProduct := make(map[int][]entity.ProductVariant)
+	for rows.Next() {
+		var v entity.ProductVariant
+		var options []byte
+		var price sql.NullFloat64
+		err := rows.Scan(
+			&v.ID,
+			&v.ProductID,
+			&v.SKU,
+			&options,
+			&price,
+			&v.Stock,
+			pq.Array(&v.Images),
+			&v.CreatedAt,
+			&v.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan variant: %w", err)
+		}
+		if err := json.Unmarshal(options, &v.Options); err != nil {
+			return nil, fmt.Errorf("unmarshal variant options: %w", err)
+		}
+		if price.Valid {
+			v.Price = &price.Float64
+		}
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return byProduct, nil
+}
+
+// lockProduct блокирует строку товара до изменения его вариантов.
+// Заказы блокируют товар раньше варианта, поэтому здесь порядок тот же.
+func lockProduct(ctx context.Context, tx *sql.Tx, productID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", apperrors.ErrProductNotFound, productID)
+	}
+	if err != nil {
+		return fmt.Errorf("lock product: %w", err)
+	}
+	return nil
+}
+
+// variantError переводит нарушения ограничений при записи варианта в доменные ошибки
+func variantError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %s", apperrors.ErrSKUExists, pqErr.Detail)
+		case pgForeignKeyViolation:
+			return apperrors.ErrProductNotFound
+		}
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// productKeyError переводит конфликт артикула или внешнего ID продукта в доменную ошибку
+func productKeyError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		switch pqErr.Constraint {
+		case "idx_products_sku":
+			return apperrors.ErrSKUExists
+		case "idx_products_external_id":
+			return apperrors.ErrExternalIDExists
+		}
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// Совпадения ts_headline отмечаются управляющими символами, а не тегами: название и описание вводит
+// админка, и до разметки их нужно экранировать как HTML (см. renderHighlight)
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+
+	nameHeadlineOptions    = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", HighlightAll=true`
+	snippetHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxWords=30, MinWords=10, MaxFragments=2`
+)
+
+var highlightMarks = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// renderHighlight экранирует результат ts_headline и заменяет отметки совпадений тегом <mark>
+func renderHighlight(s string) string {
+	return highlightMarks.Replace(html.EscapeString(s))
+}
+
+// Search выполняет полнотекстовый поиск по названию, категории и описанию
+// с учетом русской морфологии. Результаты отсортированы по релевантности.
+func (r *ProductRepo) Search(ctx context.Context, query string, limit, offset int) ([]*entity.ProductSearchResult, error) {
+	// ts_headline дорогой, поэтому считаем его только для страницы результатов
+	sqlQuery := `
+		WITH q AS (SELECT websearch_to_tsquery('russian', $1) AS query),
+		found AS (
+			SELECT p.id, COALESCE(p.sku, '') AS sku, COALESCE(p.external_id, '') AS external_id,
+			       p.name, COALESCE(p.description, '') AS description, p.price, p.category,
+			       p.category_id, p.stock, COALESCE(p.image_url, '') AS image_url, p.created_at, p.updated_at,
+			       ts_rank(p.search_vector, q.query) AS rank
+			FROM products p, q
+			WHERE p.search_vector @@ q.query
+			ORDER BY rank DESC, p.id
+			LIMIT $2 OFFSET $3
+		)
+		SELECT f.id, f.sku, f.external_id, f.name, f.description, f.price, f.category, f.category_id, f.stock, f.image_url,
+		       f.created_at, f.updated_at, f.rank,
+		       ts_headline('russian', translate(f.name, E'\x02\x03', ''), q.query, $4),
+		       ts_headline('russian', translate(f.description, E'\x02\x03', ''), q.query, $5)
+		FROM found f, q
+		ORDER BY f.rank DESC, f.id`
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query, limit, offset, nameHeadlineOptions, snippetHeadlineOptions)
+	if err != nil {
+		return nil, fmt.Errorf("search products: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*entity.ProductSearchResult, 0)
+	for rows.Next() {
+		var res entity.ProductSearchResult
+		err := rows.Scan(
+			&res.ID,
+			&res.SKU,
+			&res.ExternalID,
+			&res.Name,
+			&res.Description,
+			&res.Price,
+			&res.Category,
+			&res.CategoryID,
+			&res.Stock,
+			&res.ImageURL,
+			&res.CreatedAt,
+			&res.UpdatedAt,
+			&res.Rank,
+			&res.NameHighlight,
+			&res.Snippet,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan product: %w", err)
 		}
-		products = append(products, &p)
+		res.NameHighlight = renderHighlight(res.NameHighlight)
+		res.Snippet = renderHighlight(res.Snippet)
+		results = append(results, &res)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
-	return products, nil
+	products := make([]*entity.Product, 0, len(results))
+	for _, res := range results {
+		products = append(products, &res.Product)
+	}
+	if err := r.loadVariants(ctx, products); err != nil {
+		return nil, err
+	}
+	if err := r.loadAvailability(ctx, products); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchCount возвращает количество продуктов, подходящих под поисковый запрос
+func (r *ProductRepo) SearchCount(ctx context.Context, query string) (int, error) {
+	sqlQuery := `SELECT COUNT(*) FROM products WHERE search_vector @@ websearch_to_tsquery('russian', $1)`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, sqlQuery, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count search products: %w", err)
+	}
+	return count, nil
 }
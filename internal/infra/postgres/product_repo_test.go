@@ -0,0 +1,387 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestProductRepoSearch(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductRepo(db)
+
+	categories := createTestCategories(t, db, "sofas", "tables", "chairs")
+	products := []*entity.Product{
+		{Name: "Кожаный диван", Description: "Трехместный", Price: 50000, Category: "sofas", Stock: 1},
+		{Name: "Журнальный стол", Description: "Отлично смотрится рядом с диваном", Price: 9000, Category: "tables", Stock: 1},
+		{Name: "Кресло", Description: "Мягкое", Price: 15000, Category: "chairs", Stock: 1},
+	}
+	for _, p := range products {
+		p.CategoryID = categories[p.Category]
+		if err := repo.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Словоформа "диваны" должна находить и "диван", и "диваном"; совпадение в названии выше описания
+	results, err := repo.Search(ctx, "диваны", 10, 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Search() returned %d results, want 2", len(results))
+	}
+	if results[0].ID != products[0].ID || results[1].ID != products[1].ID {
+		t.Errorf("Search() order = [%d %d], want [%d %d]", results[0].ID, results[1].ID, products[0].ID, products[1].ID)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("Search() ranks = %v, %v, want descending", results[0].Rank, results[1].Rank)
+	}
+
+	count, err := repo.SearchCount(ctx, "диваны")
+	if err != nil {
+		t.Fatalf("SearchCount() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("SearchCount() = %d, want 2", count)
+	}
+
+	if results, err := repo.Search(ctx, "шкаф", 10, 0); err != nil || len(results) != 0 {
+		t.Errorf("Search(no match) = %d results, %v, want none", len(results), err)
+	}
+}
+
+func TestProductFilterClause(t *testing.T) {
+	minPrice, maxPrice := 1000.0, 5000.0
+
+	tests := []struct {
+		name         string
+		filter       entity.ProductFilter
+		wantContains []string
+		wantArgs     int
+	}{
+		{name: "no filters", filter: entity.ProductFilter{}},
+		{
+			name:         "categories include subtree",
+			filter:       entity.ProductFilter{Categories: []string{"Sofas"}, CategoryIDs: []int{3}},
+			wantContains: []string{"p.category_id IN (", "WITH RECURSIVE tree", "id = ANY($1) OR slug = ANY($2) OR lower(name) = ANY($2)"},
+			wantArgs:     2,
+		},
+		{
+			name:         "price range after category",
+			filter:       entity.ProductFilter{Categories: []string{"sofas"}, MinPrice: &minPrice, MaxPrice: &maxPrice},
+			wantContains: []string{"p.category_id IN (", ") AND p.price >= $3 AND p.price <= $4"},
+			wantArgs:     4,
+		},
+		{
+			name:         "only max price",
+			filter:       entity.ProductFilter{MaxPrice: &maxPrice},
+			wantContains: []string{" WHERE p.price <= $1"},
+			wantArgs:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := productFilterClause(tt.filter)
+			if len(args) != tt.wantArgs {
+				t.Errorf("productFilterClause() args = %d, want %d", len(args), tt.wantArgs)
+			}
+			if len(tt.wantContains) == 0 && where != "" {
+				t.Errorf("productFilterClause() where = %q, want empty", where)
+			}
+			for _, part := range tt.wantContains {
+				if !strings.Contains(where, part) {
+					t.Errorf("productFilterClause() where = %q, want it to contain %q", where, part)
+				}
+			}
+		})
+	}
+}
+
+func TestProductFilterClauseLowercasesCategories(t *testing.T) {
+	_, args := productFilterClause(entity.ProductFilter{Categories: []string{"Гостиная"}})
+	if len(args) != 2 {
+		t.Fatalf("productFilterClause() args = %d, want 2", len(args))
+	}
+	names, ok := args[1].(driver.Valuer)
+	if !ok {
+		t.Fatalf("category names arg has type %T", args[1])
+	}
+	value, err := names.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if value != `{"гостиная"}` {
+		t.Errorf("category names = %v, want {\"гостиная\"}", value)
+	}
+}
+
+func TestProductFilterClauseInStock(t *testing.T) {
+	where, args := productFilterClause(entity.ProductFilter{InStock: true})
+	if len(args) != 0 {
+		t.Errorf("productFilterClause() args = %d, want 0", len(args))
+	}
+	for _, part := range []string{" WHERE EXISTS (", "w.active", "stock_reservations"} {
+		if !strings.Contains(where, part) {
+			t.Errorf("productFilterClause() where = %q, want it to contain %q", where, part)
+		}
+	}
+}
+
+func TestProductRepoListAndFacets(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductRepo(db)
+
+	categories := createTestCategories(t, db, "sofas", "chairs")
+	products := []*entity.Product{
+		{Name: "Диван", Price: 500, Category: "sofas", Stock: 2},
+		{Name: "Кресло", Price: 120, Category: "chairs", Stock: 0},
+		{Name: "Стул", Price: 40, Category: "chairs", Stock: 5},
+	}
+	for _, p := range products {
+		p.CategoryID = categories[p.Category]
+		if err := repo.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	filter := entity.ProductFilter{Categories: []string{"chairs"}, Sort: entity.ProductSortPriceDesc}
+	list, err := repo.List(ctx, filter, 10, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 2 || list[0].ID != products[1].ID || list[1].ID != products[2].ID {
+		t.Errorf("List() returned unexpected products: %+v", list)
+	}
+
+	// Фасет категорий не учитывает собственный фильтр, а ценовой — учитывает
+	facets, err := repo.Facets(ctx, filter, []float64{100, 300})
+	if err != nil {
+		t.Fatalf("Facets() error = %v", err)
+	}
+	categoryCounts := make(map[int]int)
+	for _, f := range facets.Categories {
+		categoryCounts[f.CategoryID] = f.Count
+	}
+	if categoryCounts[categories["sofas"]] != 1 || categoryCounts[categories["chairs"]] != 2 {
+		t.Errorf("category facets = %v, want sofas:1 chairs:2", categoryCounts)
+	}
+	priceCounts := make(map[float64]int)
+	for _, r := range facets.PriceRanges {
+		priceCounts[r.Min] = r.Count
+	}
+	if priceCounts[0] != 1 || priceCounts[100] != 1 || priceCounts[300] != 0 {
+		t.Errorf("price facets = %v, want 0:1 100:1 300:0", priceCounts)
+	}
+
+	count, err := repo.Count(ctx, entity.ProductFilter{InStock: true})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Count(in stock) = %d, want 2", count)
+	}
+
+	// Товар, весь остаток которого в резерве неоплаченного заказа, в наличии не считается
+	order := &entity.Order{UserID: createTestUser(t, db).ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: products[0].ID, Quantity: 2}}}
+	if err := NewOrderRepo(db).Create(ctx, order, time.Hour, false); err != nil {
+		t.Fatalf("Create(order) error = %v", err)
+	}
+	if count, err = repo.Count(ctx, entity.ProductFilter{InStock: true}); err != nil || count != 1 {
+		t.Errorf("Count(in stock, sofa reserved) = %d, %v, want 1", count, err)
+	}
+}
+
+func TestProductRepoListAfter(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductRepo(db)
+
+	// Одинаковые цены проверяют, что id разрешает равенство ключа сортировки
+	categories := createTestCategories(t, db, "chairs")
+	prices := []float64{300, 100, 100, 200, 100, 400, 200}
+	for i, price := range prices {
+		p := &entity.Product{Name: string(rune('А' + i)), Price: price, Category: "chairs", CategoryID: categories["chairs"], Stock: 1}
+		if err := repo.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	sorts := []entity.ProductSort{
+		entity.ProductSortNewest, entity.ProductSortPriceAsc, entity.ProductSortPriceDesc,
+		entity.ProductSortName, entity.ProductSortPopularity,
+	}
+	for _, sort := range sorts {
+		t.Run(string(sort), func(t *testing.T) {
+			filter := entity.ProductFilter{Sort: sort}
+			want, err := repo.List(ctx, filter, len(prices), 0)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			var got []int
+			var cursor *pagination.Cursor
+			for page := 0; page <= len(prices); page++ {
+				products, next, err := repo.ListAfter(ctx, filter, cursor, 3)
+				if err != nil {
+					t.Fatalf("ListAfter() error = %v", err)
+				}
+				for _, p := range products {
+					got = append(got, p.ID)
+				}
+				if next == nil {
+					break
+				}
+				cursor = next
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("ListAfter() returned %d products, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i].ID {
+					t.Fatalf("ListAfter() order = %v, differs from List() at %d", got, i)
+				}
+			}
+		})
+	}
+}
+
+func TestProductRepoVariantStock(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductRepo(db)
+	stock := NewStockRepo(db)
+	product := createTestProduct(t, db, "Диван", 50000, 7)
+
+	red := &entity.ProductVariant{ProductID: product.ID, SKU: "SOFA-RED", Options: map[string]string{"color": "red"}, Stock: 3, Images: []string{}}
+	blue := &entity.ProductVariant{ProductID: product.ID, SKU: "SOFA-BLUE", Options: map[string]string{"color": "blue"}, Stock: 2, Images: []string{}}
+	for _, v := range []*entity.ProductVariant{red, blue} {
+		if err := repo.CreateVariant(ctx, v, 0); err != nil {
+			t.Fatalf("CreateVariant() error = %v", err)
+		}
+	}
+	// Остаток товара с вариантами — сумма остатков вариантов
+	if got := productStock(t, db, product.ID); got != 5 {
+		t.Errorf("product stock after variants = %d, want 5", got)
+	}
+
+	if err := stock.Set(ctx, &entity.StockMovement{ProductID: product.ID, VariantID: red.ID, Reason: entity.StockAdjustment}, 10); err != nil {
+		t.Fatalf("Set(variant) error = %v", err)
+	}
+	if got := productStock(t, db, product.ID); got != 12 {
+		t.Errorf("product stock after variant update = %d, want 12", got)
+	}
+
+	// Устаревший остаток в карточке и варианте не перезаписывает журнал движений
+	red.Stock, product.Stock = 3, 5
+	if err := repo.UpdateVariant(ctx, red); err != nil {
+		t.Fatalf("UpdateVariant() error = %v", err)
+	}
+	if err := repo.Update(ctx, product); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if red.Stock != 10 || product.Stock != 12 {
+		t.Errorf("stock after update = variant %d, product %d, want 10 and 12", red.Stock, product.Stock)
+	}
+
+	if err := stock.Set(ctx, &entity.StockMovement{ProductID: product.ID, Reason: entity.StockAdjustment}, 1); !apperrors.Is(err, apperrors.ErrVariantRequired) {
+		t.Errorf("Set(no variant) error = %v, want %v", err, apperrors.ErrVariantRequired)
+	}
+	other := createTestProduct(t, db, "Стол", 9000, 1)
+	if err := stock.Set(ctx, &entity.StockMovement{ProductID: other.ID, VariantID: red.ID, Reason: entity.StockAdjustment}, 1); !apperrors.Is(err, apperrors.ErrVariantNotFound) {
+		t.Errorf("Set(foreign variant) error = %v, want %v", err, apperrors.ErrVariantNotFound)
+	}
+
+	duplicate := &entity.ProductVariant{ProductID: product.ID, SKU: "SOFA-RED", Options: map[string]string{}, Images: []string{}}
+	if err := repo.CreateVariant(ctx, duplicate, 0); !apperrors.Is(err, apperrors.ErrSKUExists) {
+		t.Errorf("CreateVariant(duplicate SKU) error = %v, want %v", err, apperrors.ErrSKUExists)
+	}
+
+	if err := repo.DeleteVariant(ctx, product.ID, blue.ID, 0); err != nil {
+		t.Fatalf("DeleteVariant() error = %v", err)
+	}
+	if got := productStock(t, db, product.ID); got != 10 {
+		t.Errorf("product stock after delete = %d, want 10", got)
+	}
+}
+
+func TestProductRepoImportKeys(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewProductRepo(db)
+	categories := createTestCategories(t, db, "chairs")
+
+	withSKU := &entity.Product{SKU: "CH-1", Name: "Стул", Price: 100, Category: "chairs", CategoryID: categories["chairs"]}
+	withExternal := &entity.Product{SKU: "CH-2", ExternalID: "erp-42", Name: "Кресло", Price: 200, Category: "chairs", CategoryID: categories["chairs"]}
+	withoutKeys := &entity.Product{Name: "Табурет", Price: 50, Category: "chairs", CategoryID: categories["chairs"]}
+	for _, p := range []*entity.Product{withSKU, withExternal, withoutKeys} {
+		if err := repo.Create(ctx, p, 0); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		sku, extID string
+		want       int
+	}{
+		{name: "by sku", sku: "CH-1", want: withSKU.ID},
+		{name: "external id wins over sku", sku: "CH-1", extID: "erp-42", want: withExternal.ID},
+		{name: "unknown external id does not fall back to sku", sku: "CH-1", extID: "erp-0", want: 0},
+		{name: "no keys", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetByKey(ctx, tt.sku, tt.extID)
+			if err != nil {
+				t.Fatalf("GetByKey() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetByKey(%q, %q) = %d, want %d", tt.sku, tt.extID, got, tt.want)
+			}
+		})
+	}
+
+	// Пустые ключи хранятся как NULL и не конфликтуют между собой
+	another := &entity.Product{Name: "Пуф", Price: 50, Category: "chairs", CategoryID: categories["chairs"]}
+	if err := repo.Create(ctx, another, 0); err != nil {
+		t.Fatalf("Create(without keys) error = %v", err)
+	}
+	duplicateSKU := &entity.Product{SKU: "CH-1", Name: "Копия", Price: 1, Category: "chairs", CategoryID: categories["chairs"]}
+	if err := repo.Create(ctx, duplicateSKU, 0); !apperrors.Is(err, apperrors.ErrSKUExists) {
+		t.Errorf("Create(duplicate sku) error = %v, want %v", err, apperrors.ErrSKUExists)
+	}
+	duplicateExternal := &entity.Product{ExternalID: "erp-42", Name: "Копия", Price: 1, Category: "chairs", CategoryID: categories["chairs"]}
+	if err := repo.Create(ctx, duplicateExternal, 0); !apperrors.Is(err, apperrors.ErrExternalIDExists) {
+		t.Errorf("Create(duplicate external id) error = %v, want %v", err, apperrors.ErrExternalIDExists)
+	}
+}
+
+func TestRenderHighlight(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Диван " + highlightStart + "угловой" + highlightStop, want: "Диван <mark>угловой</mark>"},
+		{in: `<script>alert("x")</script> ` + highlightStart + "стул" + highlightStop, want: `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>стул</mark>`},
+		{in: "Стол & <mark>", want: "Стол &amp; &lt;mark&gt;"},
+	}
+	for _, tt := range tests {
+		if got := renderHighlight(tt.in); got != tt.want {
+			t.Errorf("renderHighlight(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+type PromotionRepo struct {
+	db *sql.DB
+}
+
+func NewPromotionRepo(db *sql.DB) *PromotionRepo {
+	return &PromotionRepo{db: db}
+}
+
+// usedByOrders возвращает подзапрос: сколько неотмененных заказов получили скидку по условию cond
+// (алиас d — order_discounts). Отмена заказа возвращает использование акции и промокода.
+func usedByOrders(cond string) string {
+	return `(SELECT COUNT(*) FROM order_discounts d JOIN orders o ON o.id = d.order_id
+		WHERE ` + cond + ` AND o.status <> '` + string(entity.OrderStatusCancelled) + `')`
+}
+
+var promotionColumns = `p.id, p.name, p.discount_type, p.value, p.scope, COALESCE(p.category_id, 0), COALESCE(p.product_id, 0),
+	p.min_total, p.starts_at, p.ends_at, COALESCE(p.usage_limit, 0), COALESCE(p.per_user_limit, 0), p.requires_code, p.active,
+	p.created_at, p.updated_at, ` + usedByOrders("d.promotion_id = p.id")
+
+// List возвращает акции, новые первыми
+func (r *PromotionRepo) List(ctx context.Context, limit, offset int) ([]*entity.Promotion, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+promotionColumns+` FROM promotions p ORDER BY p.created_at DESC, p.id DESC LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []*entity.Promotion{}
+	for rows.Next() {
+		p := &entity.Promotion{}
+		if err := rows.Scan(promotionDest(p)...); err != nil {
+			return nil, fmt.Errorf("scan promotion: %w", err)
+		}
+		promotions = append(promotions, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return promotions, nil
+}
+
+// Count возвращает количество акций (для пагинации)
+func (r *PromotionRepo) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM promotions`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count promotions: %w", err)
+	}
+	return count, nil
+}
+
+// GetByID возвращает акцию или nil, если ее нет
+func (r *PromotionRepo) GetByID(ctx context.Context, id int) (*entity.Promotion, error) {
+	p := &entity.Promotion{}
+	err := r.db.QueryRowContext(ctx, `SELECT `+promotionColumns+` FROM promotions p WHERE p.id = $1`, id).
+		Scan(promotionDest(p)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get promotion: %w", err)
+	}
+	return p, nil
+}
+
+// Create создает акцию
+func (r *PromotionRepo) Create(ctx context.Context, p *entity.Promotion) error {
+	query := `
+		INSERT INTO promotions (name, discount_type, value, scope, category_id, product_id, min_total,
+		                        starts_at, ends_at, usage_limit, per_user_limit, requires_code, active)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), $7, $8, $9, NULLIF($10, 0), NULLIF($11, 0), $12, $13)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		p.Name, p.Type, p.Value, p.Scope, p.CategoryID, p.ProductID, p.MinTotal,
+		p.StartsAt, p.EndsAt, p.UsageLimit, p.PerUserLimit, p.RequiresCode, p.Active,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return promotionError("create promotion", err)
+	}
+	return nil
+}
+
+// Update обновляет акцию. Уже оформленные заказы сохраняют рассчитанные скидки.
+func (r *PromotionRepo) Update(ctx context.Context, p *entity.Promotion) error {
+	query := `
+		UPDATE promotions
+		SET name = $1, discount_type = $2, value = $3, scope = $4, category_id = NULLIF($5, 0), product_id = NULLIF($6, 0),
+		    min_total = $7, starts_at = $8, ends_at = $9, usage_limit = NULLIF($10, 0), per_user_limit = NULLIF($11, 0),
+		    requires_code = $12, active = $13, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $14
+		RETURNING created_at, updated_at, ` + usedByOrders("d.promotion_id = promotions.id")
+
+	err := r.db.QueryRowContext(ctx, query,
+		p.Name, p.Type, p.Value, p.Scope, p.CategoryID, p.ProductID,
+		p.MinTotal, p.StartsAt, p.EndsAt, p.UsageLimit, p.PerUserLimit,
+		p.RequiresCode, p.Active, p.ID,
+	).Scan(&p.CreatedAt, &p.UpdatedAt, &p.UsedCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrPromotionNotFound
+	}
+	if err != nil {
+		return promotionError("update promotion", err)
+	}
+	return nil
+}
+
+// Delete удаляет акцию вместе с промокодами. Скидки в заказах остаются с названием и кодом акции.
+func (r *PromotionRepo) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete promotion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete promotion - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.ErrPromotionNotFound
+	}
+	return nil
+}
+
+// CreateCodes добавляет промокоды акции одной транзакцией: если хотя бы один код занят, не создается ни один
+func (r *PromotionRepo) CreateCodes(ctx context.Context, codes []*entity.PromoCode) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create promo codes - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, code := range codes {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO promo_codes (promotion_id, code, usage_limit)
+			VALUES ($1, $2, NULLIF($3, 0))
+			RETURNING id, created_at`,
+			code.PromotionID, code.Code, code.UsageLimit,
+		).Scan(&code.ID, &code.CreatedAt)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+			return fmt.Errorf("%w: %s", apperrors.ErrPromoCodeExists, code.Code)
+		}
+		if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+			return fmt.Errorf("%w: %d", apperrors.ErrPromotionNotFound, code.PromotionID)
+		}
+		if err != nil {
+			return fmt.Errorf("create promo code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create promo codes - commit: %w", err)
+	}
+	return nil
+}
+
+// ListCodes возвращает промокоды акции с количеством использований
+func (r *PromotionRepo) ListCodes(ctx context.Context, promotionID int) ([]*entity.PromoCode, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.promotion_id, c.code, COALESCE(c.usage_limit, 0), c.created_at, `+usedByOrders("d.promo_code_id = c.id")+`
+		FROM promo_codes c
+		WHERE c.promotion_id = $1
+		ORDER BY c.id`,
+		promotionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list promo codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []*entity.PromoCode{}
+	for rows.Next() {
+		c := &entity.PromoCode{}
+		if err := rows.Scan(&c.ID, &c.PromotionID, &c.Code, &c.UsageLimit, &c.CreatedAt, &c.UsedCount); err != nil {
+			return nil, fmt.Errorf("scan promo code: %w", err)
+		}
+		codes = append(codes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return codes, nil
+}
+
+// Applicable возвращает акции, которые сейчас применяются к корзине покупателя userID (0 — гость),
+// вместе с акцией промокода code. Для недействительного кода возвращает ErrPromoCodeNotFound или ErrPromoCodeExhausted.
+func (r *PromotionRepo) Applicable(ctx context.Context, userID int, code string) ([]*entity.Promotion, error) {
+	return applicablePromotions(ctx, r.db, userID, code, false)
+}
+
+// ProductCategories возвращает категории товаров: ID товара -> ID категории (0 — без категории)
+func (r *PromotionRepo) ProductCategories(ctx context.Context, productIDs []int) (map[int]int, error) {
+	return productCategories(ctx, r.db, productIDs)
+}
+
+// applicablePromotions отбирает действующие акции: автоматические с неисчерпанными лимитами и акцию промокода code.
+// С lock строки акций блокируются до конца транзакции, поэтому параллельные заказы видят использования друг друга
+// и не превышают лимиты.
+func applicablePromotions(ctx context.Context, q queryer, userID int, code string, lock bool) ([]*entity.Promotion, error) {
+	from := `
+		FROM promotions p
+		LEFT JOIN promo_codes c ON c.promotion_id = p.id AND c.code = $1
+		WHERE p.active AND (NOT p.requires_code OR c.id IS NOT NULL)
+		  AND (p.starts_at IS NULL OR p.starts_at <= CURRENT_TIMESTAMP)
+		  AND (p.ends_at IS NULL OR p.ends_at > CURRENT_TIMESTAMP)`
+
+	if lock {
+		rows, err := q.QueryContext(ctx, `SELECT p.id`+from+` ORDER BY p.id FOR UPDATE OF p`, code)
+		if err != nil {
+			return nil, fmt.Errorf("lock promotions: %w", err)
+		}
+		// Сами ID не нужны: строки остаются заблокированными до конца транзакции
+		for rows.Next() {
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("lock promotions: %w", err)
+		}
+	}
+
+	query := `
+		SELECT ` + promotionColumns + `,
+		       ARRAY(
+		           WITH RECURSIVE tree AS (
+		               SELECT id FROM categories WHERE id = p.category_id
+		               UNION
+		               SELECT ch.id FROM categories ch JOIN tree t ON ch.parent_id = t.id
+		           )
+		           SELECT id FROM tree
+		       ),
+		       ` + usedByOrders("d.promotion_id = p.id AND o.user_id = $2") + `,
+		       COALESCE(c.id, 0), COALESCE(c.code, ''), COALESCE(c.usage_limit, 0),
+		       ` + usedByOrders("d.promo_code_id = c.id") + from + `
+		ORDER BY p.id`
+
+	rows, err := q.QueryContext(ctx, query, code, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list applicable promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []*entity.Promotion
+	var codePromotion *entity.Promotion
+	for rows.Next() {
+		p := &entity.Promotion{}
+		pc := &entity.PromoCode{}
+		var categories []int64
+		dest := append(promotionDest(p), pq.Array(&categories), &p.UserUsedCount,
+			&pc.ID, &pc.Code, &pc.UsageLimit, &pc.UsedCount)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan promotion: %w", err)
+		}
+		for _, id := range categories {
+			p.Categories = append(p.Categories, int(id))
+		}
+
+		if pc.ID != 0 {
+			pc.PromotionID = p.ID
+			p.Code = pc
+			codePromotion = p
+			continue
+		}
+		if !p.LimitReached() {
+			promotions = append(promotions, p)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if code != "" {
+		if codePromotion == nil {
+			return nil, fmt.Errorf("%w: %s", apperrors.ErrPromoCodeNotFound, code)
+		}
+		if codePromotion.LimitReached() {
+			return nil, fmt.Errorf("%w: %s", apperrors.ErrPromoCodeExhausted, code)
+		}
+		promotions = append(promotions, codePromotion)
+	}
+	return promotions, nil
+}
+
+// productCategories возвращает категории товаров: ID товара -> ID категории
+func productCategories(ctx context.Context, q queryer, productIDs []int) (map[int]int, error) {
+	ids := make([]int64, 0, len(productIDs))
+	for _, id := range productIDs {
+		ids = append(ids, int64(id))
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, COALESCE(category_id, 0) FROM products WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("get product categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := make(map[int]int, len(productIDs))
+	for rows.Next() {
+		var productID, categoryID int
+		if err := rows.Scan(&productID, &categoryID); err != nil {
+			return nil, fmt.Errorf("scan product category: %w", err)
+		}
+		categories[productID] = categoryID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return categories, nil
+}
+
+// promotionDest возвращает адреса полей акции в порядке promotionColumns
+func promotionDest(p *entity.Promotion) []interface{} {
+	return []interface{}{
+		&p.ID, &p.Name, &p.Type, &p.Value, &p.Scope, &p.CategoryID, &p.ProductID,
+		&p.MinTotal, &p.StartsAt, &p.EndsAt, &p.UsageLimit, &p.PerUserLimit, &p.RequiresCode, &p.Active,
+		&p.CreatedAt, &p.UpdatedAt, &p.UsedCount,
+	}
+}
+
+// promotionError переводит нарушение внешнего ключа (категорию или товар удалили) в доменную ошибку
+func promotionError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return fmt.Errorf("%w: %s", apperrors.ErrInvalidPromotion, pqErr.Constraint)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+// createTestPromotion создает действующую акцию на всю корзину
+func createTestPromotion(t *testing.T, db *sql.DB, p *entity.Promotion) *entity.Promotion {
+	t.Helper()
+	p.Name = fmt.Sprintf("promotion-%d-%v", p.UsageLimit, p.RequiresCode)
+	p.Type, p.Value, p.Scope, p.Active = entity.DiscountPercent, 10, entity.PromotionScopeCart, true
+	if err := NewPromotionRepo(db).Create(context.Background(), p); err != nil {
+		t.Fatalf("Create(promotion) error = %v", err)
+	}
+	return p
+}
+
+// createPromoOrder оформляет заказ одного товара с промокодом
+func createPromoOrder(ctx context.Context, db *sql.DB, user *entity.User, product *entity.Product, code string) (*entity.Order, error) {
+	order := &entity.Order{UserID: user.ID, Status: entity.OrderStatusPending, PromoCode: code,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: product.ID, Quantity: 1}}}
+	if err := NewOrderRepo(db).Create(ctx, order, time.Hour, false); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func TestPromotionRepoUsageLimits(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	promotions := NewPromotionRepo(db)
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	chair := createTestProduct(t, db, "Стул", 5000, 10)
+
+	promo := createTestPromotion(t, db, &entity.Promotion{RequiresCode: true, PerUserLimit: 1})
+	codes := []*entity.PromoCode{{PromotionID: promo.ID, Code: "ONCE", UsageLimit: 1}, {PromotionID: promo.ID, Code: "MANY"}}
+	if err := promotions.CreateCodes(ctx, codes); err != nil {
+		t.Fatalf("CreateCodes() error = %v", err)
+	}
+	if err := promotions.CreateCodes(ctx, []*entity.PromoCode{{PromotionID: promo.ID, Code: "NEW"}, {PromotionID: promo.ID, Code: "ONCE"}}); !apperrors.Is(err, apperrors.ErrPromoCodeExists) {
+		t.Errorf("CreateCodes(taken) error = %v, want %v", err, apperrors.ErrPromoCodeExists)
+	}
+	if _, err := promotions.Applicable(ctx, alice.ID, "NEW"); !apperrors.Is(err, apperrors.ErrPromoCodeNotFound) {
+		t.Errorf("Applicable(code from failed batch) error = %v, want %v", err, apperrors.ErrPromoCodeNotFound)
+	}
+
+	first, err := createPromoOrder(ctx, db, alice, chair, "ONCE")
+	if err != nil {
+		t.Fatalf("Create(ONCE) error = %v", err)
+	}
+	if first.DiscountTotal != 500 || first.Total != 4500 {
+		t.Errorf("order discount %v total %v, want 500 and 4500", first.DiscountTotal, first.Total)
+	}
+
+	// Одноразовый код исчерпан, второй код той же акции упирается в лимит покупателя
+	if _, err := createPromoOrder(ctx, db, bob, chair, "ONCE"); !apperrors.Is(err, apperrors.ErrPromoCodeExhausted) {
+		t.Errorf("Create(ONCE again) error = %v, want %v", err, apperrors.ErrPromoCodeExhausted)
+	}
+	if _, err := createPromoOrder(ctx, db, alice, chair, "MANY"); !apperrors.Is(err, apperrors.ErrPromoCodeExhausted) {
+		t.Errorf("Create(per user limit) error = %v, want %v", err, apperrors.ErrPromoCodeExhausted)
+	}
+	if _, err := createPromoOrder(ctx, db, bob, chair, "MANY"); err != nil {
+		t.Errorf("Create(MANY by another user) error = %v", err)
+	}
+
+	// Отмена заказа возвращает использование
+	if err := NewOrderRepo(db).UpdateStatus(ctx, first.ID, entity.OrderStatusPending, entity.OrderStatusCancelled, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus(cancelled) error = %v", err)
+	}
+	if _, err := promotions.Applicable(ctx, bob.ID, "ONCE"); err != nil {
+		t.Errorf("Applicable(ONCE after cancel) error = %v", err)
+	}
+	list, err := promotions.ListCodes(ctx, promo.ID)
+	if err != nil {
+		t.Fatalf("ListCodes() error = %v", err)
+	}
+	if len(list) != 2 || list[0].UsedCount != 0 || list[1].UsedCount != 1 {
+		t.Errorf("ListCodes() = %+v, want ONCE unused and MANY used once", list)
+	}
+}
+
+func TestPromotionRepoConcurrentRedemption(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+
+	const buyers = 8
+	users := make([]*entity.User, buyers)
+	products := make([]*entity.Product, buyers)
+	for i := range users {
+		users[i] = createTestUser(t, db)
+		// У каждого покупателя свой товар, чтобы заказы не сериализовались блокировкой одного товара
+		products[i] = createTestProduct(t, db, fmt.Sprintf("Товар %d", i), 1000, 2)
+	}
+
+	// redeem параллельно оформляет по заказу на каждого покупателя
+	redeem := func(code string) ([]*entity.Order, []error) {
+		orders := make([]*entity.Order, buyers)
+		errs := make([]error, buyers)
+		var wg sync.WaitGroup
+		for i := range users {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				orders[i], errs[i] = createPromoOrder(ctx, db, users[i], products[i], code)
+			}(i)
+		}
+		wg.Wait()
+		return orders, errs
+	}
+
+	promo := createTestPromotion(t, db, &entity.Promotion{RequiresCode: true})
+	if err := NewPromotionRepo(db).CreateCodes(ctx, []*entity.PromoCode{{PromotionID: promo.ID, Code: "SINGLE", UsageLimit: 1}}); err != nil {
+		t.Fatalf("CreateCodes() error = %v", err)
+	}
+	redeemed := 0
+	_, errs := redeem("SINGLE")
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			redeemed++
+		case !apperrors.Is(err, apperrors.ErrPromoCodeExhausted):
+			t.Fatalf("Create(buyer %d, SINGLE) error = %v", i, err)
+		}
+	}
+	if redeemed != 1 {
+		t.Errorf("orders with single-use code = %d, want 1", redeemed)
+	}
+
+	// Автоматическая акция с общим лимитом: заказы оформляются все, скидку получают только первые три
+	auto := createTestPromotion(t, db, &entity.Promotion{UsageLimit: 3})
+	orders, errs := redeem("")
+	discounted := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Create(buyer %d) error = %v", i, err)
+		}
+		for _, d := range orders[i].Discounts {
+			if d.PromotionID == auto.ID {
+				discounted++
+			}
+		}
+	}
+	if discounted != auto.UsageLimit {
+		t.Errorf("orders with limited promotion = %d, want %d", discounted, auto.UsageLimit)
+	}
+}
@@ -0,0 +1,390 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+type ReturnRepo struct {
+	db *sql.DB
+}
+
+func NewReturnRepo(db *sql.DB) *ReturnRepo {
+	return &ReturnRepo{db: db}
+}
+
+const returnColumns = `id, order_id, user_id, status, reason, COALESCE(comment, ''), refund_amount,
+	COALESCE(restock_warehouse_id, 0), resolved_by, COALESCE(resolution_comment, ''), resolved_at, created_at, updated_at`
+
+// returnReference — ссылка на возврат в журнале движений остатков
+func returnReference(returnID int) string {
+	return fmt.Sprintf("return:%d", returnID)
+}
+
+// Create сохраняет запрос на возврат доставленного заказа. Количество по позиции не может превышать
+// заказанное за вычетом уже возвращаемого в других (не отклоненных) возвратах. Цены и товары позиций
+// берутся из заказа, RefundAmount — их стоимость с учетом скидок заказа.
+func (r *ReturnRepo) Create(ctx context.Context, ret *entity.OrderReturn) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create return - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка заказа не дает двум параллельным возвратам вернуть одну позицию дважды
+	var status entity.OrderStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, ret.OrderID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", apperrors.ErrOrderNotFound, ret.OrderID)
+	}
+	if err != nil {
+		return fmt.Errorf("create return - lock order: %w", err)
+	}
+	if status != entity.OrderStatusDelivered {
+		return fmt.Errorf("%w: order is %s", apperrors.ErrOrderNotReturnable, status)
+	}
+
+	var total float64
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		var ordered, returned int
+		var discount float64
+		err := tx.QueryRowContext(ctx, `
+			SELECT oi.product_id, COALESCE(oi.variant_id, 0), oi.price, oi.discount, oi.quantity,
+			       COALESCE((
+			           SELECT SUM(ri.quantity)
+			           FROM order_return_items ri
+			           JOIN order_returns rt ON rt.id = ri.return_id
+			           WHERE ri.order_item_id = oi.id AND rt.status <> $3
+			       ), 0)
+			FROM order_items oi
+			WHERE oi.id = $1 AND oi.order_id = $2`,
+			item.OrderItemID, ret.OrderID, entity.ReturnRejected,
+		).Scan(&item.ProductID, &item.VariantID, &item.Price, &discount, &ordered, &returned)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: order item %d is not in order %d", apperrors.ErrInvalidReturn, item.OrderItemID, ret.OrderID)
+		}
+		if err != nil {
+			return fmt.Errorf("create return - get order item: %w", err)
+		}
+		if item.Quantity > ordered-returned {
+			return fmt.Errorf("%w: only %d of order item %d can be returned", apperrors.ErrInvalidReturn, ordered-returned, item.OrderItemID)
+		}
+		// Скидка позиции возвращается пропорционально количеству: покупатель получает столько, сколько заплатил
+		total += item.Price*float64(item.Quantity) - discount*float64(item.Quantity)/float64(ordered)
+	}
+	ret.RefundAmount = math.Round(total*100) / 100
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO order_returns (order_id, user_id, status, reason, comment, refund_amount)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at, updated_at`,
+		ret.OrderID, ret.UserID, ret.Status, ret.Reason, ret.Comment, ret.RefundAmount,
+	).Scan(&ret.ID, &ret.CreatedAt, &ret.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create return: %w", err)
+	}
+
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO order_return_items (return_id, order_item_id, quantity)
+			VALUES ($1, $2, $3)
+			RETURNING id`,
+			ret.ID, item.OrderItemID, item.Quantity,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("create return item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create return - commit: %w", err)
+	}
+	return nil
+}
+
+// GetByID возвращает возврат с позициями или nil, если его нет
+func (r *ReturnRepo) GetByID(ctx context.Context, id int) (*entity.OrderReturn, error) {
+	ret, err := scanReturn(r.db.QueryRowContext(ctx, `SELECT `+returnColumns+` FROM order_returns WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadItems(ctx, []*entity.OrderReturn{ret}); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListByOrder возвращает возвраты заказа, начиная с первого
+func (r *ReturnRepo) ListByOrder(ctx context.Context, orderID int) ([]*entity.OrderReturn, error) {
+	return r.list(ctx, `SELECT `+returnColumns+` FROM order_returns WHERE order_id = $1 ORDER BY id`, orderID)
+}
+
+// List возвращает возвраты в статусе status (пустой — все), начиная с новых
+func (r *ReturnRepo) List(ctx context.Context, status entity.ReturnStatus, limit, offset int) ([]*entity.OrderReturn, error) {
+	return r.list(ctx, `
+		SELECT `+returnColumns+` FROM order_returns
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3`,
+		status, limit, offset,
+	)
+}
+
+// Count возвращает количество возвратов в статусе status (пустой — всех)
+func (r *ReturnRepo) Count(ctx context.Context, status entity.ReturnStatus) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM order_returns WHERE $1 = '' OR status = $1`, status).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count returns: %w", err)
+	}
+	return count, nil
+}
+
+// Approve одобряет запрошенный возврат: сохраняет сумму к возврату денег и возвращает на склад
+// позиции из decision.RestockItems, записывая в журнал остатков возвраты от имени resolvedBy.
+// Если возврат уже рассмотрен, возвращает ErrInvalidReturnTransition.
+func (r *ReturnRepo) Approve(ctx context.Context, ret *entity.OrderReturn, decision entity.ReturnDecision, resolvedBy int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("approve return - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	restock := make(map[int]bool, len(decision.RestockItems))
+	for _, id := range decision.RestockItems {
+		restock[id] = true
+	}
+
+	warehouseID := 0
+	if len(restock) > 0 {
+		warehouseID = decision.WarehouseID
+		if warehouseID == 0 {
+			if warehouseID, err = defaultWarehouseID(ctx, tx); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE order_returns
+		SET status = $1, refund_amount = $2, restock_warehouse_id = NULLIF($3, 0), resolved_by = NULLIF($4, 0),
+		    resolution_comment = NULLIF($5, ''), resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND status = $7
+		RETURNING resolved_at, updated_at`,
+		entity.ReturnApproved, ret.RefundAmount, warehouseID, resolvedBy, decision.Comment, ret.ID, entity.ReturnRequested,
+	).Scan(&ret.ResolvedAt, &ret.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: return %d", apperrors.ErrInvalidReturnTransition, ret.ID)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return fmt.Errorf("%w: %d", apperrors.ErrWarehouseNotFound, warehouseID)
+	}
+	if err != nil {
+		return fmt.Errorf("approve return: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE order_return_items SET restock = COALESCE(order_item_id = ANY($1), FALSE) WHERE return_id = $2`,
+		pq.Array(decision.RestockItems), ret.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("approve return - mark restock: %w", err)
+	}
+
+	var movements []*entity.StockMovement
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.Restock = restock[item.OrderItemID]
+		if !item.Restock {
+			continue
+		}
+		movements = append(movements, &entity.StockMovement{
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			WarehouseID: warehouseID,
+			Quantity:    item.Quantity,
+			Reason:      entity.StockReturn,
+			UserID:      resolvedBy,
+			Reference:   returnReference(ret.ID),
+		})
+	}
+
+	// Товары блокируются по возрастанию ID, как при оформлении заказа
+	sort.Slice(movements, func(i, j int) bool {
+		if movements[i].ProductID != movements[j].ProductID {
+			return movements[i].ProductID < movements[j].ProductID
+		}
+		return movements[i].VariantID < movements[j].VariantID
+	})
+	locked := 0
+	for _, m := range movements {
+		if m.ProductID != locked {
+			if err := lockProduct(ctx, tx, m.ProductID); err != nil {
+				return err
+			}
+			locked = m.ProductID
+		}
+
+		err := applyStockMovement(ctx, tx, m)
+		if errors.Is(err, apperrors.ErrVariantRequired) {
+			// Вариант позиции удален после оформления заказа — вернуть остаток некуда
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("approve return - commit: %w", err)
+	}
+
+	ret.Status = entity.ReturnApproved
+	ret.RestockWarehouseID = warehouseID
+	ret.ResolutionComment = decision.Comment
+	if resolvedBy != 0 {
+		ret.ResolvedBy = &resolvedBy
+	}
+	return nil
+}
+
+// Reject отклоняет запрошенный возврат; если он уже рассмотрен, возвращает ErrInvalidReturnTransition
+func (r *ReturnRepo) Reject(ctx context.Context, ret *entity.OrderReturn, resolvedBy int, comment string) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE order_returns
+		SET status = $1, resolved_by = NULLIF($2, 0), resolution_comment = NULLIF($3, ''),
+		    resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = $5
+		RETURNING resolved_at, updated_at`,
+		entity.ReturnRejected, resolvedBy, comment, ret.ID, entity.ReturnRequested,
+	).Scan(&ret.ResolvedAt, &ret.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: return %d", apperrors.ErrInvalidReturnTransition, ret.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("reject return: %w", err)
+	}
+
+	ret.Status = entity.ReturnRejected
+	ret.ResolutionComment = comment
+	if resolvedBy != 0 {
+		ret.ResolvedBy = &resolvedBy
+	}
+	return nil
+}
+
+// UpdateStatus переводит возврат из статуса from в to. false означает, что возврат уже не в статусе from.
+func (r *ReturnRepo) UpdateStatus(ctx context.Context, ret *entity.OrderReturn, from, to entity.ReturnStatus) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE order_returns SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = $3
+		RETURNING updated_at`,
+		to, ret.ID, from,
+	).Scan(&ret.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update return status: %w", err)
+	}
+
+	ret.Status = to
+	return true, nil
+}
+
+func (r *ReturnRepo) list(ctx context.Context, query string, args ...interface{}) ([]*entity.OrderReturn, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list returns: %w", err)
+	}
+	defer rows.Close()
+
+	returns := make([]*entity.OrderReturn, 0)
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if err := r.loadItems(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// loadItems подгружает позиции возвратов одним запросом
+func (r *ReturnRepo) loadItems(ctx context.Context, returns []*entity.OrderReturn) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(returns))
+	byID := make(map[int]*entity.OrderReturn, len(returns))
+	for i, ret := range returns {
+		ids[i] = int64(ret.ID)
+		ret.Items = make([]entity.OrderReturnItem, 0)
+		byID[ret.ID] = ret
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT ri.return_id, ri.id, ri.order_item_id, oi.product_id, COALESCE(oi.variant_id, 0), ri.quantity, oi.price, ri.restock
+		FROM order_return_items ri
+		JOIN order_items oi ON oi.id = ri.order_item_id
+		WHERE ri.return_id = ANY($1)
+		ORDER BY ri.return_id, ri.id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("load return items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var returnID int
+		var item entity.OrderReturnItem
+		err := rows.Scan(&returnID, &item.ID, &item.OrderItemID, &item.ProductID, &item.VariantID, &item.Quantity, &item.Price, &item.Restock)
+		if err != nil {
+			return fmt.Errorf("scan return item: %w", err)
+		}
+		if ret, ok := byID[returnID]; ok {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	return nil
+}
+
+func scanReturn(row interface{ Scan(...interface{}) error }) (*entity.OrderReturn, error) {
+	var ret entity.OrderReturn
+	err := row.Scan(&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.Comment, &ret.RefundAmount,
+		&ret.RestockWarehouseID, &ret.ResolvedBy, &ret.ResolutionComment, &ret.ResolvedAt, &ret.CreatedAt, &ret.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan return: %w", err)
+	}
+	return &ret, nil
+}
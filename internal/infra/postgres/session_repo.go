@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+type SessionRepo struct {
+	db *sql.DB
+}
+
+func NewSessionRepo(db *sql.DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+// Create создает сессию и заодно удаляет истекшие сессии пользователя
+func (r *SessionRepo) Create(ctx context.Context, session *entity.Session) error {
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM sessions WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`, session.UserID); err != nil {
+		return fmt.Errorf("delete expired sessions: %w", err)
+	}
+
+	query := `
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		session.UserID, session.RefreshTokenHash, session.UserAgent, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+// GetByID возвращает сессию по ID
+func (r *SessionRepo) GetByID(ctx context.Context, id string) (*entity.Session, error) {
+	query := `
+		SELECT id, user_id, COALESCE(refresh_token_hash, ''), COALESCE(user_agent, ''), expires_at, created_at, updated_at
+		FROM sessions WHERE id = $1`
+
+	session := &entity.Session{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &session.UserAgent,
+		&session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session by id: %w", err)
+	}
+	return session, nil
+}
+
+// Rotate заменяет хэш refresh токена, только если в БД все еще лежит oldHash.
+// Возвращает false, если токен уже успели обновить параллельным запросом.
+func (r *SessionRepo) Rotate(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET refresh_token_hash = $1, expires_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND refresh_token_hash = $4`
+
+	result, err := r.db.ExecContext(ctx, query, newHash, expiresAt, id, oldHash)
+	if err != nil {
+		return false, fmt.Errorf("rotate session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rotate session - get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// IsActive проверяет, что сессия существует, не истекла и принадлежит незаблокированному пользователю
+func (r *SessionRepo) IsActive(ctx context.Context, id string) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions s
+			JOIN users u ON u.id = s.user_id
+			WHERE s.id = $1 AND s.expires_at > CURRENT_TIMESTAMP AND NOT u.disabled
+		)`
+
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&active); err != nil {
+		return false, fmt.Errorf("check session: %w", err)
+	}
+	return active, nil
+}
+
+// Delete удаляет одну сессию (выход с текущего устройства)
+func (r *SessionRepo) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUser удаляет все сессии пользователя (выход со всех устройств)
+func (r *SessionRepo) DeleteByUser(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete user sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+// StockAlertRepo хранит пороги низкого остатка и подписки покупателей на поступление товара
+type StockAlertRepo struct {
+	db *sql.DB
+}
+
+func NewStockAlertRepo(db *sql.DB) *StockAlertRepo {
+	return &StockAlertRepo{db: db}
+}
+
+// SetThreshold задает порог низкого остатка товара; nil — использовать порог по умолчанию
+func (r *StockAlertRepo) SetThreshold(ctx context.Context, productID int, threshold *int) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE products SET low_stock_threshold = $1 WHERE id = $2`,
+		threshold, productID,
+	)
+	if err != nil {
+		return fmt.Errorf("set low stock threshold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("set low stock threshold - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %d", apperrors.ErrProductNotFound, productID)
+	}
+	return nil
+}
+
+// ListLowStock возвращает товары, чей остаток ниже порога, начиная с самых дефицитных
+func (r *StockAlertRepo) ListLowStock(ctx context.Context, defaultThreshold int) ([]*entity.LowStockProduct, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(sku, ''), stock, COALESCE(low_stock_threshold, $1)
+		FROM products
+		WHERE stock < COALESCE(low_stock_threshold, $1)
+		ORDER BY stock, id`,
+		defaultThreshold,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list low stock: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]*entity.LowStockProduct, 0)
+	for rows.Next() {
+		var p entity.LowStockProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.SKU, &p.Stock, &p.Threshold); err != nil {
+			return nil, fmt.Errorf("scan low stock: %w", err)
+		}
+		products = append(products, &p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return products, nil
+}
+
+// MarkLowStock пересчитывает флаг низкого остатка у товаров productIDs и возвращает те,
+// что только что опустились ниже порога. Повторно товар вернется, только когда остаток
+// сначала поднимется до порога, поэтому событие о нем не отправляется при каждом движении.
+func (r *StockAlertRepo) MarkLowStock(ctx context.Context, productIDs []int, defaultThreshold int) ([]*entity.LowStockProduct, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE products SET low_stock_alerted = stock < COALESCE(low_stock_threshold, $2)
+		WHERE id = ANY($1) AND low_stock_alerted <> (stock < COALESCE(low_stock_threshold, $2))
+		RETURNING id, name, COALESCE(sku, ''), stock, COALESCE(low_stock_threshold, $2), low_stock_alerted`,
+		pq.Array(productIDs), defaultThreshold,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mark low stock: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]*entity.LowStockProduct, 0)
+	for rows.Next() {
+		var p entity.LowStockProduct
+		var alerted bool
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.SKU, &p.Stock, &p.Threshold, &alerted); err != nil {
+			return nil, fmt.Errorf("scan low stock: %w", err)
+		}
+		if alerted {
+			products = append(products, &p)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return products, nil
+}
+
+// Subscribe ставит пользователя в очередь на уведомление о поступлении товара.
+// Повторная подписка до уведомления возвращает уже существующую.
+func (r *StockAlertRepo) Subscribe(ctx context.Context, sub *entity.StockSubscription) error {
+	err := r.db.QueryRowContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO stock_subscriptions (user_id, product_id, variant_id)
+			VALUES ($1, $2, NULLIF($3, 0))
+			ON CONFLICT (user_id, product_id, COALESCE(variant_id, 0)) WHERE notified_at IS NULL DO NOTHING
+			RETURNING id, created_at
+		)
+		SELECT id, created_at FROM inserted
+		UNION ALL
+		SELECT id, created_at FROM stock_subscriptions
+		WHERE user_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3 AND notified_at IS NULL
+		LIMIT 1`,
+		sub.UserID, sub.ProductID, sub.VariantID,
+	).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("subscribe to stock: %w", err)
+	}
+	return nil
+}
+
+// NotifyAvailable отмечает уведомленными подписки на товары productIDs, которые снова есть в продаже
+// (свободный остаток на активных точках без резервов), и возвращает их вместе с email подписчика
+func (r *StockAlertRepo) NotifyAvailable(ctx context.Context, productIDs []int) ([]*entity.StockSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE stock_subscriptions s SET notified_at = CURRENT_TIMESTAMP
+		FROM products p, users u
+		WHERE s.product_id = ANY($1) AND s.notified_at IS NULL
+		  AND p.id = s.product_id AND u.id = s.user_id
+		  AND EXISTS (
+		        SELECT 1 FROM warehouse_stock ws
+		        JOIN warehouses w ON w.id = ws.warehouse_id
+		        WHERE ws.product_id = s.product_id AND COALESCE(ws.variant_id, 0) = COALESCE(s.variant_id, 0)
+		          AND ws.stock > 0 AND w.active AND ws.stock - `+reservedStockExpr+` > 0
+		      )
+		RETURNING s.id, s.user_id, s.product_id, COALESCE(s.variant_id, 0), s.created_at, s.notified_at, u.email, p.name`,
+		pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("notify stock subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]*entity.StockSubscription, 0)
+	for rows.Next() {
+		var s entity.StockSubscription
+		err := rows.Scan(&s.ID, &s.UserID, &s.ProductID, &s.VariantID, &s.CreatedAt, &s.NotifiedAt, &s.Email, &s.ProductName)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock subscription: %w", err)
+		}
+		subs = append(subs, &s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return subs, nil
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestStockAlertRepoMarkLowStock(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	alerts := NewStockAlertRepo(db)
+	stock := NewStockRepo(db)
+	chair := createTestProduct(t, db, "Стул", 4990, 10)
+	table := createTestProduct(t, db, "Стол", 9000, 4)
+
+	mark := func() []int {
+		t.Helper()
+		products, err := alerts.MarkLowStock(ctx, []int{chair.ID, table.ID}, 5)
+		if err != nil {
+			t.Fatalf("MarkLowStock() error = %v", err)
+		}
+		ids := make([]int, 0, len(products))
+		for _, p := range products {
+			ids = append(ids, p.ProductID)
+		}
+		return ids
+	}
+
+	// Стол ниже порога по умолчанию, событие о нем отправляется один раз
+	if got := mark(); len(got) != 1 || got[0] != table.ID {
+		t.Fatalf("MarkLowStock() = %v, want [%d]", got, table.ID)
+	}
+	if got := mark(); len(got) != 0 {
+		t.Errorf("MarkLowStock() repeated = %v, want none", got)
+	}
+
+	// Собственный порог товара заменяет порог по умолчанию
+	threshold := 12
+	if err := alerts.SetThreshold(ctx, chair.ID, &threshold); err != nil {
+		t.Fatalf("SetThreshold() error = %v", err)
+	}
+	if got := mark(); len(got) != 1 || got[0] != chair.ID {
+		t.Errorf("MarkLowStock() after threshold = %v, want [%d]", got, chair.ID)
+	}
+
+	// После пополнения до порога флаг сбрасывается, и следующее падение снова дает событие
+	if err := stock.Post(ctx, &entity.StockMovement{ProductID: table.ID, Quantity: 3, Reason: entity.StockReceipt}); err != nil {
+		t.Fatalf("Post(receipt) error = %v", err)
+	}
+	if got := mark(); len(got) != 0 {
+		t.Errorf("MarkLowStock() after receipt = %v, want none", got)
+	}
+	if err := stock.Post(ctx, &entity.StockMovement{ProductID: table.ID, Quantity: -5, Reason: entity.StockSale}); err != nil {
+		t.Fatalf("Post(sale) error = %v", err)
+	}
+	if got := mark(); len(got) != 1 || got[0] != table.ID {
+		t.Errorf("MarkLowStock() after sale = %v, want [%d]", got, table.ID)
+	}
+
+	low, err := alerts.ListLowStock(ctx, 5)
+	if err != nil {
+		t.Fatalf("ListLowStock() error = %v", err)
+	}
+	if len(low) != 2 || low[0].ProductID != table.ID || low[1].Threshold != threshold {
+		t.Errorf("ListLowStock() = %+v, want table first and chair with own threshold", low)
+	}
+}
+
+func TestStockAlertRepoSubscriptions(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	alerts := NewStockAlertRepo(db)
+	stock := NewStockRepo(db)
+	user := createTestUser(t, db)
+	sofa := createTestProduct(t, db, "Диван", 50000, 0)
+
+	first := &entity.StockSubscription{UserID: user.ID, ProductID: sofa.ID}
+	if err := alerts.Subscribe(ctx, first); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	repeated := &entity.StockSubscription{UserID: user.ID, ProductID: sofa.ID}
+	if err := alerts.Subscribe(ctx, repeated); err != nil {
+		t.Fatalf("Subscribe(repeated) error = %v", err)
+	}
+	if repeated.ID != first.ID {
+		t.Errorf("Subscribe(repeated) id = %d, want existing %d", repeated.ID, first.ID)
+	}
+
+	// Пока товара нет, уведомлять некого
+	subs, err := alerts.NotifyAvailable(ctx, []int{sofa.ID})
+	if err != nil {
+		t.Fatalf("NotifyAvailable() error = %v", err)
+	}
+	if len(subs) != 0 {
+		t.Fatalf("NotifyAvailable() without stock = %d subscriptions, want 0", len(subs))
+	}
+
+	if err := stock.Post(ctx, &entity.StockMovement{ProductID: sofa.ID, Quantity: 2, Reason: entity.StockReceipt}); err != nil {
+		t.Fatalf("Post(receipt) error = %v", err)
+	}
+	subs, err = alerts.NotifyAvailable(ctx, []int{sofa.ID})
+	if err != nil {
+		t.Fatalf("NotifyAvailable() error = %v", err)
+	}
+	if len(subs) != 1 || subs[0].ID != first.ID || subs[0].Email != user.Email || subs[0].NotifiedAt == nil {
+		t.Fatalf("NotifyAvailable() = %+v, want subscription %d of %s", subs, first.ID, user.Email)
+	}
+
+	// Уведомление отправляется один раз, после него можно подписаться заново
+	subs, err = alerts.NotifyAvailable(ctx, []int{sofa.ID})
+	if err != nil {
+		t.Fatalf("NotifyAvailable() error = %v", err)
+	}
+	if len(subs) != 0 {
+		t.Errorf("NotifyAvailable() repeated = %d subscriptions, want 0", len(subs))
+	}
+	again := &entity.StockSubscription{UserID: user.ID, ProductID: sofa.ID}
+	if err := alerts.Subscribe(ctx, again); err != nil {
+		t.Fatalf("Subscribe(after notification) error = %v", err)
+	}
+	if again.ID == first.ID {
+		t.Errorf("Subscribe(after notification) reused notified subscription %d", first.ID)
+	}
+}
+
+func TestStockAlertRepoNotifyIgnoresReserved(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	alerts := NewStockAlertRepo(db)
+	orders := NewOrderRepo(db)
+	subscriber := createTestUser(t, db)
+	buyer := createTestUser(t, db)
+	lamp := createTestProduct(t, db, "Лампа", 3000, 1)
+
+	sub := &entity.StockSubscription{UserID: subscriber.ID, ProductID: lamp.ID}
+	if err := alerts.Subscribe(ctx, sub); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// Единственная лампа зарезервирована неоплаченным заказом — в продаже ее нет
+	order := &entity.Order{UserID: buyer.ID, Status: entity.OrderStatusPending,
+		Delivery: entity.Delivery{Method: entity.DeliveryCourier},
+		Items:    []entity.OrderItem{{ProductID: lamp.ID, Quantity: 1}}}
+	if err := orders.Create(ctx, order, time.Hour, false); err != nil {
+		t.Fatalf("Create(order) error = %v", err)
+	}
+	subs, err := alerts.NotifyAvailable(ctx, []int{lamp.ID})
+	if err != nil {
+		t.Fatalf("NotifyAvailable() error = %v", err)
+	}
+	if len(subs) != 0 {
+		t.Fatalf("NotifyAvailable() with reserved stock = %d subscriptions, want 0", len(subs))
+	}
+
+	// Отмена заказа возвращает лампу в продажу
+	if err := orders.UpdateStatus(ctx, order.ID, entity.OrderStatusPending, entity.OrderStatusCancelled, 0, ""); err != nil {
+		t.Fatalf("UpdateStatus(cancelled) error = %v", err)
+	}
+	subs, err = alerts.NotifyAvailable(ctx, []int{lamp.ID})
+	if err != nil {
+		t.Fatalf("NotifyAvailable() error = %v", err)
+	}
+	if len(subs) != 1 || subs[0].ID != sub.ID {
+		t.Errorf("NotifyAvailable() after cancel = %+v, want subscription %d", subs, sub.ID)
+	}
+}
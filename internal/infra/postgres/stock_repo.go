@@ -0,0 +1,468 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+// reservedStockExpr — количество, удерживаемое активными резервами неоплаченных заказов, для строки warehouse_stock ws
+const reservedStockExpr = `COALESCE((
+	SELECT SUM(sr.quantity) FROM stock_reservations sr
+	WHERE sr.status = 'active' AND sr.warehouse_id = ws.warehouse_id
+	  AND sr.product_id = ws.product_id AND COALESCE(sr.variant_id, 0) = COALESCE(ws.variant_id, 0)
+), 0)`
+
+// StockRepo ведет журнал движений остатков (stock_movements).
+// Остаток на складе меняется только вместе с записью в журнале в одной транзакции.
+type StockRepo struct {
+	db *sql.DB
+}
+
+func NewStockRepo(db *sql.DB) *StockRepo {
+	return &StockRepo{db: db}
+}
+
+// Post проводит движение: меняет остаток товара или варианта на складе на m.Quantity и пишет запись в журнал.
+// Без склада движение проводится по основному. Остаток не может стать отрицательным — в этом случае возвращается ErrInsufficientStock.
+func (r *StockRepo) Post(ctx context.Context, m *entity.StockMovement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("post stock movement - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, m.ProductID); err != nil {
+		return err
+	}
+	if m.WarehouseID == 0 {
+		if m.WarehouseID, err = defaultWarehouseID(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if err := applyStockMovement(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("post stock movement - commit: %w", err)
+	}
+	return nil
+}
+
+// Set доводит остаток товара или варианта на складе до stock корректировкой на разницу.
+// Если остаток уже равен stock, движение не создается и m.ID остается нулевым.
+func (r *StockRepo) Set(ctx context.Context, m *entity.StockMovement, stock int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("set stock - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockProduct(ctx, tx, m.ProductID); err != nil {
+		return err
+	}
+	if m.WarehouseID == 0 {
+		if m.WarehouseID, err = defaultWarehouseID(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if err := checkStockTarget(ctx, tx, m.ProductID, m.VariantID); err != nil {
+		return err
+	}
+
+	current, err := warehouseStock(ctx, tx, m.WarehouseID, m.ProductID, m.VariantID)
+	if err != nil {
+		return err
+	}
+
+	m.Quantity = stock - current
+	m.Balance = stock
+	if m.Quantity != 0 {
+		if err := applyStockMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("set stock - commit: %w", err)
+	}
+	return nil
+}
+
+// ListByProduct возвращает движения товара после курсора, новые первыми.
+// variantID и warehouseID, если не равны 0, оставляют движения только варианта и только склада.
+// Курсор следующей страницы равен nil, если страница последняя.
+func (r *StockRepo) ListByProduct(ctx context.Context, productID, variantID, warehouseID int, after *pagination.Cursor, limit int) ([]*entity.StockMovement, *pagination.Cursor, error) {
+	query := `
+		SELECT id, product_id, COALESCE(variant_id, 0), COALESCE(warehouse_id, 0), quantity, reason, balance,
+		       COALESCE(user_id, 0), reference, comment, created_at
+		FROM stock_movements
+		WHERE product_id = $1`
+	args := []interface{}{productID}
+
+	if variantID != 0 {
+		args = append(args, variantID)
+		query += fmt.Sprintf(` AND variant_id = $%d`, len(args))
+	}
+	if warehouseID != 0 {
+		args = append(args, warehouseID)
+		query += fmt.Sprintf(` AND warehouse_id = $%d`, len(args))
+	}
+	if after != nil {
+		args = append(args, after.ID)
+		query += fmt.Sprintf(` AND id < $%d`, len(args))
+	}
+
+	// Берем на одну строку больше, чтобы понять, есть ли следующая страница
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := make([]*entity.StockMovement, 0)
+	for rows.Next() {
+		m := &entity.StockMovement{}
+		err := rows.Scan(&m.ID, &m.ProductID, &m.VariantID, &m.WarehouseID, &m.Quantity, &m.Reason, &m.Balance,
+			&m.UserID, &m.Reference, &m.Comment, &m.CreatedAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan stock movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	var next *pagination.Cursor
+	if len(movements) > limit {
+		movements = movements[:limit]
+		next = &pagination.Cursor{ID: movements[limit-1].ID}
+	}
+	return movements, next, nil
+}
+
+// discrepanciesQuery находит остатки на складах, которые не равны сумме движений журнала.
+// Остатки товара без вариантов сверяются на уровне товара, товара с вариантами — по вариантам;
+// движения удаленных вариантов и складов не учитываются.
+const discrepanciesQuery = `
+	SELECT COALESCE(s.warehouse_id, m.warehouse_id), p.id, COALESCE(s.variant_id, m.variant_id),
+	       p.name, COALESCE(v.sku, p.sku, ''), COALESCE(s.stock, 0), COALESCE(m.total, 0)
+	FROM (
+		SELECT warehouse_id, product_id, COALESCE(variant_id, 0) AS variant_id, stock
+		FROM warehouse_stock
+	) s
+	FULL JOIN (
+		SELECT warehouse_id, product_id, COALESCE(variant_id, 0) AS variant_id, SUM(quantity) AS total
+		FROM stock_movements
+		WHERE warehouse_id IS NOT NULL
+		GROUP BY warehouse_id, product_id, COALESCE(variant_id, 0)
+	) m ON m.warehouse_id = s.warehouse_id AND m.product_id = s.product_id AND m.variant_id = s.variant_id
+	JOIN products p ON p.id = COALESCE(s.product_id, m.product_id)
+	LEFT JOIN product_variants v ON v.id = COALESCE(s.variant_id, m.variant_id)
+	WHERE COALESCE(s.stock, 0) <> COALESCE(m.total, 0)
+	  AND CASE WHEN COALESCE(s.variant_id, m.variant_id) = 0
+	           THEN NOT EXISTS (SELECT 1 FROM product_variants pv WHERE pv.product_id = p.id)
+	           ELSE v.id IS NOT NULL END
+	ORDER BY 2, 3, 1`
+
+// Discrepancies возвращает остатки, которые разошлись с журналом (например, после ручной правки в БД)
+func (r *StockRepo) Discrepancies(ctx context.Context) ([]*entity.StockDiscrepancy, error) {
+	rows, err := r.db.QueryContext(ctx, discrepanciesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("list stock discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDiscrepancies(rows)
+}
+
+// Reconcile приводит расходящиеся остатки на складах к сумме движений журнала, пересчитывает
+// общие остатки товаров и вариантов и возвращает исправленные позиции.
+// Отрицательная сумма журнала не применяется: такой остаток остается в списке для ручного разбора.
+func (r *StockRepo) Reconcile(ctx context.Context) ([]*entity.StockDiscrepancy, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile stock - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокируем товары по возрастанию ID, как заказы, чтобы остатки не менялись между чтением и исправлением
+	_, err = tx.ExecContext(ctx, `SELECT id FROM products ORDER BY id FOR UPDATE`)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile stock - lock products: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, discrepanciesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile stock - list discrepancies: %w", err)
+	}
+	discrepancies, err := scanDiscrepancies(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range discrepancies {
+		if d.Ledger < 0 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO warehouse_stock (warehouse_id, product_id, variant_id, stock)
+			VALUES ($1, $2, NULLIF($3, 0), $4)
+			ON CONFLICT (warehouse_id, product_id, COALESCE(variant_id, 0))
+			DO UPDATE SET stock = EXCLUDED.stock, updated_at = CURRENT_TIMESTAMP`,
+			d.WarehouseID, d.ProductID, d.VariantID, d.Ledger)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile stock: %w", err)
+		}
+	}
+
+	// Общие остатки пересчитываются по всему каталогу: они могли разойтись со складами и без журнала
+	_, err = tx.ExecContext(ctx, `
+		UPDATE product_variants v
+		SET stock = s.stock, updated_at = CURRENT_TIMESTAMP
+		FROM (
+			SELECT pv.id, COALESCE(SUM(ws.stock), 0) AS stock
+			FROM product_variants pv
+			LEFT JOIN warehouse_stock ws ON ws.variant_id = pv.id
+			GROUP BY pv.id
+		) s
+		WHERE s.id = v.id AND v.stock <> s.stock`)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile stock - sync variants: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products p
+		SET stock = s.stock, updated_at = CURRENT_TIMESTAMP
+		FROM (
+			SELECT pr.id, COALESCE(SUM(ws.stock), 0) AS stock
+			FROM products pr
+			LEFT JOIN warehouse_stock ws ON ws.product_id = pr.id
+			GROUP BY pr.id
+		) s
+		WHERE s.id = p.id AND p.stock IS DISTINCT FROM s.stock`)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile stock - sync products: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("reconcile stock - commit: %w", err)
+	}
+	return discrepancies, nil
+}
+
+func scanDiscrepancies(rows *sql.Rows) ([]*entity.StockDiscrepancy, error) {
+	discrepancies := make([]*entity.StockDiscrepancy, 0)
+	for rows.Next() {
+		d := &entity.StockDiscrepancy{}
+		err := rows.Scan(&d.WarehouseID, &d.ProductID, &d.VariantID, &d.ProductName, &d.SKU, &d.Stock, &d.Ledger)
+		if err != nil {
+			return nil, fmt.Errorf("scan stock discrepancy: %w", err)
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return discrepancies, nil
+}
+
+// applyStockMovement меняет остаток на складе m.WarehouseID на m.Quantity, пересчитывает общие остатки
+// и пишет движение в журнал; товар уже заблокирован вызывающим.
+// У товара с вариантами движение проводится только по варианту.
+func applyStockMovement(ctx context.Context, tx *sql.Tx, m *entity.StockMovement) error {
+	if err := checkStockTarget(ctx, tx, m.ProductID, m.VariantID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = $1)`, m.WarehouseID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("apply stock movement - check warehouse: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", apperrors.ErrWarehouseNotFound, m.WarehouseID)
+	}
+
+	if m.Quantity > 0 {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO warehouse_stock (warehouse_id, product_id, variant_id, stock)
+			VALUES ($1, $2, NULLIF($3, 0), $4)
+			ON CONFLICT (warehouse_id, product_id, COALESCE(variant_id, 0))
+			DO UPDATE SET stock = warehouse_stock.stock + EXCLUDED.stock, updated_at = CURRENT_TIMESTAMP
+			RETURNING stock`,
+			m.WarehouseID, m.ProductID, m.VariantID, m.Quantity,
+		).Scan(&m.Balance)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			UPDATE warehouse_stock SET stock = stock + $1, updated_at = CURRENT_TIMESTAMP
+			WHERE warehouse_id = $2 AND product_id = $3 AND COALESCE(variant_id, 0) = $4 AND stock + $1 >= 0
+			RETURNING stock`,
+			m.Quantity, m.WarehouseID, m.ProductID, m.VariantID,
+		).Scan(&m.Balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: product %d in warehouse %d", apperrors.ErrInsufficientStock, m.ProductID, m.WarehouseID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("apply stock movement: %w", err)
+	}
+
+	if err := syncStockTotals(ctx, tx, m.ProductID); err != nil {
+		return err
+	}
+
+	return insertStockMovement(ctx, tx, m)
+}
+
+// clearStock списывает остатки товара (variantID = 0) или варианта со всех складов, записывая движения по шаблону tmpl
+func clearStock(ctx context.Context, tx *sql.Tx, productID, variantID int, tmpl entity.StockMovement) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT warehouse_id, stock FROM warehouse_stock
+		WHERE product_id = $1 AND COALESCE(variant_id, 0) = $2 AND stock > 0
+		ORDER BY warehouse_id`,
+		productID, variantID)
+	if err != nil {
+		return fmt.Errorf("clear stock: %w", err)
+	}
+
+	var movements []*entity.StockMovement
+	for rows.Next() {
+		m := tmpl
+		m.ProductID, m.VariantID = productID, variantID
+		if err := rows.Scan(&m.WarehouseID, &m.Quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan warehouse stock: %w", err)
+		}
+		m.Quantity = -m.Quantity
+		movements = append(movements, &m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for _, m := range movements {
+		if err := applyStockMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkStockTarget проверяет, что остаток можно вести по товару (variantID = 0) или по его варианту
+func checkStockTarget(ctx context.Context, tx *sql.Tx, productID, variantID int) error {
+	if variantID != 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)`,
+			variantID, productID,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("check variant: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("%w: %d", apperrors.ErrVariantNotFound, variantID)
+		}
+		return nil
+	}
+
+	var hasVariants bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1)`,
+		productID,
+	).Scan(&hasVariants)
+	if err != nil {
+		return fmt.Errorf("check product variants: %w", err)
+	}
+	if hasVariants {
+		return fmt.Errorf("%w: product %d", apperrors.ErrVariantRequired, productID)
+	}
+	return nil
+}
+
+// warehouseStock возвращает остаток товара или варианта на складе; нет строки — 0
+func warehouseStock(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int) (int, error) {
+	var stock int
+	err := tx.QueryRowContext(ctx, `
+		SELECT stock FROM warehouse_stock
+		WHERE warehouse_id = $1 AND product_id = $2 AND COALESCE(variant_id, 0) = $3`,
+		warehouseID, productID, variantID,
+	).Scan(&stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get warehouse stock: %w", err)
+	}
+	return stock, nil
+}
+
+// defaultWarehouseID возвращает основной склад — активный склад с наименьшим приоритетом.
+// На него попадают остатки, заданные без склада (в карточке товара, варианте, импорте).
+func defaultWarehouseID(ctx context.Context, tx *sql.Tx) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM warehouses
+		WHERE active AND type = $1
+		ORDER BY priority, id
+		LIMIT 1`,
+		entity.WarehouseTypeWarehouse,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: no active warehouse", apperrors.ErrWarehouseNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get default warehouse: %w", err)
+	}
+	return id, nil
+}
+
+// syncStockTotals пересчитывает общие остатки вариантов и товара как суммы по складам.
+// После переноса на варианты строки уровня товара нулевые, поэтому сумма по товару — это сумма вариантов.
+func syncStockTotals(ctx context.Context, tx *sql.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE product_variants v
+		SET stock = (SELECT COALESCE(SUM(ws.stock), 0) FROM warehouse_stock ws WHERE ws.variant_id = v.id),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE v.product_id = $1`,
+		productID)
+	if err != nil {
+		return fmt.Errorf("sync variant stock: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE products
+		SET stock = (SELECT COALESCE(SUM(stock), 0) FROM warehouse_stock WHERE product_id = $1),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		productID)
+	if err != nil {
+		return fmt.Errorf("sync product stock: %w", err)
+	}
+	return nil
+}
+
+// insertStockMovement пишет уже проведенное движение в журнал; m.Balance должен содержать остаток на складе после него
+func insertStockMovement(ctx context.Context, tx *sql.Tx, m *entity.StockMovement) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO stock_movements (product_id, variant_id, warehouse_id, quantity, reason, balance, user_id, reference, comment)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, NULLIF($7, 0), $8, $9)
+		RETURNING id, created_at`,
+		m.ProductID, m.VariantID, m.WarehouseID, m.Quantity, m.Reason, m.Balance, m.UserID, m.Reference, m.Comment,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert stock movement: %w", err)
+	}
+	return nil
+}
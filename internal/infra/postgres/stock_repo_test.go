@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestStockRepoPost(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewStockRepo(db)
+	product := createTestProduct(t, db, "Стул", 4990, 5)
+
+	sale := &entity.StockMovement{ProductID: product.ID, Quantity: -3, Reason: entity.StockSale, Reference: "order:1"}
+	if err := repo.Post(ctx, sale); err != nil {
+		t.Fatalf("Post(sale) error = %v", err)
+	}
+	if sale.Balance != 2 {
+		t.Errorf("Post(sale) balance = %d, want 2", sale.Balance)
+	}
+
+	// Остаток не уходит в минус: движение отклоняется целиком
+	writeOff := &entity.StockMovement{ProductID: product.ID, Quantity: -3, Reason: entity.StockWriteOff}
+	if err := repo.Post(ctx, writeOff); !apperrors.Is(err, apperrors.ErrInsufficientStock) {
+		t.Errorf("Post(write-off) error = %v, want %v", err, apperrors.ErrInsufficientStock)
+	}
+	if got := productStock(t, db, product.ID); got != 2 {
+		t.Errorf("stock after rejected write-off = %d, want 2", got)
+	}
+
+	missing := &entity.StockMovement{ProductID: product.ID + 1000, Quantity: 1, Reason: entity.StockReceipt}
+	if err := repo.Post(ctx, missing); !apperrors.Is(err, apperrors.ErrProductNotFound) {
+		t.Errorf("Post(missing product) error = %v, want %v", err, apperrors.ErrProductNotFound)
+	}
+
+	set := &entity.StockMovement{ProductID: product.ID, Reason: entity.StockAdjustment}
+	if err := repo.Set(ctx, set, 10); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if set.Quantity != 8 || set.Balance != 10 {
+		t.Errorf("Set() quantity = %d, balance = %d, want 8 and 10", set.Quantity, set.Balance)
+	}
+	unchanged := &entity.StockMovement{ProductID: product.ID, Reason: entity.StockAdjustment}
+	if err := repo.Set(ctx, unchanged, 10); err != nil {
+		t.Fatalf("Set(same stock) error = %v", err)
+	}
+	if unchanged.ID != 0 {
+		t.Errorf("Set(same stock) created movement %d, want none", unchanged.ID)
+	}
+
+	// История: начальный остаток, продажа и корректировка, новые первыми
+	movements, next, err := repo.ListByProduct(ctx, product.ID, 0, 0, nil, 10)
+	if err != nil {
+		t.Fatalf("ListByProduct() error = %v", err)
+	}
+	if next != nil {
+		t.Errorf("ListByProduct() next cursor = %v, want nil", next)
+	}
+	wantQuantities := []int{8, -3, 5}
+	if len(movements) != len(wantQuantities) {
+		t.Fatalf("ListByProduct() returned %d movements, want %d", len(movements), len(wantQuantities))
+	}
+	for i, m := range movements {
+		if m.Quantity != wantQuantities[i] {
+			t.Errorf("movement %d quantity = %d, want %d", i, m.Quantity, wantQuantities[i])
+		}
+	}
+}
+
+func TestStockRepoReconcile(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	repo := NewStockRepo(db)
+	product := createTestProduct(t, db, "Шкаф", 30000, 4)
+
+	// Ручная правка остатка на складе в обход журнала
+	if _, err := db.ExecContext(ctx, `UPDATE warehouse_stock SET stock = 9 WHERE product_id = $1`, product.ID); err != nil {
+		t.Fatalf("update stock: %v", err)
+	}
+
+	discrepancies, err := repo.Discrepancies(ctx)
+	if err != nil {
+		t.Fatalf("Discrepancies() error = %v", err)
+	}
+	if len(discrepancies) != 1 || discrepancies[0].Stock != 9 || discrepancies[0].Ledger != 4 {
+		t.Fatalf("Discrepancies() = %+v, want stock 9 and ledger 4", discrepancies)
+	}
+
+	if _, err := repo.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := productStock(t, db, product.ID); got != 4 {
+		t.Errorf("stock after reconcile = %d, want 4", got)
+	}
+	discrepancies, err = repo.Discrepancies(ctx)
+	if err != nil {
+		t.Fatalf("Discrepancies() error = %v", err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Discrepancies() after reconcile = %+v, want none", discrepancies)
+	}
+}
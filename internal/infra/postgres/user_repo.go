@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
 )
 
@@ -34,13 +36,13 @@ func (r *UserRepo) Create(ctx context.Context, user *entity.User) error {
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	query := `SELECT id, email, password, name, role, created_at, updated_at 
+	query := `SELECT id, email, password, name, role, created_at, updated_at, disabled, disabled_at
 	          FROM users WHERE email = $1`
 
 	user := &entity.User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.Password, &user.Name,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Disabled, &user.DisabledAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -53,13 +55,13 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*entity.User,
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, id int) (*entity.User, error) {
-	query := `SELECT id, email, password, name, role, created_at, updated_at 
+	query := `SELECT id, email, password, name, role, created_at, updated_at, disabled, disabled_at
 	          FROM users WHERE id = $1`
 
 	user := &entity.User{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Email, &user.Password, &user.Name,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Disabled, &user.DisabledAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -70,3 +72,110 @@ func (r *UserRepo) GetByID(ctx context.Context, id int) (*entity.User, error) {
 	}
 	return user, nil
 }
+
+// List возвращает пользователей по фильтру, новые первыми
+func (r *UserRepo) List(ctx context.Context, filter entity.UserFilter, limit, offset int) ([]*entity.User, error) {
+	where, args := userFilterClause(filter)
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, email, name, role, created_at, updated_at, disabled, disabled_at
+		FROM users%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*entity.User, 0)
+	for rows.Next() {
+		user := &entity.User{}
+		if err := rows.Scan(
+			&user.ID, &user.Email, &user.Name, &user.Role,
+			&user.CreatedAt, &user.UpdatedAt, &user.Disabled, &user.DisabledAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate users: %w", err)
+	}
+	return users, nil
+}
+
+// Count возвращает количество пользователей по фильтру
+func (r *UserRepo) Count(ctx context.Context, filter entity.UserFilter) (int, error) {
+	where, args := userFilterClause(filter)
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count users: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateRole меняет роль пользователя
+func (r *UserRepo) UpdateRole(ctx context.Context, id int, role string) error {
+	query := `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	return r.execUserUpdate(ctx, "update user role", query, role, id)
+}
+
+// SetDisabled блокирует или разблокирует пользователя
+func (r *UserRepo) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	query := `
+		UPDATE users
+		SET disabled = $1,
+		    disabled_at = CASE WHEN $1 THEN CURRENT_TIMESTAMP ELSE NULL END,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`
+	return r.execUserUpdate(ctx, "set user disabled", query, disabled, id)
+}
+
+func (r *UserRepo) execUserUpdate(ctx context.Context, op, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s - get rows affected: %w", op, err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.ErrUserNotFound
+	}
+	return nil
+}
+
+// userFilterClause собирает WHERE для фильтра пользователей
+func userFilterClause(filter entity.UserFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(email ILIKE $%d OR name ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if filter.Disabled != nil {
+		args = append(args, *filter.Disabled)
+		conditions = append(conditions, fmt.Sprintf("disabled = $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
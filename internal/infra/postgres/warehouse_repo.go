@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/lib/pq"
+)
+
+type WarehouseRepo struct {
+	db *sql.DB
+}
+
+func NewWarehouseRepo(db *sql.DB) *WarehouseRepo {
+	return &WarehouseRepo{db: db}
+}
+
+const warehouseColumns = `id, code, name, type, address, pickup, active, priority, created_at, updated_at`
+
+// List возвращает склады и шоурумы в порядке приоритета; pickupOnly оставляет только активные пункты самовывоза
+func (r *WarehouseRepo) List(ctx context.Context, pickupOnly bool) ([]*entity.Warehouse, error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses`
+	if pickupOnly {
+		query += ` WHERE active AND pickup`
+	}
+	query += ` ORDER BY priority, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	warehouses := make([]*entity.Warehouse, 0)
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return warehouses, nil
+}
+
+// GetByID возвращает склад по ID или nil, если его нет
+func (r *WarehouseRepo) GetByID(ctx context.Context, id int) (*entity.Warehouse, error) {
+	w, err := scanWarehouse(r.db.QueryRowContext(ctx, `SELECT `+warehouseColumns+` FROM warehouses WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Create создает склад
+func (r *WarehouseRepo) Create(ctx context.Context, w *entity.Warehouse) error {
+	query := `
+		INSERT INTO warehouses (code, name, type, address, pickup, active, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		w.Code, w.Name, w.Type, w.Address, w.Pickup, w.Active, w.Priority).
+		Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return warehouseError("create warehouse", err)
+	}
+	return nil
+}
+
+// Update обновляет склад
+func (r *WarehouseRepo) Update(ctx context.Context, w *entity.Warehouse) error {
+	query := `
+		UPDATE warehouses
+		SET code = $1, name = $2, type = $3, address = $4, pickup = $5, active = $6, priority = $7,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		w.Code, w.Name, w.Type, w.Address, w.Pickup, w.Active, w.Priority, w.ID).
+		Scan(&w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrWarehouseNotFound
+	}
+	if err != nil {
+		return warehouseError("update warehouse", err)
+	}
+	return nil
+}
+
+// Delete удаляет склад без остатков и заказов; склад с историей можно только деактивировать
+func (r *WarehouseRepo) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("delete warehouse - begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hasStock bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM warehouse_stock WHERE warehouse_id = $1 AND stock > 0)`, id,
+	).Scan(&hasStock)
+	if err != nil {
+		return fmt.Errorf("delete warehouse - check stock: %w", err)
+	}
+	if hasStock {
+		return fmt.Errorf("%w: stock", apperrors.ErrWarehouseInUse)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM warehouses WHERE id = $1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgForeignKeyViolation {
+		return fmt.Errorf("%w: orders", apperrors.ErrWarehouseInUse)
+	}
+	if err != nil {
+		return fmt.Errorf("delete warehouse: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete warehouse - get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.ErrWarehouseNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("delete warehouse - commit: %w", err)
+	}
+	return nil
+}
+
+func scanWarehouse(row interface{ Scan(...interface{}) error }) (*entity.Warehouse, error) {
+	var w entity.Warehouse
+	err := row.Scan(&w.ID, &w.Code, &w.Name, &w.Type, &w.Address, &w.Pickup, &w.Active, &w.Priority, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan warehouse: %w", err)
+	}
+	return &w, nil
+}
+
+// warehouseError переводит нарушения ограничений при записи склада в доменные ошибки
+func warehouseError(op string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w: %s", apperrors.ErrWarehouseExists, pqErr.Constraint)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
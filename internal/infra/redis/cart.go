@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/go-redis/redis/v8"
+)
+
+// CartStore хранит гостевые корзины в Redis: hash cart:{id}, поле — ID товара
+// или "ID товара:ID варианта", значение — количество. Промокод корзины лежит в строке cart:{id}:promo.
+type CartStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewCartStore(client *redis.Client, ttl time.Duration) *CartStore {
+	return &CartStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Items возвращает позиции корзины, отсортированные по ID товара и варианта
+func (s *CartStore) Items(ctx context.Context, cartID string) ([]entity.CartItem, error) {
+	values, err := s.client.HGetAll(ctx, cartKey(cartID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]entity.CartItem, 0, len(values))
+	for field, value := range values {
+		productID, variantID, ok := parseCartField(field)
+		if !ok {
+			continue
+		}
+		quantity, err := strconv.Atoi(value)
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		items = append(items, entity.CartItem{ProductID: productID, VariantID: variantID, Quantity: quantity})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].ProductID != items[j].ProductID {
+			return items[i].ProductID < items[j].ProductID
+		}
+		return items[i].VariantID < items[j].VariantID
+	})
+	return items, nil
+}
+
+// AddItem увеличивает количество товара и продлевает жизнь корзины
+func (s *CartStore) AddItem(ctx context.Context, cartID string, productID, variantID, quantity int) error {
+	key := cartKey(cartID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, cartField(productID, variantID), int64(quantity))
+		pipe.Expire(ctx, key, s.ttl)
+		pipe.Expire(ctx, promoKey(cartID), s.ttl)
+		return nil
+	})
+	return err
+}
+
+// SetItem устанавливает количество товара
+func (s *CartStore) SetItem(ctx context.Context, cartID string, productID, variantID, quantity int) error {
+	key := cartKey(cartID)
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, cartField(productID, variantID), quantity)
+		pipe.Expire(ctx, key, s.ttl)
+		pipe.Expire(ctx, promoKey(cartID), s.ttl)
+		return nil
+	})
+	return err
+}
+
+// RemoveItem удаляет товар из корзины
+func (s *CartStore) RemoveItem(ctx context.Context, cartID string, productID, variantID int) error {
+	return s.client.HDel(ctx, cartKey(cartID), cartField(productID, variantID)).Err()
+}
+
+// Delete удаляет корзину целиком вместе с промокодом
+func (s *CartStore) Delete(ctx context.Context, cartID string) error {
+	return s.client.Del(ctx, cartKey(cartID), promoKey(cartID)).Err()
+}
+
+// PromoCode возвращает промокод корзины или пустую строку
+func (s *CartStore) PromoCode(ctx context.Context, cartID string) (string, error) {
+	code, err := s.client.Get(ctx, promoKey(cartID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return code, err
+}
+
+// SetPromoCode применяет промокод к корзине; код живет столько же, сколько корзина
+func (s *CartStore) SetPromoCode(ctx context.Context, cartID, code string) error {
+	return s.client.Set(ctx, promoKey(cartID), code, s.ttl).Err()
+}
+
+// RemovePromoCode убирает промокод из корзины
+func (s *CartStore) RemovePromoCode(ctx context.Context, cartID string) error {
+	return s.client.Del(ctx, promoKey(cartID)).Err()
+}
+
+func cartKey(cartID string) string {
+	return "cart:" + cartID
+}
+
+func promoKey(cartID string) string {
+	return "cart:" + cartID + ":promo"
+}
+
+// cartField возвращает поле hash для позиции: ID товара или "ID товара:ID варианта"
+func cartField(productID, variantID int) string {
+	if variantID == 0 {
+		return strconv.Itoa(productID)
+	}
+	return strconv.Itoa(productID) + ":" + strconv.Itoa(variantID)
+}
+
+func parseCartField(field string) (productID, variantID int, ok bool) {
+	productPart, variantPart, hasVariant := strings.Cut(field, ":")
+	productID, err := strconv.Atoi(productPart)
+	if err != nil {
+		return 0, 0, false
+	}
+	if hasVariant {
+		if variantID, err = strconv.Atoi(variantPart); err != nil {
+			return 0, 0, false
+		}
+	}
+	return productID, variantID, true
+}
@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotencyStore хранит ответы на запросы с заголовком Idempotency-Key: строка idempotency:{key}
+type IdempotencyStore struct {
+	client *redis.Client
+}
+
+func NewIdempotencyStore(client *redis.Client) *IdempotencyStore {
+	return &IdempotencyStore{client: client}
+}
+
+// Reserve занимает ключ под выполняющийся запрос. Если ключ уже занят,
+// возвращает сохраненное под ним значение и false.
+func (s *IdempotencyStore) Reserve(ctx context.Context, key string, value []byte, ttl time.Duration) ([]byte, bool, error) {
+	key = idempotencyKey(key)
+	for {
+		ok, err := s.client.SetNX(ctx, key, value, ttl).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			return nil, true, nil
+		}
+
+		existing, err := s.client.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			// Ключ истек между SETNX и GET — пробуем занять его снова
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+}
+
+// Save сохраняет значение под ключом на ttl
+func (s *IdempotencyStore) Save(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, idempotencyKey(key), value, ttl).Err()
+}
+
+// Release освобождает ключ, чтобы запрос можно было повторить
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, idempotencyKey(key)).Err()
+}
+
+// Extend продлевает срок ключа на ttl; истекший ключ не восстанавливается
+func (s *IdempotencyStore) Extend(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.PExpire(ctx, idempotencyKey(key), ttl).Err()
+}
+
+func idempotencyKey(key string) string {
+	return "idempotency:" + key
+}
@@ -139,9 +139,13 @@ func (s *S3Storage) UploadFile(ctx context.Context, file multipart.File, header
 	return fileURL, nil
 }
 
-// UploadBytes загружает байты в S3 (для тестов и других случаев)
+// UploadBytes загружает байты в S3 (для тестов и других случаев).
+// Имя без каталога кладется в products/, имя с каталогом (invoices/...) используется как ключ.
 func (s *S3Storage) UploadBytes(ctx context.Context, data []byte, filename, contentType string) (string, error) {
-	key := fmt.Sprintf("products/%s", filename)
+	key := filename
+	if !strings.Contains(filename, "/") {
+		key = fmt.Sprintf("products/%s", filename)
+	}
 
 	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket:      aws.String(s.bucket),
@@ -157,6 +161,29 @@ func (s *S3Storage) UploadBytes(ctx context.Context, data []byte, filename, cont
 	return s.generateFileURL(key), nil
 }
 
+// DownloadBytes скачивает файл, загруженный ранее, по его URL
+func (s *S3Storage) DownloadBytes(ctx context.Context, fileURL string) ([]byte, error) {
+	key, err := s.extractKeyFromURL(fileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from S3: %w", err)
+	}
+	defer out.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(out.Body); err != nil {
+		return nil, fmt.Errorf("failed to read file from S3: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // DeleteFile удаляет файл из S3
 func (s *S3Storage) DeleteFile(ctx context.Context, fileURL string) error {
 	key, err := s.extractKeyFromURL(fileURL)
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// attributeCodePattern — код характеристики используется в параметрах фильтра attr.<code>
+var attributeCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+type AttributeService struct {
+	attributeRepo *postgres.AttributeRepo
+	categoryRepo  *postgres.CategoryRepo
+}
+
+func NewAttributeService(attributeRepo *postgres.AttributeRepo, categoryRepo *postgres.CategoryRepo) *AttributeService {
+	return &AttributeService{
+		attributeRepo: attributeRepo,
+		categoryRepo:  categoryRepo,
+	}
+}
+
+// ListAttributes возвращает все определения характеристик
+func (s *AttributeService) ListAttributes(ctx context.Context) ([]*entity.Attribute, error) {
+	return s.attributeRepo.List(ctx)
+}
+
+// CreateAttribute создает определение характеристики
+func (s *AttributeService) CreateAttribute(ctx context.Context, attribute *entity.Attribute) error {
+	if err := prepareAttribute(attribute); err != nil {
+		return err
+	}
+	return s.attributeRepo.Create(ctx, attribute)
+}
+
+// UpdateAttribute обновляет название, единицу, границы и варианты значений характеристики
+func (s *AttributeService) UpdateAttribute(ctx context.Context, attribute *entity.Attribute) error {
+	if err := prepareAttribute(attribute); err != nil {
+		return err
+	}
+	return s.attributeRepo.Update(ctx, attribute)
+}
+
+// DeleteAttribute удаляет характеристику, которая не заполнена ни у одного товара
+func (s *AttributeService) DeleteAttribute(ctx context.Context, id int) error {
+	return s.attributeRepo.Delete(ctx, id)
+}
+
+// CategoryAttributes возвращает характеристики категории с учетом наследования от родителей
+func (s *AttributeService) CategoryAttributes(ctx context.Context, categoryID int) ([]entity.CategoryAttribute, error) {
+	category, err := s.categoryRepo.GetByID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, errors.ErrCategoryNotFound
+	}
+	return s.attributeRepo.ForCategory(ctx, categoryID)
+}
+
+// SetCategoryAttributes заменяет характеристики, привязанные непосредственно к категории
+func (s *AttributeService) SetCategoryAttributes(ctx context.Context, categoryID int, bindings []entity.CategoryAttribute) ([]entity.CategoryAttribute, error) {
+	if err := s.attributeRepo.SetCategoryAttributes(ctx, categoryID, bindings); err != nil {
+		return nil, err
+	}
+	return s.attributeRepo.ForCategory(ctx, categoryID)
+}
+
+// prepareAttribute нормализует и проверяет определение характеристики
+func prepareAttribute(a *entity.Attribute) error {
+	a.Code = strings.TrimSpace(a.Code)
+	a.Name = strings.TrimSpace(a.Name)
+	a.Unit = strings.TrimSpace(a.Unit)
+
+	if a.Name == "" || utf8.RuneCountInString(a.Name) > 100 || utf8.RuneCountInString(a.Unit) > 20 {
+		return fmt.Errorf("%w: name", errors.ErrInvalidAttribute)
+	}
+	// При обновлении код и тип не меняются и могут не передаваться
+	if a.ID == 0 {
+		if !attributeCodePattern.MatchString(a.Code) {
+			return fmt.Errorf("%w: code must match %s", errors.ErrInvalidAttribute, attributeCodePattern)
+		}
+		if !a.Type.IsValid() {
+			return fmt.Errorf("%w: unknown type %q", errors.ErrInvalidAttribute, a.Type)
+		}
+	}
+
+	if a.Min != nil && a.Max != nil && *a.Min > *a.Max {
+		return fmt.Errorf("%w: min is greater than max", errors.ErrInvalidAttribute)
+	}
+
+	options := make([]string, 0, len(a.Options))
+	seen := make(map[string]bool, len(a.Options))
+	for _, option := range a.Options {
+		option = strings.TrimSpace(option)
+		key := strings.ToLower(option)
+		if option == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		options = append(options, option)
+	}
+	a.Options = options
+
+	if a.Type == entity.AttributeTypeEnum && len(a.Options) == 0 {
+		return fmt.Errorf("%w: enum needs options", errors.ErrInvalidAttribute)
+	}
+	return nil
+}
+
+// validateProductAttributes проверяет значения характеристик товара по определениям его категории
+// и возвращает их с заполненными ID, названием, типом и единицей. Значения enum приводятся к написанию из Options.
+func validateProductAttributes(defs []entity.CategoryAttribute, values []entity.ProductAttribute) ([]entity.ProductAttribute, error) {
+	byCode := make(map[string]*entity.CategoryAttribute, len(defs))
+	for i := range defs {
+		byCode[defs[i].Code] = &defs[i]
+	}
+
+	result := make([]entity.ProductAttribute, 0, len(values))
+	filled := make(map[string]bool, len(values))
+	for _, v := range values {
+		def, ok := byCode[v.Code]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not defined for the product category", errors.ErrInvalidAttribute, v.Code)
+		}
+		if filled[v.Code] {
+			return nil, fmt.Errorf("%w: %s is set twice", errors.ErrInvalidAttribute, v.Code)
+		}
+		filled[v.Code] = true
+
+		value := entity.ProductAttribute{
+			AttributeID: def.ID,
+			Code:        def.Code,
+			Name:        def.Name,
+			Type:        def.Type,
+			Unit:        def.Unit,
+		}
+
+		switch def.Type {
+		case entity.AttributeTypeNumber:
+			number := v.Number
+			if number == nil {
+				parsed, err := parseDecimal(strings.TrimSpace(v.Value))
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s must be a number", errors.ErrInvalidAttribute, v.Code)
+				}
+				number = &parsed
+			}
+			if (def.Min != nil && *number < *def.Min) || (def.Max != nil && *number > *def.Max) {
+				return nil, fmt.Errorf("%w: %s is out of range", errors.ErrInvalidAttribute, v.Code)
+			}
+			value.Number = number
+		case entity.AttributeTypeEnum:
+			option, ok := matchOption(def.Options, v.Value)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be one of %s", errors.ErrInvalidAttribute, v.Code, strings.Join(def.Options, ", "))
+			}
+			value.Value = option
+		}
+
+		result = append(result, value)
+	}
+
+	for _, def := range defs {
+		if def.Required && !filled[def.Code] {
+			return nil, fmt.Errorf("%w: %s is required", errors.ErrInvalidAttribute, def.Code)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return byCode[result[i].Code].SortOrder < byCode[result[j].Code].SortOrder
+	})
+	return result, nil
+}
+
+// matchOption ищет значение среди допустимых без учета регистра
+func matchOption(options []string, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range options {
+		if strings.EqualFold(option, value) {
+			return option, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestValidateProductAttributes(t *testing.T) {
+	minWidth, maxWidth := 50.0, 400.0
+	defs := []entity.CategoryAttribute{
+		{
+			Attribute: entity.Attribute{ID: 1, Code: "width", Name: "Ширина", Type: entity.AttributeTypeNumber, Unit: "см", Min: &minWidth, Max: &maxWidth},
+			Required:  true,
+			SortOrder: 2,
+		},
+		{
+			Attribute: entity.Attribute{ID: 2, Code: "material", Name: "Материал", Type: entity.AttributeTypeEnum, Options: []string{"Дуб", "Бук"}},
+			SortOrder: 1,
+		},
+	}
+	number := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		values  []entity.ProductAttribute
+		want    []entity.ProductAttribute
+		wantErr bool
+	}{
+		{
+			name:   "values are completed from definitions and sorted",
+			values: []entity.ProductAttribute{{Code: "width", Value: "120,5"}, {Code: "material", Value: " дуб "}},
+			want: []entity.ProductAttribute{
+				{AttributeID: 2, Code: "material", Name: "Материал", Type: entity.AttributeTypeEnum, Value: "Дуб"},
+				{AttributeID: 1, Code: "width", Name: "Ширина", Type: entity.AttributeTypeNumber, Unit: "см", Number: number(120.5)},
+			},
+		},
+		{
+			name:   "number can be passed as number",
+			values: []entity.ProductAttribute{{Code: "width", Number: number(400)}},
+			want: []entity.ProductAttribute{
+				{AttributeID: 1, Code: "width", Name: "Ширина", Type: entity.AttributeTypeNumber, Unit: "см", Number: number(400)},
+			},
+		},
+		{name: "required attribute is missing", values: []entity.ProductAttribute{{Code: "material", Value: "Бук"}}, wantErr: true},
+		{name: "unknown attribute", values: []entity.ProductAttribute{{Code: "width", Value: "100"}, {Code: "color", Value: "red"}}, wantErr: true},
+		{name: "attribute set twice", values: []entity.ProductAttribute{{Code: "width", Value: "100"}, {Code: "width", Value: "110"}}, wantErr: true},
+		{name: "number below min", values: []entity.ProductAttribute{{Code: "width", Value: "49.9"}}, wantErr: true},
+		{name: "number above max", values: []entity.ProductAttribute{{Code: "width", Number: number(401)}}, wantErr: true},
+		{name: "not a number", values: []entity.ProductAttribute{{Code: "width", Value: "wide"}}, wantErr: true},
+		{name: "unknown option", values: []entity.ProductAttribute{{Code: "width", Value: "100"}, {Code: "material", Value: "Сосна"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateProductAttributes(defs, tt.values)
+			if tt.wantErr {
+				if !errors.Is(err, errors.ErrInvalidAttribute) {
+					t.Fatalf("validateProductAttributes() error = %v, want ErrInvalidAttribute", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateProductAttributes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateProductAttributes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareAttribute(t *testing.T) {
+	minValue, maxValue := 10.0, 5.0
+
+	tests := []struct {
+		name      string
+		attribute entity.Attribute
+		wantErr   bool
+	}{
+		{name: "number", attribute: entity.Attribute{Code: "width", Name: " Ширина ", Type: entity.AttributeTypeNumber, Unit: "см"}},
+		{name: "update without code and type", attribute: entity.Attribute{ID: 1, Name: "Ширина"}},
+		{name: "empty name", attribute: entity.Attribute{Code: "width", Name: " ", Type: entity.AttributeTypeNumber}, wantErr: true},
+		{name: "code with uppercase", attribute: entity.Attribute{Code: "Width", Name: "Ширина", Type: entity.AttributeTypeNumber}, wantErr: true},
+		{name: "code starts with digit", attribute: entity.Attribute{Code: "1width", Name: "Ширина", Type: entity.AttributeTypeNumber}, wantErr: true},
+		{name: "unknown type", attribute: entity.Attribute{Code: "width", Name: "Ширина", Type: "text"}, wantErr: true},
+		{name: "min above max", attribute: entity.Attribute{Code: "width", Name: "Ширина", Type: entity.AttributeTypeNumber, Min: &minValue, Max: &maxValue}, wantErr: true},
+		{name: "enum without options", attribute: entity.Attribute{Code: "material", Name: "Материал", Type: entity.AttributeTypeEnum, Options: []string{" ", ""}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := prepareAttribute(&tt.attribute)
+			if tt.wantErr {
+				if !errors.Is(err, errors.ErrInvalidAttribute) {
+					t.Errorf("prepareAttribute() error = %v, want ErrInvalidAttribute", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("prepareAttribute() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPrepareAttributeNormalizesOptions(t *testing.T) {
+	a := entity.Attribute{Code: "material", Name: "Материал", Type: entity.AttributeTypeEnum, Options: []string{" Дуб ", "бук", "дуб", "", "Бук"}}
+	if err := prepareAttribute(&a); err != nil {
+		t.Fatalf("prepareAttribute() error = %v", err)
+	}
+	if want := []string{"Дуб", "бук"}; !reflect.DeepEqual(a.Options, want) {
+		t.Errorf("options = %q, want %q", a.Options, want)
+	}
+}
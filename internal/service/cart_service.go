@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/redis"
+)
+
+// CartOwner определяет владельца корзины: пользователя или гостя по ID корзины
+type CartOwner struct {
+	UserID int
+	CartID string
+}
+
+type CartService struct {
+	cartRepo         *postgres.CartRepo
+	cartStore        *redis.CartStore
+	productRepo      *postgres.ProductRepo
+	orderService     *OrderService
+	promotionService *PromotionService
+}
+
+func NewCartService(cartRepo *postgres.CartRepo, cartStore *redis.CartStore, productRepo *postgres.ProductRepo,
+	orderService *OrderService, promotionService *PromotionService) *CartService {
+	return &CartService{
+		cartRepo:         cartRepo,
+		cartStore:        cartStore,
+		productRepo:      productRepo,
+		orderService:     orderService,
+		promotionService: promotionService,
+	}
+}
+
+// NewCartID генерирует идентификатор гостевой корзины
+func NewCartID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate cart id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ValidCartID проверяет формат идентификатора гостевой корзины
+func ValidCartID(cartID string) bool {
+	if len(cartID) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(cartID)
+	return err == nil
+}
+
+// GetCart возвращает корзину с текущими ценами, скидками и предупреждениями об остатках
+func (s *CartService) GetCart(ctx context.Context, owner CartOwner) (*entity.Cart, error) {
+	items, err := s.items(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := s.buildCart(ctx, owner, items)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.promoCode(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promotionService.ApplyToCart(ctx, cart, code); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+// AddItem добавляет товар в корзину; для товара с вариантами нужно указать variantID
+func (s *CartService) AddItem(ctx context.Context, owner CartOwner, productID, variantID, quantity int) (*entity.Cart, error) {
+	if quantity <= 0 {
+		return nil, errors.ErrInvalidQuantity
+	}
+	if err := s.ensureItemExists(ctx, productID, variantID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if owner.UserID != 0 {
+		err = s.cartRepo.AddItem(ctx, owner.UserID, productID, variantID, quantity)
+	} else {
+		err = s.cartStore.AddItem(ctx, owner.CartID, productID, variantID, quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, owner)
+}
+
+// UpdateItem устанавливает количество товара; количество 0 удаляет позицию
+func (s *CartService) UpdateItem(ctx context.Context, owner CartOwner, productID, variantID, quantity int) (*entity.Cart, error) {
+	if quantity < 0 {
+		return nil, errors.ErrInvalidQuantity
+	}
+	if quantity == 0 {
+		return s.RemoveItem(ctx, owner, productID, variantID)
+	}
+	if err := s.ensureItemExists(ctx, productID, variantID); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if owner.UserID != 0 {
+		err = s.cartRepo.SetItem(ctx, owner.UserID, productID, variantID, quantity)
+	} else {
+		err = s.cartStore.SetItem(ctx, owner.CartID, productID, variantID, quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, owner)
+}
+
+// RemoveItem удаляет товар из корзины
+func (s *CartService) RemoveItem(ctx context.Context, owner CartOwner, productID, variantID int) (*entity.Cart, error) {
+	var err error
+	if owner.UserID != 0 {
+		err = s.cartRepo.RemoveItem(ctx, owner.UserID, productID, variantID)
+	} else {
+		err = s.cartStore.RemoveItem(ctx, owner.CartID, productID, variantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, owner)
+}
+
+// ApplyPromoCode применяет промокод к корзине, заменяя предыдущий. Код проверяется сразу;
+// скидка пересчитывается при каждом просмотре корзины и окончательно — при оформлении заказа.
+func (s *CartService) ApplyPromoCode(ctx context.Context, owner CartOwner, code string) (*entity.Cart, error) {
+	code = entity.NormalizePromoCode(code)
+	if code == "" {
+		return nil, errors.ErrInvalidPromoCode
+	}
+	if err := s.promotionService.CheckPromoCode(ctx, owner.UserID, code); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if owner.UserID != 0 {
+		err = s.cartRepo.SetPromoCode(ctx, owner.UserID, code)
+	} else {
+		err = s.cartStore.SetPromoCode(ctx, owner.CartID, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, owner)
+}
+
+// RemovePromoCode убирает промокод из корзины
+func (s *CartService) RemovePromoCode(ctx context.Context, owner CartOwner) (*entity.Cart, error) {
+	var err error
+	if owner.UserID != 0 {
+		err = s.cartRepo.RemovePromoCode(ctx, owner.UserID)
+	} else if owner.CartID != "" {
+		err = s.cartStore.RemovePromoCode(ctx, owner.CartID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCart(ctx, owner)
+}
+
+// MergeGuestCart переносит гостевую корзину и ее промокод в корзину пользователя и удаляет гостевую из Redis
+func (s *CartService) MergeGuestCart(ctx context.Context, cartID string, userID int) error {
+	if !ValidCartID(cartID) {
+		return errors.ErrInvalidCartID
+	}
+
+	items, err := s.cartStore.Items(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	code, err := s.cartStore.PromoCode(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 && code == "" {
+		return nil
+	}
+
+	if len(items) > 0 {
+		if err := s.cartRepo.Merge(ctx, userID, items); err != nil {
+			return err
+		}
+	}
+	if code != "" {
+		if err := s.cartRepo.SetPromoCode(ctx, userID, code); err != nil {
+			return err
+		}
+	}
+
+	return s.cartStore.Delete(ctx, cartID)
+}
+
+// Checkout превращает корзину пользователя в заказ с выбранным способом получения и промокодом корзины
+// и очищает ее в транзакции заказа
+func (s *CartService) Checkout(ctx context.Context, userID int, delivery entity.Delivery) (*entity.Order, error) {
+	items, err := s.cartRepo.Items(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.ErrCartEmpty
+	}
+	code, err := s.cartRepo.PromoCode(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	orderItems := make([]entity.OrderItem, 0, len(items))
+	for _, item := range items {
+		orderItems = append(orderItems, entity.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return s.orderService.CreateOrderFromCart(ctx, userID, orderItems, delivery, code)
+}
+
+func (s *CartService) promoCode(ctx context.Context, owner CartOwner) (string, error) {
+	if owner.UserID != 0 {
+		return s.cartRepo.PromoCode(ctx, owner.UserID)
+	}
+	if owner.CartID == "" {
+		return "", nil
+	}
+	return s.cartStore.PromoCode(ctx, owner.CartID)
+}
+
+func (s *CartService) items(ctx context.Context, owner CartOwner) ([]entity.CartItem, error) {
+	if owner.UserID != 0 {
+		return s.cartRepo.Items(ctx, owner.UserID)
+	}
+	if owner.CartID == "" {
+		return []entity.CartItem{}, nil
+	}
+	return s.cartStore.Items(ctx, owner.CartID)
+}
+
+// ensureItemExists проверяет, что товар существует, а вариант указан (если они есть) и принадлежит товару
+func (s *CartService) ensureItemExists(ctx context.Context, productID, variantID int) error {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return errors.ErrProductNotFound
+	}
+
+	if variantID != 0 {
+		if product.Variant(variantID) == nil {
+			return errors.ErrVariantNotFound
+		}
+		return nil
+	}
+	if len(product.Variants) > 0 {
+		return errors.ErrVariantRequired
+	}
+	return nil
+}
+
+// buildCart подставляет в позиции актуальные цены и остатки из каталога; скидки считаются отдельно
+func (s *CartService) buildCart(ctx context.Context, owner CartOwner, items []entity.CartItem) (*entity.Cart, error) {
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products, err := s.productRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &entity.Cart{
+		CartID: owner.CartID,
+		UserID: owner.UserID,
+		Items:  make([]entity.CartItem, 0, len(items)),
+	}
+
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok {
+			item.Warning = "Товар больше не продается"
+			cart.Items = append(cart.Items, item)
+			continue
+		}
+
+		item.Name = product.Name
+		item.CategoryID = product.CategoryID
+		item.Price = product.Price
+		item.ImageURL = product.ImageURL
+		item.Stock = product.Available
+
+		if item.VariantID != 0 {
+			variant := product.Variant(item.VariantID)
+			if variant == nil {
+				item.Warning = "Вариант товара больше не продается"
+				cart.Items = append(cart.Items, item)
+				continue
+			}
+			item.SKU = variant.SKU
+			item.Options = variant.Options
+			item.Price = variant.EffectivePrice(product.Price)
+			item.Stock = variant.Available
+			if len(variant.Images) > 0 {
+				item.ImageURL = variant.Images[0]
+			}
+		} else if len(product.Variants) > 0 {
+			item.Warning = "Выберите вариант товара"
+			cart.Items = append(cart.Items, item)
+			continue
+		}
+
+		item.Subtotal = item.Price * float64(item.Quantity)
+
+		switch {
+		case item.Stock == 0:
+			item.Warning = "Нет в наличии"
+		case item.Quantity > item.Stock:
+			item.Warning = fmt.Sprintf("Доступно только %d шт.", item.Stock)
+		}
+
+		cart.Items = append(cart.Items, item)
+		cart.ItemsCount += item.Quantity
+		cart.Subtotal += item.Subtotal
+	}
+
+	cart.Subtotal = math.Round(cart.Subtotal*100) / 100
+	return cart, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidCartID(t *testing.T) {
+	tests := []struct {
+		name   string
+		cartID string
+		want   bool
+	}{
+		{"lowercase hex", "0123456789abcdef0123456789abcdef", true},
+		{"uppercase hex", "0123456789ABCDEF0123456789ABCDEF", true},
+		{"empty", "", false},
+		{"too short", "0123456789abcdef", false},
+		{"too long", strings.Repeat("a", 33), false},
+		{"not hex", "0123456789abcdef0123456789abcdeg", false},
+		{"path traversal", "../../../../../../../../cart:1:ab", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidCartID(tt.cartID); got != tt.want {
+				t.Errorf("ValidCartID(%q) = %v, want %v", tt.cartID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCartIDIsValid(t *testing.T) {
+	id, err := NewCartID()
+	if err != nil {
+		t.Fatalf("NewCartID() error = %v", err)
+	}
+	if !ValidCartID(id) {
+		t.Errorf("ValidCartID(NewCartID()) = false for %q", id)
+	}
+
+	other, err := NewCartID()
+	if err != nil {
+		t.Fatalf("NewCartID() error = %v", err)
+	}
+	if id == other {
+		t.Errorf("NewCartID() returned %q twice", id)
+	}
+}
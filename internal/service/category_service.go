@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+type CategoryService struct {
+	categoryRepo *postgres.CategoryRepo
+}
+
+func NewCategoryService(categoryRepo *postgres.CategoryRepo) *CategoryService {
+	return &CategoryService{categoryRepo: categoryRepo}
+}
+
+// Tree возвращает дерево категорий; количество товаров включает подкатегории
+func (s *CategoryService) Tree(ctx context.Context) ([]*entity.Category, error) {
+	categories, err := s.categoryRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.categoryRepo.ProductCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCategoryTree(categories, counts), nil
+}
+
+// GetCategory возвращает категорию с подкатегориями
+func (s *CategoryService) GetCategory(ctx context.Context, id int) (*entity.Category, error) {
+	tree, err := s.Tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if category := findCategory(tree, id); category != nil {
+		return category, nil
+	}
+	return nil, errors.ErrCategoryNotFound
+}
+
+// CreateCategory создает категорию; slug по умолчанию строится из названия
+func (s *CategoryService) CreateCategory(ctx context.Context, category *entity.Category) error {
+	if err := s.prepare(ctx, category); err != nil {
+		return err
+	}
+	return s.categoryRepo.Create(ctx, category)
+}
+
+// UpdateCategory обновляет категорию и возвращает ее вместе с подкатегориями.
+// Категорию нельзя перенести внутрь ее же поддерева.
+func (s *CategoryService) UpdateCategory(ctx context.Context, category *entity.Category) (*entity.Category, error) {
+	if err := s.prepare(ctx, category); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		return nil, err
+	}
+	return s.GetCategory(ctx, category.ID)
+}
+
+// DeleteCategory удаляет пустую категорию
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int) error {
+	return s.categoryRepo.Delete(ctx, id)
+}
+
+// prepare нормализует поля категории и проверяет родителя
+func (s *CategoryService) prepare(ctx context.Context, category *entity.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" || utf8.RuneCountInString(category.Name) > 100 {
+		return errors.ErrInvalidCategory
+	}
+
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	} else {
+		category.Slug = slugify(category.Slug)
+	}
+
+	if category.ParentID == nil {
+		return nil
+	}
+
+	categories, err := s.categoryRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[int]*int, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+	if _, ok := parents[*category.ParentID]; !ok {
+		return errors.ErrCategoryNotFound
+	}
+
+	// Поднимаемся от нового родителя к корню: встретили саму категорию — получился бы цикл
+	for id := category.ParentID; id != nil; id = parents[*id] {
+		if category.ID != 0 && *id == category.ID {
+			return errors.ErrInvalidCategory
+		}
+	}
+	return nil
+}
+
+// buildCategoryTree собирает дерево из плоского списка, сохраняя порядок сортировки
+func buildCategoryTree(categories []*entity.Category, counts map[int]int) []*entity.Category {
+	byID := make(map[int]*entity.Category, len(categories))
+	for _, c := range categories {
+		c.Children = nil
+		byID[c.ID] = c
+	}
+
+	roots := make([]*entity.Category, 0)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+
+	var count func(c *entity.Category) int
+	count = func(c *entity.Category) int {
+		c.ProductCount = counts[c.ID]
+		for _, child := range c.Children {
+			c.ProductCount += count(child)
+		}
+		return c.ProductCount
+	}
+	for _, root := range roots {
+		count(root)
+	}
+
+	return roots
+}
+
+func findCategory(categories []*entity.Category, id int) *entity.Category {
+	for _, c := range categories {
+		if c.ID == id {
+			return c
+		}
+		if found := findCategory(c.Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+var slugTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// slugify строит URL-slug с транслитерацией кириллицы (та же схема, что в миграции категорий)
+func slugify(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		if translit, ok := slugTranslit[r]; ok {
+			b.WriteString(translit)
+			dash = false
+			continue
+		}
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "category"
+	}
+	if len(slug) > 120 {
+		slug = strings.TrimSuffix(slug[:120], "-")
+	}
+	return slug
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Гостиная", "gostinaya"},
+		{"Шкафы и стеллажи", "shkafy-i-stellazhi"},
+		{"Ёлочные игрушки", "elochnye-igrushki"},
+		{"Объект", "obekt"},
+		{"  Office Chairs 2.0  ", "office-chairs-2-0"},
+		{"---", "category"},
+		{"", "category"},
+		{strings.Repeat("ab ", 60), strings.TrimSuffix(strings.Repeat("ab-", 40), "-")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := slugify(tt.value); got != tt.want {
+				t.Errorf("slugify(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	id := func(v int) *int { return &v }
+	categories := []*entity.Category{
+		{ID: 1, Name: "Гостиная"},
+		{ID: 2, ParentID: id(1), Name: "Диваны"},
+		{ID: 3, ParentID: id(2), Name: "Угловые"},
+		{ID: 4, Name: "Кухня"},
+		{ID: 5, ParentID: id(1), Name: "Кресла"},
+		// Родитель не найден — категория показывается в корне, а не теряется
+		{ID: 6, ParentID: id(99), Name: "Сирота"},
+	}
+	counts := map[int]int{1: 1, 2: 2, 3: 3, 4: 4, 6: 5}
+
+	roots := buildCategoryTree(categories, counts)
+
+	if len(roots) != 3 || roots[0].ID != 1 || roots[1].ID != 4 || roots[2].ID != 6 {
+		t.Fatalf("roots = %v, want [1 4 6]", categoryIDs(roots))
+	}
+	if got := categoryIDs(roots[0].Children); len(got) != 2 || got[0] != 2 || got[1] != 5 {
+		t.Errorf("children of 1 = %v, want [2 5]", got)
+	}
+
+	wantCounts := map[int]int{1: 6, 2: 5, 3: 3, 4: 4, 5: 0, 6: 5}
+	for id, want := range wantCounts {
+		if c := findCategory(roots, id); c == nil || c.ProductCount != want {
+			t.Errorf("category %d product count = %v, want %d", id, c, want)
+		}
+	}
+}
+
+func categoryIDs(categories []*entity.Category) []int {
+	ids := make([]int, 0, len(categories))
+	for _, c := range categories {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/spreadsheet"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// ExportFormatJSON — выгрузка массивом продуктов в формате API
+const ExportFormatJSON = "json"
+
+// exportContentTypes — поддерживаемые форматы выгрузки
+var exportContentTypes = map[string]string{
+	string(spreadsheet.FormatCSV):  "text/csv; charset=utf-8",
+	string(spreadsheet.FormatXLSX): "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportFormatJSON:               "application/json",
+}
+
+// exportImagesSeparator разделяет URL галереи в столбце images
+const exportImagesSeparator = "|"
+
+// orderExportColumns — столбцы выгрузки заказов для логистики, по строке на позицию заказа
+var orderExportColumns = []interface{}{
+	"order_id", "created_at", "status", "customer_email", "customer_name", "delivery_method", "pickup_warehouse_id",
+	"order_subtotal", "order_discount", "order_total", "promo_code",
+	"product_id", "variant_id", "sku", "product_name", "quantity", "price", "discount",
+}
+
+type ExportService struct {
+	productRepo   *postgres.ProductRepo
+	attributeRepo *postgres.AttributeRepo
+	orderRepo     *postgres.OrderRepo
+}
+
+func NewExportService(productRepo *postgres.ProductRepo, attributeRepo *postgres.AttributeRepo, orderRepo *postgres.OrderRepo) *ExportService {
+	return &ExportService{
+		productRepo:   productRepo,
+		attributeRepo: attributeRepo,
+		orderRepo:     orderRepo,
+	}
+}
+
+// ExportContentType возвращает Content-Type формата выгрузки; false — формат не поддерживается
+func ExportContentType(format string) (string, bool) {
+	contentType, ok := exportContentTypes[format]
+	return contentType, ok
+}
+
+// ExportProducts пишет в w продукты по фильтру. CSV и XLSX содержат столбцы импорта
+// (sku, external_id, ..., attr.<code>), поэтому файл можно отредактировать и загрузить обратно.
+func (s *ExportService) ExportProducts(ctx context.Context, w io.Writer, format string, filter entity.ProductFilter) error {
+	if format == ExportFormatJSON {
+		return s.exportJSON(ctx, w, filter)
+	}
+
+	attributes, err := s.attributeRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	codes := make([]string, 0, len(attributes))
+	for _, a := range attributes {
+		codes = append(codes, a.Code)
+	}
+	sort.Strings(codes)
+
+	writer, err := spreadsheet.NewWriter(w, spreadsheet.Format(format))
+	if err != nil {
+		return err
+	}
+
+	if err := writer.WriteRow(exportHeader(codes)...); err != nil {
+		return err
+	}
+
+	err = s.productRepo.Export(ctx, filter, func(p *entity.Product) error {
+		return writer.WriteRow(exportRow(p, codes)...)
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportHeader возвращает заголовок выгрузки; порядок совпадает с exportRow
+func exportHeader(codes []string) []interface{} {
+	header := []interface{}{"id"}
+	for _, column := range catalogColumns {
+		header = append(header, column)
+	}
+	header = append(header, "image_url", "images", "created_at", "updated_at")
+	for _, code := range codes {
+		header = append(header, importAttrPrefix+code)
+	}
+	return header
+}
+
+// exportRow раскладывает продукт по столбцам в порядке заголовка выгрузки
+func exportRow(p *entity.Product, codes []string) []interface{} {
+	images := make([]string, 0, len(p.Images))
+	for _, img := range p.Images {
+		images = append(images, img.URL)
+	}
+
+	row := []interface{}{
+		p.ID, p.SKU, p.ExternalID, p.Name, p.Description, p.Price, p.Stock, p.CategoryID, p.Category,
+		p.ImageURL, strings.Join(images, exportImagesSeparator),
+		p.CreatedAt.Format(time.RFC3339), p.UpdatedAt.Format(time.RFC3339),
+	}
+
+	values := make(map[string]interface{}, len(p.Attributes))
+	for _, a := range p.Attributes {
+		if a.Number != nil {
+			values[a.Code] = *a.Number
+		} else {
+			values[a.Code] = a.Value
+		}
+	}
+	for _, code := range codes {
+		row = append(row, values[code])
+	}
+	return row
+}
+
+// ExportOrders пишет в w заказы по фильтру в CSV или XLSX, по строке на позицию заказа
+func (s *ExportService) ExportOrders(ctx context.Context, w io.Writer, format string, filter entity.OrderFilter) error {
+	writer, err := spreadsheet.NewWriter(w, spreadsheet.Format(format))
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteRow(orderExportColumns...); err != nil {
+		return err
+	}
+
+	err = s.orderRepo.Export(ctx, filter, func(o *entity.AdminOrder) error {
+		order := []interface{}{
+			o.ID, o.CreatedAt.Format(time.RFC3339), o.Status, o.CustomerEmail, o.CustomerName, o.Method,
+			o.PickupWarehouseID, o.Subtotal, o.DiscountTotal, o.Total, o.PromoCode,
+		}
+		if len(o.Items) == 0 {
+			return writer.WriteRow(order...)
+		}
+		for _, item := range o.Items {
+			row := append(order[:len(order):len(order)], item.ProductID, item.VariantID, item.SKU, item.ProductName, item.Quantity, item.Price,
+				item.Discount)
+			if err := writer.WriteRow(row...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportJSON пишет JSON-массив по одному продукту за раз
+func (s *ExportService) exportJSON(ctx context.Context, w io.Writer, filter entity.ProductFilter) error {
+	out := bufio.NewWriter(w)
+	if _, err := out.WriteString("["); err != nil {
+		return err
+	}
+
+	first := true
+	err := s.productRepo.Export(ctx, filter, func(p *entity.Product) error {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("encode product %d: %w", p.ID, err)
+		}
+		if !first {
+			out.WriteString(",")
+		}
+		first = false
+		out.WriteString("\n")
+		_, err = out.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestExportRow(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	product := &entity.Product{
+		ID: 5, SKU: "00123", ExternalID: "1C-5", Name: "Стул", Description: "Дуб",
+		Price: 4990.5, Stock: 7, CategoryID: 2, Category: "Стулья", ImageURL: "/img/1.jpg",
+		CreatedAt: created, UpdatedAt: created.Add(time.Hour),
+		Images: []entity.ProductImage{{URL: "/img/1.jpg"}, {URL: "/img/2.jpg"}},
+		Attributes: []entity.ProductAttribute{
+			{Code: "width", Number: ptr(45.0)},
+			{Code: "material", Value: "дуб"},
+		},
+	}
+	codes := []string{"color", "material", "width"}
+
+	header := exportHeader(codes)
+	row := exportRow(product, codes)
+	if len(row) != len(header) {
+		t.Fatalf("len(row) = %d, want %d", len(row), len(header))
+	}
+
+	got := make(map[string]interface{}, len(header))
+	for i, column := range header {
+		got[column.(string)] = row[i]
+	}
+	want := map[string]interface{}{
+		"id": 5, "sku": "00123", "external_id": "1C-5", "name": "Стул", "description": "Дуб",
+		"price": 4990.5, "stock": 7, "category_id": 2, "category": "Стулья",
+		"image_url": "/img/1.jpg", "images": "/img/1.jpg|/img/2.jpg",
+		"created_at": "2024-03-01T10:00:00Z", "updated_at": "2024-03-01T11:00:00Z",
+		"attr.color": nil, "attr.material": "дуб", "attr.width": 45.0,
+	}
+	for column, value := range want {
+		if got[column] != value {
+			t.Errorf("%s = %v, want %v", column, got[column], value)
+		}
+	}
+}
+
+func TestExportHeaderImportable(t *testing.T) {
+	codes := []string{"material", "width"}
+	header := exportHeader(codes)
+
+	rows := [][]string{make([]string, len(header)), make([]string, len(header))}
+	for i, column := range header {
+		rows[0][i] = column.(string)
+		if column == "sku" {
+			rows[1][i] = "A-1"
+		}
+	}
+
+	// Выгруженный файл загружается обратно: служебные столбцы пропускаются
+	sheet, err := parseImportSheet(rows)
+	if err != nil {
+		t.Fatalf("parseImportSheet() error = %v", err)
+	}
+	for _, column := range catalogReadOnlyColumns {
+		if _, ok := sheet.columns[column]; ok {
+			t.Errorf("read-only column %q is imported", column)
+		}
+	}
+	if len(sheet.attrs) != len(codes) {
+		t.Errorf("attrs = %v, want %v", sheet.attrs, codes)
+	}
+	if got := sheet.cell(sheet.rows[0], "sku"); got != "A-1" {
+		t.Errorf("cell(sku) = %q, want A-1", got)
+	}
+}
+
+func TestExportContentType(t *testing.T) {
+	for _, format := range []string{"csv", "xlsx", "json"} {
+		if _, ok := ExportContentType(format); !ok {
+			t.Errorf("ExportContentType(%q) not supported", format)
+		}
+	}
+	if _, ok := ExportContentType("pdf"); ok {
+		t.Error("ExportContentType(pdf) supported, want unsupported")
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/redis"
+)
+
+// unreachableAddr — адрес, на котором никто не слушает: кэш и Kafka в интеграционных тестах
+// недоступны, а их ошибки сервисы только логируют
+const unreachableAddr = "127.0.0.1:1"
+
+// testServices — сервисы поверх тестовой базы
+type testServices struct {
+	db       *sql.DB
+	products *ProductService
+	orders   *OrderService
+	producer *kafka.Producer
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+	db := pgtest.Open(t)
+
+	productRepo := postgres.NewProductRepo(db)
+	producer := kafka.NewProducer([]string{unreachableAddr}, "test")
+	stockAlerts := NewStockAlertService(postgres.NewStockAlertRepo(db), productRepo, producer, 0)
+	products := NewProductService(productRepo, postgres.NewCategoryRepo(db), postgres.NewProductImageRepo(db),
+		postgres.NewAttributeRepo(db), nil, redis.NewCache(unreachableAddr, time.Minute), stockAlerts, nil)
+
+	return &testServices{
+		db:       db,
+		products: products,
+		orders:   NewOrderService(postgres.NewOrderRepo(db), products, producer, time.Hour),
+		producer: producer,
+	}
+}
+
+// createTestUser создает покупателя
+func (s *testServices) createTestUser(t *testing.T) *entity.User {
+	t.Helper()
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	user := &entity.User{Email: fmt.Sprintf("user%d@example.com", count+1), Password: "hash", Name: "Test", Role: entity.RoleCustomer}
+	if err := postgres.NewUserRepo(s.db).Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createTestProduct создает товар с остатком на основном складе в отдельной категории
+func (s *testServices) createTestProduct(t *testing.T, name string, price float64, stock int) *entity.Product {
+	t.Helper()
+	category := &entity.Category{Name: "category-" + name, Slug: "category-" + name}
+	if err := postgres.NewCategoryRepo(s.db).Create(context.Background(), category); err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	product := &entity.Product{Name: name, Price: price, Category: category.Name, CategoryID: category.ID, Stock: stock}
+	if err := postgres.NewProductRepo(s.db).Create(context.Background(), product, 0); err != nil {
+		t.Fatalf("create product %q: %v", name, err)
+	}
+	return product
+}
+
+// createTestOrder оформляет заказ с доставкой
+func (s *testServices) createTestOrder(t *testing.T, userID int, items ...entity.OrderItem) *entity.Order {
+	t.Helper()
+	order, err := s.orders.CreateOrder(context.Background(), userID, items, entity.Delivery{}, "")
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	return order
+}
@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/spreadsheet"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+const (
+	// maxImportRows — сколько строк данных принимается в одном файле
+	maxImportRows = 20000
+	// importProgressEvery — как часто сохраняется прогресс задачи
+	importProgressEvery = 100
+	// importAttrPrefix — префикс столбцов с характеристиками: attr.width, attr.frame_material
+	importAttrPrefix = "attr."
+)
+
+var (
+	// catalogColumns — столбцы файла каталога, общие для импорта и выгрузки; порядок в файле произвольный
+	catalogColumns = []string{"sku", "external_id", "name", "description", "price", "stock", "category_id", "category"}
+	// catalogReadOnlyColumns — столбцы выгрузки, которые импорт пропускает
+	catalogReadOnlyColumns = []string{"id", "image_url", "images", "created_at", "updated_at"}
+)
+
+type ImportService struct {
+	importRepo     *postgres.ImportJobRepo
+	productRepo    *postgres.ProductRepo
+	categoryRepo   *postgres.CategoryRepo
+	productService *ProductService
+}
+
+func NewImportService(importRepo *postgres.ImportJobRepo, productRepo *postgres.ProductRepo, categoryRepo *postgres.CategoryRepo,
+	productService *ProductService) *ImportService {
+	return &ImportService{
+		importRepo:     importRepo,
+		productRepo:    productRepo,
+		categoryRepo:   categoryRepo,
+		productService: productService,
+	}
+}
+
+// importSheet — разобранный файл: индексы столбцов по именам и строки данных с номерами строк файла
+type importSheet struct {
+	columns map[string]int
+	attrs   map[string]int
+	rows    [][]string
+	numbers []int
+}
+
+// cell возвращает значение столбца строки без пробелов по краям; отсутствующий столбец — пустая строка
+func (s *importSheet) cell(row []string, column string) string {
+	idx, ok := s.columns[column]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// StartImport разбирает файл и запускает задачу импорта в фоне. Ошибки формата файла
+// возвращаются сразу; ошибки отдельных строк попадают в отчет задачи.
+func (s *ImportService) StartImport(ctx context.Context, userID int, filename string, data []byte, dryRun bool) (*entity.ImportJob, error) {
+	format, ok := spreadsheet.FormatFromFilename(filename)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected .csv or .xlsx file", errors.ErrInvalidImportFile)
+	}
+
+	rows, err := spreadsheet.Read(data, format)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := parseImportSheet(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &entity.ImportJob{
+		Format:    string(format),
+		Filename:  filename,
+		DryRun:    dryRun,
+		TotalRows: len(sheet.rows),
+		CreatedBy: userID,
+	}
+	if err := s.importRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Задача живет дольше HTTP-запроса, поэтому контекст запроса ей не передается
+	go s.run(context.Background(), job, sheet)
+
+	return job, nil
+}
+
+// GetJob возвращает задачу импорта с построчным отчетом
+func (s *ImportService) GetJob(ctx context.Context, id int) (*entity.ImportJob, error) {
+	job, err := s.importRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, errors.ErrImportJobNotFound
+	}
+	return job, nil
+}
+
+// ListJobs возвращает последние задачи импорта
+func (s *ImportService) ListJobs(ctx context.Context, limit int) ([]*entity.ImportJob, error) {
+	return s.importRepo.List(ctx, limit)
+}
+
+// FailInterrupted помечает задачи, которые не успели завершиться до остановки сервера.
+// Задачи выполняются в памяти процесса, поэтому после перезапуска продолжить их нельзя.
+func (s *ImportService) FailInterrupted(ctx context.Context) error {
+	return s.importRepo.FailUnfinished(ctx, "import interrupted by server restart")
+}
+
+// parseImportSheet проверяет заголовок и отбрасывает пустые строки
+func parseImportSheet(rows [][]string) (*importSheet, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", errors.ErrInvalidImportFile)
+	}
+
+	sheet := &importSheet{columns: make(map[string]int), attrs: make(map[string]int)}
+	for idx, raw := range rows[0] {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" {
+			continue
+		}
+
+		if code, ok := strings.CutPrefix(name, importAttrPrefix); ok {
+			if !attributeCodePattern.MatchString(code) {
+				return nil, fmt.Errorf("%w: bad attribute column %q", errors.ErrInvalidImportFile, raw)
+			}
+			if _, dup := sheet.attrs[code]; dup {
+				return nil, fmt.Errorf("%w: duplicate column %q", errors.ErrInvalidImportFile, raw)
+			}
+			sheet.attrs[code] = idx
+			continue
+		}
+
+		if slices.Contains(catalogReadOnlyColumns, name) {
+			continue
+		}
+		if !slices.Contains(catalogColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", errors.ErrInvalidImportFile, raw)
+		}
+		if _, dup := sheet.columns[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate column %q", errors.ErrInvalidImportFile, raw)
+		}
+		sheet.columns[name] = idx
+	}
+
+	_, hasSKU := sheet.columns["sku"]
+	_, hasExternalID := sheet.columns["external_id"]
+	if !hasSKU && !hasExternalID {
+		return nil, fmt.Errorf("%w: sku or external_id column is required", errors.ErrInvalidImportFile)
+	}
+
+	for i, row := range rows[1:] {
+		if isBlankRow(row) {
+			continue
+		}
+		sheet.rows = append(sheet.rows, row)
+		sheet.numbers = append(sheet.numbers, i+2)
+	}
+	if len(sheet.rows) == 0 {
+		return nil, fmt.Errorf("%w: no data rows", errors.ErrInvalidImportFile)
+	}
+	if len(sheet.rows) > maxImportRows {
+		return nil, fmt.Errorf("%w: more than %d rows", errors.ErrInvalidImportFile, maxImportRows)
+	}
+	return sheet, nil
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// run обрабатывает строки задачи по очереди и сохраняет отчет
+func (s *ImportService) run(ctx context.Context, job *entity.ImportJob, sheet *importSheet) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Import job %d panicked: %v", job.ID, r)
+			s.finish(ctx, job, entity.ImportJobFailed, fmt.Sprintf("internal error: %v", r))
+		}
+	}()
+
+	if err := s.importRepo.Start(ctx, job.ID); err != nil {
+		log.Printf("Import job %d: %v", job.ID, err)
+	}
+
+	categories, err := s.categoryIndex(ctx)
+	if err != nil {
+		s.finish(ctx, job, entity.ImportJobFailed, err.Error())
+		return
+	}
+
+	job.Rows = make([]entity.ImportRow, 0, len(sheet.rows))
+	seen := make(map[string]int, len(sheet.rows))
+	for i, row := range sheet.rows {
+		result := s.importRow(ctx, sheet, row, sheet.numbers[i], categories, seen, job.DryRun, job.CreatedBy)
+		job.Rows = append(job.Rows, result)
+
+		job.ProcessedRows++
+		switch result.Action {
+		case entity.ImportRowCreate:
+			job.Created++
+		case entity.ImportRowUpdate:
+			job.Updated++
+		default:
+			job.Failed++
+		}
+
+		if job.ProcessedRows%importProgressEvery == 0 {
+			if err := s.importRepo.Progress(ctx, job); err != nil {
+				log.Printf("Import job %d: %v", job.ID, err)
+			}
+		}
+	}
+
+	s.finish(ctx, job, entity.ImportJobCompleted, "")
+}
+
+func (s *ImportService) finish(ctx context.Context, job *entity.ImportJob, status entity.ImportJobStatus, reason string) {
+	job.Status = status
+	job.Error = reason
+	if err := s.importRepo.Finish(ctx, job); err != nil {
+		log.Printf("Import job %d: %v", job.ID, err)
+	}
+}
+
+// categoryIndex строит поиск категории по ID, slug и названию без учета регистра
+func (s *ImportService) categoryIndex(ctx context.Context) (map[string]int, error) {
+	categories, err := s.categoryRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(categories)*2)
+	for _, c := range categories {
+		index[strings.ToLower(c.Name)] = c.ID
+	}
+	// slug приоритетнее названия, если они совпали у разных категорий
+	for _, c := range categories {
+		index[c.Slug] = c.ID
+	}
+	return index, nil
+}
+
+// importRow создает или обновляет товар по строке файла. Товар ищется по external_id,
+// а если он не задан — по sku. В dry-run строка только проверяется.
+// Изменения остатков пишутся в журнал от имени actorID — автора импорта.
+func (s *ImportService) importRow(ctx context.Context, sheet *importSheet, row []string, number int,
+	categories map[string]int, seen map[string]int, dryRun bool, actorID int) entity.ImportRow {
+	result := entity.ImportRow{
+		Row:        number,
+		SKU:        sheet.cell(row, "sku"),
+		ExternalID: sheet.cell(row, "external_id"),
+	}
+	fail := func(messages ...string) entity.ImportRow {
+		result.Action = entity.ImportRowError
+		result.Errors = append(result.Errors, messages...)
+		return result
+	}
+
+	key := "sku:" + result.SKU
+	if result.ExternalID != "" {
+		key = "external_id:" + result.ExternalID
+	}
+	if result.SKU == "" && result.ExternalID == "" {
+		return fail("sku or external_id is required")
+	}
+	if first, dup := seen[key]; dup {
+		return fail(fmt.Sprintf("duplicate of row %d", first))
+	}
+	seen[key] = number
+
+	productID, err := s.productRepo.GetByKey(ctx, result.SKU, result.ExternalID)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	product := &entity.Product{SKU: result.SKU, ExternalID: result.ExternalID}
+	var existing *entity.Product
+	if productID != 0 {
+		if existing, err = s.productRepo.GetByID(ctx, productID); err != nil {
+			return fail(err.Error())
+		}
+	}
+	if existing != nil {
+		updated := *existing
+		product = &updated
+		product.Variants, product.Images = nil, nil
+		if result.SKU != "" {
+			product.SKU = result.SKU
+		}
+		if result.ExternalID != "" {
+			product.ExternalID = result.ExternalID
+		}
+		result.ProductID = product.ID
+	}
+
+	if errs := applyImportCells(sheet, row, product, existing, categories); len(errs) > 0 {
+		return fail(errs...)
+	}
+
+	if existing == nil {
+		result.Action = entity.ImportRowCreate
+	} else {
+		result.Action = entity.ImportRowUpdate
+	}
+
+	if dryRun {
+		if err := s.checkProduct(ctx, product, existing); err != nil {
+			return fail(err.Error())
+		}
+		return result
+	}
+
+	if existing == nil {
+		err = s.productService.CreateProduct(ctx, product, nil, nil, actorID)
+	} else {
+		err = s.productService.UpdateProduct(ctx, product, nil, nil)
+	}
+	if err != nil {
+		return fail(err.Error())
+	}
+	result.ProductID = product.ID
+	return result
+}
+
+// applyImportCells переносит непустые ячейки строки в товар и возвращает ошибки значений.
+// У существующего товара пустая ячейка оставляет поле без изменений.
+func applyImportCells(sheet *importSheet, row []string, product, existing *entity.Product, categories map[string]int) []string {
+	var errs []string
+
+	if v := sheet.cell(row, "name"); v != "" {
+		product.Name = v
+	} else if existing == nil {
+		errs = append(errs, "name is required")
+	}
+	if v := sheet.cell(row, "description"); v != "" {
+		product.Description = v
+	}
+
+	if v := sheet.cell(row, "price"); v != "" {
+		price, err := parseDecimal(v)
+		if err != nil || price < 0 {
+			errs = append(errs, fmt.Sprintf("price: %q is not a non-negative number", v))
+		}
+		product.Price = price
+	} else if existing == nil {
+		errs = append(errs, "price is required")
+	}
+
+	// Остаток существующего товара меняется только движениями остатков, поэтому столбец stock задает
+	// лишь начальный остаток нового товара (в выгрузке он есть у всех строк)
+	if v := sheet.cell(row, "stock"); v != "" && existing == nil {
+		stock, err := strconv.Atoi(v)
+		if err != nil || stock < 0 {
+			errs = append(errs, fmt.Sprintf("stock: %q is not a non-negative integer", v))
+		}
+		product.Stock = stock
+	}
+
+	category := sheet.cell(row, "category_id")
+	if category == "" {
+		category = strings.ToLower(sheet.cell(row, "category"))
+	}
+	if category != "" {
+		if id, ok := categories[category]; ok {
+			product.CategoryID = id
+		} else if id, err := strconv.Atoi(category); err == nil {
+			// неизвестный ID проверит resolveCategory
+			product.CategoryID = id
+		} else {
+			errs = append(errs, fmt.Sprintf("category %q not found", category))
+		}
+	} else if existing == nil {
+		errs = append(errs, "category or category_id is required")
+	}
+
+	product.Attributes = importAttributes(sheet, row, existing)
+	return errs
+}
+
+// importAttributes объединяет сохраненные характеристики товара с непустыми столбцами attr.<code>.
+// nil означает, что строка характеристик не меняет.
+func importAttributes(sheet *importSheet, row []string, existing *entity.Product) []entity.ProductAttribute {
+	values := make(map[string]string)
+	for code, idx := range sheet.attrs {
+		if idx < len(row) {
+			if v := strings.TrimSpace(row[idx]); v != "" {
+				values[code] = v
+			}
+		}
+	}
+	if len(values) == 0 && existing != nil {
+		return nil
+	}
+
+	attributes := make([]entity.ProductAttribute, 0, len(values))
+	if existing != nil {
+		for _, a := range existing.Attributes {
+			if _, ok := values[a.Code]; !ok {
+				attributes = append(attributes, entity.ProductAttribute{Code: a.Code, Number: a.Number, Value: a.Value})
+			}
+		}
+	}
+	for code, v := range values {
+		attributes = append(attributes, entity.ProductAttribute{Code: code, Value: v})
+	}
+	return attributes
+}
+
+// checkProduct выполняет проверки CreateProduct/UpdateProduct без записи (dry-run)
+func (s *ImportService) checkProduct(ctx context.Context, product, existing *entity.Product) error {
+	if err := s.productService.resolveCategory(ctx, product); err != nil {
+		return err
+	}
+
+	if product.Attributes == nil && existing != nil && product.CategoryID != existing.CategoryID {
+		product.Attributes = existing.Attributes
+	}
+	if product.Attributes == nil {
+		return nil
+	}
+	return s.productService.resolveAttributes(ctx, product)
+}
+
+// parseDecimal разбирает число с точкой или запятой в качестве десятичного разделителя
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.ReplaceAll(s, " ", ""), ",", ".", 1), 64)
+}
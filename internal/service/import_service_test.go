@@ -0,0 +1,187 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestParseImportSheet(t *testing.T) {
+	sheet, err := parseImportSheet([][]string{
+		{" SKU ", "Name", "", "attr.width"},
+		{"A-1", "Стул", "", "45"},
+		{"", " ", ""},
+		nil,
+		{"A-2", "Стол"},
+	})
+	if err != nil {
+		t.Fatalf("parseImportSheet() error = %v", err)
+	}
+
+	if want := map[string]int{"sku": 0, "name": 1}; !reflect.DeepEqual(sheet.columns, want) {
+		t.Errorf("columns = %v, want %v", sheet.columns, want)
+	}
+	if want := map[string]int{"width": 3}; !reflect.DeepEqual(sheet.attrs, want) {
+		t.Errorf("attrs = %v, want %v", sheet.attrs, want)
+	}
+	// Номера строк совпадают с номерами в файле, пустые строки пропущены
+	if want := []int{2, 5}; !reflect.DeepEqual(sheet.numbers, want) {
+		t.Errorf("numbers = %v, want %v", sheet.numbers, want)
+	}
+	if got := sheet.cell(sheet.rows[1], "name"); got != "Стол" {
+		t.Errorf("cell(name) = %q, want Стол", got)
+	}
+	if got := sheet.cell(sheet.rows[1], "price"); got != "" {
+		t.Errorf("cell(missing column) = %q, want empty", got)
+	}
+}
+
+func TestParseImportSheetInvalid(t *testing.T) {
+	tooMany := [][]string{{"sku"}}
+	for i := 0; i <= maxImportRows; i++ {
+		tooMany = append(tooMany, []string{"A"})
+	}
+
+	tests := []struct {
+		name string
+		rows [][]string
+	}{
+		{name: "empty file", rows: nil},
+		{name: "unknown column", rows: [][]string{{"sku", "colour"}, {"A-1", "red"}}},
+		{name: "duplicate column", rows: [][]string{{"sku", "Name", "name"}, {"A-1", "a", "b"}}},
+		{name: "bad attribute column", rows: [][]string{{"sku", "attr.frame-material"}, {"A-1", "1"}}},
+		{name: "duplicate attribute column", rows: [][]string{{"sku", "attr.width", "ATTR.width"}, {"A-1", "1", "2"}}},
+		{name: "no key column", rows: [][]string{{"name", "price"}, {"Стул", "100"}}},
+		{name: "no data rows", rows: [][]string{{"sku", "name"}, {"", ""}}},
+		{name: "too many rows", rows: tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseImportSheet(tt.rows); !errors.Is(err, errors.ErrInvalidImportFile) {
+				t.Errorf("parseImportSheet() error = %v, want ErrInvalidImportFile", err)
+			}
+		})
+	}
+}
+
+func TestApplyImportCells(t *testing.T) {
+	header := []string{"sku", "name", "description", "price", "stock", "category", "category_id", "attr.width", "attr.material"}
+	categories := map[string]int{"7": 7, "chairs": 7, "стулья": 7}
+
+	tests := []struct {
+		name     string
+		row      []string
+		existing *entity.Product
+		want     entity.Product
+		wantErrs []string
+	}{
+		{
+			name: "new product",
+			row:  []string{"A-1", "Стул", "Мягкий", "1 500,50", "3", "Стулья", "", "45", ""},
+			want: entity.Product{Name: "Стул", Description: "Мягкий", Price: 1500.5, Stock: 3, CategoryID: 7,
+				Attributes: []entity.ProductAttribute{{Code: "width", Value: "45"}}},
+		},
+		{
+			name: "category id wins over name",
+			row:  []string{"A-1", "Стул", "", "100", "", "unknown", "12", "", ""},
+			want: entity.Product{Name: "Стул", Price: 100, CategoryID: 12, Attributes: []entity.ProductAttribute{}},
+		},
+		{
+			name:     "new product without required fields",
+			row:      []string{"A-1", "", "", "", "", "", "", "", ""},
+			want:     entity.Product{Attributes: []entity.ProductAttribute{}},
+			wantErrs: []string{"name is required", "price is required", "category or category_id is required"},
+		},
+		{
+			name:     "bad values",
+			row:      []string{"A-1", "Стул", "", "-1", "2.5", "kitchen", "", "", ""},
+			want:     entity.Product{Name: "Стул", Price: -1, Attributes: []entity.ProductAttribute{}},
+			wantErrs: []string{`price: "-1" is not a non-negative number`, `stock: "2.5" is not a non-negative integer`, `category "kitchen" not found`},
+		},
+		{
+			name: "existing product keeps fields for empty cells",
+			row:  []string{"A-1", "", "", "", "", "", "", "", "Дуб"},
+			existing: &entity.Product{Name: "Стул", Price: 100, CategoryID: 7, Attributes: []entity.ProductAttribute{
+				{Code: "width", Number: ptr(45.0)}, {Code: "material", Value: "Бук"},
+			}},
+			want: entity.Product{Name: "Стул", Price: 100, CategoryID: 7, Attributes: []entity.ProductAttribute{
+				{Code: "width", Number: ptr(45.0)}, {Code: "material", Value: "Дуб"},
+			}},
+		},
+		{
+			name:     "existing product ignores stock",
+			row:      []string{"A-1", "", "", "", "40", "", "", "", ""},
+			existing: &entity.Product{Name: "Стул", Price: 100, Stock: 3, CategoryID: 7},
+			want:     entity.Product{Name: "Стул", Price: 100, Stock: 3, CategoryID: 7},
+		},
+		{
+			name:     "existing product without attribute cells",
+			row:      []string{"A-1", "Новый стул", "", "", "", "", "", "", ""},
+			existing: &entity.Product{Name: "Стул", Price: 100, CategoryID: 7},
+			want:     entity.Product{Name: "Новый стул", Price: 100, CategoryID: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet, err := parseImportSheet([][]string{header, tt.row})
+			if err != nil {
+				t.Fatalf("parseImportSheet() error = %v", err)
+			}
+
+			var product entity.Product
+			if tt.existing != nil {
+				product = *tt.existing
+			}
+			errs := applyImportCells(sheet, sheet.rows[0], &product, tt.existing, categories)
+
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("applyImportCells() errors = %q, want %q", errs, tt.wantErrs)
+			}
+			sortAttributes(product.Attributes)
+			sortAttributes(tt.want.Attributes)
+			if !reflect.DeepEqual(product, tt.want) {
+				t.Errorf("applyImportCells() product = %+v, want %+v", product, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "15990", want: 15990},
+		{value: "15990.50", want: 15990.5},
+		{value: "15990,50", want: 15990.5},
+		{value: "15 990,50", want: 15990.5},
+		{value: "1,000.5", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDecimal(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDecimal(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseDecimal(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func sortAttributes(attributes []entity.ProductAttribute) {
+	sort.Slice(attributes, func(i, j int) bool { return strings.Compare(attributes[i].Code, attributes[j].Code) < 0 })
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+	storage "github.com/DenisOzindzheDev/furniture-shop/internal/infra/s3"
+)
+
+type InvoiceService struct {
+	invoiceRepo *postgres.InvoiceRepo
+	orderRepo   *postgres.OrderRepo
+	userRepo    *postgres.UserRepo
+	pdfService  *PDFService
+	storage     *storage.S3Storage
+}
+
+func NewInvoiceService(invoiceRepo *postgres.InvoiceRepo, orderRepo *postgres.OrderRepo, userRepo *postgres.UserRepo,
+	pdfService *PDFService, storage *storage.S3Storage) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo: invoiceRepo,
+		orderRepo:   orderRepo,
+		userRepo:    userRepo,
+		pdfService:  pdfService,
+		storage:     storage,
+	}
+}
+
+// GetInvoice возвращает счет заказа и его PDF. Покупатель (userID) получает счет только по своему заказу,
+// anyOwner снимает эту проверку для сотрудников. При первом запросе счет выписывается и сохраняется в S3,
+// дальше отдается сохраненный документ.
+func (s *InvoiceService) GetInvoice(ctx context.Context, orderID, userID int, anyOwner bool) (*entity.OrderInvoice, []byte, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil || (!anyOwner && order.UserID != userID) {
+		return nil, nil, errors.ErrOrderNotFound
+	}
+
+	invoice, err := s.invoiceRepo.GetByOrder(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if invoice == nil {
+		if order.Status == entity.OrderStatusCancelled {
+			return nil, nil, errors.ErrInvoiceNotAvailable
+		}
+		if invoice, err = s.issue(ctx, orderID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if invoice.FileURL != "" && s.storage != nil {
+		data, err := s.storage.DownloadBytes(ctx, invoice.FileURL)
+		if err == nil {
+			return invoice, data, nil
+		}
+		log.Printf("Download invoice %s error, regenerating: %v", invoice.Number, err)
+	}
+
+	buyer, err := s.userRepo.GetByID(ctx, order.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if buyer == nil {
+		return nil, nil, errors.ErrUserNotFound
+	}
+
+	buf, err := s.pdfService.GenerateOrderInvoicePDF(order, buyer, invoice)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := buf.Bytes()
+
+	// Без S3 счет все равно отдается: номер и дата хранятся в базе, документ генерируется заново
+	if s.storage != nil {
+		fileURL, err := s.storage.UploadBytes(ctx, data, fmt.Sprintf("invoices/%s.pdf", invoice.Token), "application/pdf")
+		if err != nil {
+			log.Printf("Upload invoice %s error: %v", invoice.Number, err)
+		} else if err := s.invoiceRepo.SetFileURL(ctx, orderID, fileURL); err != nil {
+			log.Printf("Save invoice %s url error: %v", invoice.Number, err)
+		} else {
+			invoice.FileURL = fileURL
+		}
+	}
+
+	return invoice, data, nil
+}
+
+// issue выписывает счет; ключ файла случайный, потому что объекты в бакете публичные
+func (s *InvoiceService) issue(ctx context.Context, orderID int) (*entity.OrderInvoice, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generate invoice token: %w", err)
+	}
+
+	return s.invoiceRepo.Create(ctx, &entity.OrderInvoice{
+		OrderID: orderID,
+		Number:  fmt.Sprintf("INV-%06d", orderID),
+		Token:   hex.EncodeToString(token),
+	})
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/config"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+func TestInvoiceServiceGetInvoice(t *testing.T) {
+	s := newTestServices(t)
+	ctx := context.Background()
+	invoices := NewInvoiceService(postgres.NewInvoiceRepo(s.db), postgres.NewOrderRepo(s.db), postgres.NewUserRepo(s.db),
+		NewPDFService(config.PDF{CompanyName: "Furniture Shop", VATRate: 20}), nil)
+
+	user := s.createTestUser(t)
+	other := s.createTestUser(t)
+	chair := s.createTestProduct(t, "Стул", 4990, 2)
+	order := s.createTestOrder(t, user.ID, entity.OrderItem{ProductID: chair.ID, Quantity: 1})
+
+	invoice, data, err := invoices.GetInvoice(ctx, order.ID, user.ID, false)
+	if err != nil {
+		t.Fatalf("GetInvoice() error = %v", err)
+	}
+	if want := fmt.Sprintf("INV-%06d", order.ID); invoice.Number != want {
+		t.Errorf("invoice number = %q, want %q", invoice.Number, want)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Error("GetInvoice() data is not a PDF")
+	}
+
+	// Повторный запрос без S3 генерирует тот же документ по сохраненному счету
+	again, againData, err := invoices.GetInvoice(ctx, order.ID, 0, true)
+	if err != nil {
+		t.Fatalf("GetInvoice(staff) error = %v", err)
+	}
+	if again.Number != invoice.Number || !again.IssuedAt.Equal(invoice.IssuedAt) {
+		t.Errorf("GetInvoice(repeated) = %s at %v, want %s at %v", again.Number, again.IssuedAt, invoice.Number, invoice.IssuedAt)
+	}
+	if !bytes.Equal(againData, data) {
+		t.Error("GetInvoice(repeated) returned a different document")
+	}
+
+	if _, _, err := invoices.GetInvoice(ctx, order.ID, other.ID, false); !errors.Is(err, errors.ErrOrderNotFound) {
+		t.Errorf("GetInvoice(other user) error = %v, want %v", err, errors.ErrOrderNotFound)
+	}
+
+	// По отмененному заказу без счета счет не выписывается
+	cancelled := s.createTestOrder(t, user.ID, entity.OrderItem{ProductID: chair.ID, Quantity: 1})
+	if _, err := s.orders.ChangeStatus(ctx, cancelled.ID, entity.OrderStatusCancelled, 0, ""); err != nil {
+		t.Fatalf("ChangeStatus(cancelled) error = %v", err)
+	}
+	if _, _, err := invoices.GetInvoice(ctx, cancelled.ID, user.ID, false); !errors.Is(err, errors.ErrInvoiceNotAvailable) {
+		t.Errorf("GetInvoice(cancelled) error = %v, want %v", err, errors.ErrInvoiceNotAvailable)
+	}
+}
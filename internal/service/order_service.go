@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+const (
+	// expiredReservationsBatch — сколько просроченных заказов отменяется за один проход
+	expiredReservationsBatch = 100
+	// maxBulkOrders — сколько заказов можно перевести в новый статус одним запросом
+	maxBulkOrders = 100
+	// maxOrderNoteLength — длина внутренней заметки к заказу
+	maxOrderNoteLength = 2000
+)
+
+type OrderService struct {
+	orderRepo      *postgres.OrderRepo
+	productService *ProductService
+	producer       *kafka.Producer
+	reservationTTL time.Duration
+}
+
+func NewOrderService(orderRepo *postgres.OrderRepo, productService *ProductService, producer *kafka.Producer, reservationTTL time.Duration) *OrderService {
+	return &OrderService{
+		orderRepo:      orderRepo,
+		productService: productService,
+		producer:       producer,
+		reservationTTL: reservationTTL,
+	}
+}
+
+// CreateOrder оформляет заказ пользователя: резервирует остатки на складах под способ получения, фиксирует цены
+// и применяет действующие акции и промокод promoCode (пустой — без промокода).
+// Неоплаченный заказ отменяется после истечения резерва (см. RunReservationSweeper).
+func (s *OrderService) CreateOrder(ctx context.Context, userID int, items []entity.OrderItem, delivery entity.Delivery,
+	promoCode string) (*entity.Order, error) {
+	return s.createOrder(ctx, userID, items, delivery, promoCode, false)
+}
+
+// CreateOrderFromCart оформляет заказ, как CreateOrder, и очищает корзину пользователя в той же транзакции
+func (s *OrderService) CreateOrderFromCart(ctx context.Context, userID int, items []entity.OrderItem, delivery entity.Delivery,
+	promoCode string) (*entity.Order, error) {
+	return s.createOrder(ctx, userID, items, delivery, promoCode, true)
+}
+
+func (s *OrderService) createOrder(ctx context.Context, userID int, items []entity.OrderItem, delivery entity.Delivery,
+	promoCode string, fromCart bool) (*entity.Order, error) {
+	items, err := mergeOrderItems(items)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err = normalizeDelivery(delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &entity.Order{
+		UserID:    userID,
+		Status:    entity.OrderStatusPending,
+		PromoCode: entity.NormalizePromoCode(promoCode),
+		Delivery:  delivery,
+		Items:     items,
+	}
+
+	if err := s.orderRepo.Create(ctx, order, s.reservationTTL, fromCart); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	s.productService.InvalidateProducts(ctx, productIDs...)
+
+	go s.producer.SendEvent(context.Background(), kafka.EventOrderCreated, map[string]interface{}{
+		"order_id":        order.ID,
+		"user_id":         order.UserID,
+		"subtotal":        order.Subtotal,
+		"discount_total":  order.DiscountTotal,
+		"total":           order.Total,
+		"promo_code":      order.PromoCode,
+		"discounts":       order.Discounts,
+		"delivery_method": order.Method,
+		"items":           order.Items,
+	})
+
+	return order, nil
+}
+
+// normalizeDelivery проверяет способ получения; пустой способ означает доставку
+func normalizeDelivery(d entity.Delivery) (entity.Delivery, error) {
+	switch d.Method {
+	case "", entity.DeliveryCourier:
+		if d.PickupWarehouseID != 0 {
+			return d, fmt.Errorf("%w: pickup point is only allowed for pickup", errors.ErrInvalidDelivery)
+		}
+		d.Method = entity.DeliveryCourier
+	case entity.DeliveryPickup:
+		if d.PickupWarehouseID <= 0 {
+			return d, fmt.Errorf("%w: pickup point is required", errors.ErrInvalidDelivery)
+		}
+	default:
+		return d, fmt.Errorf("%w: %q", errors.ErrInvalidDelivery, d.Method)
+	}
+	return d, nil
+}
+
+// GetOrder возвращает заказ, если он принадлежит пользователю
+func (s *OrderService) GetOrder(ctx context.Context, userID, orderID int) (*entity.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.UserID != userID {
+		return nil, errors.ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ListOrders возвращает заказы пользователя с пагинацией
+func (s *OrderService) ListOrders(ctx context.Context, userID, page, pageSize int) ([]*entity.Order, int, error) {
+	offset := (page - 1) * pageSize
+
+	orders, err := s.orderRepo.ListByUser(ctx, userID, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.orderRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// ListOrdersAfter возвращает заказы пользователя после курсора и курсор следующей страницы
+// (пустая строка — страница последняя)
+func (s *OrderService) ListOrdersAfter(ctx context.Context, userID int, cursor string, limit int) ([]*entity.Order, string, error) {
+	after, err := pagination.Decode(cursor, "", pagination.ValueTime)
+	if err != nil {
+		return nil, "", err
+	}
+
+	orders, next, err := s.orderRepo.ListByUserAfter(ctx, userID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if next == nil {
+		return orders, "", nil
+	}
+	return orders, next.Encode(), nil
+}
+
+// statusEvents сопоставляет статус заказа с событием Kafka
+var statusEvents = map[entity.OrderStatus]kafka.EventType{
+	entity.OrderStatusPaid:      kafka.EventOrderPaid,
+	entity.OrderStatusShipped:   kafka.EventOrderShipped,
+	entity.OrderStatusDelivered: kafka.EventOrderDelivered,
+	entity.OrderStatusCancelled: kafka.EventOrderCancelled,
+}
+
+// ChangeStatus переводит заказ в новый статус, отклоняя недопустимые переходы.
+// actorID = 0 означает системный переход (например, по вебхуку оплаты).
+func (s *OrderService) ChangeStatus(ctx context.Context, orderID int, to entity.OrderStatus, actorID int, comment string) (*entity.Order, error) {
+	if !to.IsValid() {
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.ErrOrderNotFound
+	}
+
+	from := order.Status
+	if !from.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s -> %s", errors.ErrInvalidStatusTransition, from, to)
+	}
+
+	if err := s.orderRepo.UpdateStatus(ctx, orderID, from, to, actorID, comment); err != nil {
+		return nil, err
+	}
+
+	// Оплата списывает резервы со склада, отмена снимает их или возвращает товар
+	if to == entity.OrderStatusPaid || to == entity.OrderStatusCancelled {
+		productIDs := make([]int, 0, len(order.Items))
+		for _, item := range order.Items {
+			productIDs = append(productIDs, item.ProductID)
+		}
+		s.productService.StockChanged(ctx, productIDs...)
+	}
+
+	order, err = s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if eventType, ok := statusEvents[to]; ok {
+		go s.producer.SendEvent(context.Background(), eventType, map[string]interface{}{
+			"order_id":    order.ID,
+			"user_id":     order.UserID,
+			"from_status": from,
+			"to_status":   to,
+			"changed_by":  actorID,
+		})
+	}
+
+	return order, nil
+}
+
+// CancelOrder отменяет неоплаченный заказ пользователя; оплаченный заказ отменяет поддержка
+func (s *OrderService) CancelOrder(ctx context.Context, userID, orderID int, comment string) (*entity.Order, error) {
+	order, err := s.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != entity.OrderStatusPending {
+		return nil, fmt.Errorf("%w: order is %s", errors.ErrOrderNotCancellable, order.Status)
+	}
+
+	if comment == "" {
+		comment = "Отменен покупателем"
+	}
+	return s.ChangeStatus(ctx, orderID, entity.OrderStatusCancelled, userID, comment)
+}
+
+// ListAllOrders возвращает заказы всех покупателей по фильтру для админки
+func (s *OrderService) ListAllOrders(ctx context.Context, filter entity.OrderFilter, page, pageSize int) ([]*entity.AdminOrder, int, error) {
+	for _, status := range filter.Statuses {
+		if !status.IsValid() {
+			return nil, 0, fmt.Errorf("%w: %q", errors.ErrInvalidOrderStatus, status)
+		}
+	}
+	offset := (page - 1) * pageSize
+
+	orders, err := s.orderRepo.List(ctx, filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.orderRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// GetOrderDetails возвращает карточку заказа для оператора: покупатель, позиции, история статусов и заметки
+func (s *OrderService) GetOrderDetails(ctx context.Context, orderID int) (*entity.OrderDetails, error) {
+	order, err := s.orderRepo.GetAdminOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.ErrOrderNotFound
+	}
+
+	history, err := s.orderRepo.ListStatusHistory(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.orderRepo.ListNotes(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.OrderDetails{AdminOrder: *order, History: history, Notes: notes}, nil
+}
+
+// AddNote добавляет к заказу внутреннюю заметку сотрудника
+func (s *OrderService) AddNote(ctx context.Context, orderID, authorID int, text string) (*entity.OrderNote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxOrderNoteLength {
+		return nil, errors.ErrInvalidOrderNote
+	}
+
+	note := &entity.OrderNote{OrderID: orderID, Text: text}
+	if authorID != 0 {
+		note.AuthorID = &authorID
+	}
+	if err := s.orderRepo.AddNote(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// BulkChangeStatus переводит заказы в статус to по одному, как ChangeStatus. Ошибка одного заказа
+// не останавливает остальные и возвращается в его результате.
+func (s *OrderService) BulkChangeStatus(ctx context.Context, orderIDs []int, to entity.OrderStatus, actorID int, comment string) ([]entity.BulkStatusResult, error) {
+	if !to.IsValid() {
+		return nil, errors.ErrInvalidOrderStatus
+	}
+
+	seen := make(map[int]bool, len(orderIDs))
+	ids := make([]int, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxBulkOrders {
+		return nil, errors.ErrInvalidBulkOrders
+	}
+
+	results := make([]entity.BulkStatusResult, 0, len(ids))
+	for _, id := range ids {
+		result := entity.BulkStatusResult{OrderID: id}
+		order, err := s.ChangeStatus(ctx, id, to, actorID, comment)
+		if err != nil {
+			if !errors.Is(err, errors.ErrOrderNotFound) && !errors.Is(err, errors.ErrInvalidStatusTransition) &&
+				!errors.Is(err, errors.ErrInsufficientStock) {
+				log.Printf("Bulk change order %d status error: %v", id, err)
+			}
+			result.Error = err.Error()
+		} else {
+			result.Status = order.Status
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ReleaseExpiredReservations отменяет неоплаченные заказы с истекшим резервом, возвращая товар в продажу.
+// Возвращает количество отмененных заказов.
+func (s *OrderService) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		ids, err := s.orderRepo.ListExpiredReservations(ctx, expiredReservationsBatch)
+		if err != nil {
+			return released, err
+		}
+
+		cancelled := 0
+		for _, id := range ids {
+			_, err := s.ChangeStatus(ctx, id, entity.OrderStatusCancelled, 0, "Резерв истек: заказ не оплачен")
+			if errors.Is(err, errors.ErrInvalidStatusTransition) {
+				// Заказ успели оплатить или отменить параллельно
+				continue
+			}
+			if err != nil {
+				return released, err
+			}
+			cancelled++
+		}
+		released += cancelled
+
+		if len(ids) < expiredReservationsBatch || cancelled == 0 {
+			return released, nil
+		}
+	}
+}
+
+// RunReservationSweeper раз в interval снимает истекшие резервы, пока не отменен ctx
+func (s *OrderService) RunReservationSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				log.Printf("Release expired reservations error: %v", err)
+			}
+			if released > 0 {
+				log.Printf("Cancelled %d unpaid orders with expired reservations", released)
+			}
+		}
+	}
+}
+
+// GetStatusHistory возвращает историю переходов заказа
+func (s *OrderService) GetStatusHistory(ctx context.Context, orderID int) ([]*entity.OrderStatusHistory, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.ErrOrderNotFound
+	}
+	return s.orderRepo.ListStatusHistory(ctx, orderID)
+}
+
+// mergeOrderItems проверяет количество и схлопывает повторяющиеся товары (варианты) в одну позицию
+func mergeOrderItems(items []entity.OrderItem) ([]entity.OrderItem, error) {
+	if len(items) == 0 {
+		return nil, errors.ErrEmptyOrder
+	}
+
+	type itemKey struct{ productID, variantID int }
+
+	merged := make([]entity.OrderItem, 0, len(items))
+	index := make(map[itemKey]int, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, errors.ErrInvalidQuantity
+		}
+		key := itemKey{item.ProductID, item.VariantID}
+		if i, ok := index[key]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, entity.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return merged, nil
+}
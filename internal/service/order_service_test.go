@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestMergeOrderItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []entity.OrderItem
+		want    []entity.OrderItem
+		wantErr error
+	}{
+		{
+			name:  "repeated product is merged in first position",
+			items: []entity.OrderItem{{ProductID: 2, Quantity: 1}, {ProductID: 1, Quantity: 3}, {ProductID: 2, Quantity: 4}},
+			want:  []entity.OrderItem{{ProductID: 2, Quantity: 5}, {ProductID: 1, Quantity: 3}},
+		},
+		{
+			name:  "variants of one product stay separate",
+			items: []entity.OrderItem{{ProductID: 1, VariantID: 2, Quantity: 1}, {ProductID: 1, VariantID: 3, Quantity: 1}, {ProductID: 1, VariantID: 2, Quantity: 2}},
+			want:  []entity.OrderItem{{ProductID: 1, VariantID: 2, Quantity: 3}, {ProductID: 1, VariantID: 3, Quantity: 1}},
+		},
+		{
+			name:  "client price is dropped",
+			items: []entity.OrderItem{{ProductID: 1, Quantity: 1, Price: 0.01}},
+			want:  []entity.OrderItem{{ProductID: 1, Quantity: 1}},
+		},
+		{name: "empty order", items: nil, wantErr: errors.ErrEmptyOrder},
+		{name: "zero quantity", items: []entity.OrderItem{{ProductID: 1, Quantity: 0}}, wantErr: errors.ErrInvalidQuantity},
+		{name: "negative quantity", items: []entity.OrderItem{{ProductID: 1, Quantity: 2}, {ProductID: 1, Quantity: -1}}, wantErr: errors.ErrInvalidQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeOrderItems(tt.items)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("mergeOrderItems() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeOrderItems() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeOrderItems() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		delivery entity.Delivery
+		want     entity.Delivery
+		wantErr  bool
+	}{
+		{name: "empty method means delivery", delivery: entity.Delivery{}, want: entity.Delivery{Method: entity.DeliveryCourier}},
+		{name: "pickup with point", delivery: entity.Delivery{Method: entity.DeliveryPickup, PickupWarehouseID: 3}, want: entity.Delivery{Method: entity.DeliveryPickup, PickupWarehouseID: 3}},
+		{name: "pickup without point", delivery: entity.Delivery{Method: entity.DeliveryPickup}, wantErr: true},
+		{name: "delivery with point", delivery: entity.Delivery{Method: entity.DeliveryCourier, PickupWarehouseID: 3}, wantErr: true},
+		{name: "unknown method", delivery: entity.Delivery{Method: "drone"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeDelivery(tt.delivery)
+			if tt.wantErr {
+				if !errors.Is(err, errors.ErrInvalidDelivery) {
+					t.Fatalf("normalizeDelivery() error = %v, want %v", err, errors.ErrInvalidDelivery)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeDelivery() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeDelivery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderServiceAdminValidation(t *testing.T) {
+	s := &OrderService{}
+	ctx := context.Background()
+	tooMany := make([]int, maxBulkOrders+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+
+	if _, _, err := s.ListAllOrders(ctx, entity.OrderFilter{Statuses: []entity.OrderStatus{"lost"}}, 1, 20); !errors.Is(err, errors.ErrInvalidOrderStatus) {
+		t.Errorf("ListAllOrders(unknown status) error = %v, want %v", err, errors.ErrInvalidOrderStatus)
+	}
+	if _, err := s.BulkChangeStatus(ctx, []int{1}, "lost", 0, ""); !errors.Is(err, errors.ErrInvalidOrderStatus) {
+		t.Errorf("BulkChangeStatus(unknown status) error = %v, want %v", err, errors.ErrInvalidOrderStatus)
+	}
+	if _, err := s.BulkChangeStatus(ctx, nil, entity.OrderStatusShipped, 0, ""); !errors.Is(err, errors.ErrInvalidBulkOrders) {
+		t.Errorf("BulkChangeStatus(no orders) error = %v, want %v", err, errors.ErrInvalidBulkOrders)
+	}
+	if _, err := s.BulkChangeStatus(ctx, tooMany, entity.OrderStatusShipped, 0, ""); !errors.Is(err, errors.ErrInvalidBulkOrders) {
+		t.Errorf("BulkChangeStatus(%d orders) error = %v, want %v", len(tooMany), err, errors.ErrInvalidBulkOrders)
+	}
+	for _, text := range []string{"  ", strings.Repeat("я", maxOrderNoteLength+1)} {
+		if _, err := s.AddNote(ctx, 1, 0, text); !errors.Is(err, errors.ErrInvalidOrderNote) {
+			t.Errorf("AddNote(%d runes) error = %v, want %v", len([]rune(text)), err, errors.ErrInvalidOrderNote)
+		}
+	}
+}
+
+func TestOrderServiceBulkChangeStatus(t *testing.T) {
+	s := newTestServices(t)
+	ctx := context.Background()
+	user := s.createTestUser(t)
+	chair := s.createTestProduct(t, "Стул", 4990, 5)
+	paid := s.createTestOrder(t, user.ID, entity.OrderItem{ProductID: chair.ID, Quantity: 1})
+	pending := s.createTestOrder(t, user.ID, entity.OrderItem{ProductID: chair.ID, Quantity: 1})
+	if _, err := s.orders.ChangeStatus(ctx, paid.ID, entity.OrderStatusPaid, 0, ""); err != nil {
+		t.Fatalf("ChangeStatus(paid) error = %v", err)
+	}
+
+	// Повторяющиеся ID обрабатываются один раз, ошибка одного заказа не мешает остальным
+	results, err := s.orders.BulkChangeStatus(ctx, []int{paid.ID, pending.ID, paid.ID, pending.ID + 100}, entity.OrderStatusShipped, 0, "")
+	if err != nil {
+		t.Fatalf("BulkChangeStatus() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("BulkChangeStatus() results = %+v, want 3", results)
+	}
+	if results[0].OrderID != paid.ID || results[0].Status != entity.OrderStatusShipped || results[0].Error != "" {
+		t.Errorf("paid order result = %+v, want shipped", results[0])
+	}
+	for _, result := range results[1:] {
+		if result.Error == "" || result.Status != "" {
+			t.Errorf("result = %+v, want an error", result)
+		}
+	}
+
+	details, err := s.orders.GetOrderDetails(ctx, paid.ID)
+	if err != nil {
+		t.Fatalf("GetOrderDetails() error = %v", err)
+	}
+	if details.Status != entity.OrderStatusShipped || details.CustomerEmail != user.Email {
+		t.Errorf("GetOrderDetails() = %s for %q, want shipped for %q", details.Status, details.CustomerEmail, user.Email)
+	}
+	if _, err := s.orders.GetOrderDetails(ctx, pending.ID+100); !errors.Is(err, errors.ErrOrderNotFound) {
+		t.Errorf("GetOrderDetails(missing) error = %v, want %v", err, errors.ErrOrderNotFound)
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// paymentCurrency — валюта платежей магазина
+const paymentCurrency = "RUB"
+
+// PaymentProvider — адаптер платежной системы (встроенный payment.FakeProvider, ЮKassa и т.п.)
+type PaymentProvider interface {
+	// Name — имя провайдера в API и в адресе вебхука /api/payments/webhook/{name}
+	Name() string
+	// CreatePayment регистрирует платеж у провайдера и заполняет ProviderPaymentID, Status и ConfirmationURL
+	CreatePayment(ctx context.Context, intent *entity.PaymentIntent) error
+	// Capture списывает заблокированные (authorized) деньги. Если провайдер списал их сразу,
+	// он переводит intent в succeeded, иначе об успехе сообщит следующий вебхук.
+	Capture(ctx context.Context, intent *entity.PaymentIntent) error
+	// Refund возвращает покупателю amount по проведенному платежу
+	Refund(ctx context.Context, intent *entity.PaymentIntent, amount float64) error
+	// ParseWebhook проверяет подпись уведомления (ErrInvalidSignature) и разбирает его
+	ParseWebhook(header http.Header, body []byte) (*entity.PaymentEvent, error)
+}
+
+type PaymentService struct {
+	paymentRepo     *postgres.PaymentRepo
+	orderService    *OrderService
+	producer        *kafka.Producer
+	providers       map[string]PaymentProvider
+	defaultProvider string
+}
+
+func NewPaymentService(paymentRepo *postgres.PaymentRepo, orderService *OrderService, producer *kafka.Producer,
+	defaultProvider string, providers ...PaymentProvider) *PaymentService {
+	byName := make(map[string]PaymentProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &PaymentService{
+		paymentRepo:     paymentRepo,
+		orderService:    orderService,
+		producer:        producer,
+		providers:       byName,
+		defaultProvider: defaultProvider,
+	}
+}
+
+// provider возвращает провайдер по имени; пустое имя — провайдер по умолчанию
+func (s *PaymentService) provider(name string) (PaymentProvider, error) {
+	if name == "" {
+		name = s.defaultProvider
+	}
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errors.ErrPaymentProviderNotFound, name)
+	}
+	return p, nil
+}
+
+// CreatePayment создает платеж по неоплаченному заказу пользователя. Повторный вызов возвращает
+// еще не оплаченный платеж того же провайдера, чтобы покупатель не получил две ссылки на оплату.
+func (s *PaymentService) CreatePayment(ctx context.Context, userID, orderID int, providerName string) (*entity.PaymentIntent, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderService.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != entity.OrderStatusPending {
+		return nil, fmt.Errorf("%w: order is %s", errors.ErrOrderNotPayable, order.Status)
+	}
+
+	pending, err := s.paymentRepo.GetLatestByOrder(ctx, orderID, entity.PaymentPending)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil && pending.Provider == provider.Name() && pending.ProviderPaymentID != "" &&
+		sameAmount(pending.Amount, order.Total) {
+		return pending, nil
+	}
+
+	intent := &entity.PaymentIntent{
+		OrderID:  orderID,
+		Provider: provider.Name(),
+		Amount:   order.Total,
+		Currency: paymentCurrency,
+		Status:   entity.PaymentPending,
+	}
+	if err := s.paymentRepo.Create(ctx, intent); err != nil {
+		return nil, err
+	}
+
+	if err := provider.CreatePayment(ctx, intent); err != nil {
+		if _, cancelErr := s.paymentRepo.UpdateStatus(ctx, intent, entity.PaymentPending, entity.PaymentCancelled); cancelErr != nil {
+			log.Printf("Cancel failed payment %d error: %v", intent.ID, cancelErr)
+		}
+		return nil, fmt.Errorf("%w: %v", errors.ErrPaymentProviderFailed, err)
+	}
+	if err := s.paymentRepo.SaveProviderPayment(ctx, intent); err != nil {
+		return nil, err
+	}
+
+	return intent, nil
+}
+
+// HandleWebhook обрабатывает уведомление провайдера: проверяет подпись и переводит платеж в новый статус.
+// Успешный платеж переводит заказ в paid. Повторные уведомления ничего не меняют.
+func (s *PaymentService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) error {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return err
+	}
+
+	event, err := provider.ParseWebhook(header, body)
+	if err != nil {
+		return err
+	}
+
+	intent, err := s.paymentRepo.GetByProviderPaymentID(ctx, provider.Name(), event.ProviderPaymentID)
+	if err != nil {
+		return err
+	}
+	if intent == nil {
+		return fmt.Errorf("%w: %s", errors.ErrPaymentNotFound, event.ProviderPaymentID)
+	}
+
+	switch event.Status {
+	case entity.PaymentAuthorized, entity.PaymentSucceeded:
+		if !sameAmount(event.Amount, intent.Amount) {
+			return fmt.Errorf("%w: amount %.2f, expected %.2f", errors.ErrInvalidPaymentEvent, event.Amount, intent.Amount)
+		}
+		if event.Status == entity.PaymentAuthorized {
+			return s.capture(ctx, provider, intent)
+		}
+		return s.succeed(ctx, provider, intent, intent.Status)
+	case entity.PaymentCancelled:
+		if intent.Status == entity.PaymentPending || intent.Status == entity.PaymentAuthorized {
+			_, err := s.paymentRepo.UpdateStatus(ctx, intent, intent.Status, entity.PaymentCancelled)
+			return err
+		}
+	}
+	// pending ничего не меняет, а о возвратах магазин знает сам: их инициирует Refund
+	return nil
+}
+
+// capture списывает заблокированные деньги. Если списание не удалось, платеж остается authorized
+// и повторный вебхук попробует снова.
+func (s *PaymentService) capture(ctx context.Context, provider PaymentProvider, intent *entity.PaymentIntent) error {
+	if intent.Status == entity.PaymentPending {
+		if _, err := s.paymentRepo.UpdateStatus(ctx, intent, entity.PaymentPending, entity.PaymentAuthorized); err != nil {
+			return err
+		}
+	}
+	if intent.Status != entity.PaymentAuthorized {
+		return nil
+	}
+
+	if err := provider.Capture(ctx, intent); err != nil {
+		return fmt.Errorf("%w: capture: %v", errors.ErrPaymentProviderFailed, err)
+	}
+	if intent.Status != entity.PaymentSucceeded {
+		return nil
+	}
+	return s.succeed(ctx, provider, intent, entity.PaymentAuthorized)
+}
+
+// succeed отмечает платеж проведенным и оплачивает заказ. Если заказ оплатить уже нельзя
+// (отменен по истечении резерва, оплачен другим платежом, товара не осталось), деньги возвращаются.
+func (s *PaymentService) succeed(ctx context.Context, provider PaymentProvider, intent *entity.PaymentIntent, from entity.PaymentStatus) error {
+	if from != entity.PaymentPending && from != entity.PaymentAuthorized {
+		return nil
+	}
+	ok, err := s.paymentRepo.UpdateStatus(ctx, intent, from, entity.PaymentSucceeded)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	comment := fmt.Sprintf("Оплачен через %s, платеж %s", provider.Name(), intent.ProviderPaymentID)
+	_, err = s.orderService.ChangeStatus(ctx, intent.OrderID, entity.OrderStatusPaid, 0, comment)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errors.ErrInvalidStatusTransition), errors.Is(err, errors.ErrInsufficientStock):
+		log.Printf("Order %d cannot be paid by payment %d, refunding: %v", intent.OrderID, intent.ID, err)
+		return s.refund(ctx, provider, intent, intent.Amount)
+	default:
+		// Временная ошибка: платеж возвращается в прежний статус, чтобы повторный вебхук провел оплату
+		if _, rollbackErr := s.paymentRepo.UpdateStatus(ctx, intent, entity.PaymentSucceeded, from); rollbackErr != nil {
+			log.Printf("Rollback payment %d status error: %v", intent.ID, rollbackErr)
+		}
+		return err
+	}
+}
+
+// Refund возвращает покупателю amount по проведенному платежу заказа; amount = 0 — весь остаток
+func (s *PaymentService) Refund(ctx context.Context, orderID int, amount float64) (*entity.PaymentIntent, error) {
+	intent, err := s.paymentRepo.GetLatestByOrder(ctx, orderID, entity.PaymentSucceeded)
+	if err != nil {
+		return nil, err
+	}
+	if intent == nil {
+		return nil, fmt.Errorf("%w: order %d has no succeeded payments", errors.ErrPaymentNotFound, orderID)
+	}
+
+	amount = math.Round(amount*100) / 100
+	remaining := refundable(intent)
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, fmt.Errorf("%w: %.2f", errors.ErrInvalidRefund, amount)
+	}
+
+	provider, err := s.provider(intent.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refund(ctx, provider, intent, amount); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// Refundable возвращает, сколько еще можно вернуть по проведенному платежу заказа; 0 — платежа нет
+func (s *PaymentService) Refundable(ctx context.Context, orderID int) (float64, error) {
+	intent, err := s.paymentRepo.GetLatestByOrder(ctx, orderID, entity.PaymentSucceeded)
+	if err != nil {
+		return 0, err
+	}
+	if intent == nil {
+		return 0, nil
+	}
+	return refundable(intent), nil
+}
+
+// refund сначала учитывает возврат в базе, чтобы параллельные возвраты не превысили сумму платежа,
+// и откатывает его, если провайдер отказал
+func (s *PaymentService) refund(ctx context.Context, provider PaymentProvider, intent *entity.PaymentIntent, amount float64) error {
+	if err := s.paymentRepo.AddRefund(ctx, intent, amount); err != nil {
+		return err
+	}
+
+	if err := provider.Refund(ctx, intent, amount); err != nil {
+		if rollbackErr := s.paymentRepo.AddRefund(ctx, intent, -amount); rollbackErr != nil {
+			log.Printf("Rollback refund of payment %d error: %v", intent.ID, rollbackErr)
+		}
+		return fmt.Errorf("%w: refund: %v", errors.ErrPaymentProviderFailed, err)
+	}
+
+	go s.producer.SendEvent(context.Background(), kafka.EventPaymentRefunded, map[string]interface{}{
+		"order_id":        intent.OrderID,
+		"payment_id":      intent.ID,
+		"provider":        intent.Provider,
+		"amount":          amount,
+		"refunded_amount": intent.RefundedAmount,
+	})
+	return nil
+}
+
+// ListOrderPayments возвращает платежи заказа
+func (s *PaymentService) ListOrderPayments(ctx context.Context, orderID int) ([]*entity.PaymentIntent, error) {
+	return s.paymentRepo.ListByOrder(ctx, orderID)
+}
+
+// refundable возвращает невозвращенный остаток платежа
+func refundable(intent *entity.PaymentIntent) float64 {
+	return math.Round((intent.Amount-intent.RefundedAmount)*100) / 100
+}
+
+// sameAmount сравнивает суммы с точностью до копейки
+func sameAmount(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
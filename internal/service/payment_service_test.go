@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/payment"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+const testPaymentSecret = "0123456789abcdef0123456789abcdef"
+
+// paymentTest — сервис платежей с фейковым провайдером поверх тестовой базы
+type paymentTest struct {
+	*testServices
+	provider *payment.FakeProvider
+	payments *PaymentService
+}
+
+func newPaymentTest(t *testing.T) *paymentTest {
+	t.Helper()
+	s := newTestServices(t)
+	provider, err := payment.NewFakeProvider(testPaymentSecret, "")
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+	return &paymentTest{
+		testServices: s,
+		provider:     provider,
+		payments:     NewPaymentService(postgres.NewPaymentRepo(s.db), s.orders, s.producer, provider.Name(), provider),
+	}
+}
+
+// webhook отправляет сервису подписанное уведомление фейкового провайдера
+func (p *paymentTest) webhook(intent *entity.PaymentIntent, status entity.PaymentStatus, amount float64) error {
+	body := []byte(fmt.Sprintf(`{"payment_id": %q, "status": %q, "amount": %v}`, intent.ProviderPaymentID, status, amount))
+	header := http.Header{payment.FakeSignatureHeader: []string{p.provider.Sign(body)}}
+	return p.payments.HandleWebhook(context.Background(), p.provider.Name(), header, body)
+}
+
+// payment возвращает платеж из базы
+func (p *paymentTest) payment(t *testing.T, intent *entity.PaymentIntent) *entity.PaymentIntent {
+	t.Helper()
+	got, err := postgres.NewPaymentRepo(p.db).GetByProviderPaymentID(context.Background(), intent.Provider, intent.ProviderPaymentID)
+	if err != nil || got == nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	return got
+}
+
+// orderStatus возвращает статус заказа из базы
+func (p *paymentTest) orderStatus(t *testing.T, order *entity.Order) entity.OrderStatus {
+	t.Helper()
+	got, err := p.orders.GetOrder(context.Background(), order.UserID, order.ID)
+	if err != nil {
+		t.Fatalf("GetOrder() error = %v", err)
+	}
+	return got.Status
+}
+
+func TestPaymentServiceWebhook(t *testing.T) {
+	p := newPaymentTest(t)
+	ctx := context.Background()
+	user := p.createTestUser(t)
+	chair := p.createTestProduct(t, "Стул", 4990, 3)
+	order := p.createTestOrder(t, user.ID, entity.OrderItem{ProductID: chair.ID, Quantity: 2})
+
+	intent, err := p.payments.CreatePayment(ctx, user.ID, order.ID, "")
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	// Повторный запрос возвращает тот же неоплаченный платеж
+	again, err := p.payments.CreatePayment(ctx, user.ID, order.ID, "")
+	if err != nil {
+		t.Fatalf("CreatePayment(repeated) error = %v", err)
+	}
+	if again.ID != intent.ID {
+		t.Errorf("CreatePayment(repeated) id = %d, want %d", again.ID, intent.ID)
+	}
+
+	// Подпись другим ключом отклоняется
+	body := []byte(fmt.Sprintf(`{"payment_id": %q, "status": "succeeded", "amount": %v}`, intent.ProviderPaymentID, order.Total))
+	other, err := payment.NewFakeProvider("fedcba9876543210fedcba9876543210", "")
+	if err != nil {
+		t.Fatalf("NewFakeProvider() error = %v", err)
+	}
+	header := http.Header{payment.FakeSignatureHeader: []string{other.Sign(body)}}
+	if err := p.payments.HandleWebhook(ctx, p.provider.Name(), header, body); !errors.Is(err, errors.ErrInvalidSignature) {
+		t.Errorf("HandleWebhook(bad signature) error = %v, want %v", err, errors.ErrInvalidSignature)
+	}
+
+	// Сумма, не совпадающая с платежом, не оплачивает заказ
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total-1); !errors.Is(err, errors.ErrInvalidPaymentEvent) {
+		t.Errorf("HandleWebhook(wrong amount) error = %v, want %v", err, errors.ErrInvalidPaymentEvent)
+	}
+	if got := p.orderStatus(t, order); got != entity.OrderStatusPending {
+		t.Fatalf("order status after wrong amount = %s, want pending", got)
+	}
+
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+		t.Fatalf("HandleWebhook(succeeded) error = %v", err)
+	}
+	if got := p.orderStatus(t, order); got != entity.OrderStatusPaid {
+		t.Errorf("order status = %s, want paid", got)
+	}
+
+	// Повторные уведомления ничего не меняют: заказ не оплачивается дважды, деньги не возвращаются
+	for i := 0; i < 2; i++ {
+		if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+			t.Fatalf("HandleWebhook(repeated) error = %v", err)
+		}
+	}
+	if err := p.webhook(intent, entity.PaymentCancelled, order.Total); err != nil {
+		t.Fatalf("HandleWebhook(cancelled after success) error = %v", err)
+	}
+	got := p.payment(t, intent)
+	if got.Status != entity.PaymentSucceeded || got.RefundedAmount != 0 {
+		t.Errorf("payment after repeated webhooks = %s refunded %v, want succeeded without refund", got.Status, got.RefundedAmount)
+	}
+	if got := p.orderStatus(t, order); got != entity.OrderStatusPaid {
+		t.Errorf("order status after repeated webhooks = %s, want paid", got)
+	}
+
+	// Оплаченный заказ больше нельзя оплатить
+	if _, err := p.payments.CreatePayment(ctx, user.ID, order.ID, ""); !errors.Is(err, errors.ErrOrderNotPayable) {
+		t.Errorf("CreatePayment(paid order) error = %v, want %v", err, errors.ErrOrderNotPayable)
+	}
+}
+
+func TestPaymentServiceRefundsUnpayableOrder(t *testing.T) {
+	p := newPaymentTest(t)
+	ctx := context.Background()
+	user := p.createTestUser(t)
+	table := p.createTestProduct(t, "Стол", 9000, 1)
+	order := p.createTestOrder(t, user.ID, entity.OrderItem{ProductID: table.ID, Quantity: 1})
+
+	intent, err := p.payments.CreatePayment(ctx, user.ID, order.ID, "")
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+
+	// Заказ отменен (например, истек резерв), пока покупатель платил
+	if _, err := p.orders.ChangeStatus(ctx, order.ID, entity.OrderStatusCancelled, 0, ""); err != nil {
+		t.Fatalf("ChangeStatus(cancelled) error = %v", err)
+	}
+
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+		t.Fatalf("HandleWebhook(succeeded) error = %v", err)
+	}
+	got := p.payment(t, intent)
+	if got.RefundedAmount != order.Total {
+		t.Errorf("refunded amount = %v, want %v", got.RefundedAmount, order.Total)
+	}
+	if status := p.orderStatus(t, order); status != entity.OrderStatusCancelled {
+		t.Errorf("order status = %s, want cancelled", status)
+	}
+
+	// Повторное уведомление не возвращает деньги второй раз
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+		t.Fatalf("HandleWebhook(repeated) error = %v", err)
+	}
+	if got := p.payment(t, intent); got.RefundedAmount != order.Total {
+		t.Errorf("refunded amount after repeat = %v, want %v", got.RefundedAmount, order.Total)
+	}
+}
+
+func TestPaymentServiceRefund(t *testing.T) {
+	p := newPaymentTest(t)
+	ctx := context.Background()
+	user := p.createTestUser(t)
+	sofa := p.createTestProduct(t, "Диван", 50000, 1)
+	order := p.createTestOrder(t, user.ID, entity.OrderItem{ProductID: sofa.ID, Quantity: 1})
+
+	if _, err := p.payments.Refund(ctx, order.ID, 0); !errors.Is(err, errors.ErrPaymentNotFound) {
+		t.Errorf("Refund(unpaid) error = %v, want %v", err, errors.ErrPaymentNotFound)
+	}
+
+	intent, err := p.payments.CreatePayment(ctx, user.ID, order.ID, "")
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+
+	if _, err := p.payments.Refund(ctx, order.ID, 20000); err != nil {
+		t.Fatalf("Refund(partial) error = %v", err)
+	}
+	if _, err := p.payments.Refund(ctx, order.ID, 30000.01); !errors.Is(err, errors.ErrInvalidRefund) {
+		t.Errorf("Refund(over remaining) error = %v, want %v", err, errors.ErrInvalidRefund)
+	}
+	// Нулевая сумма возвращает весь остаток
+	refunded, err := p.payments.Refund(ctx, order.ID, 0)
+	if err != nil {
+		t.Fatalf("Refund(rest) error = %v", err)
+	}
+	if refunded.RefundedAmount != order.Total {
+		t.Errorf("refunded amount = %v, want %v", refunded.RefundedAmount, order.Total)
+	}
+}
+
+func TestSameAmount(t *testing.T) {
+	tests := []struct {
+		a, b float64
+		want bool
+	}{
+		{a: 15990, b: 15990.004, want: true},
+		{a: 0.1 + 0.2, b: 0.3, want: true},
+		{a: 15990, b: 15990.01, want: false},
+	}
+	for _, tt := range tests {
+		if got := sameAmount(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameAmount(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
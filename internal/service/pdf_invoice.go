@@ -0,0 +1,200 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// invoiceFont — семейство, под которым регистрируется шрифт из pdf.font_path
+const invoiceFont = "invoice"
+
+// Ширины колонок таблицы позиций счета: №, наименование, артикул, количество, цена, сумма (итого 190 мм)
+var invoiceColumns = []float64{10, 80, 30, 18, 26, 26}
+
+// GenerateOrderInvoicePDF формирует счет по заказу: реквизиты продавца из настроек pdf, покупатель,
+// позиции, НДС и итоги. Документ зависит только от переданных данных, поэтому повторная генерация дает тот же счет.
+func (s *PDFService) GenerateOrderInvoicePDF(order *entity.Order, buyer *entity.User, invoice *entity.OrderInvoice) (*bytes.Buffer, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// Ресурсы сортируются, а даты берутся из счета, чтобы повторная генерация давала тот же файл
+	pdf.SetCatalogSort(true)
+	pdf.SetCreationDate(invoice.IssuedAt)
+	pdf.SetModificationDate(invoice.IssuedAt)
+	pdf.SetTitle(fmt.Sprintf("Счет %s", invoice.Number), true)
+	pdf.SetAuthor(s.cfg.CompanyName, true)
+
+	font, err := s.invoiceFont(pdf)
+	if err != nil {
+		return nil, err
+	}
+	pdf.AddPage()
+
+	s.addInvoiceHeader(pdf, font)
+
+	pdf.SetFont(font, "B", 16)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Счет № %s от %s", invoice.Number, invoice.IssuedAt.Format("02.01.2006")), "", 1, "L", false, 0, "")
+	pdf.SetFont(font, "", 11)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Заказ № %d от %s", order.ID, order.CreatedAt.Format("02.01.2006 15:04")), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	s.addInvoiceParty(pdf, font, "Продавец:", s.cfg.CompanyName)
+	buyerText := buyer.Email
+	if buyer.Name != "" {
+		buyerText = fmt.Sprintf("%s, %s", buyer.Name, buyer.Email)
+	}
+	s.addInvoiceParty(pdf, font, "Покупатель:", buyerText)
+	s.addInvoiceParty(pdf, font, "Получение:", deliveryTitle(order.Method))
+	pdf.Ln(4)
+
+	subtotal := s.addInvoiceItems(pdf, font, order.Items)
+	pdf.Ln(2)
+	s.addInvoiceTotals(pdf, font, subtotal, order.Total)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to generate invoice PDF: %w", err)
+	}
+	return &buf, nil
+}
+
+// invoiceFont подключает шрифт с кириллицей из pdf.font_path; без него используется встроенный Arial.
+// Жирное начертание использует тот же файл.
+func (s *PDFService) invoiceFont(pdf *gofpdf.Fpdf) (string, error) {
+	if s.cfg.FontPath == "" {
+		return "Arial", nil
+	}
+	data, err := os.ReadFile(s.cfg.FontPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load invoice font: %w", err)
+	}
+	pdf.AddUTF8FontFromBytes(invoiceFont, "", data)
+	pdf.AddUTF8FontFromBytes(invoiceFont, "B", data)
+	return invoiceFont, nil
+}
+
+// addInvoiceHeader выводит логотип и реквизиты продавца
+func (s *PDFService) addInvoiceHeader(pdf *gofpdf.Fpdf, font string) {
+	top := pdf.GetY()
+	if s.cfg.LogoPath != "" {
+		info := pdf.RegisterImageOptions(s.cfg.LogoPath, gofpdf.ImageOptions{ReadDpi: true})
+		if pdf.Ok() && info != nil {
+			height := 20.0
+			width := info.Width() * height / info.Height()
+			pdf.ImageOptions(s.cfg.LogoPath, 10, top, width, height, false, gofpdf.ImageOptions{}, 0, "")
+			pdf.SetY(top + height + 2)
+		} else {
+			// Недоступный логотип не должен мешать выписке счета
+			pdf.ClearError()
+		}
+	}
+
+	pdf.SetFont(font, "B", 13)
+	pdf.CellFormat(0, 7, s.cfg.CompanyName, "", 1, "L", false, 0, "")
+	if s.cfg.CompanyDetails != "" {
+		pdf.SetFont(font, "", 9)
+		pdf.MultiCell(0, 4.5, s.cfg.CompanyDetails, "", "L", false)
+	}
+	pdf.Ln(6)
+}
+
+// addInvoiceParty выводит строку «подпись — значение»
+func (s *PDFService) addInvoiceParty(pdf *gofpdf.Fpdf, font, label, value string) {
+	pdf.SetFont(font, "B", 11)
+	pdf.CellFormat(30, 6, label, "", 0, "L", false, 0, "")
+	pdf.SetFont(font, "", 11)
+	pdf.CellFormat(0, 6, value, "", 1, "L", false, 0, "")
+}
+
+// addInvoiceItems выводит таблицу позиций и возвращает их сумму
+func (s *PDFService) addInvoiceItems(pdf *gofpdf.Fpdf, font string, items []entity.OrderItem) float64 {
+	headers := []string{"№", "Наименование", "Артикул", "Кол-во", "Цена", "Сумма"}
+	pdf.SetFont(font, "B", 10)
+	pdf.SetFillColor(240, 240, 240)
+	for i, header := range headers {
+		pdf.CellFormat(invoiceColumns[i], 7, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(font, "", 10)
+	var subtotal float64
+	for i, item := range items {
+		sum := item.Price * float64(item.Quantity)
+		subtotal += sum
+
+		name := item.ProductName
+		if name == "" {
+			name = fmt.Sprintf("Товар #%d", item.ProductID)
+		}
+		pdf.CellFormat(invoiceColumns[0], 7, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(invoiceColumns[1], 7, fitText(pdf, name, invoiceColumns[1]-2), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(invoiceColumns[2], 7, fitText(pdf, item.SKU, invoiceColumns[2]-2), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(invoiceColumns[3], 7, fmt.Sprintf("%d", item.Quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(invoiceColumns[4], 7, formatMoney(item.Price), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(invoiceColumns[5], 7, formatMoney(sum), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetFillColor(255, 255, 255)
+
+	return math.Round(subtotal*100) / 100
+}
+
+// addInvoiceTotals выводит итоги: сумму позиций, скидку, если итог заказа меньше, и НДС, включенный в цену
+func (s *PDFService) addInvoiceTotals(pdf *gofpdf.Fpdf, font string, subtotal, total float64) {
+	label := invoiceColumns[0] + invoiceColumns[1] + invoiceColumns[2] + invoiceColumns[3] + invoiceColumns[4]
+	row := func(title string, amount float64, bold bool) {
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont(font, style, 11)
+		pdf.CellFormat(label, 7, title, "", 0, "R", false, 0, "")
+		pdf.CellFormat(invoiceColumns[5], 7, formatMoney(amount), "", 1, "R", false, 0, "")
+	}
+
+	if discount := math.Round((subtotal-total)*100) / 100; discount > 0 {
+		row("Сумма по позициям:", subtotal, false)
+		row("Скидка:", discount, false)
+	}
+	row("Итого к оплате:", total, true)
+
+	if s.cfg.VATRate > 0 {
+		row(fmt.Sprintf("В том числе НДС %s%%:", strconv.FormatFloat(s.cfg.VATRate, 'f', -1, 64)), includedVAT(total, s.cfg.VATRate), false)
+	} else {
+		pdf.SetFont(font, "", 11)
+		pdf.CellFormat(0, 7, "Без НДС", "", 1, "R", false, 0, "")
+	}
+}
+
+// includedVAT возвращает НДС по ставке rate (в процентах), включенный в сумму total
+func includedVAT(total, rate float64) float64 {
+	return math.Round(total*rate/(100+rate)*100) / 100
+}
+
+// deliveryTitle возвращает название способа получения для документов
+func deliveryTitle(method entity.DeliveryMethod) string {
+	if method == entity.DeliveryPickup {
+		return "Самовывоз"
+	}
+	return "Доставка"
+}
+
+// formatMoney форматирует сумму в рублях
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.2f руб.", amount)
+}
+
+// fitText обрезает строку с многоточием, чтобы она поместилась в ячейку ширины width
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
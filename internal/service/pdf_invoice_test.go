@@ -0,0 +1,70 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/config"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestIncludedVAT(t *testing.T) {
+	tests := []struct {
+		total, rate float64
+		want        float64
+	}{
+		{total: 12000, rate: 20, want: 2000},
+		{total: 15990, rate: 20, want: 2665},
+		{total: 1000, rate: 10, want: 90.91},
+		{total: 1000, rate: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := includedVAT(tt.total, tt.rate); got != tt.want {
+			t.Errorf("includedVAT(%v, %v) = %v, want %v", tt.total, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateOrderInvoicePDFIsStable(t *testing.T) {
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	order := &entity.Order{
+		ID:        42,
+		UserID:    7,
+		Total:     14980,
+		CreatedAt: issued.Add(-time.Hour),
+		Delivery:  entity.Delivery{Method: entity.DeliveryPickup},
+		Items: []entity.OrderItem{
+			{ProductID: 1, ProductName: "Chair", SKU: "CH-1", Quantity: 2, Price: 4990},
+			{ProductID: 2, SKU: "TB-1", Quantity: 1, Price: 6000},
+		},
+	}
+	buyer := &entity.User{ID: 7, Email: "buyer@example.com", Name: "Buyer"}
+	invoice := &entity.OrderInvoice{OrderID: order.ID, Number: "INV-000042", IssuedAt: issued}
+
+	for _, rate := range []float64{20, 0} {
+		s := NewPDFService(config.PDF{CompanyName: "Furniture Shop", CompanyDetails: "INN 7700000000", VATRate: rate})
+		first, err := s.GenerateOrderInvoicePDF(order, buyer, invoice)
+		if err != nil {
+			t.Fatalf("GenerateOrderInvoicePDF(vat %v) error = %v", rate, err)
+		}
+		second, err := s.GenerateOrderInvoicePDF(order, buyer, invoice)
+		if err != nil {
+			t.Fatalf("GenerateOrderInvoicePDF(vat %v, repeated) error = %v", rate, err)
+		}
+		if !bytes.HasPrefix(first.Bytes(), []byte("%PDF-")) {
+			t.Errorf("GenerateOrderInvoicePDF(vat %v) is not a PDF", rate)
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("GenerateOrderInvoicePDF(vat %v) differs between calls", rate)
+		}
+	}
+}
+
+func TestGenerateOrderInvoicePDFMissingFont(t *testing.T) {
+	s := NewPDFService(config.PDF{CompanyName: "Furniture Shop", FontPath: "testdata/missing.ttf"})
+	invoice := &entity.OrderInvoice{Number: "INV-000001", IssuedAt: time.Now()}
+	if _, err := s.GenerateOrderInvoicePDF(&entity.Order{}, &entity.User{}, invoice); err == nil {
+		t.Error("GenerateOrderInvoicePDF() error = nil, want font error")
+	}
+}
@@ -7,19 +7,23 @@ import (
 	"image/jpeg"
 	"image/png"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/DenisOzindzheDev/furniture-shop/internal/config"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
 	"github.com/jung-kurt/gofpdf"
 )
 
 type PDFService struct {
 	baseURL string
+	cfg     config.PDF
 }
 
-func NewPDFService(baseURL string) *PDFService {
+func NewPDFService(cfg config.PDF) *PDFService {
 	return &PDFService{
-		baseURL: baseURL,
+		baseURL: cfg.BaseURL,
+		cfg:     cfg,
 	}
 }
 
@@ -73,8 +77,8 @@ func (s *PDFService) addFirstPage(pdf *gofpdf.Fpdf, product *entity.Product) {
 	pdf.SetFont("Arial", "B", 12)
 	pdf.CellFormat(40, 7, "Наличие:", "", 0, "L", false, 0, "")
 	pdf.SetFont("Arial", "", 12)
-	stockText := fmt.Sprintf("%d шт.", product.Stock)
-	if product.Stock == 0 {
+	stockText := fmt.Sprintf("%d шт.", product.Available)
+	if product.Available <= 0 {
 		stockText = "Нет в наличии"
 		pdf.SetTextColor(255, 0, 0)
 	}
@@ -83,6 +87,11 @@ func (s *PDFService) addFirstPage(pdf *gofpdf.Fpdf, product *entity.Product) {
 
 	pdf.Ln(5)
 
+	if len(product.Attributes) > 0 {
+		s.addSpecTable(pdf, product.Attributes)
+		pdf.Ln(5)
+	}
+
 	if product.Description != "" {
 		pdf.SetFont("Arial", "B", 12)
 		pdf.CellFormat(0, 7, "Описание:", "", 1, "L", false, 0, "")
@@ -129,6 +138,33 @@ func (s *PDFService) addDetailsPage(pdf *gofpdf.Fpdf, product *entity.Product) {
 	pdf.CellFormat(0, 6, product.UpdatedAt.Format("02.01.2006 15:04"), "", 1, "L", false, 0, "")
 }
 
+// addSpecTable выводит характеристики товара таблицей «название — значение»
+func (s *PDFService) addSpecTable(pdf *gofpdf.Fpdf, attributes []entity.ProductAttribute) {
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 7, "Характеристики:", "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "", 11)
+	for i, attribute := range attributes {
+		fill := i%2 == 0
+		pdf.SetFillColor(240, 240, 240)
+		pdf.CellFormat(70, 7, attribute.Name, "1", 0, "L", fill, 0, "")
+		pdf.CellFormat(0, 7, formatAttributeValue(attribute), "1", 1, "L", fill, 0, "")
+	}
+	pdf.SetFillColor(255, 255, 255)
+}
+
+// formatAttributeValue возвращает значение характеристики с единицей измерения
+func formatAttributeValue(attribute entity.ProductAttribute) string {
+	value := attribute.Value
+	if attribute.Number != nil {
+		value = strconv.FormatFloat(*attribute.Number, 'f', -1, 64)
+	}
+	if attribute.Unit != "" {
+		value += " " + attribute.Unit
+	}
+	return value
+}
+
 func (s *PDFService) addProductImage(pdf *gofpdf.Fpdf, imageURL string) {
 	imgData, err := s.downloadImage(imageURL)
 	if err != nil {
@@ -2,29 +2,73 @@ package service
 
 import (
 	"context"
+	"log"
 	"mime/multipart"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/redis"
 )
 
 type ProductService struct {
-	productRepo  *postgres.ProductRepo
-	imageSerivce *ImageService
-	cache        *redis.Cache
+	productRepo   *postgres.ProductRepo
+	categoryRepo  *postgres.CategoryRepo
+	imageRepo     *postgres.ProductImageRepo
+	attributeRepo *postgres.AttributeRepo
+	imageSerivce  *ImageService
+	cache         *redis.Cache
+	stockAlerts   *StockAlertService
+	// priceBuckets — границы ценовых диапазонов фасета цены по возрастанию
+	priceBuckets []float64
 }
 
-func NewProductService(productRepo *postgres.ProductRepo, imageService *ImageService, cache *redis.Cache) *ProductService {
+func NewProductService(productRepo *postgres.ProductRepo, categoryRepo *postgres.CategoryRepo, imageRepo *postgres.ProductImageRepo,
+	attributeRepo *postgres.AttributeRepo, imageService *ImageService, cache *redis.Cache, stockAlerts *StockAlertService,
+	priceBuckets []float64) *ProductService {
 	return &ProductService{
-		productRepo:  productRepo,
-		imageSerivce: imageService,
-		cache:        cache,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		imageRepo:     imageRepo,
+		attributeRepo: attributeRepo,
+		imageSerivce:  imageService,
+		cache:         cache,
+		stockAlerts:   stockAlerts,
+		priceBuckets:  normalizePriceBuckets(priceBuckets),
 	}
 }
 
-func (s *ProductService) CreateProduct(ctx context.Context, product *entity.Product, imageFile multipart.File, imageHeader *multipart.FileHeader) error {
+// normalizePriceBuckets упорядочивает границы фасета цены и отбрасывает неположительные и повторяющиеся
+func normalizePriceBuckets(buckets []float64) []float64 {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	result := make([]float64, 0, len(sorted))
+	for _, b := range sorted {
+		if b > 0 && (len(result) == 0 || b > result[len(result)-1]) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// CreateProduct создает продукт; actorID — пользователь, от имени которого начальный остаток пишется в журнал
+func (s *ProductService) CreateProduct(ctx context.Context, product *entity.Product, imageFile multipart.File, imageHeader *multipart.FileHeader, actorID int) error {
+	if product.Stock < 0 {
+		return errors.ErrInvalidQuantity
+	}
+	if err := s.resolveCategory(ctx, product); err != nil {
+		return err
+	}
+	if err := s.resolveAttributes(ctx, product); err != nil {
+		return err
+	}
+
 	if imageFile != nil && imageHeader != nil {
 		imageURL, err := s.imageSerivce.UploadImage(ctx, imageFile, imageHeader)
 		if err != nil {
@@ -33,7 +77,7 @@ func (s *ProductService) CreateProduct(ctx context.Context, product *entity.Prod
 		product.ImageURL = imageURL
 	}
 
-	if err := s.productRepo.Create(ctx, product); err != nil {
+	if err := s.productRepo.Create(ctx, product, actorID); err != nil {
 		if product.ImageURL != "" {
 			s.imageSerivce.DeleteImage(ctx, product.ImageURL)
 		}
@@ -41,10 +85,12 @@ func (s *ProductService) CreateProduct(ctx context.Context, product *entity.Prod
 	}
 
 	s.invalidateProductCache(ctx, product.Category, product.ID)
+	s.stockAlerts.Check(ctx, product.ID)
 
 	return nil
 }
 
+// UpdateProduct обновляет продукт. Остаток не меняется: он проводится движениями остатков (StockService).
 func (s *ProductService) UpdateProduct(ctx context.Context, product *entity.Product, imageFile multipart.File, imageHeader *multipart.FileHeader) error {
 	oldProduct, err := s.productRepo.GetByID(ctx, product.ID)
 	if err != nil {
@@ -54,26 +100,55 @@ func (s *ProductService) UpdateProduct(ctx context.Context, product *entity.Prod
 		return errors.ErrProductNotFound
 	}
 
+	if product.CategoryID == 0 {
+		product.CategoryID = oldProduct.CategoryID
+	}
+	if err := s.resolveCategory(ctx, product); err != nil {
+		return err
+	}
+
+	// Характеристики перепроверяются, если они переданы или сменилась категория;
+	// nil в product.Attributes оставляет сохраненные значения как есть
+	if product.Attributes == nil && product.CategoryID != oldProduct.CategoryID {
+		product.Attributes = oldProduct.Attributes
+	}
+	if product.Attributes != nil {
+		if err := s.resolveAttributes(ctx, product); err != nil {
+			return err
+		}
+	}
+
+	var imageURL string
 	if imageFile != nil && imageHeader != nil {
-		imageURL, err := s.imageSerivce.UploadImage(ctx, imageFile, imageHeader)
+		imageURL, err = s.imageSerivce.UploadImage(ctx, imageFile, imageHeader)
 		if err != nil {
 			return err
 		}
+	}
+
+	if err := s.productRepo.Update(ctx, product); err != nil {
+		s.imageSerivce.DeleteImage(ctx, imageURL)
+		return err
+	}
 
-		if oldProduct.ImageURL != "" {
-			s.imageSerivce.DeleteImage(ctx, oldProduct.ImageURL)
+	// Новое изображение из формы заменяет главное изображение галереи
+	if imageURL != "" {
+		image := &entity.ProductImage{ProductID: product.ID, URL: imageURL, AltText: product.Name}
+		oldURL, err := s.imageRepo.ReplacePrimary(ctx, image)
+		if err != nil {
+			s.imageSerivce.DeleteImage(ctx, imageURL)
+			return err
 		}
+		s.imageSerivce.DeleteImage(ctx, oldURL)
 
 		product.ImageURL = imageURL
-	} else {
-		product.ImageURL = oldProduct.ImageURL
+		if product.Images, err = s.imageRepo.ListByProduct(ctx, product.ID); err != nil {
+			return err
+		}
 	}
 
-	if err := s.productRepo.Update(ctx, product); err != nil {
-		if product.ImageURL != "" && product.ImageURL != oldProduct.ImageURL {
-			s.imageSerivce.DeleteImage(ctx, product.ImageURL)
-		}
-		return err
+	if product.Attributes == nil {
+		product.Attributes = oldProduct.Attributes
 	}
 
 	s.invalidateProductCache(ctx, oldProduct.Category, product.ID)
@@ -84,6 +159,36 @@ func (s *ProductService) UpdateProduct(ctx context.Context, product *entity.Prod
 	return nil
 }
 
+// resolveCategory проверяет категорию продукта и подставляет ее название
+func (s *ProductService) resolveCategory(ctx context.Context, product *entity.Product) error {
+	category, err := s.categoryRepo.GetByID(ctx, product.CategoryID)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return errors.ErrCategoryNotFound
+	}
+
+	product.Category = category.Name
+	return nil
+}
+
+// resolveAttributes проверяет характеристики продукта по определениям его категории
+func (s *ProductService) resolveAttributes(ctx context.Context, product *entity.Product) error {
+	defs, err := s.attributeRepo.ForCategory(ctx, product.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := validateProductAttributes(defs, product.Attributes)
+	if err != nil {
+		return err
+	}
+
+	product.Attributes = attributes
+	return nil
+}
+
 // DeleteProduct удаляет продукт
 func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
 	product, err := s.productRepo.GetByID(ctx, id)
@@ -94,14 +199,19 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
 		return errors.ErrProductNotFound
 	}
 
-	if product.ImageURL != "" {
-		s.imageSerivce.DeleteImage(ctx, product.ImageURL)
+	images, err := s.imageRepo.ListByProduct(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	if err := s.productRepo.Delete(ctx, id); err != nil {
 		return err
 	}
 
+	for _, img := range images {
+		s.imageSerivce.DeleteImage(ctx, img.URL)
+	}
+
 	s.invalidateProductCache(ctx, product.Category, id)
 
 	return nil
@@ -111,39 +221,114 @@ func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
 func (s *ProductService) invalidateProductCache(ctx context.Context, category string, productID int) {
 	s.cache.Delete(ctx, "products:all")
 	s.cache.Delete(ctx, "products:"+category)
-	s.cache.Delete(ctx, "product:"+string(rune(productID)))
+	s.cache.Delete(ctx, productCacheKey(productID))
 }
 
-// ListProducts возвращает список продуктов с пагинацией
-func (s *ProductService) ListProducts(ctx context.Context, category string, page, pageSize int) ([]*entity.Product, int, error) {
-	cacheKey := ""
-	if category != "" {
-		cacheKey = "products:" + category
-	} else {
-		cacheKey = "products:all"
+// InvalidateProducts сбрасывает кэш карточек и списков их категорий после изменений,
+// которые кэш не видит (резервы, остатки, галерея)
+func (s *ProductService) InvalidateProducts(ctx context.Context, ids ...int) {
+	for _, id := range ids {
+		s.cache.Delete(ctx, productCacheKey(id))
 	}
+	s.cache.Delete(ctx, "products:all")
+
+	categories, err := s.productRepo.Categories(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to invalidate category caches for products %v: %v", ids, err)
+		return
+	}
+	for _, category := range categories {
+		s.cache.Delete(ctx, "products:"+category)
+	}
+}
 
+// StockChanged сбрасывает кэш карточек после изменения остатков и проверяет пороги и подписки на поступление
+func (s *ProductService) StockChanged(ctx context.Context, ids ...int) {
+	s.InvalidateProducts(ctx, ids...)
+	s.stockAlerts.Check(ctx, ids...)
+}
+
+func productCacheKey(id int) string {
+	return "product:" + strconv.Itoa(id)
+}
+
+// ListProducts возвращает список продуктов с фильтрами, сортировкой и пагинацией
+func (s *ProductService) ListProducts(ctx context.Context, filter entity.ProductFilter, page, pageSize int) ([]*entity.Product, int, error) {
 	offset := (page - 1) * pageSize
 
 	var products []*entity.Product
 
-	products, err := s.productRepo.List(ctx, category, pageSize, offset)
+	products, err := s.productRepo.List(ctx, filter, pageSize, offset)
 	if err != nil {
 		return nil, 0, err
 	}
-	total, err := s.productRepo.Count(ctx, category)
+	total, err := s.productRepo.Count(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	go s.cache.Set(context.Background(), cacheKey, products)
+	if cacheKey, ok := listCacheKey(filter); ok {
+		go s.cache.Set(context.Background(), cacheKey, products)
+	}
 
 	return products, total, nil
 }
 
-// GetProduct возвращает продукт по ID
+// ListProductsAfter возвращает страницу продуктов после курсора и курсор следующей страницы
+// (пустая строка — страница последняя). Общее количество не считается.
+func (s *ProductService) ListProductsAfter(ctx context.Context, filter entity.ProductFilter, cursor string, limit int) ([]*entity.Product, string, error) {
+	after, err := pagination.Decode(cursor, string(filter.Sort), productCursorKind(filter.Sort))
+	if err != nil {
+		return nil, "", err
+	}
+
+	products, next, err := s.productRepo.ListAfter(ctx, filter, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if next == nil {
+		return products, "", nil
+	}
+
+	next.Sort = string(filter.Sort)
+	return products, next.Encode(), nil
+}
+
+// productCursorKind возвращает тип ключа сортировки каталога в курсоре (см. postgres.productKeysetFor)
+func productCursorKind(sort entity.ProductSort) pagination.ValueKind {
+	switch sort {
+	case entity.ProductSortPriceAsc, entity.ProductSortPriceDesc:
+		return pagination.ValueDecimal
+	case entity.ProductSortName:
+		return pagination.ValueText
+	case entity.ProductSortPopularity:
+		return pagination.ValueInt
+	default:
+		return pagination.ValueTime
+	}
+}
+
+// ProductFacets возвращает фасеты каталога для текущих фильтров
+func (s *ProductService) ProductFacets(ctx context.Context, filter entity.ProductFilter) (*entity.ProductFacets, error) {
+	return s.productRepo.Facets(ctx, filter, s.priceBuckets)
+}
+
+// listCacheKey возвращает ключ кэша списка; кэшируется только выдача по умолчанию или по одной категории
+func listCacheKey(filter entity.ProductFilter) (string, bool) {
+	if filter.MinPrice != nil || filter.MaxPrice != nil || filter.InStock || len(filter.Categories) > 1 || len(filter.CategoryIDs) > 0 ||
+		len(filter.Attributes) > 0 || filter.UpdatedFrom != nil || filter.UpdatedTo != nil ||
+		(filter.Sort != "" && filter.Sort != entity.ProductSortNewest) {
+		return "", false
+	}
+	if len(filter.Categories) == 1 {
+		return "products:" + filter.Categories[0], true
+	}
+	return "products:all", true
+}
+
+// GetProduct возвращает продукт по ID вместе с галереей изображений
 func (s *ProductService) GetProduct(ctx context.Context, id int) (*entity.Product, error) {
-	cacheKey := "product:" + string(rune(id))
+	cacheKey := productCacheKey(id)
 
 	var product *entity.Product
 	err := s.cache.Get(ctx, cacheKey, &product)
@@ -159,24 +344,219 @@ func (s *ProductService) GetProduct(ctx context.Context, id int) (*entity.Produc
 		return nil, errors.ErrProductNotFound
 	}
 
+	product.Images, err = s.imageRepo.ListByProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	go s.cache.Set(context.Background(), cacheKey, product)
 
 	return product, nil
 }
 
-// UpdateStock обновляет количество товара на складе
-func (s *ProductService) UpdateStock(ctx context.Context, id, stock int) error {
-	if err := s.productRepo.UpdateStock(ctx, id, stock); err != nil {
+// CreateVariant добавляет вариант к товару; остаток варианта пишется в журнал от имени actorID
+func (s *ProductService) CreateVariant(ctx context.Context, variant *entity.ProductVariant, actorID int) error {
+	if err := s.prepareVariant(ctx, variant); err != nil {
+		return err
+	}
+
+	if err := s.productRepo.CreateVariant(ctx, variant, actorID); err != nil {
+		return err
+	}
+
+	s.StockChanged(ctx, variant.ProductID)
+
+	return nil
+}
+
+// UpdateVariant обновляет вариант товара. Остаток не меняется: он проводится движениями остатков (StockService).
+func (s *ProductService) UpdateVariant(ctx context.Context, variant *entity.ProductVariant) error {
+	// Остаток из запроса не используется
+	variant.Stock = 0
+	if err := s.prepareVariant(ctx, variant); err != nil {
+		return err
+	}
+
+	if err := s.productRepo.UpdateVariant(ctx, variant); err != nil {
 		return err
 	}
 
-	s.cache.Delete(ctx, "product:"+string(rune(id)))
+	s.InvalidateProducts(ctx, variant.ProductID)
 
 	return nil
 }
 
-// SearchProducts выполняет поиск продуктов
-func (s *ProductService) SearchProducts(ctx context.Context, query string, page, pageSize int) ([]*entity.Product, error) {
+// DeleteVariant удаляет вариант товара; его остаток списывается в журнале от имени actorID
+func (s *ProductService) DeleteVariant(ctx context.Context, productID, variantID, actorID int) error {
+	if err := s.productRepo.DeleteVariant(ctx, productID, variantID, actorID); err != nil {
+		return err
+	}
+
+	s.StockChanged(ctx, productID)
+
+	return nil
+}
+
+// prepareVariant нормализует и проверяет вариант перед записью
+func (s *ProductService) prepareVariant(ctx context.Context, variant *entity.ProductVariant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if variant.SKU == "" || variant.Stock < 0 || (variant.Price != nil && *variant.Price <= 0) {
+		return errors.ErrInvalidVariant
+	}
+
+	options := make(map[string]string, len(variant.Options))
+	for name, value := range variant.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return errors.ErrInvalidVariant
+		}
+		options[name] = value
+	}
+	variant.Options = options
+
+	if variant.Images == nil {
+		variant.Images = []string{}
+	}
+
+	return s.ensureProduct(ctx, variant.ProductID)
+}
+
+// ListImages возвращает галерею товара
+func (s *ProductService) ListImages(ctx context.Context, productID int) ([]entity.ProductImage, error) {
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.imageRepo.ListByProduct(ctx, productID)
+}
+
+// AddImages загружает изображения в хранилище и добавляет их в конец галереи.
+// Все файлы проверяются до загрузки, чтобы не оставлять в галерее часть пачки из-за неверного файла.
+func (s *ProductService) AddImages(ctx context.Context, productID int, headers []*multipart.FileHeader, altText string) ([]entity.ProductImage, error) {
+	for _, header := range headers {
+		if err := s.imageSerivce.ValidateImage(header); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	added := make([]entity.ProductImage, 0, len(headers))
+	defer func() {
+		if len(added) > 0 {
+			s.InvalidateProducts(ctx, productID)
+		}
+	}()
+
+	for _, header := range headers {
+		image, err := s.addImage(ctx, productID, header, strings.TrimSpace(altText))
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, *image)
+	}
+
+	return added, nil
+}
+
+func (s *ProductService) addImage(ctx context.Context, productID int, header *multipart.FileHeader, altText string) (*entity.ProductImage, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	imageURL, err := s.imageSerivce.UploadImage(ctx, file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	image := &entity.ProductImage{ProductID: productID, URL: imageURL, AltText: altText}
+	if err := s.imageRepo.Add(ctx, image); err != nil {
+		s.imageSerivce.DeleteImage(ctx, imageURL)
+		return nil, err
+	}
+	return image, nil
+}
+
+// UpdateImageAltText меняет альтернативный текст изображения
+func (s *ProductService) UpdateImageAltText(ctx context.Context, productID, imageID int, altText string) (*entity.ProductImage, error) {
+	image, err := s.imageRepo.UpdateAltText(ctx, productID, imageID, strings.TrimSpace(altText))
+	if err != nil {
+		return nil, err
+	}
+
+	s.InvalidateProducts(ctx, productID)
+
+	return image, nil
+}
+
+// SetPrimaryImage делает изображение главным; его URL становится image_url товара
+func (s *ProductService) SetPrimaryImage(ctx context.Context, productID, imageID int) error {
+	if err := s.imageRepo.SetPrimary(ctx, productID, imageID); err != nil {
+		return err
+	}
+
+	s.InvalidateProducts(ctx, productID)
+
+	return nil
+}
+
+// ReorderImages задает порядок изображений галереи
+func (s *ProductService) ReorderImages(ctx context.Context, productID int, imageIDs []int) error {
+	if err := s.imageRepo.Reorder(ctx, productID, imageIDs); err != nil {
+		return err
+	}
+
+	s.InvalidateProducts(ctx, productID)
+
+	return nil
+}
+
+// DeleteImage удаляет изображение из галереи и файл из хранилища
+func (s *ProductService) DeleteImage(ctx context.Context, productID, imageID int) error {
+	imageURL, err := s.imageRepo.Delete(ctx, productID, imageID)
+	if err != nil {
+		return err
+	}
+
+	s.imageSerivce.DeleteImage(ctx, imageURL)
+	s.InvalidateProducts(ctx, productID)
+
+	return nil
+}
+
+func (s *ProductService) ensureProduct(ctx context.Context, id int) error {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return errors.ErrProductNotFound
+	}
+	return nil
+}
+
+// maxSearchQueryLen ограничивает длину поискового запроса в символах
+const maxSearchQueryLen = 200
+
+// SearchProducts выполняет полнотекстовый поиск продуктов и возвращает страницу результатов и их общее количество
+func (s *ProductService) SearchProducts(ctx context.Context, query string, page, pageSize int) ([]*entity.ProductSearchResult, int, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return nil, 0, errors.ErrInvalidSearchQuery
+	}
+
 	offset := (page - 1) * pageSize
-	return s.productRepo.Search(ctx, query, pageSize, offset)
+	results, err := s.productRepo.Search(ctx, query, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.productRepo.SearchCount(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestSearchProductsRejectsInvalidQuery(t *testing.T) {
+	// Запрос проверяется до обращения к репозиторию
+	s := &ProductService{}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "empty", query: ""},
+		{name: "only spaces", query: "  \t "},
+		{name: "too long", query: strings.Repeat("д", maxSearchQueryLen+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.SearchProducts(context.Background(), tt.query, 1, 10); !errors.Is(err, errors.ErrInvalidSearchQuery) {
+				t.Errorf("SearchProducts(%q) error = %v, want %v", tt.query, err, errors.ErrInvalidSearchQuery)
+			}
+		})
+	}
+}
+
+func TestListCacheKey(t *testing.T) {
+	price := 1000.0
+
+	tests := []struct {
+		name   string
+		filter entity.ProductFilter
+		want   string
+		wantOK bool
+	}{
+		{name: "default listing", filter: entity.ProductFilter{}, want: "products:all", wantOK: true},
+		{name: "explicit newest", filter: entity.ProductFilter{Sort: entity.ProductSortNewest}, want: "products:all", wantOK: true},
+		{name: "single category", filter: entity.ProductFilter{Categories: []string{"sofas"}}, want: "products:sofas", wantOK: true},
+		{name: "several categories", filter: entity.ProductFilter{Categories: []string{"sofas", "chairs"}}},
+		{name: "price filter", filter: entity.ProductFilter{MinPrice: &price}},
+		{name: "in stock", filter: entity.ProductFilter{InStock: true}},
+		{name: "other sort", filter: entity.ProductFilter{Sort: entity.ProductSortPriceAsc}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := listCacheKey(tt.filter)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("listCacheKey() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPrepareVariantRejectsInvalid(t *testing.T) {
+	// Поля варианта проверяются до обращения к репозиторию
+	s := &ProductService{}
+	zero, negative := 0.0, -1.0
+
+	tests := []struct {
+		name    string
+		variant entity.ProductVariant
+	}{
+		{name: "empty sku", variant: entity.ProductVariant{SKU: "  "}},
+		{name: "negative stock", variant: entity.ProductVariant{SKU: "A", Stock: -1}},
+		{name: "zero price", variant: entity.ProductVariant{SKU: "A", Price: &zero}},
+		{name: "negative price", variant: entity.ProductVariant{SKU: "A", Price: &negative}},
+		{name: "empty option name", variant: entity.ProductVariant{SKU: "A", Options: map[string]string{" ": "red"}}},
+		{name: "empty option value", variant: entity.ProductVariant{SKU: "A", Options: map[string]string{"color": ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.prepareVariant(context.Background(), &tt.variant); !errors.Is(err, errors.ErrInvalidVariant) {
+				t.Errorf("prepareVariant() error = %v, want %v", err, errors.ErrInvalidVariant)
+			}
+		})
+	}
+}
+
+func TestNormalizePriceBuckets(t *testing.T) {
+	tests := []struct {
+		in   []float64
+		want []float64
+	}{
+		{in: nil, want: []float64{}},
+		{in: []float64{30000, 5000, 15000}, want: []float64{5000, 15000, 30000}},
+		{in: []float64{0, -100, 5000, 5000, 100000}, want: []float64{5000, 100000}},
+	}
+	for _, tt := range tests {
+		if got := normalizePriceBuckets(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizePriceBuckets(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
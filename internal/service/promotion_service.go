@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+const (
+	// maxPromoCodesBatch — сколько промокодов можно создать одним запросом
+	maxPromoCodesBatch = 1000
+	// generatedPromoCodeLength — длина случайной части сгенерированного промокода
+	generatedPromoCodeLength = 8
+	// promoCodeAlphabet — символы сгенерированных кодов без похожих друг на друга (0/O, 1/I)
+	promoCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// promoCodePattern — допустимый вид промокода после нормализации
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,64}$`)
+
+// PromoCodeBatch — промокоды для создания: явный список Codes или Count случайных кодов с префиксом Prefix.
+// UsageLimit = 1 делает коды одноразовыми, 0 — без ограничения.
+type PromoCodeBatch struct {
+	Codes      []string
+	Count      int
+	Prefix     string
+	UsageLimit int
+}
+
+type PromotionService struct {
+	promotionRepo *postgres.PromotionRepo
+}
+
+func NewPromotionService(promotionRepo *postgres.PromotionRepo) *PromotionService {
+	return &PromotionService{promotionRepo: promotionRepo}
+}
+
+// ListPromotions возвращает акции с пагинацией, новые первыми
+func (s *PromotionService) ListPromotions(ctx context.Context, page, pageSize int) ([]*entity.Promotion, int, error) {
+	offset := (page - 1) * pageSize
+
+	promotions, err := s.promotionRepo.List(ctx, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.promotionRepo.Count(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return promotions, total, nil
+}
+
+// GetPromotion возвращает акцию по ID
+func (s *PromotionService) GetPromotion(ctx context.Context, id int) (*entity.Promotion, error) {
+	p, err := s.promotionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("%w: %d", errors.ErrPromotionNotFound, id)
+	}
+	return p, nil
+}
+
+// CreatePromotion создает акцию
+func (s *PromotionService) CreatePromotion(ctx context.Context, p *entity.Promotion) error {
+	if err := preparePromotion(p); err != nil {
+		return err
+	}
+	return s.promotionRepo.Create(ctx, p)
+}
+
+// UpdatePromotion обновляет акцию; скидки уже оформленных заказов не пересчитываются
+func (s *PromotionService) UpdatePromotion(ctx context.Context, p *entity.Promotion) error {
+	if err := preparePromotion(p); err != nil {
+		return err
+	}
+	return s.promotionRepo.Update(ctx, p)
+}
+
+// DeletePromotion удаляет акцию с ее промокодами. Чтобы сохранить статистику использований, акцию лучше выключить.
+func (s *PromotionService) DeletePromotion(ctx context.Context, id int) error {
+	return s.promotionRepo.Delete(ctx, id)
+}
+
+// ListPromoCodes возвращает промокоды акции
+func (s *PromotionService) ListPromoCodes(ctx context.Context, promotionID int) ([]*entity.PromoCode, error) {
+	if _, err := s.GetPromotion(ctx, promotionID); err != nil {
+		return nil, err
+	}
+	return s.promotionRepo.ListCodes(ctx, promotionID)
+}
+
+// CreatePromoCodes добавляет промокоды акции, которая применяется по коду. Коды приводятся к верхнему регистру.
+func (s *PromotionService) CreatePromoCodes(ctx context.Context, promotionID int, batch PromoCodeBatch) ([]*entity.PromoCode, error) {
+	p, err := s.GetPromotion(ctx, promotionID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.RequiresCode {
+		return nil, fmt.Errorf("%w: promotion %d is applied without a code", errors.ErrInvalidPromotion, promotionID)
+	}
+	if batch.UsageLimit < 0 {
+		return nil, fmt.Errorf("%w: usage limit must not be negative", errors.ErrInvalidPromoCode)
+	}
+
+	values, err := promoCodeValues(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]*entity.PromoCode, 0, len(values))
+	for _, value := range values {
+		codes = append(codes, &entity.PromoCode{PromotionID: promotionID, Code: value, UsageLimit: batch.UsageLimit})
+	}
+	if err := s.promotionRepo.CreateCodes(ctx, codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CheckPromoCode проверяет, что промокод существует, действует и не исчерпан для покупателя userID (0 — гость)
+func (s *PromotionService) CheckPromoCode(ctx context.Context, userID int, code string) error {
+	_, err := s.promotionRepo.Applicable(ctx, userID, code)
+	return err
+}
+
+// ApplyToCart считает скидки корзины по действующим акциям и промокоду code и заполняет Discounts,
+// DiscountTotal, Total и скидки позиций. Недействительный промокод не мешает показать корзину:
+// причина, по которой он не дал скидки, пишется в PromoWarning.
+func (s *PromotionService) ApplyToCart(ctx context.Context, cart *entity.Cart, code string) error {
+	cart.PromoCode = code
+	cart.Discounts = []entity.OrderDiscount{}
+	cart.Total = cart.Subtotal
+	if len(cart.Items) == 0 {
+		return nil
+	}
+
+	promotions, err := s.promotionRepo.Applicable(ctx, cart.UserID, code)
+	if code != "" && (errors.Is(err, errors.ErrPromoCodeNotFound) || errors.Is(err, errors.ErrPromoCodeExhausted)) {
+		cart.PromoWarning = "Промокод не найден или истек"
+		if errors.Is(err, errors.ErrPromoCodeExhausted) {
+			cart.PromoWarning = "Промокод уже использован"
+		}
+		promotions, err = s.promotionRepo.Applicable(ctx, cart.UserID, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := make([]entity.DiscountLine, len(cart.Items))
+	for i, item := range cart.Items {
+		lines[i] = entity.DiscountLine{ProductID: item.ProductID, CategoryID: item.CategoryID, Amount: item.Subtotal}
+	}
+	cart.Discounts = entity.ApplyPromotions(lines, promotions)
+	for i := range cart.Items {
+		cart.Items[i].Discount = lines[i].Discount
+	}
+
+	codeApplied := false
+	for _, discount := range cart.Discounts {
+		cart.DiscountTotal += discount.Amount
+		codeApplied = codeApplied || discount.Code != ""
+	}
+	cart.DiscountTotal = math.Round(cart.DiscountTotal*100) / 100
+	cart.Total = math.Round((cart.Subtotal-cart.DiscountTotal)*100) / 100
+
+	if code != "" && cart.PromoWarning == "" && !codeApplied {
+		cart.PromoWarning = "Промокод не действует на товары в корзине"
+		for _, p := range promotions {
+			if p.Code != nil && p.MinTotal > 0 {
+				cart.PromoWarning = fmt.Sprintf("Промокод действует на покупку от %s", formatMoney(p.MinTotal))
+			}
+		}
+	}
+	return nil
+}
+
+// preparePromotion нормализует поля акции и проверяет, что они согласованы
+func preparePromotion(p *entity.Promotion) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Value = math.Round(p.Value*100) / 100
+	p.MinTotal = math.Round(p.MinTotal*100) / 100
+
+	switch {
+	case p.Name == "" || utf8.RuneCountInString(p.Name) > 255:
+		return fmt.Errorf("%w: name must be 1-255 characters", errors.ErrInvalidPromotion)
+	case !p.Type.IsValid():
+		return fmt.Errorf("%w: unknown type %q", errors.ErrInvalidPromotion, p.Type)
+	case p.Value <= 0:
+		return fmt.Errorf("%w: value must be positive", errors.ErrInvalidPromotion)
+	case p.Type == entity.DiscountPercent && p.Value > 100:
+		return fmt.Errorf("%w: percent must not exceed 100", errors.ErrInvalidPromotion)
+	case !p.Scope.IsValid():
+		return fmt.Errorf("%w: unknown scope %q", errors.ErrInvalidPromotion, p.Scope)
+	case (p.Scope == entity.PromotionScopeCategory) != (p.CategoryID > 0):
+		return fmt.Errorf("%w: category_id is required for category scope only", errors.ErrInvalidPromotion)
+	case (p.Scope == entity.PromotionScopeProduct) != (p.ProductID > 0):
+		return fmt.Errorf("%w: product_id is required for product scope only", errors.ErrInvalidPromotion)
+	case p.MinTotal < 0 || p.UsageLimit < 0 || p.PerUserLimit < 0:
+		return fmt.Errorf("%w: min total and limits must not be negative", errors.ErrInvalidPromotion)
+	case p.StartsAt != nil && p.EndsAt != nil && !p.StartsAt.Before(*p.EndsAt):
+		return fmt.Errorf("%w: starts_at must be before ends_at", errors.ErrInvalidPromotion)
+	}
+	return nil
+}
+
+// promoCodeValues возвращает нормализованные коды партии: явные или сгенерированные
+func promoCodeValues(batch PromoCodeBatch) ([]string, error) {
+	if (len(batch.Codes) > 0) == (batch.Count > 0) {
+		return nil, fmt.Errorf("%w: either codes or count is required", errors.ErrInvalidPromoCode)
+	}
+	if len(batch.Codes) > maxPromoCodesBatch || batch.Count > maxPromoCodesBatch {
+		return nil, fmt.Errorf("%w: at most %d codes per request", errors.ErrInvalidPromoCode, maxPromoCodesBatch)
+	}
+
+	if len(batch.Codes) > 0 {
+		values := make([]string, 0, len(batch.Codes))
+		seen := make(map[string]bool, len(batch.Codes))
+		for _, code := range batch.Codes {
+			code = entity.NormalizePromoCode(code)
+			if !promoCodePattern.MatchString(code) {
+				return nil, fmt.Errorf("%w: %q", errors.ErrInvalidPromoCode, code)
+			}
+			if seen[code] {
+				return nil, fmt.Errorf("%w: %s", errors.ErrPromoCodeExists, code)
+			}
+			seen[code] = true
+			values = append(values, code)
+		}
+		return values, nil
+	}
+
+	prefix := entity.NormalizePromoCode(batch.Prefix)
+	if prefix != "" && !promoCodePattern.MatchString(prefix+strings.Repeat("A", generatedPromoCodeLength)) {
+		return nil, fmt.Errorf("%w: prefix %q", errors.ErrInvalidPromoCode, prefix)
+	}
+
+	values := make([]string, 0, batch.Count)
+	seen := make(map[string]bool, batch.Count)
+	for len(values) < batch.Count {
+		code, err := generatePromoCode(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[code] {
+			seen[code] = true
+			values = append(values, code)
+		}
+	}
+	return values, nil
+}
+
+// generatePromoCode генерирует случайный код с префиксом
+func generatePromoCode(prefix string) (string, error) {
+	buf := make([]byte, generatedPromoCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate promo code: %w", err)
+	}
+	for i, b := range buf {
+		buf[i] = promoCodeAlphabet[int(b)%len(promoCodeAlphabet)]
+	}
+	return prefix + string(buf), nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestPreparePromotion(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	valid := func() *entity.Promotion {
+		return &entity.Promotion{Name: " Весна ", Type: entity.DiscountPercent, Value: 10.004, Scope: entity.PromotionScopeCart}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(p *entity.Promotion)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(p *entity.Promotion) {}},
+		{name: "category scope", modify: func(p *entity.Promotion) { p.Scope, p.CategoryID = entity.PromotionScopeCategory, 3 }},
+		{name: "empty name", modify: func(p *entity.Promotion) { p.Name = "  " }, wantErr: true},
+		{name: "unknown type", modify: func(p *entity.Promotion) { p.Type = "gift" }, wantErr: true},
+		{name: "zero value", modify: func(p *entity.Promotion) { p.Value = 0.001 }, wantErr: true},
+		{name: "percent above 100", modify: func(p *entity.Promotion) { p.Value = 101 }, wantErr: true},
+		{name: "category without id", modify: func(p *entity.Promotion) { p.Scope = entity.PromotionScopeCategory }, wantErr: true},
+		{name: "product id on cart scope", modify: func(p *entity.Promotion) { p.ProductID = 5 }, wantErr: true},
+		{name: "negative limit", modify: func(p *entity.Promotion) { p.PerUserLimit = -1 }, wantErr: true},
+		{name: "ends before start", modify: func(p *entity.Promotion) { p.StartsAt, p.EndsAt = &later, &now }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid()
+			tt.modify(p)
+			err := preparePromotion(p)
+			if tt.wantErr {
+				if !errors.Is(err, errors.ErrInvalidPromotion) {
+					t.Fatalf("preparePromotion() error = %v, want %v", err, errors.ErrInvalidPromotion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("preparePromotion() error = %v", err)
+			}
+			if p.Name != "Весна" || p.Value != 10 {
+				t.Errorf("preparePromotion() name %q value %v, want normalized", p.Name, p.Value)
+			}
+		})
+	}
+}
+
+func TestPromoCodeValues(t *testing.T) {
+	got, err := promoCodeValues(PromoCodeBatch{Codes: []string{" spring-24 ", "VIP_1"}})
+	if err != nil {
+		t.Fatalf("promoCodeValues(codes) error = %v", err)
+	}
+	if want := []string{"SPRING-24", "VIP_1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("promoCodeValues(codes) = %v, want %v", got, want)
+	}
+
+	generated, err := promoCodeValues(PromoCodeBatch{Count: 50, Prefix: "sale"})
+	if err != nil {
+		t.Fatalf("promoCodeValues(count) error = %v", err)
+	}
+	seen := make(map[string]bool, len(generated))
+	for _, code := range generated {
+		if !strings.HasPrefix(code, "SALE") || len(code) != len("SALE")+generatedPromoCodeLength || !promoCodePattern.MatchString(code) {
+			t.Errorf("generated code %q, want SALE and %d characters", code, generatedPromoCodeLength)
+		}
+		if seen[code] {
+			t.Errorf("generated code %q twice", code)
+		}
+		seen[code] = true
+	}
+	if len(generated) != 50 {
+		t.Errorf("promoCodeValues(count) = %d codes, want 50", len(generated))
+	}
+
+	tests := []struct {
+		name  string
+		batch PromoCodeBatch
+		want  error
+	}{
+		{name: "neither codes nor count", batch: PromoCodeBatch{}, want: errors.ErrInvalidPromoCode},
+		{name: "both codes and count", batch: PromoCodeBatch{Codes: []string{"ABC"}, Count: 1}, want: errors.ErrInvalidPromoCode},
+		{name: "too many", batch: PromoCodeBatch{Count: maxPromoCodesBatch + 1}, want: errors.ErrInvalidPromoCode},
+		{name: "bad characters", batch: PromoCodeBatch{Codes: []string{"скидка"}}, want: errors.ErrInvalidPromoCode},
+		{name: "too short", batch: PromoCodeBatch{Codes: []string{"AB"}}, want: errors.ErrInvalidPromoCode},
+		{name: "duplicate after normalization", batch: PromoCodeBatch{Codes: []string{"abc", "ABC"}}, want: errors.ErrPromoCodeExists},
+		{name: "bad prefix", batch: PromoCodeBatch{Count: 1, Prefix: "no spaces"}, want: errors.ErrInvalidPromoCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := promoCodeValues(tt.batch); !errors.Is(err, tt.want) {
+				t.Errorf("promoCodeValues() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
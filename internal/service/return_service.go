@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+type ReturnService struct {
+	returnRepo     *postgres.ReturnRepo
+	orderService   *OrderService
+	paymentService *PaymentService
+	productService *ProductService
+	producer       *kafka.Producer
+}
+
+func NewReturnService(returnRepo *postgres.ReturnRepo, orderService *OrderService, paymentService *PaymentService,
+	productService *ProductService, producer *kafka.Producer) *ReturnService {
+	return &ReturnService{
+		returnRepo:     returnRepo,
+		orderService:   orderService,
+		paymentService: paymentService,
+		productService: productService,
+		producer:       producer,
+	}
+}
+
+// RequestReturn создает запрос на возврат позиций доставленного заказа пользователя.
+// Повторяющиеся позиции складываются.
+func (s *ReturnService) RequestReturn(ctx context.Context, userID, orderID int, reason entity.ReturnReason,
+	comment string, items []entity.OrderReturnItem) (*entity.OrderReturn, error) {
+	if !reason.IsValid() {
+		return nil, fmt.Errorf("%w: unknown reason %q", errors.ErrInvalidReturn, reason)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no items", errors.ErrInvalidReturn)
+	}
+
+	merged := make([]entity.OrderReturnItem, 0, len(items))
+	index := make(map[int]int, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return nil, errors.ErrInvalidQuantity
+		}
+		if i, ok := index[item.OrderItemID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.OrderItemID] = len(merged)
+		merged = append(merged, entity.OrderReturnItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	// GetOrder проверяет, что заказ принадлежит пользователю
+	if _, err := s.orderService.GetOrder(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+
+	ret := &entity.OrderReturn{
+		OrderID: orderID,
+		UserID:  userID,
+		Status:  entity.ReturnRequested,
+		Reason:  reason,
+		Comment: comment,
+		Items:   merged,
+	}
+	if err := s.returnRepo.Create(ctx, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListOrderReturns возвращает возвраты заказа пользователя
+func (s *ReturnService) ListOrderReturns(ctx context.Context, userID, orderID int) ([]*entity.OrderReturn, error) {
+	if _, err := s.orderService.GetOrder(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+	return s.returnRepo.ListByOrder(ctx, orderID)
+}
+
+// ListReturns возвращает возвраты для админки; пустой status — все возвраты
+func (s *ReturnService) ListReturns(ctx context.Context, status entity.ReturnStatus, page, pageSize int) ([]*entity.OrderReturn, int, error) {
+	if status != "" && !status.IsValid() {
+		return nil, 0, fmt.Errorf("%w: unknown status %q", errors.ErrInvalidReturn, status)
+	}
+	offset := (page - 1) * pageSize
+
+	returns, err := s.returnRepo.List(ctx, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.returnRepo.Count(ctx, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return returns, total, nil
+}
+
+// GetReturn возвращает возврат для админки
+func (s *ReturnService) GetReturn(ctx context.Context, id int) (*entity.OrderReturn, error) {
+	ret, err := s.returnRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, fmt.Errorf("%w: %d", errors.ErrReturnNotFound, id)
+	}
+	return ret, nil
+}
+
+// Approve одобряет возврат: возвращает на склад выбранные позиции и деньги покупателю.
+// Если провайдер не вернул деньги, возврат остается approved и повторный вызов попробует снова.
+func (s *ReturnService) Approve(ctx context.Context, id int, decision entity.ReturnDecision, actorID int) (*entity.OrderReturn, error) {
+	ret, err := s.GetReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ret.Status {
+	case entity.ReturnRequested:
+		if err := s.approve(ctx, ret, decision, actorID); err != nil {
+			return nil, err
+		}
+	case entity.ReturnApproved:
+		// Повтор после сбоя возврата денег: решение уже принято, остатки проведены
+	default:
+		return nil, fmt.Errorf("%w: return is %s", errors.ErrInvalidReturnTransition, ret.Status)
+	}
+
+	if err := s.complete(ctx, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// approve проверяет решение поддержки и проводит его
+func (s *ReturnService) approve(ctx context.Context, ret *entity.OrderReturn, decision entity.ReturnDecision, actorID int) error {
+	inReturn := make(map[int]bool, len(ret.Items))
+	for _, item := range ret.Items {
+		inReturn[item.OrderItemID] = true
+	}
+	for _, id := range decision.RestockItems {
+		if !inReturn[id] {
+			return fmt.Errorf("%w: order item %d is not in return %d", errors.ErrInvalidReturn, id, ret.ID)
+		}
+	}
+
+	if decision.RefundAmount != nil {
+		amount := math.Round(*decision.RefundAmount*100) / 100
+		if amount < 0 {
+			return fmt.Errorf("%w: %.2f", errors.ErrInvalidRefund, amount)
+		}
+		ret.RefundAmount = amount
+	}
+	if ret.RefundAmount > 0 {
+		refundable, err := s.paymentService.Refundable(ctx, ret.OrderID)
+		if err != nil {
+			return err
+		}
+		if ret.RefundAmount > refundable {
+			return fmt.Errorf("%w: %.2f, only %.2f can be refunded", errors.ErrInvalidRefund, ret.RefundAmount, refundable)
+		}
+	}
+
+	if err := s.returnRepo.Approve(ctx, ret, decision, actorID); err != nil {
+		return err
+	}
+
+	var productIDs []int
+	for _, item := range ret.Items {
+		if item.Restock {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	if len(productIDs) > 0 {
+		s.productService.StockChanged(ctx, productIDs...)
+	}
+	return nil
+}
+
+// complete возвращает деньги по одобренному возврату и закрывает его
+func (s *ReturnService) complete(ctx context.Context, ret *entity.OrderReturn) error {
+	ok, err := s.returnRepo.UpdateStatus(ctx, ret, entity.ReturnApproved, entity.ReturnCompleted)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: return %d is being completed", errors.ErrInvalidReturnTransition, ret.ID)
+	}
+
+	if ret.RefundAmount > 0 {
+		if _, err := s.paymentService.Refund(ctx, ret.OrderID, ret.RefundAmount); err != nil {
+			if _, rollbackErr := s.returnRepo.UpdateStatus(ctx, ret, entity.ReturnCompleted, entity.ReturnApproved); rollbackErr != nil {
+				log.Printf("Rollback return %d status error: %v", ret.ID, rollbackErr)
+			}
+			return err
+		}
+	}
+
+	items := make([]map[string]interface{}, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		items = append(items, map[string]interface{}{
+			"order_item_id": item.OrderItemID,
+			"product_id":    item.ProductID,
+			"variant_id":    item.VariantID,
+			"quantity":      item.Quantity,
+			"restock":       item.Restock,
+		})
+	}
+	go s.producer.SendEvent(context.Background(), kafka.EventOrderReturned, map[string]interface{}{
+		"return_id":     ret.ID,
+		"order_id":      ret.OrderID,
+		"user_id":       ret.UserID,
+		"reason":        ret.Reason,
+		"refund_amount": ret.RefundAmount,
+		"items":         items,
+	})
+	return nil
+}
+
+// Reject отклоняет запрошенный возврат
+func (s *ReturnService) Reject(ctx context.Context, id, actorID int, comment string) (*entity.OrderReturn, error) {
+	ret, err := s.GetReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.returnRepo.Reject(ctx, ret, actorID, comment); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/payment"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// flakyProvider — фейковый провайдер, который отказывает в возврате денег, пока задан refundErr
+type flakyProvider struct {
+	*payment.FakeProvider
+	refundErr error
+}
+
+func (p *flakyProvider) Refund(ctx context.Context, intent *entity.PaymentIntent, amount float64) error {
+	if p.refundErr != nil {
+		return p.refundErr
+	}
+	return p.FakeProvider.Refund(ctx, intent, amount)
+}
+
+// returnTest — сервис возвратов поверх тестовой базы и доставленного оплаченного заказа
+type returnTest struct {
+	*paymentTest
+	flaky   *flakyProvider
+	returns *ReturnService
+	order   *entity.Order
+}
+
+func newReturnTest(t *testing.T) *returnTest {
+	t.Helper()
+	p := newPaymentTest(t)
+	flaky := &flakyProvider{FakeProvider: p.provider}
+	p.payments = NewPaymentService(postgres.NewPaymentRepo(p.db), p.orders, p.producer, flaky.Name(), flaky)
+
+	ctx := context.Background()
+	user := p.createTestUser(t)
+	chair := p.createTestProduct(t, "Стул", 5000, 4)
+	table := p.createTestProduct(t, "Стол", 20000, 1)
+	order := p.createTestOrder(t, user.ID,
+		entity.OrderItem{ProductID: chair.ID, Quantity: 4},
+		entity.OrderItem{ProductID: table.ID, Quantity: 1},
+	)
+
+	intent, err := p.payments.CreatePayment(ctx, user.ID, order.ID, "")
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	if err := p.webhook(intent, entity.PaymentSucceeded, order.Total); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	for _, status := range []entity.OrderStatus{entity.OrderStatusShipped, entity.OrderStatusDelivered} {
+		if _, err := p.orders.ChangeStatus(ctx, order.ID, status, 0, ""); err != nil {
+			t.Fatalf("ChangeStatus(%s) error = %v", status, err)
+		}
+	}
+
+	return &returnTest{
+		paymentTest: p,
+		flaky:       flaky,
+		returns:     NewReturnService(postgres.NewReturnRepo(p.db), p.orders, p.payments, p.products, p.producer),
+		order:       order,
+	}
+}
+
+// requestReturn возвращает все позиции заказа
+func (r *returnTest) requestReturn(t *testing.T) *entity.OrderReturn {
+	t.Helper()
+	items := make([]entity.OrderReturnItem, 0, len(r.order.Items))
+	for _, item := range r.order.Items {
+		items = append(items, entity.OrderReturnItem{OrderItemID: item.ID, Quantity: item.Quantity})
+	}
+	ret, err := r.returns.RequestReturn(context.Background(), r.order.UserID, r.order.ID, entity.ReturnDefective, "", items)
+	if err != nil {
+		t.Fatalf("RequestReturn() error = %v", err)
+	}
+	return ret
+}
+
+// stock возвращает остаток товара
+func (r *returnTest) stock(t *testing.T, productID int) int {
+	t.Helper()
+	product, err := r.products.GetProduct(context.Background(), productID)
+	if err != nil {
+		t.Fatalf("GetProduct() error = %v", err)
+	}
+	return product.Stock
+}
+
+func TestReturnServiceApprovePartialRestock(t *testing.T) {
+	r := newReturnTest(t)
+	ctx := context.Background()
+	chair, table := r.order.Items[0], r.order.Items[1]
+	ret := r.requestReturn(t)
+	if ret.RefundAmount != r.order.Total {
+		t.Fatalf("RequestReturn() refund amount = %v, want %v", ret.RefundAmount, r.order.Total)
+	}
+
+	// Позиция чужого возврата не принимается
+	if _, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{RestockItems: []int{chair.ID + table.ID}}, 0); !errors.Is(err, errors.ErrInvalidReturn) {
+		t.Errorf("Approve(foreign item) error = %v, want %v", err, errors.ErrInvalidReturn)
+	}
+
+	// На склад возвращаются только стулья: стол пришел сломанным
+	got, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{RestockItems: []int{chair.ID}}, 0)
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if got.Status != entity.ReturnCompleted {
+		t.Errorf("status = %s, want %s", got.Status, entity.ReturnCompleted)
+	}
+	if stock := r.stock(t, chair.ProductID); stock != chair.Quantity {
+		t.Errorf("chair stock = %d, want %d", stock, chair.Quantity)
+	}
+	if stock := r.stock(t, table.ProductID); stock != 0 {
+		t.Errorf("table stock = %d, want 0", stock)
+	}
+
+	// Рассмотренный возврат нельзя одобрить повторно
+	if _, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{}, 0); !errors.Is(err, errors.ErrInvalidReturnTransition) {
+		t.Errorf("Approve(completed) error = %v, want %v", err, errors.ErrInvalidReturnTransition)
+	}
+}
+
+func TestReturnServiceApproveCapsRefund(t *testing.T) {
+	r := newReturnTest(t)
+	ctx := context.Background()
+	ret := r.requestReturn(t)
+
+	// Часть денег уже вернули вне возврата
+	if _, err := r.payments.Refund(ctx, r.order.ID, 5000); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+
+	over := r.order.Total
+	if _, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{RefundAmount: &over}, 0); !errors.Is(err, errors.ErrInvalidRefund) {
+		t.Errorf("Approve(over refundable) error = %v, want %v", err, errors.ErrInvalidRefund)
+	}
+	negative := -1.0
+	if _, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{RefundAmount: &negative}, 0); !errors.Is(err, errors.ErrInvalidRefund) {
+		t.Errorf("Approve(negative) error = %v, want %v", err, errors.ErrInvalidRefund)
+	}
+	if got, err := r.returns.GetReturn(ctx, ret.ID); err != nil || got.Status != entity.ReturnRequested {
+		t.Fatalf("return after rejected decisions = %v, %v, want requested", got, err)
+	}
+
+	rest := r.order.Total - 5000
+	got, err := r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{RefundAmount: &rest}, 0)
+	if err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if got.RefundAmount != rest {
+		t.Errorf("refund amount = %v, want %v", got.RefundAmount, rest)
+	}
+	if refundable, err := r.payments.Refundable(ctx, r.order.ID); err != nil || refundable != 0 {
+		t.Errorf("Refundable() = %v, %v, want 0", refundable, err)
+	}
+}
+
+func TestReturnServiceApproveRetriesRefund(t *testing.T) {
+	r := newReturnTest(t)
+	ctx := context.Background()
+	ret := r.requestReturn(t)
+	chair := r.order.Items[0]
+
+	r.flaky.refundErr = stderrors.New("provider unavailable")
+	decision := entity.ReturnDecision{RestockItems: []int{chair.ID}}
+	if _, err := r.returns.Approve(ctx, ret.ID, decision, 0); !errors.Is(err, errors.ErrPaymentProviderFailed) {
+		t.Fatalf("Approve(provider failure) error = %v, want %v", err, errors.ErrPaymentProviderFailed)
+	}
+
+	// Возврат остается одобренным, остатки проведены, деньги не списаны с платежа
+	got, err := r.returns.GetReturn(ctx, ret.ID)
+	if err != nil {
+		t.Fatalf("GetReturn() error = %v", err)
+	}
+	if got.Status != entity.ReturnApproved {
+		t.Errorf("status after failure = %s, want %s", got.Status, entity.ReturnApproved)
+	}
+	if stock := r.stock(t, chair.ProductID); stock != chair.Quantity {
+		t.Errorf("chair stock after failure = %d, want %d", stock, chair.Quantity)
+	}
+	if refundable, err := r.payments.Refundable(ctx, r.order.ID); err != nil || refundable != r.order.Total {
+		t.Errorf("Refundable() after failure = %v, %v, want %v", refundable, err, r.order.Total)
+	}
+
+	// Повтор возвращает деньги и не проводит остатки второй раз
+	r.flaky.refundErr = nil
+	got, err = r.returns.Approve(ctx, ret.ID, entity.ReturnDecision{}, 0)
+	if err != nil {
+		t.Fatalf("Approve(retry) error = %v", err)
+	}
+	if got.Status != entity.ReturnCompleted {
+		t.Errorf("status after retry = %s, want %s", got.Status, entity.ReturnCompleted)
+	}
+	if stock := r.stock(t, chair.ProductID); stock != chair.Quantity {
+		t.Errorf("chair stock after retry = %d, want %d", stock, chair.Quantity)
+	}
+	intent, err := postgres.NewPaymentRepo(r.db).GetLatestByOrder(ctx, r.order.ID, entity.PaymentRefunded)
+	if err != nil || intent == nil {
+		t.Fatalf("get refunded payment: %v", err)
+	}
+	if intent.RefundedAmount != r.order.Total {
+		t.Errorf("refunded amount = %v, want %v", intent.RefundedAmount, r.order.Total)
+	}
+}
+
+func TestRequestReturnRejectsInvalid(t *testing.T) {
+	s := &ReturnService{}
+	items := []entity.OrderReturnItem{{OrderItemID: 1, Quantity: 1}}
+	tests := []struct {
+		name   string
+		reason entity.ReturnReason
+		items  []entity.OrderReturnItem
+		want   error
+	}{
+		{name: "unknown reason", reason: "bored", items: items, want: errors.ErrInvalidReturn},
+		{name: "no items", reason: entity.ReturnDefective, want: errors.ErrInvalidReturn},
+		{name: "zero quantity", reason: entity.ReturnDefective, items: []entity.OrderReturnItem{{OrderItemID: 1}}, want: errors.ErrInvalidQuantity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.RequestReturn(context.Background(), 1, 1, tt.reason, "", tt.items); !errors.Is(err, tt.want) {
+				t.Errorf("RequestReturn() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/kafka"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// StockAlertService следит за остатками после их изменения: сообщает о низком остатке
+// (product.low_stock) и уведомляет подписчиков о поступлении товара (product.back_in_stock)
+type StockAlertService struct {
+	alertRepo        *postgres.StockAlertRepo
+	productRepo      *postgres.ProductRepo
+	producer         *kafka.Producer
+	defaultThreshold int
+}
+
+func NewStockAlertService(alertRepo *postgres.StockAlertRepo, productRepo *postgres.ProductRepo, producer *kafka.Producer,
+	defaultThreshold int) *StockAlertService {
+	return &StockAlertService{
+		alertRepo:        alertRepo,
+		productRepo:      productRepo,
+		producer:         producer,
+		defaultThreshold: defaultThreshold,
+	}
+}
+
+// Check проверяет остатки товаров после изменения. Ошибки только логируются:
+// изменение остатка уже сохранено, а недошедшее событие не должно его откатывать.
+func (s *StockAlertService) Check(ctx context.Context, productIDs ...int) {
+	if len(productIDs) == 0 {
+		return
+	}
+
+	lowStock, err := s.alertRepo.MarkLowStock(ctx, productIDs, s.defaultThreshold)
+	if err != nil {
+		log.Printf("Check low stock error: %v", err)
+	}
+	for _, p := range lowStock {
+		go s.producer.SendEvent(context.Background(), kafka.EventProductLowStock, map[string]interface{}{
+			"product_id": p.ProductID,
+			"name":       p.Name,
+			"sku":        p.SKU,
+			"stock":      p.Stock,
+			"threshold":  p.Threshold,
+		})
+	}
+
+	subs, err := s.alertRepo.NotifyAvailable(ctx, productIDs)
+	if err != nil {
+		log.Printf("Notify stock subscriptions error: %v", err)
+	}
+	for _, sub := range subs {
+		go s.producer.SendEvent(context.Background(), kafka.EventProductBackInStock, map[string]interface{}{
+			"subscription_id": sub.ID,
+			"user_id":         sub.UserID,
+			"email":           sub.Email,
+			"product_id":      sub.ProductID,
+			"variant_id":      sub.VariantID,
+			"product_name":    sub.ProductName,
+		})
+	}
+}
+
+// SetThreshold задает порог низкого остатка товара (nil — порог по умолчанию) и сразу проверяет остаток
+func (s *StockAlertService) SetThreshold(ctx context.Context, productID int, threshold *int) error {
+	if threshold != nil && *threshold < 0 {
+		return errors.ErrInvalidQuantity
+	}
+	if err := s.alertRepo.SetThreshold(ctx, productID, threshold); err != nil {
+		return err
+	}
+
+	s.Check(ctx, productID)
+	return nil
+}
+
+// ListLowStock возвращает товары с остатком ниже порога
+func (s *StockAlertService) ListLowStock(ctx context.Context) ([]*entity.LowStockProduct, error) {
+	return s.alertRepo.ListLowStock(ctx, s.defaultThreshold)
+}
+
+// Subscribe ставит пользователя в очередь на уведомление о поступлении товара.
+// Подписаться можно только на товар, которого нет в продаже (свободный остаток без резервов равен нулю,
+// как в корзине); у товара с вариантами — на конкретный вариант.
+func (s *StockAlertService) Subscribe(ctx context.Context, userID, productID, variantID int) (*entity.StockSubscription, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.ErrProductNotFound
+	}
+
+	available := product.Available
+	if variantID != 0 {
+		variant := product.Variant(variantID)
+		if variant == nil {
+			return nil, errors.ErrVariantNotFound
+		}
+		available = variant.Available
+	} else if len(product.Variants) > 0 {
+		return nil, errors.ErrVariantRequired
+	}
+	if available > 0 {
+		return nil, errors.ErrProductInStock
+	}
+
+	sub := &entity.StockSubscription{UserID: userID, ProductID: productID, VariantID: variantID}
+	if err := s.alertRepo.Subscribe(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+func TestSetThresholdRejectsNegative(t *testing.T) {
+	s := &StockAlertService{}
+	threshold := -1
+	if err := s.SetThreshold(context.Background(), 1, &threshold); !errors.Is(err, errors.ErrInvalidQuantity) {
+		t.Errorf("SetThreshold(-1) error = %v, want %v", err, errors.ErrInvalidQuantity)
+	}
+}
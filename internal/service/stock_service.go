@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+// maxStockReferenceLength — длина stock_movements.reference
+const maxStockReferenceLength = 100
+
+type StockService struct {
+	stockRepo      *postgres.StockRepo
+	productService *ProductService
+}
+
+func NewStockService(stockRepo *postgres.StockRepo, productService *ProductService) *StockService {
+	return &StockService{
+		stockRepo:      stockRepo,
+		productService: productService,
+	}
+}
+
+// PostMovement проводит движение остатка. Для receipt, return, sale и write_off передается
+// положительное количество, знак определяется причиной; adjustment принимает изменение со знаком.
+func (s *StockService) PostMovement(ctx context.Context, m *entity.StockMovement) error {
+	if !m.Reason.IsValid() {
+		return errors.ErrInvalidStockMovement
+	}
+	if sign := m.Reason.Sign(); sign != 0 {
+		if m.Quantity <= 0 {
+			return errors.ErrInvalidQuantity
+		}
+		m.Quantity *= sign
+	} else if m.Quantity == 0 {
+		return errors.ErrInvalidQuantity
+	}
+
+	if err := normalizeMovement(m); err != nil {
+		return err
+	}
+
+	if err := s.stockRepo.Post(ctx, m); err != nil {
+		return err
+	}
+
+	s.productService.StockChanged(ctx, m.ProductID)
+
+	return nil
+}
+
+// SetStock устанавливает остаток товара (или варианта) по результату пересчета.
+// Разница записывается в журнал корректировкой; если остаток не изменился, возвращается nil вместо движения.
+func (s *StockService) SetStock(ctx context.Context, m *entity.StockMovement, stock int) (*entity.StockMovement, error) {
+	if stock < 0 {
+		return nil, errors.ErrInvalidQuantity
+	}
+	m.Reason = entity.StockAdjustment
+	if err := normalizeMovement(m); err != nil {
+		return nil, err
+	}
+
+	if err := s.stockRepo.Set(ctx, m, stock); err != nil {
+		return nil, err
+	}
+	if m.ID == 0 {
+		return nil, nil
+	}
+
+	s.productService.StockChanged(ctx, m.ProductID)
+
+	return m, nil
+}
+
+// ListMovements возвращает историю движений товара (или его варианта, или по одному складу) по курсору, новые первыми
+func (s *StockService) ListMovements(ctx context.Context, productID, variantID, warehouseID int, cursor string, limit int) ([]*entity.StockMovement, string, error) {
+	after, err := pagination.Decode(cursor, "", pagination.ValueNone)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// История удаленного варианта остается доступной, поэтому проверяется только товар
+	if _, err := s.productService.GetProduct(ctx, productID); err != nil {
+		return nil, "", err
+	}
+
+	movements, next, err := s.stockRepo.ListByProduct(ctx, productID, variantID, warehouseID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if next == nil {
+		return movements, "", nil
+	}
+	return movements, next.Encode(), nil
+}
+
+// Discrepancies возвращает остатки, которые не сходятся с журналом
+func (s *StockService) Discrepancies(ctx context.Context) ([]*entity.StockDiscrepancy, error) {
+	return s.stockRepo.Discrepancies(ctx)
+}
+
+// Reconcile приводит остатки к журналу и возвращает найденные расхождения
+func (s *StockService) Reconcile(ctx context.Context) ([]*entity.StockDiscrepancy, error) {
+	discrepancies, err := s.stockRepo.Reconcile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		ids = append(ids, d.ProductID)
+	}
+	if len(ids) > 0 {
+		s.productService.StockChanged(ctx, ids...)
+	}
+
+	return discrepancies, nil
+}
+
+// normalizeMovement обрезает пробелы в ссылке и комментарии движения и проверяет длину ссылки
+func normalizeMovement(m *entity.StockMovement) error {
+	m.Reference = strings.TrimSpace(m.Reference)
+	m.Comment = strings.TrimSpace(m.Comment)
+	if utf8.RuneCountInString(m.Reference) > maxStockReferenceLength {
+		return errors.ErrInvalidStockMovement
+	}
+	return nil
+}
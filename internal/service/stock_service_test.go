@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestPostMovementRejectsInvalid(t *testing.T) {
+	s := &StockService{}
+
+	tests := []struct {
+		name     string
+		movement entity.StockMovement
+		want     error
+	}{
+		{name: "unknown reason", movement: entity.StockMovement{ProductID: 1, Quantity: 1, Reason: "gift"}, want: errors.ErrInvalidStockMovement},
+		{name: "negative receipt", movement: entity.StockMovement{ProductID: 1, Quantity: -2, Reason: entity.StockReceipt}, want: errors.ErrInvalidQuantity},
+		{name: "zero sale", movement: entity.StockMovement{ProductID: 1, Reason: entity.StockSale}, want: errors.ErrInvalidQuantity},
+		{name: "zero adjustment", movement: entity.StockMovement{ProductID: 1, Reason: entity.StockAdjustment}, want: errors.ErrInvalidQuantity},
+		{name: "long reference", movement: entity.StockMovement{ProductID: 1, Quantity: 1, Reason: entity.StockReceipt, Reference: strings.Repeat("r", maxStockReferenceLength+1)}, want: errors.ErrInvalidStockMovement},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.movement
+			if err := s.PostMovement(context.Background(), &m); !errors.Is(err, tt.want) {
+				t.Errorf("PostMovement() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetStockRejectsNegative(t *testing.T) {
+	s := &StockService{}
+	if _, err := s.SetStock(context.Background(), &entity.StockMovement{ProductID: 1}, -1); !errors.Is(err, errors.ErrInvalidQuantity) {
+		t.Errorf("SetStock(-1) error = %v, want %v", err, errors.ErrInvalidQuantity)
+	}
+}
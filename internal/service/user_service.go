@@ -3,6 +3,8 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
+	"time"
 
 	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
@@ -12,34 +14,48 @@ import (
 )
 
 type UserService struct {
-	userRepo   *postgres.UserRepo
-	jwtManager *auth.JWTManager
-	producer   *kafka.Producer
+	userRepo    *postgres.UserRepo
+	sessionRepo *postgres.SessionRepo
+	jwtManager  *auth.JWTManager
+	producer    *kafka.Producer
+	rbac        *auth.RBAC
+	refreshTTL  time.Duration
 }
 
-func NewUserService(userRepo *postgres.UserRepo, jwtManager *auth.JWTManager, producer *kafka.Producer) *UserService {
+// AuthTokens — пара токенов: короткоживущий access и ротируемый refresh
+type AuthTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+func NewUserService(userRepo *postgres.UserRepo, sessionRepo *postgres.SessionRepo, jwtManager *auth.JWTManager,
+	producer *kafka.Producer, rbac *auth.RBAC, refreshTTL time.Duration) *UserService {
 	return &UserService{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
-		producer:   producer,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		jwtManager:  jwtManager,
+		producer:    producer,
+		rbac:        rbac,
+		refreshTTL:  refreshTTL,
 	}
 }
 
-func (s *UserService) Register(ctx context.Context, user *entity.User) (string, error) {
+func (s *UserService) Register(ctx context.Context, user *entity.User, userAgent string) (*AuthTokens, error) {
 	existing, err := s.userRepo.GetByEmail(ctx, user.Email)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if existing != nil {
-		return "", errors.ErrUserExists
+		return nil, errors.ErrUserExists
 	}
 
 	if err := user.HashPassword(); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	go s.producer.SendEvent(context.Background(), kafka.EventUserRegistered, map[string]interface{}{
@@ -47,25 +63,213 @@ func (s *UserService) Register(ctx context.Context, user *entity.User) (string,
 		"email":   user.Email,
 	})
 
-	return s.jwtManager.Generate(user.ID, user.Email, user.Role)
+	return s.startSession(ctx, user, userAgent)
 }
 
-func (s *UserService) Login(ctx context.Context, email, password string) (string, *entity.User, error) {
+func (s *UserService) Login(ctx context.Context, email, password, userAgent string) (*AuthTokens, *entity.User, error) {
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 	if user == nil || !user.CheckPassword(password) {
-		return "", nil, errors.ErrInvalidCredentials
+		return nil, nil, errors.ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return nil, nil, errors.ErrUserDisabled
 	}
 
-	token, err := s.jwtManager.Generate(user.ID, user.Email, user.Role)
+	tokens, err := s.startSession(ctx, user, userAgent)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
 
-	return token, user, nil
+	return tokens, user, nil
 }
+
+// Refresh выдает новую пару токенов и ротирует refresh токен.
+// Повторное использование старого refresh токена считается утечкой — сессия удаляется.
+func (s *UserService) Refresh(ctx context.Context, refreshToken string) (*AuthTokens, error) {
+	sessionID, secret, err := auth.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || time.Now().After(session.ExpiresAt) {
+		return nil, errors.ErrInvalidToken
+	}
+
+	hash := auth.HashRefreshSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshTokenHash)) != 1 {
+		if err := s.sessionRepo.Delete(ctx, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.ErrInvalidToken
+	}
+	if user.Disabled {
+		return nil, errors.ErrUserDisabled
+	}
+
+	newSecret, err := auth.NewRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.refreshTTL)
+
+	rotated, err := s.sessionRepo.Rotate(ctx, session.ID, session.RefreshTokenHash, auth.HashRefreshSecret(newSecret), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, errors.ErrInvalidToken
+	}
+
+	return s.issueTokens(user, session.ID, newSecret)
+}
+
+// Logout завершает текущую сессию
+func (s *UserService) Logout(ctx context.Context, sessionID string) error {
+	return s.sessionRepo.Delete(ctx, sessionID)
+}
+
+// LogoutAll завершает все сессии пользователя
+func (s *UserService) LogoutAll(ctx context.Context, userID int) error {
+	return s.sessionRepo.DeleteByUser(ctx, userID)
+}
+
 func (s *UserService) GetProfile(ctx context.Context, userID int) (*entity.User, error) {
 	return s.userRepo.GetByID(ctx, userID)
 }
+
+// ListUsers возвращает страницу пользователей по фильтру и общее количество
+func (s *UserService) ListUsers(ctx context.Context, filter entity.UserFilter, page, pageSize int) ([]*entity.User, int, error) {
+	offset := (page - 1) * pageSize
+
+	users, err := s.userRepo.List(ctx, filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.userRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetUser возвращает пользователя для админки
+func (s *UserService) GetUser(ctx context.Context, id int) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SetRole назначает пользователю роль. Роль зашита в access токен,
+// поэтому сессии пользователя завершаются, чтобы новые права применились сразу.
+func (s *UserService) SetRole(ctx context.Context, actorID, userID int, role string) (*entity.User, error) {
+	if !s.rbac.HasRole(role) {
+		return nil, errors.ErrInvalidRole
+	}
+	if actorID == userID {
+		return nil, errors.ErrCannotModifySelf
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// DisableUser блокирует пользователя и завершает все его сессии
+func (s *UserService) DisableUser(ctx context.Context, actorID, userID int) (*entity.User, error) {
+	if actorID == userID {
+		return nil, errors.ErrCannotModifySelf
+	}
+
+	if err := s.userRepo.SetDisabled(ctx, userID, true); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, userID)
+}
+
+// EnableUser снимает блокировку с пользователя
+func (s *UserService) EnableUser(ctx context.Context, userID int) (*entity.User, error) {
+	if err := s.userRepo.SetDisabled(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	return s.GetUser(ctx, userID)
+}
+
+// RevokeSessions принудительно завершает все сессии пользователя
+func (s *UserService) RevokeSessions(ctx context.Context, userID int) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.sessionRepo.DeleteByUser(ctx, userID)
+}
+
+// startSession создает новую сессию и выдает для нее токены
+func (s *UserService) startSession(ctx context.Context, user *entity.User, userAgent string) (*AuthTokens, error) {
+	secret, err := auth.NewRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &entity.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: auth.HashRefreshSecret(secret),
+		UserAgent:        truncate(userAgent, 500),
+		ExpiresAt:        time.Now().Add(s.refreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session.ID, secret)
+}
+
+func (s *UserService) issueTokens(user *entity.User, sessionID, refreshSecret string) (*AuthTokens, error) {
+	accessToken, err := s.jwtManager.Generate(user.ID, user.Email, user.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: auth.FormatRefreshToken(sessionID, refreshSecret),
+		ExpiresAt:    time.Now().Add(s.jwtManager.TTL()),
+	}, nil
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
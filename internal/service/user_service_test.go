@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres/pgtest"
+)
+
+func TestUserServiceRefusesSelfChanges(t *testing.T) {
+	// Проверки выполняются до обращения к репозиториям, поэтому база не нужна
+	s := NewUserService(nil, nil, nil, nil, auth.NewRBAC(nil), time.Hour)
+	ctx := context.Background()
+
+	if _, err := s.SetRole(ctx, 1, 1, entity.RoleCustomer); !errors.Is(err, errors.ErrCannotModifySelf) {
+		t.Errorf("SetRole(self) error = %v, want %v", err, errors.ErrCannotModifySelf)
+	}
+	if _, err := s.SetRole(ctx, 1, 2, "root"); !errors.Is(err, errors.ErrInvalidRole) {
+		t.Errorf("SetRole(unknown role) error = %v, want %v", err, errors.ErrInvalidRole)
+	}
+	if _, err := s.DisableUser(ctx, 1, 1); !errors.Is(err, errors.ErrCannotModifySelf) {
+		t.Errorf("DisableUser(self) error = %v, want %v", err, errors.ErrCannotModifySelf)
+	}
+}
+
+func TestUserServiceRevokesSessions(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	sessions := postgres.NewSessionRepo(db)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	s := NewUserService(postgres.NewUserRepo(db), sessions, jwtManager, nil, auth.NewRBAC(nil), time.Hour)
+
+	admin := createTestUser(t, db, "admin@example.com", entity.RoleAdmin)
+	user := createTestUser(t, db, "user@example.com", entity.RoleCustomer)
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{name: "role change", change: func() error {
+			_, err := s.SetRole(ctx, admin.ID, user.ID, entity.RoleSupport)
+			return err
+		}},
+		{name: "disable", change: func() error {
+			_, err := s.DisableUser(ctx, admin.ID, user.ID)
+			return err
+		}},
+		{name: "forced logout", change: func() error {
+			return s.RevokeSessions(ctx, user.ID)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.EnableUser(ctx, user.ID); err != nil {
+				t.Fatalf("EnableUser() error = %v", err)
+			}
+			tokens, _, err := s.Login(ctx, user.Email, testPassword, "test")
+			if err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+			if got := authStatus(t, jwtManager, sessions, tokens.AccessToken); got != http.StatusOK {
+				t.Fatalf("status before %s = %d, want %d", tt.name, got, http.StatusOK)
+			}
+
+			if err := tt.change(); err != nil {
+				t.Fatalf("%s error = %v", tt.name, err)
+			}
+
+			if got := authStatus(t, jwtManager, sessions, tokens.AccessToken); got != http.StatusUnauthorized {
+				t.Errorf("access token after %s: status = %d, want %d", tt.name, got, http.StatusUnauthorized)
+			}
+			if _, err := s.Refresh(ctx, tokens.RefreshToken); err == nil {
+				t.Errorf("Refresh() after %s succeeded, want error", tt.name)
+			}
+		})
+	}
+}
+
+func TestUserServiceRejectsDisabledUser(t *testing.T) {
+	db := pgtest.Open(t)
+	ctx := context.Background()
+	users := postgres.NewUserRepo(db)
+	sessions := postgres.NewSessionRepo(db)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	s := NewUserService(users, sessions, jwtManager, nil, auth.NewRBAC(nil), time.Hour)
+
+	user := createTestUser(t, db, "user@example.com", entity.RoleCustomer)
+	tokens, _, err := s.Login(ctx, user.Email, testPassword, "test")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	// Блокировка напрямую в базе, без удаления сессий: доступ должен закрыть сам флаг disabled
+	if err := users.SetDisabled(ctx, user.ID, true); err != nil {
+		t.Fatalf("SetDisabled() error = %v", err)
+	}
+
+	if _, _, err := s.Login(ctx, user.Email, testPassword, "test"); !errors.Is(err, errors.ErrUserDisabled) {
+		t.Errorf("Login() error = %v, want %v", err, errors.ErrUserDisabled)
+	}
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, errors.ErrUserDisabled) {
+		t.Errorf("Refresh() error = %v, want %v", err, errors.ErrUserDisabled)
+	}
+	if got := authStatus(t, jwtManager, sessions, tokens.AccessToken); got != http.StatusUnauthorized {
+		t.Errorf("AuthMiddleware status = %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+const testPassword = "password123"
+
+func createTestUser(t *testing.T, db *sql.DB, email, role string) *entity.User {
+	t.Helper()
+	user := &entity.User{Email: email, Password: testPassword, Name: "Test", Role: role}
+	if err := user.HashPassword(); err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if err := postgres.NewUserRepo(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// authStatus прогоняет запрос с access токеном через AuthMiddleware
+func authStatus(t *testing.T, jwtManager *auth.JWTManager, sessions auth.SessionChecker, accessToken string) int {
+	t.Helper()
+	handler := auth.AuthMiddleware(jwtManager, sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/infra/postgres"
+)
+
+type WarehouseService struct {
+	warehouseRepo *postgres.WarehouseRepo
+}
+
+func NewWarehouseService(warehouseRepo *postgres.WarehouseRepo) *WarehouseService {
+	return &WarehouseService{warehouseRepo: warehouseRepo}
+}
+
+// ListWarehouses возвращает все склады и шоурумы, включая неактивные
+func (s *WarehouseService) ListWarehouses(ctx context.Context) ([]*entity.Warehouse, error) {
+	return s.warehouseRepo.List(ctx, false)
+}
+
+// ListPickupPoints возвращает активные точки, где можно забрать заказ
+func (s *WarehouseService) ListPickupPoints(ctx context.Context) ([]*entity.Warehouse, error) {
+	return s.warehouseRepo.List(ctx, true)
+}
+
+// GetWarehouse возвращает склад по ID
+func (s *WarehouseService) GetWarehouse(ctx context.Context, id int) (*entity.Warehouse, error) {
+	w, err := s.warehouseRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, errors.ErrWarehouseNotFound
+	}
+	return w, nil
+}
+
+// CreateWarehouse создает склад или шоурум
+func (s *WarehouseService) CreateWarehouse(ctx context.Context, w *entity.Warehouse) error {
+	if err := prepareWarehouse(w); err != nil {
+		return err
+	}
+	return s.warehouseRepo.Create(ctx, w)
+}
+
+// UpdateWarehouse обновляет склад. Остатки неактивного склада сохраняются, но не продаются и не показываются в наличии.
+func (s *WarehouseService) UpdateWarehouse(ctx context.Context, w *entity.Warehouse) error {
+	if err := prepareWarehouse(w); err != nil {
+		return err
+	}
+	return s.warehouseRepo.Update(ctx, w)
+}
+
+// DeleteWarehouse удаляет склад без остатков и заказов
+func (s *WarehouseService) DeleteWarehouse(ctx context.Context, id int) error {
+	return s.warehouseRepo.Delete(ctx, id)
+}
+
+// prepareWarehouse нормализует поля склада и проверяет обязательные
+func prepareWarehouse(w *entity.Warehouse) error {
+	w.Code = strings.ToLower(strings.TrimSpace(w.Code))
+	w.Name = strings.TrimSpace(w.Name)
+	w.Address = strings.TrimSpace(w.Address)
+	if w.Type == "" {
+		w.Type = entity.WarehouseTypeWarehouse
+	}
+
+	if w.Code == "" || utf8.RuneCountInString(w.Code) > 50 ||
+		w.Name == "" || utf8.RuneCountInString(w.Name) > 255 ||
+		!w.Type.IsValid() {
+		return errors.ErrInvalidWarehouse
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestPrepareWarehouse(t *testing.T) {
+	w := &entity.Warehouse{Code: "  SPB-1 ", Name: " Склад СПб ", Address: " Невский, 1 "}
+	if err := prepareWarehouse(w); err != nil {
+		t.Fatalf("prepareWarehouse() error = %v", err)
+	}
+	want := entity.Warehouse{Code: "spb-1", Name: "Склад СПб", Address: "Невский, 1", Type: entity.WarehouseTypeWarehouse}
+	if *w != want {
+		t.Errorf("prepareWarehouse() = %+v, want %+v", *w, want)
+	}
+
+	tests := []struct {
+		name      string
+		warehouse entity.Warehouse
+	}{
+		{name: "empty code", warehouse: entity.Warehouse{Code: " ", Name: "Склад"}},
+		{name: "empty name", warehouse: entity.Warehouse{Code: "main"}},
+		{name: "long code", warehouse: entity.Warehouse{Code: strings.Repeat("c", 51), Name: "Склад"}},
+		{name: "unknown type", warehouse: entity.Warehouse{Code: "main", Name: "Склад", Type: "shop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := prepareWarehouse(&tt.warehouse); !errors.Is(err, errors.ErrInvalidWarehouse) {
+				t.Errorf("prepareWarehouse() error = %v, want %v", err, errors.ErrInvalidWarehouse)
+			}
+		})
+	}
+}
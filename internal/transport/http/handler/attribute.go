@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type AttributeHandler struct {
+	attributeService *service.AttributeService
+}
+
+type AttributeAdminHandler struct {
+	attributeService *service.AttributeService
+}
+
+func NewAttributeHandler(attributeService *service.AttributeService) *AttributeHandler {
+	return &AttributeHandler{attributeService: attributeService}
+}
+
+func NewAttributeAdminHandler(attributeService *service.AttributeService) *AttributeAdminHandler {
+	return &AttributeAdminHandler{attributeService: attributeService}
+}
+
+// AttributeRequest represents the request body for creating or updating an attribute definition
+// @Description AttributeRequest содержит определение характеристики. code и type задаются только при создании.
+type AttributeRequest struct {
+	Code    string               `json:"code" example:"width"`
+	Name    string               `json:"name" example:"Ширина"`
+	Type    entity.AttributeType `json:"type" example:"number"`
+	Unit    string               `json:"unit" example:"см"`
+	Min     *float64             `json:"min" example:"1"`
+	Max     *float64             `json:"max" example:"1000"`
+	Options []string             `json:"options"`
+}
+
+// CategoryAttributeRequest represents a single attribute binding for a category
+// @Description CategoryAttributeRequest привязывает характеристику к категории
+type CategoryAttributeRequest struct {
+	AttributeID int  `json:"attribute_id" example:"1"`
+	Required    bool `json:"required" example:"true"`
+	SortOrder   int  `json:"sort_order" example:"10"`
+}
+
+// GetCategoryAttributes godoc
+// @Summary Характеристики категории
+// @Description Возвращает характеристики товаров категории, включая унаследованные от родительских категорий. Используется для фильтров attr.<code> в списке товаров.
+// @Tags categories
+// @Produce json
+// @Param id path int true "ID категории"
+// @Success 200 {array} entity.CategoryAttribute
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /categories/{id}/attributes [get]
+func (h *AttributeHandler) GetCategoryAttributes(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
+	attributes, err := h.attributeService.CategoryAttributes(r.Context(), categoryID)
+	if err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attributes)
+}
+
+// ListAttributes godoc
+// @Summary Список характеристик
+// @Description Возвращает все определения характеристик товаров. Требуется право products:read.
+// @Tags admin-attributes
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Attribute
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/attributes [get]
+func (h *AttributeAdminHandler) ListAttributes(w http.ResponseWriter, r *http.Request) {
+	attributes, err := h.attributeService.ListAttributes(r.Context())
+	if err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attributes)
+}
+
+// CreateAttribute godoc
+// @Summary Создание характеристики
+// @Description Создает характеристику: number (диапазон в единицах unit) или enum (значение из options). Требуется право products:write.
+// @Tags admin-attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AttributeRequest true "Характеристика"
+// @Success 201 {object} entity.Attribute
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/attributes [post]
+func (h *AttributeAdminHandler) CreateAttribute(w http.ResponseWriter, r *http.Request) {
+	var req AttributeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	attribute := req.toAttribute()
+	if err := h.attributeService.CreateAttribute(r.Context(), attribute); err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, attribute)
+}
+
+// UpdateAttribute godoc
+// @Summary Обновление характеристики
+// @Description Обновляет название, единицу, границы и варианты значений. Код и тип не меняются. Требуется право products:write.
+// @Tags admin-attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID характеристики"
+// @Param request body AttributeRequest true "Характеристика"
+// @Success 200 {object} entity.Attribute
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/attributes/{id} [put]
+func (h *AttributeAdminHandler) UpdateAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID характеристики", err.Error())
+		return
+	}
+
+	var req AttributeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	attribute := req.toAttribute()
+	attribute.ID = id
+	if err := h.attributeService.UpdateAttribute(r.Context(), attribute); err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attribute)
+}
+
+// DeleteAttribute godoc
+// @Summary Удаление характеристики
+// @Description Удаляет характеристику, которая не заполнена ни у одного товара. Требуется право products:write.
+// @Tags admin-attributes
+// @Security BearerAuth
+// @Param id path int true "ID характеристики"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/attributes/{id} [delete]
+func (h *AttributeAdminHandler) DeleteAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID характеристики", err.Error())
+		return
+	}
+
+	if err := h.attributeService.DeleteAttribute(r.Context(), id); err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetCategoryAttributes godoc
+// @Summary Характеристики категории (админ)
+// @Description Заменяет характеристики, привязанные непосредственно к категории. Подкатегории наследуют привязки. Требуется право products:write.
+// @Tags admin-attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID категории"
+// @Param request body []CategoryAttributeRequest true "Привязки характеристик"
+// @Success 200 {array} entity.CategoryAttribute
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/categories/{id}/attributes [put]
+func (h *AttributeAdminHandler) SetCategoryAttributes(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
+	var req []CategoryAttributeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	bindings := make([]entity.CategoryAttribute, 0, len(req))
+	for _, b := range req {
+		bindings = append(bindings, entity.CategoryAttribute{
+			Attribute: entity.Attribute{ID: b.AttributeID},
+			Required:  b.Required,
+			SortOrder: b.SortOrder,
+		})
+	}
+
+	attributes, err := h.attributeService.SetCategoryAttributes(r.Context(), categoryID, bindings)
+	if err != nil {
+		writeAttributeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, attributes)
+}
+
+func (req AttributeRequest) toAttribute() *entity.Attribute {
+	return &entity.Attribute{
+		Code:    req.Code,
+		Name:    req.Name,
+		Type:    req.Type,
+		Unit:    req.Unit,
+		Min:     req.Min,
+		Max:     req.Max,
+		Options: req.Options,
+	}
+}
+
+// writeAttributeError переводит ошибки характеристик в HTTP-ответ
+func writeAttributeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidAttribute):
+		writeProductError(w, http.StatusBadRequest, "Некорректная характеристика", err.Error())
+	case errors.Is(err, errors.ErrAttributeNotFound):
+		writeProductError(w, http.StatusNotFound, "Характеристика не найдена", err.Error())
+	case errors.Is(err, errors.ErrCategoryNotFound):
+		writeProductError(w, http.StatusNotFound, "Категория не найдена", err.Error())
+	case errors.Is(err, errors.ErrAttributeExists):
+		writeProductError(w, http.StatusConflict, "Характеристика с таким кодом уже существует", err.Error())
+	case errors.Is(err, errors.ErrAttributeInUse):
+		writeProductError(w, http.StatusConflict, "Характеристика заполнена у товаров", err.Error())
+	default:
+		log.Printf("Attribute error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе с характеристиками", err.Error())
+	}
+}
@@ -0,0 +1,363 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+// CartIDHeader — заголовок, в котором гость передает ID своей корзины
+const CartIDHeader = "X-Cart-ID"
+
+type CartHandler struct {
+	cartService *service.CartService
+}
+
+func NewCartHandler(cartService *service.CartService) *CartHandler {
+	return &CartHandler{cartService: cartService}
+}
+
+// AddCartItemRequest represents the request body for adding a product to the cart
+// @Description AddCartItemRequest содержит товар, его вариант (для товаров с вариантами) и количество
+type AddCartItemRequest struct {
+	ProductID int `json:"product_id" example:"1"`
+	VariantID int `json:"variant_id,omitempty" example:"3"`
+	Quantity  int `json:"quantity" example:"1"`
+}
+
+// ApplyPromoCodeRequest represents the request body for applying a promo code to the cart
+// @Description ApplyPromoCodeRequest содержит промокод; регистр не важен
+type ApplyPromoCodeRequest struct {
+	Code string `json:"code" example:"SOFA15"`
+}
+
+// UpdateCartItemRequest represents the request body for changing item quantity
+// @Description UpdateCartItemRequest содержит новое количество; 0 удаляет позицию
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" example:"2"`
+}
+
+// GetCart godoc
+// @Summary Просмотр корзины
+// @Description Возвращает корзину с актуальными ценами, скидками по акциям и промокоду и предупреждениями об остатках.
+// @Description subtotal — сумма по ценам каталога, total — к оплате. Если промокод не дал скидки, причина указывается в promo_warning.
+// @Description Гость передает ID корзины в заголовке X-Cart-ID, пользователь — JWT токен.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart [get]
+func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.cartOwner(w, r, false)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.GetCart(r.Context(), owner)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// AddCartItem godoc
+// @Summary Добавление товара в корзину
+// @Description Добавляет товар в корзину. Если у гостя еще нет корзины, она создается, а ее ID возвращается в заголовке X-Cart-ID.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Param request body AddCartItemRequest true "Товар и количество"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/items [post]
+func (h *CartHandler) AddCartItem(w http.ResponseWriter, r *http.Request) {
+	var req AddCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	owner, ok := h.cartOwner(w, r, true)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.AddItem(r.Context(), owner, req.ProductID, req.VariantID, req.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// UpdateCartItem godoc
+// @Summary Изменение количества товара в корзине
+// @Description Устанавливает количество товара в корзине. Количество 0 удаляет позицию.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Param product_id path int true "ID продукта"
+// @Param variant_id query int false "ID варианта товара"
+// @Param request body UpdateCartItemRequest true "Новое количество"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/items/{product_id} [put]
+func (h *CartHandler) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("product_id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+	variantID, err := cartVariantID(r)
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID варианта", err.Error())
+		return
+	}
+
+	var req UpdateCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	owner, ok := h.cartOwner(w, r, true)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.UpdateItem(r.Context(), owner, productID, variantID, req.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// RemoveCartItem godoc
+// @Summary Удаление товара из корзины
+// @Description Удаляет позицию из корзины
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Param product_id path int true "ID продукта"
+// @Param variant_id query int false "ID варианта товара"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/items/{product_id} [delete]
+func (h *CartHandler) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("product_id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+	variantID, err := cartVariantID(r)
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID варианта", err.Error())
+		return
+	}
+
+	owner, ok := h.cartOwner(w, r, false)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.RemoveItem(r.Context(), owner, productID, variantID)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// ApplyPromoCode godoc
+// @Summary Применение промокода к корзине
+// @Description Проверяет промокод и применяет его к корзине вместо предыдущего. Скидка пересчитывается при каждом просмотре корзины
+// @Description и окончательно — при оформлении заказа. Если у гостя еще нет корзины, она создается, а ее ID возвращается в заголовке X-Cart-ID.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Param request body ApplyPromoCodeRequest true "Промокод"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/promo-code [put]
+func (h *CartHandler) ApplyPromoCode(w http.ResponseWriter, r *http.Request) {
+	var req ApplyPromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	owner, ok := h.cartOwner(w, r, true)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.ApplyPromoCode(r.Context(), owner, req.Code)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// RemovePromoCode godoc
+// @Summary Отмена промокода
+// @Description Убирает промокод из корзины. Автоматические акции продолжают действовать.
+// @Tags cart
+// @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
+// @Success 200 {object} entity.Cart
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/promo-code [delete]
+func (h *CartHandler) RemovePromoCode(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.cartOwner(w, r, false)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.RemovePromoCode(r.Context(), owner)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cart)
+}
+
+// Checkout godoc
+// @Summary Оформление заказа из корзины
+// @Description Превращает корзину текущего пользователя в заказ с примененным к корзине промокодом и очищает ее.
+// @Description Гостю нужно сначала войти — гостевая корзина и ее промокод объединятся с корзиной пользователя.
+// @Description Без тела запроса заказ оформляется с доставкой.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом в течение суток вернет сохраненный ответ"
+// @Param request body DeliveryRequest false "Способ получения"
+// @Success 201 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /cart/checkout [post]
+func (h *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	var req DeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	order, err := h.cartService.Checkout(r.Context(), claims.UserID, req.toEntity())
+	if err != nil {
+		if errors.Is(err, errors.ErrCartEmpty) {
+			writeOrderError(w, http.StatusBadRequest, "Корзина пуста", err.Error())
+			return
+		}
+		writeCreateOrderError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, order)
+}
+
+// cartOwner определяет владельца корзины по JWT или заголовку X-Cart-ID.
+// Если create = true, гостю без корзины выдается новый ID.
+func (h *CartHandler) cartOwner(w http.ResponseWriter, r *http.Request, create bool) (service.CartOwner, bool) {
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		return service.CartOwner{UserID: claims.UserID}, true
+	}
+
+	cartID := r.Header.Get(CartIDHeader)
+	if cartID != "" && !service.ValidCartID(cartID) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID корзины", errors.ErrInvalidCartID.Error())
+		return service.CartOwner{}, false
+	}
+
+	if cartID == "" && create {
+		var err error
+		cartID, err = service.NewCartID()
+		if err != nil {
+			writeOrderError(w, http.StatusInternalServerError, "Не удалось создать корзину", err.Error())
+			return service.CartOwner{}, false
+		}
+	}
+
+	if cartID != "" {
+		w.Header().Set(CartIDHeader, cartID)
+	}
+	return service.CartOwner{CartID: cartID}, true
+}
+
+// cartVariantID читает необязательный параметр variant_id; 0 — товар без вариантов
+func cartVariantID(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("variant_id")
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// writeCartError переводит ошибки корзины в HTTP-ответ
+func writeCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeOrderError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeOrderError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantNotFound):
+		writeOrderError(w, http.StatusNotFound, "Вариант товара не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantRequired):
+		writeOrderError(w, http.StatusBadRequest, "Не указан вариант товара", err.Error())
+	case errors.Is(err, errors.ErrInvalidPromoCode):
+		writeOrderError(w, http.StatusBadRequest, "Некорректный промокод", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeNotFound):
+		writeOrderError(w, http.StatusNotFound, "Промокод не найден или истек", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeExhausted):
+		writeOrderError(w, http.StatusConflict, "Промокод уже использован", err.Error())
+	default:
+		log.Printf("Cart error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при работе с корзиной", err.Error())
+	}
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+type CategoryAdminHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categoryService: categoryService}
+}
+
+func NewCategoryAdminHandler(categoryService *service.CategoryService) *CategoryAdminHandler {
+	return &CategoryAdminHandler{categoryService: categoryService}
+}
+
+// CategoryRequest represents the request body for creating or updating a category
+// @Description CategoryRequest содержит поля категории; slug строится из названия, если не передан
+type CategoryRequest struct {
+	ParentID  *int   `json:"parent_id" example:"1"`
+	Name      string `json:"name" example:"Угловые диваны"`
+	Slug      string `json:"slug" example:"uglovye-divany"`
+	SortOrder int    `json:"sort_order" example:"10"`
+}
+
+// GetCategoryTree godoc
+// @Summary Дерево категорий
+// @Description Возвращает дерево категорий. product_count включает товары подкатегорий.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Success 200 {array} entity.Category
+// @Failure 500 {object} ErrorProductResponse
+// @Router /categories [get]
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categoryService.Tree(r.Context())
+	if err != nil {
+		log.Printf("Category tree error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Не удалось получить категории", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// GetCategory godoc
+// @Summary Получение категории
+// @Description Возвращает категорию с подкатегориями
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "ID категории"
+// @Success 200 {object} entity.Category
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /categories/{id} [get]
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
+	category, err := h.categoryService.GetCategory(r.Context(), id)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// CreateCategory godoc
+// @Summary Создание категории
+// @Description Создает категорию. Требуется право products:write.
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CategoryRequest true "Категория"
+// @Success 201 {object} entity.Category
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/categories [post]
+func (h *CategoryAdminHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	category := &entity.Category{
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		Slug:      req.Slug,
+		SortOrder: req.SortOrder,
+	}
+	if err := h.categoryService.CreateCategory(r.Context(), category); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Обновление категории
+// @Description Обновляет категорию; переименование применяется и к товарам категории. Категорию нельзя перенести внутрь ее подкатегорий. Требуется право products:write.
+// @Tags admin-categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID категории"
+// @Param request body CategoryRequest true "Категория"
+// @Success 200 {object} entity.Category
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/categories/{id} [put]
+func (h *CategoryAdminHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
+	var req CategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	category := &entity.Category{
+		ID:        id,
+		ParentID:  req.ParentID,
+		Name:      req.Name,
+		Slug:      req.Slug,
+		SortOrder: req.SortOrder,
+	}
+	updated, err := h.categoryService.UpdateCategory(r.Context(), category)
+	if err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteCategory godoc
+// @Summary Удаление категории
+// @Description Удаляет категорию без подкатегорий и товаров. Требуется право products:write.
+// @Tags admin-categories
+// @Security BearerAuth
+// @Param id path int true "ID категории"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/categories/{id} [delete]
+func (h *CategoryAdminHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(r.Context(), id); err != nil {
+		writeCategoryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeCategoryError переводит ошибки категорий в HTTP-ответ
+func writeCategoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrCategoryNotFound):
+		writeProductError(w, http.StatusNotFound, "Категория не найдена", err.Error())
+	case errors.Is(err, errors.ErrInvalidCategory):
+		writeProductError(w, http.StatusBadRequest, "Некорректная категория", "название обязательно (до 100 символов), родитель не может быть подкатегорией самой категории")
+	case errors.Is(err, errors.ErrCategoryExists):
+		writeProductError(w, http.StatusConflict, "Категория уже существует", err.Error())
+	case errors.Is(err, errors.ErrCategoryNotEmpty):
+		writeProductError(w, http.StatusConflict, "Категория не пуста", err.Error())
+	default:
+		log.Printf("Category error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе с категорией", err.Error())
+	}
+}
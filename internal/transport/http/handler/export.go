@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type ExportAdminHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportAdminHandler(exportService *service.ExportService) *ExportAdminHandler {
+	return &ExportAdminHandler{exportService: exportService}
+}
+
+// ExportProducts godoc
+// @Summary Выгрузка каталога
+// @Description Потоково выгружает каталог или его часть в CSV, XLSX или JSON. Столбцы CSV/XLSX совпадают с форматом импорта (id, image_url, images, created_at и updated_at импорт пропускает).
+// @Description URL галереи в столбце images разделены символом |. Требуется право products:read.
+// @Tags admin-products
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "Формат" Enums(csv, xlsx, json) default(csv)
+// @Param category query []string false "Фильтр по slug или названию категории, включая подкатегории" collectionFormat(multi)
+// @Param category_id query []int false "Фильтр по ID категории, включая подкатегории" collectionFormat(multi)
+// @Param in_stock query bool false "Только товары в наличии"
+// @Param updated_from query string false "Изменены не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param updated_to query string false "Изменены раньше (RFC3339 или YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/export [get]
+func (h *ExportAdminHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := service.ExportContentType(format)
+	if !ok {
+		writeProductError(w, http.StatusBadRequest, "Неподдерживаемый формат", "ожидается csv, xlsx или json")
+		return
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный фильтр", err.Error())
+		return
+	}
+	for name, dst := range map[string]**time.Time{"updated_from": &filter.UpdatedFrom, "updated_to": &filter.UpdatedTo} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseDateParam(raw)
+		if err != nil {
+			writeProductError(w, http.StatusBadRequest, "Некорректный фильтр", fmt.Sprintf("%s: ожидается RFC3339 или YYYY-MM-DD", name))
+			return
+		}
+		*dst = &t
+	}
+
+	// Выгрузка большого каталога дольше WriteTimeout сервера
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	out := &trackingWriter{w: w}
+	if err := h.exportService.ExportProducts(r.Context(), out, format, filter); err != nil {
+		log.Printf("Export products error: %v", err)
+		// Если часть файла уже отправлена, статус не поменять: клиент получит обрезанный файл
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			writeProductError(w, http.StatusInternalServerError, "Ошибка выгрузки каталога", err.Error())
+		}
+	}
+}
+
+// parseDateParam разбирает дату в формате RFC3339 или YYYY-MM-DD (начало дня UTC)
+func parseDateParam(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
+
+// trackingWriter запоминает, начата ли уже запись ответа
+type trackingWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+// ExportOrders godoc
+// @Summary Выгрузка заказов
+// @Description Потоково выгружает заказы по фильтрам списка заказов в CSV или XLSX для логистики: по строке на позицию заказа
+// @Description (order_id, created_at, status, customer_email, customer_name, delivery_method, pickup_warehouse_id, order_subtotal, order_discount, order_total, promo_code, product_id, variant_id, sku, product_name, quantity, price, discount). Требуется право orders:read.
+// @Tags admin-orders
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param format query string false "Формат" Enums(csv, xlsx) default(csv)
+// @Param status query []string false "Статусы (несколько через запятую или повтором параметра)" collectionFormat(multi)
+// @Param created_from query string false "Созданы не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Созданы раньше (RFC3339 или YYYY-MM-DD)"
+// @Param email query string false "Подстрока email покупателя"
+// @Param total_min query number false "Минимальная сумма заказа"
+// @Param total_max query number false "Максимальная сумма заказа"
+// @Param product_id query int false "Заказы с этим товаром"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/export [get]
+func (h *ExportAdminHandler) ExportOrders(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType, ok := service.ExportContentType(format)
+	if !ok || format == service.ExportFormatJSON {
+		writeOrderError(w, http.StatusBadRequest, "Неподдерживаемый формат", "ожидается csv или xlsx")
+		return
+	}
+
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный фильтр", err.Error())
+		return
+	}
+	for _, status := range filter.Statuses {
+		if !status.IsValid() {
+			writeOrderError(w, http.StatusBadRequest, "Неизвестный статус заказа", string(status))
+			return
+		}
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("orders-%s.%s", time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	out := &trackingWriter{w: w}
+	if err := h.exportService.ExportOrders(r.Context(), out, format, filter); err != nil {
+		log.Printf("Export orders error: %v", err)
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			writeOrderError(w, http.StatusInternalServerError, "Ошибка выгрузки заказов", err.Error())
+		}
+	}
+}
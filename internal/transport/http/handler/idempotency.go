@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+)
+
+const (
+	// IdempotencyKeyHeader — заголовок, которым клиент помечает повторы одного и того же запроса
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader выставляется в ответе, повторенном из сохраненного
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	// idempotencyLockTTL — сколько ключ удерживается за выполняющимся запросом, если сервер упал, не дописав ответ.
+	// Пока запрос выполняется, ключ продлевается, так что долгий запрос его не теряет.
+	idempotencyLockTTL = time.Minute
+	maxIdempotencyKey  = 255
+	maxIdempotentBody  = 1 << 20
+)
+
+// IdempotencyStore хранит сохраненные ответы по ключу идемпотентности
+type IdempotencyStore interface {
+	// Reserve занимает ключ; если он уже занят, возвращает сохраненное значение и false
+	Reserve(ctx context.Context, key string, value []byte, ttl time.Duration) ([]byte, bool, error)
+	Save(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+	// Extend продлевает ключ еще на ttl
+	Extend(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// idempotentResponse — сохраненный ответ. Status = 0 означает, что запрос еще выполняется.
+type idempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware повторяет ответ на запрос с уже встречавшимся заголовком Idempotency-Key,
+// не выполняя его снова. Ключ действует ttl в пределах пользователя и маршрута; тот же ключ с другим
+// телом запроса отклоняется с 409. Ответы 5xx не сохраняются, такой запрос можно повторить.
+// Запросы без заголовка проходят как обычно. Ставится после AuthMiddleware.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				writeOrderError(w, http.StatusBadRequest, "Некорректный ключ идемпотентности", "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			if err != nil {
+				writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+			storeKey := idempotencyScope(r) + ":" + key
+
+			pending, _ := json.Marshal(idempotentResponse{Fingerprint: fingerprint})
+			saved, reserved, err := store.Reserve(r.Context(), storeKey, pending, idempotencyLockTTL)
+			if err != nil {
+				log.Printf("Idempotency key reserve error: %v", err)
+				writeOrderError(w, http.StatusInternalServerError, "Не удалось проверить ключ идемпотентности", err.Error())
+				return
+			}
+			if !reserved {
+				replayIdempotent(w, saved, fingerprint)
+				return
+			}
+
+			// Ключ продлевается и ответ сохраняется, даже если клиент отключился: запрос все равно выполняется
+			ctx := context.WithoutCancel(r.Context())
+
+			stop := holdIdempotencyKey(ctx, store, storeKey, idempotencyLockTTL)
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			stop()
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			if rec.status >= http.StatusInternalServerError {
+				if err := store.Release(ctx, storeKey); err != nil {
+					log.Printf("Idempotency key release error: %v", err)
+				}
+				return
+			}
+
+			done, _ := json.Marshal(idempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+			if err := store.Save(ctx, storeKey, done, ttl); err != nil {
+				log.Printf("Idempotency response save error: %v", err)
+			}
+		})
+	}
+}
+
+// holdIdempotencyKey продлевает ключ выполняющегося запроса каждую треть ttl, пока не вызвана stop.
+// После возврата из stop ключ больше не продлевается, поэтому сохраненный ответ не получит чужой срок.
+func holdIdempotencyKey(ctx context.Context, store IdempotencyStore, key string, ttl time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.Extend(ctx, key, ttl); err != nil {
+					log.Printf("Idempotency key extend error: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// replayIdempotent отдает сохраненный ответ или конфликт, если запрос с ключом отличается или еще выполняется
+func replayIdempotent(w http.ResponseWriter, saved []byte, fingerprint string) {
+	var resp idempotentResponse
+	if err := json.Unmarshal(saved, &resp); err != nil {
+		log.Printf("Idempotency response decode error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Не удалось прочитать сохраненный ответ", err.Error())
+		return
+	}
+
+	switch {
+	case resp.Fingerprint != fingerprint:
+		writeOrderError(w, http.StatusConflict, "Ключ идемпотентности уже использован с другим запросом",
+			"Idempotency-Key was reused with a different request body")
+	case resp.Status == 0:
+		writeOrderError(w, http.StatusConflict, "Запрос с этим ключом идемпотентности еще выполняется",
+			"retry after the original request completes")
+	default:
+		if resp.ContentType != "" {
+			w.Header().Set("Content-Type", resp.ContentType)
+		}
+		w.Header().Set(IdempotentReplayHeader, "true")
+		w.WriteHeader(resp.Status)
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+// idempotencyScope ограничивает ключ пользователем и маршрутом, чтобы чужие ключи не пересекались
+func idempotencyScope(r *http.Request) string {
+	userID := 0
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		userID = claims.UserID
+	}
+	return strconv.Itoa(userID) + ":" + r.Method + ":" + r.URL.Path
+}
+
+// responseRecorder пропускает ответ клиенту и запоминает его для повтора
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyStore — IdempotencyStore в памяти без истечения ключей
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	values  map[string][]byte
+	extends int
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{values: make(map[string][]byte)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, value []byte, _ time.Duration) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.values[key]; ok {
+		return existing, false, nil
+	}
+	s.values[key] = value
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Save(_ context.Context, key string, value []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Extend(_ context.Context, _ string, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extends++
+	return nil
+}
+
+func (s *memoryIdempotencyStore) extendCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.extends
+}
+
+// countingHandler отвечает status и считает, сколько раз запрос действительно выполнился
+type countingHandler struct {
+	mu     sync.Mutex
+	calls  int
+	status int
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.calls++
+	calls := h.calls
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.status)
+	w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `}`))
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotencyMiddlewareReplay(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	h := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, idempotentRequest("key-1", `{"items":[1]}`))
+	if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayHeader) != "" {
+		t.Fatalf("first response = %d replayed=%q, want 201 not replayed", first.Code, first.Header().Get(IdempotentReplayHeader))
+	}
+
+	// Повтор с тем же ключом и телом отдает сохраненный ответ, не выполняя запрос снова
+	replay := httptest.NewRecorder()
+	h.ServeHTTP(replay, idempotentRequest("key-1", `{"items":[1]}`))
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", replay.Code, replay.Body.String(), first.Code, first.Body.String())
+	}
+	if replay.Header().Get(IdempotentReplayHeader) != "true" || replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v, want replay flag and saved content type", replay.Header())
+	}
+
+	// Другой ключ и запрос без ключа выполняются как обычно
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-2", `{"items":[1]}`))
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("", `{"items":[1]}`))
+	if next.calls != 3 {
+		t.Errorf("handler calls = %d, want 3", next.calls)
+	}
+}
+
+func TestIdempotencyMiddlewareConflicts(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	next := &countingHandler{status: http.StatusCreated}
+	h := IdempotencyMiddleware(store, time.Hour)(next)
+
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-1", `{"items":[1]}`))
+
+	// Тот же ключ с другим телом — ошибка клиента, а не повтор
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, idempotentRequest("key-1", `{"items":[2]}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("reused key status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Пока первый запрос выполняется, повтор получает 409, а не второй заказ
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	slowHandler := IdempotencyMiddleware(store, time.Hour)(slow)
+	done := make(chan struct{})
+	go func() {
+		slowHandler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-slow", `{}`))
+		close(done)
+	}()
+	<-inFlight
+
+	rec = httptest.NewRecorder()
+	slowHandler.ServeHTTP(rec, idempotentRequest("key-slow", `{}`))
+	close(release)
+	<-done
+	if rec.Code != http.StatusConflict {
+		t.Errorf("in-progress status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if next.calls != 1 {
+		t.Errorf("handler calls = %d, want 1", next.calls)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesOnServerError(t *testing.T) {
+	next := &countingHandler{status: http.StatusInternalServerError}
+	h := IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next)
+
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-1", `{}`))
+
+	// Ответ 5xx не сохраняется: повтор с тем же ключом выполняется заново
+	next.status = http.StatusCreated
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	if rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayHeader) != "" {
+		t.Errorf("retry after 5xx = %d replayed=%q, want 201 not replayed", rec.Code, rec.Header().Get(IdempotentReplayHeader))
+	}
+	if next.calls != 2 {
+		t.Errorf("handler calls = %d, want 2", next.calls)
+	}
+
+	// Ответ 4xx сохраняется и повторяется
+	next.status = http.StatusBadRequest
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-2", `{}`))
+	h.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-2", `{}`))
+	if next.calls != 3 {
+		t.Errorf("handler calls after 4xx replay = %d, want 3", next.calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsLongKey(t *testing.T) {
+	next := &countingHandler{status: http.StatusCreated}
+	rec := httptest.NewRecorder()
+	IdempotencyMiddleware(newMemoryIdempotencyStore(), time.Hour)(next).
+		ServeHTTP(rec, idempotentRequest(strings.Repeat("k", maxIdempotencyKey+1), `{}`))
+	if rec.Code != http.StatusBadRequest || next.calls != 0 {
+		t.Errorf("long key = %d with %d calls, want 400 without calls", rec.Code, next.calls)
+	}
+}
+
+func TestHoldIdempotencyKey(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	stop := holdIdempotencyKey(context.Background(), store, "1:POST:/api/orders:key", 30*time.Millisecond)
+
+	// Долгий запрос: ключ продлевается, пока обработчик работает
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	held := store.extendCount()
+	if held == 0 {
+		t.Fatal("key was not extended while the request was running")
+	}
+
+	// После stop ключ больше не продлевается, иначе сохраненный ответ получил бы срок блокировки
+	time.Sleep(50 * time.Millisecond)
+	if got := store.extendCount(); got != held {
+		t.Errorf("extends after stop = %d, want %d", got, held)
+	}
+}
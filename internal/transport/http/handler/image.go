@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+)
+
+// ReorderImagesRequest represents the request body for reordering the product gallery
+// @Description ReorderImagesRequest содержит ID всех изображений товара в новом порядке
+type ReorderImagesRequest struct {
+	ImageIDs []int `json:"image_ids"`
+}
+
+// UpdateImageRequest represents the request body for updating a gallery image
+// @Description UpdateImageRequest содержит альтернативный текст изображения
+type UpdateImageRequest struct {
+	AltText string `json:"alt_text" example:"Диван Осло, вид сбоку"`
+}
+
+// ListProductImages godoc
+// @Summary Галерея товара
+// @Description Возвращает изображения товара в порядке отображения. Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Success 200 {array} entity.ProductImage
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images [get]
+func (h *ProductAdminHandler) ListProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	images, err := h.productService.ListImages(r.Context(), productID)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, images)
+}
+
+// UploadProductImages godoc
+// @Summary Загрузка изображений товара
+// @Description Загружает одно или несколько изображений в конец галереи. Первое изображение товара становится главным. Требуется право products:write.
+// @Tags admin-products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param images formData file true "Изображения (JPEG, PNG, WebP до 10MB каждое), поле можно повторять"
+// @Param alt_text formData string false "Альтернативный текст для загружаемых изображений"
+// @Success 201 {array} entity.ProductImage
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 413 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images [post]
+func (h *ProductAdminHandler) UploadProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Ошибка разбора формы", err.Error())
+		return
+	}
+
+	headers := r.MultipartForm.File["images"]
+	if len(headers) == 0 {
+		writeProductError(w, http.StatusBadRequest, "Отсутствуют изображения", "поле images пустое")
+		return
+	}
+
+	images, err := h.productService.AddImages(r.Context(), productID, headers, r.FormValue("alt_text"))
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, images)
+}
+
+// ReorderProductImages godoc
+// @Summary Порядок изображений товара
+// @Description Задает порядок галереи. В запросе должны быть перечислены все изображения товара ровно по одному разу. Требуется право products:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param request body ReorderImagesRequest true "Новый порядок"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images/order [put]
+func (h *ProductAdminHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	if err := h.productService.ReorderImages(r.Context(), productID, req.ImageIDs); err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateProductImage godoc
+// @Summary Обновление изображения товара
+// @Description Меняет альтернативный текст изображения. Требуется право products:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param image_id path int true "ID изображения"
+// @Param request body UpdateImageRequest true "Альтернативный текст"
+// @Success 200 {object} entity.ProductImage
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images/{image_id} [put]
+func (h *ProductAdminHandler) UpdateProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, imageID, ok := imageIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	image, err := h.productService.UpdateImageAltText(r.Context(), productID, imageID, req.AltText)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, image)
+}
+
+// SetPrimaryProductImage godoc
+// @Summary Главное изображение товара
+// @Description Делает изображение главным; его URL возвращается в image_url товара. Требуется право products:write.
+// @Tags admin-products
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param image_id path int true "ID изображения"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images/{image_id}/primary [post]
+func (h *ProductAdminHandler) SetPrimaryProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, imageID, ok := imageIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.SetPrimaryImage(r.Context(), productID, imageID); err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteProductImage godoc
+// @Summary Удаление изображения товара
+// @Description Удаляет изображение из галереи и из S3. Если удалено главное изображение, главным становится первое из оставшихся. Требуется право products:write.
+// @Tags admin-products
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param image_id path int true "ID изображения"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/images/{image_id} [delete]
+func (h *ProductAdminHandler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	productID, imageID, ok := imageIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.DeleteImage(r.Context(), productID, imageID); err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// imageIDsFromPath читает ID продукта и изображения из пути; при ошибке ответ уже записан
+func imageIDsFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return 0, 0, false
+	}
+	imageID, err := strconv.Atoi(r.PathValue("image_id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID изображения", err.Error())
+		return 0, 0, false
+	}
+	return productID, imageID, true
+}
+
+// writeImageError переводит ошибки галереи в HTTP-ответ
+func writeImageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrFileTooLarge):
+		writeProductError(w, http.StatusRequestEntityTooLarge, "Слишком большой файл", err.Error())
+	case errors.Is(err, errors.ErrInvalidFileType):
+		writeProductError(w, http.StatusBadRequest, "Недопустимый тип файла", err.Error())
+	case errors.Is(err, errors.ErrInvalidImageOrder):
+		writeProductError(w, http.StatusBadRequest, "Некорректный порядок изображений", err.Error())
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeProductError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+	case errors.Is(err, errors.ErrImageNotFound):
+		writeProductError(w, http.StatusNotFound, "Изображение не найдено", err.Error())
+	default:
+		log.Printf("Product image error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе с изображениями", err.Error())
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+// maxImportFileSize — максимальный размер файла импорта
+const maxImportFileSize = 32 << 20
+
+type ImportAdminHandler struct {
+	importService *service.ImportService
+}
+
+func NewImportAdminHandler(importService *service.ImportService) *ImportAdminHandler {
+	return &ImportAdminHandler{importService: importService}
+}
+
+// ImportProducts godoc
+// @Summary Импорт каталога из CSV/XLSX
+// @Description Запускает фоновую задачу импорта. Товары ищутся по external_id, а если он не задан — по sku; найденные обновляются, остальные создаются.
+// @Description Столбцы: sku, external_id, name, description, price, stock, category_id или category (slug/название), attr.<code> для характеристик.
+// @Description Столбец stock задает начальный остаток только новых товаров: остаток существующих меняется движениями остатков.
+// @Description Столбцы выгрузки id, image_url, images, created_at и updated_at пропускаются, поэтому файл GET /admin/products/export можно загрузить обратно.
+// @Description Пустая ячейка у существующего товара оставляет значение без изменений. В режиме dry_run строки только проверяются.
+// @Description Прогресс и построчный отчет — GET /admin/imports/{id}. Требуется право products:write.
+// @Tags admin-products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Файл .csv (разделитель , или ;) или .xlsx (первый лист) до 32MB, первая строка — заголовок"
+// @Param dry_run formData bool false "Только проверить файл, не меняя каталог"
+// @Success 202 {object} entity.ImportJob
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/import [post]
+func (h *ImportAdminHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeProductError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Ошибка разбора формы", err.Error())
+		return
+	}
+
+	dryRun := false
+	if v := r.FormValue("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProductError(w, http.StatusBadRequest, "Некорректный параметр dry_run", "ожидается true или false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Отсутствует файл", err.Error())
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Ошибка чтения файла", err.Error())
+		return
+	}
+
+	job, err := h.importService.StartImport(r.Context(), claims.UserID, header.Filename, data, dryRun)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// ListImports godoc
+// @Summary Задачи импорта
+// @Description Возвращает последние задачи импорта без построчного отчета. Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Количество задач" minimum(1) maximum(100) default(20)
+// @Success 200 {array} entity.ImportJob
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/imports [get]
+func (h *ImportAdminHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	limit := pagination.DefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > pagination.MaxLimit {
+			writeProductError(w, http.StatusBadRequest, "Некорректный limit", "ожидается число от 1 до 100")
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, err := h.importService.ListJobs(r.Context(), limit)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// GetImport godoc
+// @Summary Статус задачи импорта
+// @Description Возвращает прогресс задачи и построчный отчет: действие (create, update, error), ID товара и ошибки строки. Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID задачи"
+// @Success 200 {object} entity.ImportJob
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/imports/{id} [get]
+func (h *ImportAdminHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID задачи", err.Error())
+		return
+	}
+
+	job, err := h.importService.GetJob(r.Context(), id)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+// writeImportError переводит ошибки импорта в HTTP-ответ
+func writeImportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidImportFile):
+		writeProductError(w, http.StatusBadRequest, "Некорректный файл импорта", err.Error())
+	case errors.Is(err, errors.ErrImportJobNotFound):
+		writeProductError(w, http.StatusNotFound, "Задача импорта не найдена", err.Error())
+	default:
+		log.Printf("Import error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка импорта", err.Error())
+	}
+}
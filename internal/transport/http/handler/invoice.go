@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type InvoiceHandler struct {
+	invoiceService *service.InvoiceService
+	rbac           *auth.RBAC
+}
+
+func NewInvoiceHandler(invoiceService *service.InvoiceService, rbac *auth.RBAC) *InvoiceHandler {
+	return &InvoiceHandler{invoiceService: invoiceService, rbac: rbac}
+}
+
+// GetOrderInvoice godoc
+// @Summary Счет по заказу
+// @Description Возвращает PDF счета: реквизиты продавца, покупатель, позиции, НДС и итоги. Счет выписывается при первом запросе
+// @Description и дальше не меняется — повторные запросы возвращают тот же документ. Доступен владельцу заказа и сотрудникам с правом orders:read.
+// @Description Для отмененного заказа, по которому счет еще не выписан, возвращается 409.
+// @Tags orders
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {file} file "PDF файл"
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id}/invoice [get]
+func (h *InvoiceHandler) GetOrderInvoice(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	staff := h.rbac.HasPermission(claims.Role, auth.PermOrdersRead)
+	invoice, data, err := h.invoiceService.GetInvoice(r.Context(), id, claims.UserID, staff)
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrOrderNotFound):
+			writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+		case errors.Is(err, errors.ErrInvoiceNotAvailable):
+			writeOrderError(w, http.StatusConflict, "Счет по отмененному заказу не выписывается", err.Error())
+		default:
+			log.Printf("Invoice error: %v", err)
+			writeOrderError(w, http.StatusInternalServerError, "Ошибка при формировании счета", err.Error())
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("invoice_%s.pdf", invoice.Number)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+	w.Header().Set("Cache-Control", "private")
+
+	http.ServeContent(w, r, filename, invoice.IssuedAt, bytes.NewReader(data))
+}
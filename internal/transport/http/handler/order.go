@@ -0,0 +1,739 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type OrderHandler struct {
+	orderService *service.OrderService
+}
+
+// OrderAdminHandler handles order lifecycle operations
+// @Description OrderAdminHandler provides endpoints for order management by administrators
+type OrderAdminHandler struct {
+	orderService *service.OrderService
+}
+
+func NewOrderHandler(orderService *service.OrderService) *OrderHandler {
+	return &OrderHandler{orderService: orderService}
+}
+
+func NewOrderAdminHandler(orderService *service.OrderService) *OrderAdminHandler {
+	return &OrderAdminHandler{orderService: orderService}
+}
+
+// OrderItemRequest represents a single line of the checkout request
+// @Description OrderItemRequest содержит товар, его вариант (для товаров с вариантами) и количество
+type OrderItemRequest struct {
+	ProductID int `json:"product_id" example:"1"`
+	VariantID int `json:"variant_id,omitempty" example:"3"`
+	Quantity  int `json:"quantity" example:"2"`
+}
+
+// DeliveryRequest represents the delivery choice at checkout
+// @Description DeliveryRequest содержит способ получения: delivery (по умолчанию) или pickup с ID пункта выдачи из GET /pickup-points
+type DeliveryRequest struct {
+	DeliveryMethod    string `json:"delivery_method,omitempty" example:"pickup" enums:"delivery,pickup"`
+	PickupWarehouseID int    `json:"pickup_warehouse_id,omitempty" example:"2"`
+}
+
+// toEntity преобразует запрос в способ получения заказа
+func (d DeliveryRequest) toEntity() entity.Delivery {
+	return entity.Delivery{
+		Method:            entity.DeliveryMethod(d.DeliveryMethod),
+		PickupWarehouseID: d.PickupWarehouseID,
+	}
+}
+
+// CreateOrderRequest represents the request body for checkout
+// @Description CreateOrderRequest содержит позиции заказа, способ получения и необязательный промокод
+type CreateOrderRequest struct {
+	Items     []OrderItemRequest `json:"items"`
+	PromoCode string             `json:"promo_code,omitempty" example:"SOFA15"`
+	DeliveryRequest
+}
+
+// OrdersResponse represents the response for order list operations
+// @Description OrdersResponse contains paginated list of orders with metadata.
+// В режиме курсоров (cursor/limit) total, page и page_size не заполняются, а следующая страница запрашивается по next_cursor.
+type OrdersResponse struct {
+	Orders     []*entity.Order `json:"orders"`
+	Total      *int            `json:"total,omitempty"`
+	Page       int             `json:"page,omitempty"`
+	PageSize   int             `json:"page_size,omitempty"`
+	Limit      int             `json:"limit,omitempty"`
+	HasMore    bool            `json:"has_more"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ChangeOrderStatusRequest represents the request body for a status transition
+// @Description ChangeOrderStatusRequest содержит целевой статус и комментарий
+type ChangeOrderStatusRequest struct {
+	Status  entity.OrderStatus `json:"status" example:"shipped"`
+	Comment string             `json:"comment" example:"Передан в службу доставки"`
+}
+
+// CancelOrderRequest represents the request body for cancelling an order
+// @Description CancelOrderRequest содержит необязательную причину отмены
+type CancelOrderRequest struct {
+	Comment string `json:"comment,omitempty" example:"Передумал"`
+}
+
+// AdminOrdersResponse represents the admin order list
+// @Description AdminOrdersResponse содержит страницу заказов с контактами покупателей и данные пагинации
+type AdminOrdersResponse struct {
+	Orders   []*entity.AdminOrder `json:"orders"`
+	Total    int                  `json:"total"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+	HasMore  bool                 `json:"has_more"`
+}
+
+// AddOrderNoteRequest represents the request body for an internal order note
+// @Description AddOrderNoteRequest содержит текст заметки (до 2000 символов)
+type AddOrderNoteRequest struct {
+	Text string `json:"text" example:"Покупатель просит позвонить за час до доставки"`
+}
+
+// BulkOrderStatusRequest represents the request body for a bulk status change
+// @Description BulkOrderStatusRequest содержит до 100 ID заказов, целевой статус и комментарий
+type BulkOrderStatusRequest struct {
+	OrderIDs []int              `json:"order_ids"`
+	Status   entity.OrderStatus `json:"status" example:"shipped"`
+	Comment  string             `json:"comment" example:"Передан в службу доставки"`
+}
+
+// BulkOrderStatusResponse represents the result of a bulk status change
+// @Description BulkOrderStatusResponse содержит результат по каждому заказу; у заказов, которые перевести не удалось, заполнено error
+type BulkOrderStatusResponse struct {
+	Results   []entity.BulkStatusResult `json:"results"`
+	Succeeded int                       `json:"succeeded"`
+	Failed    int                       `json:"failed"`
+}
+
+// ErrorOrderResponse представляет стандартную структуру ошибки для order-хендлеров
+// @Description ErrorOrderResponse используется для отображения ошибок API заказов
+type ErrorOrderResponse struct {
+	Code    int    `json:"code" example:"500"`
+	Message string `json:"message" example:"Internal server error"`
+	Details string `json:"details,omitempty" example:"недостаточно товара на складе"`
+}
+
+// CreateOrder godoc
+// @Summary Оформление заказа
+// @Description Создает заказ текущего пользователя. Цены фиксируются на момент покупки, товар резервируется:
+// @Description при доставке — на складах в порядке приоритета, при самовывозе — только в выбранном пункте выдачи.
+// @Description Резерв держится до reserved_until и списывается со склада при оплате; неоплаченный заказ затем отменяется.
+// @Description К заказу применяются действующие акции и промокод: subtotal — сумма по ценам каталога, discounts — скидки по акциям, total — к оплате.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом в течение суток вернет сохраненный ответ"
+// @Param request body CreateOrderRequest true "Позиции заказа"
+// @Success 201 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders [post]
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	var req CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	items := make([]entity.OrderItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, entity.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	order, err := h.orderService.CreateOrder(r.Context(), claims.UserID, items, req.toEntity(), req.PromoCode)
+	if err != nil {
+		writeCreateOrderError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, order)
+}
+
+// ListOrders godoc
+// @Summary Список заказов пользователя
+// @Description Возвращает заказы текущего пользователя с пагинацией, новые первыми. Если передан cursor или limit, используется пагинация по курсору без подсчета total.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param limit query int false "Размер страницы в режиме курсоров" default(20)
+// @Success 200 {object} OrdersResponse
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders [get]
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	if cursor, limit, ok := cursorParams(r); ok {
+		orders, next, err := h.orderService.ListOrdersAfter(r.Context(), claims.UserID, cursor, limit)
+		if err != nil {
+			if errors.Is(err, errors.ErrInvalidCursor) {
+				writeOrderError(w, http.StatusBadRequest, "Некорректный курсор", err.Error())
+				return
+			}
+			log.Printf("List orders error: %v", err)
+			writeOrderError(w, http.StatusInternalServerError, "Не удалось получить список заказов", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, OrdersResponse{
+			Orders:     orders,
+			Limit:      limit,
+			HasMore:    next != "",
+			NextCursor: next,
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	orders, total, err := h.orderService.ListOrders(r.Context(), claims.UserID, page, pageSize)
+	if err != nil {
+		log.Printf("List orders error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Не удалось получить список заказов", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, OrdersResponse{
+		Orders:   orders,
+		Total:    &total,
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  total > 0 && (page*pageSize) < total,
+	})
+}
+
+// GetOrder godoc
+// @Summary Получение заказа
+// @Description Возвращает заказ текущего пользователя по ID вместе с позициями
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id} [get]
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	order, err := h.orderService.GetOrder(r.Context(), claims.UserID, id)
+	if err != nil {
+		if errors.Is(err, errors.ErrOrderNotFound) {
+			writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+			return
+		}
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при получении заказа", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// CancelOrder godoc
+// @Summary Отмена заказа
+// @Description Отменяет неоплаченный заказ текущего пользователя и снимает резерв товара. Оплаченный заказ отменить нельзя — для него оформляется возврат.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Param request body CancelOrderRequest false "Причина отмены"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id}/cancel [post]
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	order, err := h.orderService.CancelOrder(r.Context(), claims.UserID, id, req.Comment)
+	if err != nil {
+		writeChangeStatusError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// ChangeOrderStatus godoc
+// @Summary Смена статуса заказа
+// @Description Переводит заказ в новый статус. Недопустимые переходы (например, delivered → pending) отклоняются.
+// @Description При оплате резерв списывается со склада, при отмене резерв снимается, а списанный товар возвращается на склад. Требуется право orders:write.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Param request body ChangeOrderStatusRequest true "Новый статус"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/status [post]
+func (h *OrderAdminHandler) ChangeOrderStatus(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req ChangeOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	order, err := h.orderService.ChangeStatus(r.Context(), id, req.Status, claims.UserID, req.Comment)
+	if err != nil {
+		writeChangeStatusError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// CancelOrder godoc
+// @Summary Отмена заказа сотрудником
+// @Description Отменяет заказ любого покупателя: резерв снимается, а списанный товар возвращается на склад.
+// @Description Требуется право orders:cancel (его дает и orders:*), поэтому отменять заказы может роль без права менять другие статусы.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Param request body CancelOrderRequest false "Причина отмены"
+// @Success 200 {object} entity.Order
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/cancel [post]
+func (h *OrderAdminHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	order, err := h.orderService.ChangeStatus(r.Context(), id, entity.OrderStatusCancelled, claims.UserID, req.Comment)
+	if err != nil {
+		writeChangeStatusError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// ListAllOrders godoc
+// @Summary Список заказов (админ)
+// @Description Возвращает заказы всех покупателей, новые первыми, с контактами покупателя, позициями и пагинацией. Требуется право orders:read.
+// @Tags admin-orders
+// @Produce json
+// @Security BearerAuth
+// @Param status query []string false "Статусы (несколько через запятую или повтором параметра)" collectionFormat(multi)
+// @Param created_from query string false "Созданы не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Созданы раньше (RFC3339 или YYYY-MM-DD)"
+// @Param email query string false "Подстрока email покупателя"
+// @Param total_min query number false "Минимальная сумма заказа"
+// @Param total_max query number false "Максимальная сумма заказа"
+// @Param product_id query int false "Заказы с этим товаром"
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} AdminOrdersResponse
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders [get]
+func (h *OrderAdminHandler) ListAllOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный фильтр", err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	orders, total, err := h.orderService.ListAllOrders(r.Context(), filter, page, pageSize)
+	if err != nil {
+		if errors.Is(err, errors.ErrInvalidOrderStatus) {
+			writeOrderError(w, http.StatusBadRequest, "Неизвестный статус заказа", err.Error())
+			return
+		}
+		log.Printf("List all orders error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при получении заказов", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AdminOrdersResponse{
+		Orders:   orders,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  total > 0 && (page*pageSize) < total,
+	})
+}
+
+// GetOrderDetails godoc
+// @Summary Карточка заказа (админ)
+// @Description Возвращает заказ с контактами покупателя, позициями, историей статусов и внутренними заметками. Требуется право orders:read.
+// @Tags admin-orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {object} entity.OrderDetails
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id} [get]
+func (h *OrderAdminHandler) GetOrderDetails(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	details, err := h.orderService.GetOrderDetails(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errors.ErrOrderNotFound) {
+			writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+			return
+		}
+		log.Printf("Get order details error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при получении заказа", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+// AddOrderNote godoc
+// @Summary Заметка к заказу
+// @Description Добавляет к заказу внутреннюю заметку от имени текущего сотрудника. Покупатель заметки не видит. Требуется право orders:write.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Param request body AddOrderNoteRequest true "Текст заметки"
+// @Success 201 {object} entity.OrderNote
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/notes [post]
+func (h *OrderAdminHandler) AddOrderNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req AddOrderNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	note, err := h.orderService.AddNote(r.Context(), id, actorID(r), req.Text)
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrInvalidOrderNote):
+			writeOrderError(w, http.StatusBadRequest, "Некорректная заметка", err.Error())
+		case errors.Is(err, errors.ErrOrderNotFound):
+			writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+		default:
+			log.Printf("Add order note error: %v", err)
+			writeOrderError(w, http.StatusInternalServerError, "Ошибка при сохранении заметки", err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+// BulkChangeOrderStatus godoc
+// @Summary Массовая смена статуса заказов
+// @Description Переводит до 100 заказов в один статус. Каждый заказ обрабатывается отдельно, как при обычной смене статуса:
+// @Description недопустимый переход или ошибка одного заказа не мешают остальным и возвращаются в его результате. Требуется право orders:write.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkOrderStatusRequest true "Заказы и новый статус"
+// @Success 200 {object} BulkOrderStatusResponse
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/status [post]
+func (h *OrderAdminHandler) BulkChangeOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var req BulkOrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	results, err := h.orderService.BulkChangeStatus(r.Context(), req.OrderIDs, req.Status, actorID(r), req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrInvalidOrderStatus):
+			writeOrderError(w, http.StatusBadRequest, "Неизвестный статус заказа", err.Error())
+		case errors.Is(err, errors.ErrInvalidBulkOrders):
+			writeOrderError(w, http.StatusBadRequest, "Некорректный список заказов", err.Error())
+		default:
+			log.Printf("Bulk change order status error: %v", err)
+			writeOrderError(w, http.StatusInternalServerError, "Ошибка при смене статуса заказов", err.Error())
+		}
+		return
+	}
+
+	resp := BulkOrderStatusResponse{Results: results}
+	for _, result := range results {
+		if result.Error == "" {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetOrderStatusHistory godoc
+// @Summary История статусов заказа
+// @Description Возвращает все переходы статуса заказа с автором и временем. Требуется право orders:read.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {array} entity.OrderStatusHistory
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/status [get]
+func (h *OrderAdminHandler) GetOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	history, err := h.orderService.GetStatusHistory(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errors.ErrOrderNotFound) {
+			writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+			return
+		}
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при получении истории заказа", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+// writeChangeStatusError переводит ошибки смены статуса в HTTP-ответ
+func writeChangeStatusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidOrderStatus):
+		writeOrderError(w, http.StatusBadRequest, "Неизвестный статус заказа", err.Error())
+	case errors.Is(err, errors.ErrOrderNotFound):
+		writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+	case errors.Is(err, errors.ErrInvalidStatusTransition):
+		writeOrderError(w, http.StatusConflict, "Недопустимый переход статуса", err.Error())
+	case errors.Is(err, errors.ErrOrderNotCancellable):
+		writeOrderError(w, http.StatusConflict, "Заказ нельзя отменить", err.Error())
+	case errors.Is(err, errors.ErrInsufficientStock):
+		writeOrderError(w, http.StatusConflict, "Зарезервированный товар списан со склада", err.Error())
+	default:
+		log.Printf("Change order status error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при смене статуса заказа", err.Error())
+	}
+}
+
+// writeCreateOrderError переводит ошибки оформления заказа в HTTP-ответ
+func writeCreateOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrEmptyOrder):
+		writeOrderError(w, http.StatusBadRequest, "Заказ не содержит товаров", err.Error())
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeOrderError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+	case errors.Is(err, errors.ErrInvalidDelivery):
+		writeOrderError(w, http.StatusBadRequest, "Некорректный способ получения", err.Error())
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeOrderError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantNotFound):
+		writeOrderError(w, http.StatusNotFound, "Вариант товара не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantRequired):
+		writeOrderError(w, http.StatusBadRequest, "Не указан вариант товара", err.Error())
+	case errors.Is(err, errors.ErrInsufficientStock):
+		writeOrderError(w, http.StatusConflict, "Недостаточно товара на складе", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeNotFound):
+		writeOrderError(w, http.StatusNotFound, "Промокод не найден или истек", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeExhausted):
+		writeOrderError(w, http.StatusConflict, "Промокод уже использован", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeNotApplicable):
+		writeOrderError(w, http.StatusConflict, "Промокод не действует на товары заказа", err.Error())
+	default:
+		log.Printf("Create order error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при оформлении заказа", err.Error())
+	}
+}
+
+// parseOrderFilter разбирает фильтр заказов админки из query-параметров
+func parseOrderFilter(r *http.Request) (entity.OrderFilter, error) {
+	query := r.URL.Query()
+	filter := entity.OrderFilter{Email: strings.TrimSpace(query.Get("email"))}
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.Statuses = append(filter.Statuses, entity.OrderStatus(status))
+			}
+		}
+	}
+
+	for name, dst := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseDateParam(raw)
+		if err != nil {
+			return filter, fmt.Errorf("%s: ожидается RFC3339 или YYYY-MM-DD", name)
+		}
+		*dst = &t
+	}
+
+	for name, dst := range map[string]**float64{"total_min": &filter.TotalMin, "total_max": &filter.TotalMax} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		total, err := strconv.ParseFloat(raw, 64)
+		if err != nil || total < 0 {
+			return filter, fmt.Errorf("%s: ожидается неотрицательное число", name)
+		}
+		*dst = &total
+	}
+	if filter.TotalMin != nil && filter.TotalMax != nil && *filter.TotalMin > *filter.TotalMax {
+		return filter, fmt.Errorf("total_min больше total_max")
+	}
+
+	if raw := query.Get("product_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			return filter, fmt.Errorf("product_id: ожидается положительное целое число")
+		}
+		filter.ProductID = id
+	}
+
+	return filter, nil
+}
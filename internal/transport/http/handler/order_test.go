@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+func TestParseOrderFilter(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	minTotal, maxTotal := 1000.0, 5000.5
+
+	tests := []struct {
+		name    string
+		query   string
+		want    entity.OrderFilter
+		wantErr bool
+	}{
+		{name: "empty", query: ""},
+		{
+			name:  "statuses by comma and repeat",
+			query: "status=paid,+shipped&status=delivered&email=+ivan+",
+			want: entity.OrderFilter{
+				Statuses: []entity.OrderStatus{entity.OrderStatusPaid, entity.OrderStatusShipped, entity.OrderStatusDelivered},
+				Email:    "ivan",
+			},
+		},
+		{
+			name:  "dates totals and product",
+			query: "created_from=2024-03-01&created_to=2024-03-31T12:00:00Z&total_min=1000&total_max=5000.5&product_id=7",
+			want:  entity.OrderFilter{CreatedFrom: &from, CreatedTo: &to, TotalMin: &minTotal, TotalMax: &maxTotal, ProductID: 7},
+		},
+		{name: "bad date", query: "created_from=01.03.2024", wantErr: true},
+		{name: "negative total", query: "total_min=-1", wantErr: true},
+		{name: "min above max", query: "total_min=10&total_max=5", wantErr: true},
+		{name: "bad product", query: "product_id=0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOrderFilter(httptest.NewRequest("GET", "/admin/orders?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseOrderFilter() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOrderFilter() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOrderFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+// maxWebhookBody ограничивает размер уведомления платежной системы
+const maxWebhookBody = 1 << 20
+
+type PaymentHandler struct {
+	paymentService *service.PaymentService
+}
+
+type PaymentAdminHandler struct {
+	paymentService *service.PaymentService
+}
+
+func NewPaymentHandler(paymentService *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{paymentService: paymentService}
+}
+
+func NewPaymentAdminHandler(paymentService *service.PaymentService) *PaymentAdminHandler {
+	return &PaymentAdminHandler{paymentService: paymentService}
+}
+
+// CreatePaymentRequest represents the request body for paying an order
+// @Description CreatePaymentRequest выбирает платежного провайдера. Без тела используется провайдер по умолчанию (payments.default_provider).
+type CreatePaymentRequest struct {
+	Provider string `json:"provider,omitempty" example:"fake"`
+}
+
+// RefundRequest represents the request body for refunding an order payment
+// @Description RefundRequest задает сумму возврата. 0 или пустое тело — вернуть весь остаток платежа.
+type RefundRequest struct {
+	Amount float64 `json:"amount,omitempty" example:"1500"`
+}
+
+// CreatePayment godoc
+// @Summary Оплата заказа
+// @Description Создает платеж по неоплаченному заказу и возвращает confirmation_url для перехода к оплате. Повторный вызов возвращает уже созданный неоплаченный платеж.
+// @Description Заказ переводится в paid, когда провайдер присылает вебхук об успешной оплате.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом в течение суток вернет сохраненный ответ"
+// @Param id path int true "ID заказа"
+// @Param request body CreatePaymentRequest false "Провайдер"
+// @Success 201 {object} entity.PaymentIntent
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 502 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id}/pay [post]
+func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	intent, err := h.paymentService.CreatePayment(r.Context(), claims.UserID, id, req.Provider)
+	if err != nil {
+		writePaymentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, intent)
+}
+
+// PaymentWebhook godoc
+// @Summary Вебхук платежной системы
+// @Description Принимает уведомление провайдера о смене статуса платежа. Подпись проверяет провайдер; при успешной оплате заказ переводится в paid,
+// @Description а если заказ оплатить уже нельзя (например, он отменен по истечении резерва), деньги возвращаются. Повторные уведомления безопасны.
+// @Description Встроенный провайдер fake (только при payments.fake_enabled) принимает {"payment_id": "...", "status": "succeeded", "amount": 15990} с HMAC-SHA256 тела (hex, ключ payments.fake_secret) в заголовке X-Fake-Signature.
+// @Tags payments
+// @Accept json
+// @Param provider path string true "Провайдер" example(fake)
+// @Success 200
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /payments/webhook/{provider} [post]
+func (h *PaymentHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	if err := h.paymentService.HandleWebhook(r.Context(), r.PathValue("provider"), r.Header, body); err != nil {
+		writePaymentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListOrderPayments godoc
+// @Summary Платежи заказа
+// @Description Возвращает все платежи заказа с суммами возвратов. Требуется право orders:read.
+// @Tags admin-orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {array} entity.PaymentIntent
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/payments [get]
+func (h *PaymentAdminHandler) ListOrderPayments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	payments, err := h.paymentService.ListOrderPayments(r.Context(), id)
+	if err != nil {
+		writePaymentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, payments)
+}
+
+// RefundOrderPayment godoc
+// @Summary Возврат оплаты
+// @Description Возвращает покупателю всю сумму или ее часть по проведенному платежу заказа. Статус заказа не меняется. Требуется право payments:refund.
+// @Tags admin-orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом в течение суток вернет сохраненный ответ"
+// @Param id path int true "ID заказа"
+// @Param request body RefundRequest false "Сумма возврата"
+// @Success 200 {object} entity.PaymentIntent
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 502 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/orders/{id}/refund [post]
+func (h *PaymentAdminHandler) RefundOrderPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	intent, err := h.paymentService.Refund(r.Context(), id, req.Amount)
+	if err != nil {
+		writePaymentError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, intent)
+}
+
+// writePaymentError переводит ошибки платежей в HTTP-ответ
+func writePaymentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrOrderNotFound):
+		writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+	case errors.Is(err, errors.ErrPaymentNotFound):
+		writeOrderError(w, http.StatusNotFound, "Платеж не найден", err.Error())
+	case errors.Is(err, errors.ErrPaymentProviderNotFound):
+		writeOrderError(w, http.StatusNotFound, "Платежный провайдер не подключен", err.Error())
+	case errors.Is(err, errors.ErrOrderNotPayable):
+		writeOrderError(w, http.StatusConflict, "Заказ нельзя оплатить", err.Error())
+	case errors.Is(err, errors.ErrInvalidSignature):
+		writeOrderError(w, http.StatusUnauthorized, "Неверная подпись уведомления", err.Error())
+	case errors.Is(err, errors.ErrInvalidPaymentEvent):
+		writeOrderError(w, http.StatusBadRequest, "Некорректное уведомление о платеже", err.Error())
+	case errors.Is(err, errors.ErrInvalidRefund):
+		writeOrderError(w, http.StatusBadRequest, "Некорректная сумма возврата", err.Error())
+	case errors.Is(err, errors.ErrPaymentProviderFailed):
+		log.Printf("Payment provider error: %v", err)
+		writeOrderError(w, http.StatusBadGateway, "Платежная система недоступна", err.Error())
+	default:
+		log.Printf("Payment error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при обработке платежа", err.Error())
+	}
+}
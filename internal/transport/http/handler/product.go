@@ -2,12 +2,15 @@ package handler
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
@@ -49,13 +52,28 @@ type UpdateProductRequest struct {
 }
 
 // ProductsResponse represents the response for product list operations
-// @Description ProductsResponse contains paginated list of products with metadata
+// @Description ProductsResponse contains paginated list of products with metadata.
+// В режиме курсоров (cursor/limit) total, page и page_size не заполняются, а следующая страница запрашивается по next_cursor.
 type ProductsResponse struct {
-	Products []*entity.Product `json:"products"`
-	Total    int               `json:"total"`
-	Page     int               `json:"page"`
-	PageSize int               `json:"page_size"`
-	HasMore  bool              `json:"has_more"`
+	Products   []*entity.Product     `json:"products"`
+	Total      *int                  `json:"total,omitempty"`
+	Page       int                   `json:"page,omitempty"`
+	PageSize   int                   `json:"page_size,omitempty"`
+	Limit      int                   `json:"limit,omitempty"`
+	HasMore    bool                  `json:"has_more"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+	Facets     *entity.ProductFacets `json:"facets,omitempty"`
+}
+
+// ProductSearchResponse represents the response for full-text product search
+// @Description ProductSearchResponse содержит найденные продукты, отсортированные по релевантности
+type ProductSearchResponse struct {
+	Query    string                        `json:"query"`
+	Products []*entity.ProductSearchResult `json:"products"`
+	Total    int                           `json:"total"`
+	Page     int                           `json:"page"`
+	PageSize int                           `json:"page_size"`
+	HasMore  bool                          `json:"has_more"`
 }
 
 // ErrorResponse represents a standard error response
@@ -85,18 +103,51 @@ func NewProductPDFHandler(productService *service.ProductService, pdfService *se
 
 // List products godoc
 // @Summary Получение списка продуктов
-// @Description Возвращает список продуктов с возможностью фильтрации по категории и пагинацией
+// @Description Возвращает список продуктов с фильтрами, сортировкой и пагинацией, а также фасеты по категориям и ценовым диапазонам для боковой панели фильтров. Если передан cursor или limit, используется пагинация по курсору: total не считается, фасеты возвращаются только для первой страницы.
 // @Tags products
 // @Accept json
 // @Produce json
-// @Param category query string false "Фильтр по категории"
+// @Param category query []string false "Фильтр по slug или названию категории, включая подкатегории (можно передать несколько раз)" collectionFormat(multi)
+// @Param category_id query []int false "Фильтр по ID категории, включая подкатегории" collectionFormat(multi)
+// @Param min_price query number false "Минимальная цена"
+// @Param max_price query number false "Максимальная цена"
+// @Param in_stock query bool false "Только товары в наличии"
+// @Param attr.{code} query string false "Фильтр по характеристике-перечислению, значения через запятую: attr.frame_material=Массив сосны,ЛДСП"
+// @Param attr.{code}.min query number false "Нижняя граница числовой характеристики: attr.width.min=180"
+// @Param attr.{code}.max query number false "Верхняя граница числовой характеристики: attr.width.max=240"
+// @Param sort query string false "Сортировка" Enums(newest, price_asc, price_desc, name, popularity) default(newest)
 // @Param page query int false "Номер страницы" default(1)
 // @Param page_size query int false "Размер страницы" default(20)
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param limit query int false "Размер страницы в режиме курсоров" default(20)
 // @Success 200 {object} ProductsResponse
+// @Failure 400 {object} ErrorProductResponse
 // @Failure 500 {object} ErrorProductResponse
 // @Router /products [get]
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	category := r.URL.Query().Get("category")
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректные параметры фильтра", err.Error())
+		return
+	}
+
+	if cursor, limit, ok := cursorParams(r); ok {
+		resp, err := listProductsAfter(r, h.productService, filter, cursor, limit)
+		if err != nil {
+			writeProductListError(w, err)
+			return
+		}
+		// Фасеты нужны только для первой страницы — дальше витрина подгружает товары при прокрутке
+		if cursor == "" {
+			if resp.Facets, err = h.productService.ProductFacets(r.Context(), filter); err != nil {
+				writeProductError(w, http.StatusInternalServerError, "Не удалось получить фасеты каталога", err.Error())
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
@@ -106,26 +157,77 @@ func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 		pageSize = 20
 	}
 
-	products, total, err := h.productService.ListProducts(r.Context(), category, page, pageSize)
+	products, total, err := h.productService.ListProducts(r.Context(), filter, page, pageSize)
 	if err != nil {
 		writeProductError(w, http.StatusInternalServerError, "Не удалось получить список продуктов", err.Error())
 		return
 	}
 
+	facets, err := h.productService.ProductFacets(r.Context(), filter)
+	if err != nil {
+		writeProductError(w, http.StatusInternalServerError, "Не удалось получить фасеты каталога", err.Error())
+		return
+	}
+
 	hasMore := total > 0 && (page*pageSize) < total
 
 	writeJSON(w, http.StatusOK, ProductsResponse{
 		Products: products,
-		Total:    total,
+		Total:    &total,
 		Page:     page,
 		PageSize: pageSize,
 		HasMore:  hasMore,
+		Facets:   facets,
+	})
+}
+
+// SearchProducts godoc
+// @Summary Полнотекстовый поиск продуктов
+// @Description Ищет продукты по названию, категории и описанию с учетом русской морфологии ("диван" находит "диваны"). Поддерживает синтаксис web-поиска: "точная фраза", -исключение, or. Совпадения в name_highlight и snippet выделены тегом <mark>, остальной текст экранирован как HTML.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} ProductSearchResponse
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /products/search [get]
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	results, total, err := h.productService.SearchProducts(r.Context(), query, page, pageSize)
+	if err != nil {
+		if errors.Is(err, errors.ErrInvalidSearchQuery) {
+			writeProductError(w, http.StatusBadRequest, "Некорректный поисковый запрос", "параметр q обязателен и не длиннее 200 символов")
+			return
+		}
+		writeProductError(w, http.StatusInternalServerError, "Ошибка поиска продуктов", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProductSearchResponse{
+		Query:    query,
+		Products: results,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  total > 0 && (page*pageSize) < total,
 	})
 }
 
 // GetProduct godoc
 // @Summary Получение информации о продукте
-// @Description Возвращает детальную информацию о продукте по ID
+// @Description Возвращает детальную информацию о продукте по ID вместе с вариантами и галереей изображений
 // @Tags products
 // @Accept json
 // @Produce json
@@ -158,7 +260,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 
 // CreateProduct godoc
 // @Summary Создание нового продукта
-// @Description Создает новый продукт с возможностью загрузки изображения. Требуются права администратора.
+// @Description Создает новый продукт с возможностью загрузки изображения. Требуется право products:write.
 // @Tags admin-products
 // @Accept multipart/form-data
 // @Produce json
@@ -166,23 +268,21 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 // @Param name formData string true "Название продукта"
 // @Param description formData string true "Описание продукта"
 // @Param price formData number true "Цена продукта"
-// @Param category formData string true "Категория продукта"
+// @Param category_id formData integer true "ID категории"
 // @Param stock formData integer true "Количество на складе"
+// @Param sku formData string false "Артикул"
+// @Param external_id formData string false "ID товара во внешней системе"
 // @Param image formData file false "Изображение продукта (JPEG, PNG, WebP до 10MB)"
+// @Param attributes formData string false "Характеристики категории в виде JSON-объекта по кодам, например {\"width\":220,\"frame_material\":\"Массив сосны\"}"
 // @Success 201 {object} entity.Product
 // @Failure 400 {object} ErrorProductResponse
 // @Failure 401 {object} ErrorProductResponse
 // @Failure 403 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
 // @Failure 413 {object} ErrorProductResponse
 // @Failure 500 {object} ErrorProductResponse
 // @Router /admin/products [post]
 func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
-	claims := auth.GetUserFromContext(r.Context())
-	if claims == nil || claims.Role != "admin" {
-		writeProductError(w, http.StatusForbidden, "Создать продукты может только администратор", errors.ErrInvalidToken.Error())
-		return
-	}
-
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		writeProductError(w, http.StatusBadRequest, "Ошибка запроса", err.Error())
 		return
@@ -191,14 +291,20 @@ func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Reque
 	name := r.FormValue("name")
 	description := r.FormValue("description")
 	priceStr := r.FormValue("price")
-	category := r.FormValue("category")
+	categoryStr := r.FormValue("category_id")
 	stockStr := r.FormValue("stock")
 
-	if name == "" || description == "" || priceStr == "" || category == "" || stockStr == "" {
+	if name == "" || description == "" || priceStr == "" || categoryStr == "" || stockStr == "" {
 		writeProductError(w, http.StatusBadRequest, "Отсутствуют обязательные поля", "")
 		return
 	}
 
+	categoryID, err := strconv.Atoi(categoryStr)
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+		return
+	}
+
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
 		writeProductError(w, http.StatusBadRequest, "Некорректная цена", err.Error())
@@ -211,6 +317,12 @@ func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	attributes, err := parseAttributeValues(r.FormValue("attributes"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректные характеристики", err.Error())
+		return
+	}
+
 	file, header, err := r.FormFile("image")
 	if err != nil && err != http.ErrMissingFile {
 		writeProductError(w, http.StatusBadRequest, "Ошибка чтения файла", err.Error())
@@ -226,16 +338,29 @@ func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Reque
 		Name:        name,
 		Description: description,
 		Price:       price,
-		Category:    category,
+		CategoryID:  categoryID,
 		Stock:       stock,
+		SKU:         strings.TrimSpace(r.FormValue("sku")),
+		ExternalID:  strings.TrimSpace(r.FormValue("external_id")),
+		Attributes:  attributes,
 	}
 
-	if err := h.productService.CreateProduct(r.Context(), product, file, header); err != nil {
+	if err := h.productService.CreateProduct(r.Context(), product, file, header, actorID(r)); err != nil {
+		if errors.Is(err, errors.ErrInvalidAttribute) {
+			writeProductError(w, http.StatusBadRequest, "Некорректные характеристики", err.Error())
+			return
+		}
 		switch err {
 		case errors.ErrFileTooLarge:
 			writeProductError(w, http.StatusRequestEntityTooLarge, "Слишком большой файл", err.Error())
 		case errors.ErrInvalidFileType:
 			writeProductError(w, http.StatusBadRequest, "Недопустимый тип файла", err.Error())
+		case errors.ErrCategoryNotFound:
+			writeProductError(w, http.StatusBadRequest, "Категория не найдена", err.Error())
+		case errors.ErrInvalidQuantity:
+			writeProductError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+		case errors.ErrSKUExists, errors.ErrExternalIDExists:
+			writeProductError(w, http.StatusConflict, "Артикул или внешний ID уже занят", err.Error())
 		default:
 			writeProductError(w, http.StatusInternalServerError, "Ошибка при создании продукта", err.Error())
 		}
@@ -247,7 +372,8 @@ func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Reque
 
 // UpdateProduct godoc
 // @Summary Обновление продукта
-// @Description Обновляет существующий продукт. Все поля опциональны - обновляются только переданные поля. Требуются права администратора.
+// @Description Обновляет существующий продукт. Все поля опциональны - обновляются только переданные поля.
+// @Description Остаток здесь не меняется: он проводится через POST /admin/products/{id}/stock/movements. Требуется право products:write.
 // @Tags admin-products
 // @Accept multipart/form-data
 // @Produce json
@@ -256,24 +382,21 @@ func (h *ProductAdminHandler) CreateProduct(w http.ResponseWriter, r *http.Reque
 // @Param name formData string false "Название продукта"
 // @Param description formData string false "Описание продукта"
 // @Param price formData number false "Цена продукта"
-// @Param category formData string false "Категория продукта"
-// @Param stock formData integer false "Количество на складе"
+// @Param category_id formData integer false "ID категории"
+// @Param sku formData string false "Артикул"
+// @Param external_id formData string false "ID товара во внешней системе"
 // @Param image formData file false "Изображение продукта (JPEG, PNG, WebP до 10MB)"
+// @Param attributes formData string false "Характеристики в виде JSON-объекта по кодам; заменяют все сохраненные значения"
 // @Success 200 {object} entity.Product
 // @Failure 400 {object} ErrorProductResponse
 // @Failure 401 {object} ErrorProductResponse
 // @Failure 403 {object} ErrorProductResponse
 // @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
 // @Failure 413 {object} ErrorProductResponse
 // @Failure 500 {object} ErrorProductResponse
 // @Router /admin/products/{id} [put]
 func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	claims := auth.GetUserFromContext(r.Context())
-	if claims == nil || claims.Role != "admin" {
-		writeProductError(w, http.StatusForbidden, "Доступ запрещён", "только администратор может обновлять продукты")
-		return
-	}
-
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -302,8 +425,13 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 	if v := r.FormValue("description"); v != "" {
 		existingProduct.Description = v
 	}
-	if v := r.FormValue("category"); v != "" {
-		existingProduct.Category = v
+	if v := r.FormValue("category_id"); v != "" {
+		categoryID, err := strconv.Atoi(v)
+		if err != nil {
+			writeProductError(w, http.StatusBadRequest, "Некорректный ID категории", err.Error())
+			return
+		}
+		existingProduct.CategoryID = categoryID
 	}
 	if v := r.FormValue("price"); v != "" {
 		price, err := strconv.ParseFloat(v, 64)
@@ -313,13 +441,29 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 		}
 		existingProduct.Price = price
 	}
-	if v := r.FormValue("stock"); v != "" {
-		stock, err := strconv.Atoi(v)
+	if _, ok := r.MultipartForm.Value["stock"]; ok {
+		writeProductError(w, http.StatusBadRequest, "Остаток меняется движениями остатков",
+			"use POST /api/admin/products/{id}/stock/movements to change stock")
+		return
+	}
+	if v := strings.TrimSpace(r.FormValue("sku")); v != "" {
+		existingProduct.SKU = v
+	}
+	if v := strings.TrimSpace(r.FormValue("external_id")); v != "" {
+		existingProduct.ExternalID = v
+	}
+	// Без поля attributes сохраненные характеристики не меняются
+	existingProduct.Attributes = nil
+	if _, ok := r.MultipartForm.Value["attributes"]; ok {
+		attributes, err := parseAttributeValues(r.FormValue("attributes"))
 		if err != nil {
-			writeProductError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+			writeProductError(w, http.StatusBadRequest, "Некорректные характеристики", err.Error())
 			return
 		}
-		existingProduct.Stock = stock
+		if attributes == nil {
+			attributes = []entity.ProductAttribute{}
+		}
+		existingProduct.Attributes = attributes
 	}
 
 	file, header, err := r.FormFile("image")
@@ -334,6 +478,10 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 	}()
 
 	if err := h.productService.UpdateProduct(r.Context(), existingProduct, file, header); err != nil {
+		if errors.Is(err, errors.ErrInvalidAttribute) {
+			writeProductError(w, http.StatusBadRequest, "Некорректные характеристики", err.Error())
+			return
+		}
 		switch err {
 		case errors.ErrFileTooLarge:
 			writeProductError(w, http.StatusRequestEntityTooLarge, "Слишком большой файл", err.Error())
@@ -341,6 +489,12 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 			writeProductError(w, http.StatusBadRequest, "Недопустимый тип файла", err.Error())
 		case errors.ErrProductNotFound:
 			writeProductError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+		case errors.ErrCategoryNotFound:
+			writeProductError(w, http.StatusBadRequest, "Категория не найдена", err.Error())
+		case errors.ErrInvalidQuantity:
+			writeProductError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+		case errors.ErrSKUExists, errors.ErrExternalIDExists:
+			writeProductError(w, http.StatusConflict, "Артикул или внешний ID уже занят", err.Error())
 		default:
 			writeProductError(w, http.StatusInternalServerError, "Ошибка при обновлении продукта", err.Error())
 		}
@@ -352,7 +506,7 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 
 // DeleteProduct godoc
 // @Summary Удаление продукта
-// @Description Удаляет продукт по ID. Также удаляет связанное изображение из S3. Требуются права администратора.
+// @Description Удаляет продукт по ID. Также удаляет связанное изображение из S3. Требуется право products:write.
 // @Tags admin-products
 // @Accept json
 // @Produce json
@@ -366,12 +520,6 @@ func (h *ProductAdminHandler) UpdateProduct(w http.ResponseWriter, r *http.Reque
 // @Failure 500 {object} ErrorProductResponse
 // @Router /admin/products/{id} [delete]
 func (h *ProductAdminHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
-	claims := auth.GetUserFromContext(r.Context())
-	if claims == nil || claims.Role != "admin" {
-		writeProductError(w, http.StatusForbidden, "Доступ запрещён", "только администратор может удалять продукты")
-		return
-	}
-
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -393,27 +541,47 @@ func (h *ProductAdminHandler) DeleteProduct(w http.ResponseWriter, r *http.Reque
 
 // ListProducts godoc (Admin)
 // @Summary Получение списка продуктов (админ)
-// @Description Возвращает список продуктов с пагинацией для админ-панели. Требуются права администратора.
+// @Description Возвращает список продуктов с фильтрами, сортировкой и пагинацией для админ-панели. Если передан cursor или limit, используется пагинация по курсору. Требуется право products:read.
 // @Tags admin-products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param category query string false "Фильтр по категории"
+// @Param category query []string false "Фильтр по slug или названию категории, включая подкатегории" collectionFormat(multi)
+// @Param category_id query []int false "Фильтр по ID категории, включая подкатегории" collectionFormat(multi)
+// @Param min_price query number false "Минимальная цена"
+// @Param max_price query number false "Максимальная цена"
+// @Param in_stock query bool false "Только товары в наличии"
+// @Param attr.{code} query string false "Фильтр по характеристике-перечислению, значения через запятую: attr.frame_material=Массив сосны,ЛДСП"
+// @Param attr.{code}.min query number false "Нижняя граница числовой характеристики: attr.width.min=180"
+// @Param attr.{code}.max query number false "Верхняя граница числовой характеристики: attr.width.max=240"
+// @Param sort query string false "Сортировка" Enums(newest, price_asc, price_desc, name, popularity) default(newest)
 // @Param page query int false "Номер страницы" minimum(1) default(1)
 // @Param page_size query int false "Размер страницы" minimum(1) maximum(100) default(20)
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param limit query int false "Размер страницы в режиме курсоров" minimum(1) maximum(100) default(20)
 // @Success 200 {object} ProductsResponse
+// @Failure 400 {object} ErrorProductResponse
 // @Failure 401 {object} ErrorProductResponse
 // @Failure 403 {object} ErrorProductResponse
 // @Failure 500 {object} ErrorProductResponse
 // @Router /admin/products [get]
 func (h *ProductAdminHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
-	claims := auth.GetUserFromContext(r.Context())
-	if claims == nil || claims.Role != "admin" {
-		writeProductError(w, http.StatusForbidden, "Доступ запрещён", "только администратор может получать список продуктов")
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректные параметры фильтра", err.Error())
+		return
+	}
+
+	if cursor, limit, ok := cursorParams(r); ok {
+		resp, err := listProductsAfter(r, h.productService, filter, cursor, limit)
+		if err != nil {
+			writeProductListError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
 		return
 	}
 
-	category := r.URL.Query().Get("category")
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
@@ -423,7 +591,7 @@ func (h *ProductAdminHandler) ListProducts(w http.ResponseWriter, r *http.Reques
 		pageSize = 20
 	}
 
-	products, total, err := h.productService.ListProducts(r.Context(), category, page, pageSize)
+	products, total, err := h.productService.ListProducts(r.Context(), filter, page, pageSize)
 	if err != nil {
 		writeProductError(w, http.StatusInternalServerError, "Ошибка при получении списка продуктов", err.Error())
 		return
@@ -432,7 +600,7 @@ func (h *ProductAdminHandler) ListProducts(w http.ResponseWriter, r *http.Reques
 	hasMore := total > 0 && (page*pageSize) < total
 	writeJSON(w, http.StatusOK, ProductsResponse{
 		Products: products,
-		Total:    total,
+		Total:    &total,
 		Page:     page,
 		PageSize: pageSize,
 		HasMore:  hasMore,
@@ -565,3 +733,194 @@ func (h *ProductPDFHandler) TestPDF(w http.ResponseWriter, r *http.Request) {
 
 	http.ServeContent(w, r, "test_product.pdf", time.Now(), bytes.NewReader(pdfBuffer.Bytes()))
 }
+
+// parseProductFilter читает фильтры каталога из query-параметров.
+// Категории (slug, название или ID) можно передать несколько раз или через запятую: ?category=a&category=b, ?category_id=1,2
+func parseProductFilter(r *http.Request) (entity.ProductFilter, error) {
+	query := r.URL.Query()
+	filter := entity.ProductFilter{Sort: entity.ProductSortNewest}
+
+	for _, value := range query["category"] {
+		for _, category := range strings.Split(value, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				filter.Categories = append(filter.Categories, category)
+			}
+		}
+	}
+
+	for _, value := range query["category_id"] {
+		for _, raw := range strings.Split(value, ",") {
+			if raw = strings.TrimSpace(raw); raw == "" {
+				continue
+			}
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				return filter, fmt.Errorf("category_id: ожидается целое число")
+			}
+			filter.CategoryIDs = append(filter.CategoryIDs, id)
+		}
+	}
+
+	for name, dst := range map[string]**float64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(raw, 64)
+		if err != nil || price < 0 {
+			return filter, fmt.Errorf("%s: ожидается неотрицательное число", name)
+		}
+		*dst = &price
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return filter, fmt.Errorf("min_price больше max_price")
+	}
+
+	if raw := query.Get("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("in_stock: ожидается true или false")
+		}
+		filter.InStock = inStock
+	}
+
+	attributes, err := parseAttributeFilters(query)
+	if err != nil {
+		return filter, err
+	}
+	filter.Attributes = attributes
+
+	if raw := query.Get("sort"); raw != "" {
+		filter.Sort = entity.ProductSort(raw)
+		if !filter.Sort.IsValid() {
+			return filter, fmt.Errorf("sort: неизвестная сортировка %q", raw)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseAttributeFilters читает фильтры по характеристикам: attr.<code>=a,b для перечислений,
+// attr.<code>.min и attr.<code>.max для чисел
+func parseAttributeFilters(query url.Values) ([]entity.AttributeFilter, error) {
+	byCode := make(map[string]*entity.AttributeFilter)
+	get := func(code string) *entity.AttributeFilter {
+		if f, ok := byCode[code]; ok {
+			return f
+		}
+		f := &entity.AttributeFilter{Code: code}
+		byCode[code] = f
+		return f
+	}
+
+	for name, values := range query {
+		code, ok := strings.CutPrefix(name, "attr.")
+		if !ok || code == "" {
+			continue
+		}
+
+		bound := ""
+		if base, suffix, found := strings.Cut(code, "."); found {
+			if suffix != "min" && suffix != "max" {
+				return nil, fmt.Errorf("%s: ожидается attr.<code>, attr.<code>.min или attr.<code>.max", name)
+			}
+			code, bound = base, suffix
+		}
+
+		if bound == "" {
+			f := get(code)
+			for _, value := range values {
+				for _, option := range strings.Split(value, ",") {
+					if option = strings.TrimSpace(option); option != "" {
+						f.Values = append(f.Values, option)
+					}
+				}
+			}
+			continue
+		}
+
+		raw := strings.TrimSpace(values[0])
+		if raw == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ожидается число", name)
+		}
+		if bound == "min" {
+			get(code).Min = &number
+		} else {
+			get(code).Max = &number
+		}
+	}
+
+	filters := make([]entity.AttributeFilter, 0, len(byCode))
+	for _, f := range byCode {
+		if f.Min == nil && f.Max == nil && len(f.Values) == 0 {
+			continue
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return nil, fmt.Errorf("attr.%s.min больше attr.%s.max", f.Code, f.Code)
+		}
+		filters = append(filters, *f)
+	}
+	// Порядок не зависит от обхода map, чтобы запрос и курсоры были стабильными
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Code < filters[j].Code })
+	return filters, nil
+}
+
+// parseAttributeValues читает значения характеристик из JSON-объекта {"code": значение}.
+// Числа передаются числом или строкой, перечисления — строкой.
+func parseAttributeValues(raw string) ([]entity.ProductAttribute, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("attributes: ожидается JSON-объект: %w", err)
+	}
+
+	attributes := make([]entity.ProductAttribute, 0, len(values))
+	for code, value := range values {
+		attribute := entity.ProductAttribute{Code: code}
+		switch v := value.(type) {
+		case float64:
+			attribute.Number = &v
+		case string:
+			attribute.Value = v
+		case nil:
+			// null означает, что значение не задано
+			continue
+		default:
+			return nil, fmt.Errorf("attributes.%s: ожидается число или строка", code)
+		}
+		attributes = append(attributes, attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Code < attributes[j].Code })
+	return attributes, nil
+}
+
+// listProductsAfter собирает ответ списка продуктов в режиме курсоров
+func listProductsAfter(r *http.Request, productService *service.ProductService, filter entity.ProductFilter, cursor string, limit int) (ProductsResponse, error) {
+	products, next, err := productService.ListProductsAfter(r.Context(), filter, cursor, limit)
+	if err != nil {
+		return ProductsResponse{}, err
+	}
+
+	return ProductsResponse{
+		Products:   products,
+		Limit:      limit,
+		HasMore:    next != "",
+		NextCursor: next,
+	}, nil
+}
+
+// writeProductListError переводит ошибки списка продуктов в HTTP-ответ
+func writeProductListError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errors.ErrInvalidCursor) {
+		writeProductError(w, http.StatusBadRequest, "Некорректный курсор", "курсор поврежден или выдан для другой сортировки")
+		return
+	}
+	writeProductError(w, http.StatusInternalServerError, "Не удалось получить список продуктов", err.Error())
+}
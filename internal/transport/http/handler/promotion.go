@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type PromotionAdminHandler struct {
+	promotionService *service.PromotionService
+}
+
+func NewPromotionAdminHandler(promotionService *service.PromotionService) *PromotionAdminHandler {
+	return &PromotionAdminHandler{promotionService: promotionService}
+}
+
+// PromotionRequest represents the request body for creating or updating a promotion
+// @Description PromotionRequest содержит условия акции. type: percent — процент, fixed — сумма на заказ. scope: cart — вся корзина,
+// @Description category — категория с подкатегориями (category_id), product — товар (product_id). min_total — минимальная сумма подходящих товаров.
+// @Description Нулевые usage_limit и per_user_limit не ограничивают использование. requires_code — акция действует только по промокоду. active по умолчанию true.
+type PromotionRequest struct {
+	Name         string     `json:"name" example:"-15% на диваны"`
+	Type         string     `json:"type" example:"percent" enums:"percent,fixed"`
+	Value        float64    `json:"value" example:"15"`
+	Scope        string     `json:"scope" example:"category" enums:"cart,category,product"`
+	CategoryID   int        `json:"category_id,omitempty" example:"3"`
+	ProductID    int        `json:"product_id,omitempty" example:"0"`
+	MinTotal     float64    `json:"min_total" example:"0"`
+	StartsAt     *time.Time `json:"starts_at,omitempty" example:"2025-06-02T00:00:00Z"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" example:"2025-06-09T00:00:00Z"`
+	UsageLimit   int        `json:"usage_limit,omitempty" example:"0"`
+	PerUserLimit int        `json:"per_user_limit,omitempty" example:"1"`
+	RequiresCode bool       `json:"requires_code" example:"false"`
+	Active       *bool      `json:"active,omitempty" example:"true"`
+}
+
+// toEntity преобразует запрос в акцию
+func (req PromotionRequest) toEntity(id int) *entity.Promotion {
+	p := &entity.Promotion{
+		ID:           id,
+		Name:         req.Name,
+		Type:         entity.DiscountType(req.Type),
+		Value:        req.Value,
+		Scope:        entity.PromotionScope(req.Scope),
+		CategoryID:   req.CategoryID,
+		ProductID:    req.ProductID,
+		MinTotal:     req.MinTotal,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+		RequiresCode: req.RequiresCode,
+		Active:       true,
+	}
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+	return p
+}
+
+// CreatePromoCodesRequest represents the request body for adding promo codes
+// @Description CreatePromoCodesRequest содержит либо список кодов codes, либо count — сколько случайных кодов сгенерировать с префиксом prefix.
+// @Description usage_limit = 1 делает коды одноразовыми, 0 — без ограничения. За один запрос — не больше 1000 кодов.
+type CreatePromoCodesRequest struct {
+	Codes      []string `json:"codes,omitempty" example:"SOFA15"`
+	Count      int      `json:"count,omitempty" example:"0"`
+	Prefix     string   `json:"prefix,omitempty" example:"SALE-"`
+	UsageLimit int      `json:"usage_limit,omitempty" example:"1"`
+}
+
+// PromotionsResponse represents the admin list of promotions
+// @Description PromotionsResponse содержит страницу акций и данные пагинации
+type PromotionsResponse struct {
+	Promotions []*entity.Promotion `json:"promotions"`
+	Total      int                 `json:"total"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
+	HasMore    bool                `json:"has_more"`
+}
+
+// ListPromotions godoc
+// @Summary Список акций
+// @Description Возвращает акции с количеством использований, новые первыми. Требуется право products:read.
+// @Tags admin-promotions
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} PromotionsResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions [get]
+func (h *PromotionAdminHandler) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	promotions, total, err := h.promotionService.ListPromotions(r.Context(), page, pageSize)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PromotionsResponse{
+		Promotions: promotions,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		HasMore:    total > 0 && (page*pageSize) < total,
+	})
+}
+
+// GetPromotion godoc
+// @Summary Получение акции
+// @Description Возвращает акцию по ID. Требуется право products:read.
+// @Tags admin-promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Success 200 {object} entity.Promotion
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions/{id} [get]
+func (h *PromotionAdminHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID акции", err.Error())
+		return
+	}
+
+	promotion, err := h.promotionService.GetPromotion(r.Context(), id)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promotion)
+}
+
+// CreatePromotion godoc
+// @Summary Создание акции
+// @Description Создает акцию. Акция без requires_code сразу применяется к корзинам и заказам в период действия;
+// @Description для акции с requires_code коды добавляются через POST /admin/promotions/{id}/codes. Требуется право products:write.
+// @Tags admin-promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PromotionRequest true "Акция"
+// @Success 201 {object} entity.Promotion
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions [post]
+func (h *PromotionAdminHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var req PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	promotion := req.toEntity(0)
+	if err := h.promotionService.CreatePromotion(r.Context(), promotion); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, promotion)
+}
+
+// UpdatePromotion godoc
+// @Summary Обновление акции
+// @Description Обновляет условия акции. Скидки уже оформленных заказов не пересчитываются. Требуется право products:write.
+// @Tags admin-promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Param request body PromotionRequest true "Акция"
+// @Success 200 {object} entity.Promotion
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions/{id} [put]
+func (h *PromotionAdminHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID акции", err.Error())
+		return
+	}
+
+	var req PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	promotion := req.toEntity(id)
+	if err := h.promotionService.UpdatePromotion(r.Context(), promotion); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, promotion)
+}
+
+// DeletePromotion godoc
+// @Summary Удаление акции
+// @Description Удаляет акцию вместе с промокодами. Скидки в заказах сохраняются с названием и кодом. Чтобы сохранить статистику, акцию лучше выключить (active = false).
+// @Description Требуется право products:write.
+// @Tags admin-promotions
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions/{id} [delete]
+func (h *PromotionAdminHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID акции", err.Error())
+		return
+	}
+
+	if err := h.promotionService.DeletePromotion(r.Context(), id); err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPromoCodes godoc
+// @Summary Промокоды акции
+// @Description Возвращает промокоды акции с количеством использований. Требуется право products:read.
+// @Tags admin-promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Success 200 {array} entity.PromoCode
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions/{id}/codes [get]
+func (h *PromotionAdminHandler) ListPromoCodes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID акции", err.Error())
+		return
+	}
+
+	codes, err := h.promotionService.ListPromoCodes(r.Context(), id)
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, codes)
+}
+
+// CreatePromoCodes godoc
+// @Summary Добавление промокодов
+// @Description Добавляет акции с requires_code промокоды из списка или генерирует случайные. Коды приводятся к верхнему регистру;
+// @Description если хотя бы один код уже занят, не создается ни один. Требуется право products:write.
+// @Tags admin-promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID акции"
+// @Param request body CreatePromoCodesRequest true "Промокоды"
+// @Success 201 {array} entity.PromoCode
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/promotions/{id}/codes [post]
+func (h *PromotionAdminHandler) CreatePromoCodes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID акции", err.Error())
+		return
+	}
+
+	var req CreatePromoCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	codes, err := h.promotionService.CreatePromoCodes(r.Context(), id, service.PromoCodeBatch{
+		Codes:      req.Codes,
+		Count:      req.Count,
+		Prefix:     req.Prefix,
+		UsageLimit: req.UsageLimit,
+	})
+	if err != nil {
+		writePromotionError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, codes)
+}
+
+// writePromotionError переводит ошибки акций и промокодов в HTTP-ответ
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrPromotionNotFound):
+		writeProductError(w, http.StatusNotFound, "Акция не найдена", err.Error())
+	case errors.Is(err, errors.ErrInvalidPromotion):
+		writeProductError(w, http.StatusBadRequest, "Некорректная акция", err.Error())
+	case errors.Is(err, errors.ErrInvalidPromoCode):
+		writeProductError(w, http.StatusBadRequest, "Некорректный промокод", err.Error())
+	case errors.Is(err, errors.ErrPromoCodeExists):
+		writeProductError(w, http.StatusConflict, "Промокод уже существует", err.Error())
+	default:
+		log.Printf("Promotion error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе с акциями", err.Error())
+	}
+}
@@ -0,0 +1,342 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type ReturnHandler struct {
+	returnService *service.ReturnService
+}
+
+type ReturnAdminHandler struct {
+	returnService *service.ReturnService
+}
+
+func NewReturnHandler(returnService *service.ReturnService) *ReturnHandler {
+	return &ReturnHandler{returnService: returnService}
+}
+
+func NewReturnAdminHandler(returnService *service.ReturnService) *ReturnAdminHandler {
+	return &ReturnAdminHandler{returnService: returnService}
+}
+
+// ReturnItemRequest represents a single line of the return request
+// @Description ReturnItemRequest — позиция заказа и возвращаемое количество
+type ReturnItemRequest struct {
+	OrderItemID int `json:"order_item_id" example:"12"`
+	Quantity    int `json:"quantity" example:"1"`
+}
+
+// CreateReturnRequest represents the request body for requesting a return
+// @Description CreateReturnRequest содержит причину (defective, damaged, wrong_item, not_as_described, changed_mind, other) и позиции заказа
+type CreateReturnRequest struct {
+	Reason  entity.ReturnReason `json:"reason" example:"defective"`
+	Comment string              `json:"comment,omitempty" example:"Сломана ножка"`
+	Items   []ReturnItemRequest `json:"items"`
+}
+
+// ApproveReturnRequest represents the admin decision on a return
+// @Description ApproveReturnRequest задает сумму возврата денег (не указана — стоимость позиций, 0 — без возврата денег),
+// @Description позиции заказа, которые возвращаются на склад, и склад (0 — основной)
+type ApproveReturnRequest struct {
+	RefundAmount *float64 `json:"refund_amount,omitempty" example:"15990"`
+	RestockItems []int    `json:"restock_items,omitempty"`
+	WarehouseID  int      `json:"warehouse_id,omitempty" example:"1"`
+	Comment      string   `json:"comment,omitempty" example:"Товар принят на склад"`
+}
+
+// RejectReturnRequest represents the request body for rejecting a return
+// @Description RejectReturnRequest содержит причину отказа
+type RejectReturnRequest struct {
+	Comment string `json:"comment,omitempty" example:"Следы эксплуатации"`
+}
+
+// ReturnsResponse represents the admin list of returns
+// @Description ReturnsResponse содержит страницу возвратов и данные пагинации
+type ReturnsResponse struct {
+	Returns  []*entity.OrderReturn `json:"returns"`
+	Total    int                   `json:"total"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"page_size"`
+	HasMore  bool                  `json:"has_more"`
+}
+
+// CreateReturn godoc
+// @Summary Запрос на возврат
+// @Description Создает запрос на возврат позиций доставленного заказа. Вернуть можно не больше заказанного за вычетом уже возвращаемого.
+// @Description refund_amount в ответе — стоимость позиций; окончательную сумму определяет поддержка при одобрении.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Param request body CreateReturnRequest true "Причина и позиции"
+// @Success 201 {object} entity.OrderReturn
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id}/returns [post]
+func (h *ReturnHandler) CreateReturn(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	var req CreateReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	items := make([]entity.OrderReturnItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, entity.OrderReturnItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	ret, err := h.returnService.RequestReturn(r.Context(), claims.UserID, id, req.Reason, req.Comment, items)
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+// ListOrderReturns godoc
+// @Summary Возвраты заказа
+// @Description Возвращает возвраты заказа текущего пользователя с позициями и решением поддержки
+// @Tags orders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID заказа"
+// @Success 200 {array} entity.OrderReturn
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /orders/{id}/returns [get]
+func (h *ReturnHandler) ListOrderReturns(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeOrderError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID заказа", err.Error())
+		return
+	}
+
+	returns, err := h.returnService.ListOrderReturns(r.Context(), claims.UserID, id)
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, returns)
+}
+
+// ListReturns godoc
+// @Summary Список возвратов (админ)
+// @Description Возвращает возвраты, новые первыми, с фильтром по статусу и пагинацией. Требуется право orders:read.
+// @Tags admin-returns
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Статус: requested, approved, rejected, completed"
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} ReturnsResponse
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/returns [get]
+func (h *ReturnAdminHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	returns, total, err := h.returnService.ListReturns(r.Context(), entity.ReturnStatus(query.Get("status")), page, pageSize)
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ReturnsResponse{
+		Returns:  returns,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  total > 0 && (page*pageSize) < total,
+	})
+}
+
+// GetReturn godoc
+// @Summary Получение возврата (админ)
+// @Description Возвращает возврат по ID с позициями. Требуется право orders:read.
+// @Tags admin-returns
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID возврата"
+// @Success 200 {object} entity.OrderReturn
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/returns/{id} [get]
+func (h *ReturnAdminHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID возврата", err.Error())
+		return
+	}
+
+	ret, err := h.returnService.GetReturn(r.Context(), id)
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// ApproveReturn godoc
+// @Summary Одобрение возврата
+// @Description Одобряет возврат: позиции из restock_items возвращаются на склад, деньги — покупателю через платежную систему. Возврат закрывается (completed).
+// @Description Если платежная система не ответила, возврат остается approved и повторный вызов только вернет деньги. Требуется право returns:write.
+// @Tags admin-returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом в течение суток вернет сохраненный ответ"
+// @Param id path int true "ID возврата"
+// @Param request body ApproveReturnRequest false "Решение"
+// @Success 200 {object} entity.OrderReturn
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 502 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/returns/{id}/approve [post]
+func (h *ReturnAdminHandler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID возврата", err.Error())
+		return
+	}
+
+	var req ApproveReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	ret, err := h.returnService.Approve(r.Context(), id, entity.ReturnDecision{
+		RefundAmount: req.RefundAmount,
+		RestockItems: req.RestockItems,
+		WarehouseID:  req.WarehouseID,
+		Comment:      req.Comment,
+	}, actorID(r))
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// RejectReturn godoc
+// @Summary Отклонение возврата
+// @Description Отклоняет запрошенный возврат с указанием причины. Требуется право returns:write.
+// @Tags admin-returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID возврата"
+// @Param request body RejectReturnRequest false "Причина отказа"
+// @Success 200 {object} entity.OrderReturn
+// @Failure 400 {object} ErrorOrderResponse
+// @Failure 401 {object} ErrorOrderResponse
+// @Failure 403 {object} ErrorOrderResponse
+// @Failure 404 {object} ErrorOrderResponse
+// @Failure 409 {object} ErrorOrderResponse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /admin/returns/{id}/reject [post]
+func (h *ReturnAdminHandler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeOrderError(w, http.StatusBadRequest, "Некорректный ID возврата", err.Error())
+		return
+	}
+
+	var req RejectReturnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeOrderError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	ret, err := h.returnService.Reject(r.Context(), id, actorID(r), req.Comment)
+	if err != nil {
+		writeReturnError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// writeReturnError переводит ошибки возвратов в HTTP-ответ
+func writeReturnError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrOrderNotFound):
+		writeOrderError(w, http.StatusNotFound, "Заказ не найден", err.Error())
+	case errors.Is(err, errors.ErrReturnNotFound):
+		writeOrderError(w, http.StatusNotFound, "Возврат не найден", err.Error())
+	case errors.Is(err, errors.ErrWarehouseNotFound):
+		writeOrderError(w, http.StatusNotFound, "Склад не найден", err.Error())
+	case errors.Is(err, errors.ErrInvalidReturn):
+		writeOrderError(w, http.StatusBadRequest, "Некорректный возврат", err.Error())
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeOrderError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+	case errors.Is(err, errors.ErrInvalidRefund):
+		writeOrderError(w, http.StatusBadRequest, "Некорректная сумма возврата", err.Error())
+	case errors.Is(err, errors.ErrOrderNotReturnable):
+		writeOrderError(w, http.StatusConflict, "Вернуть можно только доставленный заказ", err.Error())
+	case errors.Is(err, errors.ErrInvalidReturnTransition):
+		writeOrderError(w, http.StatusConflict, "Возврат уже рассмотрен", err.Error())
+	case errors.Is(err, errors.ErrPaymentProviderFailed):
+		log.Printf("Return refund provider error: %v", err)
+		writeOrderError(w, http.StatusBadGateway, "Платежная система недоступна", err.Error())
+	default:
+		log.Printf("Return error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Ошибка при обработке возврата", err.Error())
+	}
+}
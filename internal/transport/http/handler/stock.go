@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type StockAdminHandler struct {
+	stockService *service.StockService
+}
+
+func NewStockAdminHandler(stockService *service.StockService) *StockAdminHandler {
+	return &StockAdminHandler{stockService: stockService}
+}
+
+// UpdateStockRequest represents the request body for setting product or variant stock
+// @Description UpdateStockRequest содержит остаток склада по результату пересчета; для товара с вариантами нужен variant_id. Без warehouse_id используется основной склад.
+type UpdateStockRequest struct {
+	VariantID   int    `json:"variant_id,omitempty" example:"3"`
+	WarehouseID int    `json:"warehouse_id,omitempty" example:"1"`
+	Stock       int    `json:"stock" example:"10"`
+	Reference   string `json:"reference,omitempty" example:"inv-2024-05"`
+	Comment     string `json:"comment,omitempty" example:"Инвентаризация склада"`
+}
+
+// StockMovementRequest represents the request body for posting a stock movement
+// @Description StockMovementRequest содержит склад, причину и количество движения. Для receipt, return, sale и write_off количество положительное, для adjustment — со знаком. Без warehouse_id используется основной склад.
+type StockMovementRequest struct {
+	VariantID   int    `json:"variant_id,omitempty" example:"3"`
+	WarehouseID int    `json:"warehouse_id,omitempty" example:"1"`
+	Reason      string `json:"reason" example:"receipt" enums:"receipt,sale,return,adjustment,write_off"`
+	Quantity    int    `json:"quantity" example:"12"`
+	Reference   string `json:"reference,omitempty" example:"supply-1042"`
+	Comment     string `json:"comment,omitempty" example:"Поставка от фабрики"`
+}
+
+// StockMovementsResponse represents a page of stock movements
+// @Description StockMovementsResponse содержит движения остатка, новые первыми; следующая страница запрашивается по next_cursor
+type StockMovementsResponse struct {
+	Movements  []*entity.StockMovement `json:"movements"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+// UpdateStock godoc
+// @Summary Изменение остатка
+// @Description Устанавливает остаток товара на складе или, для товара с вариантами, остаток указанного варианта. Общий остаток товара пересчитывается по всем складам.
+// @Description Разница с текущим остатком записывается в журнал движений корректировкой (adjustment). Требуется право stock:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param request body UpdateStockRequest true "Новый остаток"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/stock [put]
+func (h *StockAdminHandler) UpdateStock(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	var req UpdateStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	movement := &entity.StockMovement{
+		ProductID:   productID,
+		VariantID:   req.VariantID,
+		WarehouseID: req.WarehouseID,
+		UserID:      actorID(r),
+		Reference:   req.Reference,
+		Comment:     req.Comment,
+	}
+	if _, err := h.stockService.SetStock(r.Context(), movement, req.Stock); err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostStockMovement godoc
+// @Summary Движение остатка
+// @Description Проводит поступление, продажу, возврат, корректировку или списание и записывает его в журнал вместе с автором и ссылкой на документ.
+// @Description У товара с вариантами движение проводится по варианту. Остаток не может стать отрицательным. Требуется право stock:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param request body StockMovementRequest true "Движение"
+// @Success 201 {object} entity.StockMovement
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/stock/movements [post]
+func (h *StockAdminHandler) PostStockMovement(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	var req StockMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	movement := &entity.StockMovement{
+		ProductID:   productID,
+		VariantID:   req.VariantID,
+		WarehouseID: req.WarehouseID,
+		Quantity:    req.Quantity,
+		Reason:      entity.StockReason(req.Reason),
+		UserID:      actorID(r),
+		Reference:   req.Reference,
+		Comment:     req.Comment,
+	}
+	if err := h.stockService.PostMovement(r.Context(), movement); err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, movement)
+}
+
+// ListStockMovements godoc
+// @Summary История остатка товара
+// @Description Возвращает журнал движений остатка товара, новые первыми, с пагинацией по курсору. Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param variant_id query int false "Только движения варианта"
+// @Param warehouse_id query int false "Только движения склада"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param limit query int false "Размер страницы" minimum(1) maximum(100) default(20)
+// @Success 200 {object} StockMovementsResponse
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/stock/movements [get]
+func (h *StockAdminHandler) ListStockMovements(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	variantID := 0
+	if v := r.URL.Query().Get("variant_id"); v != "" {
+		if variantID, err = strconv.Atoi(v); err != nil {
+			writeProductError(w, http.StatusBadRequest, "Некорректный ID варианта", err.Error())
+			return
+		}
+	}
+	warehouseID := 0
+	if v := r.URL.Query().Get("warehouse_id"); v != "" {
+		if warehouseID, err = strconv.Atoi(v); err != nil {
+			writeProductError(w, http.StatusBadRequest, "Некорректный ID склада", err.Error())
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	movements, next, err := h.stockService.ListMovements(r.Context(), productID, variantID, warehouseID,
+		r.URL.Query().Get("cursor"), pagination.ClampLimit(limit))
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, StockMovementsResponse{Movements: movements, NextCursor: next})
+}
+
+// ListStockDiscrepancies godoc
+// @Summary Расхождения остатков с журналом
+// @Description Возвращает остатки товаров и вариантов на складах, которые не равны сумме движений склада в журнале (например, после правки остатков в обход API). Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.StockDiscrepancy
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/stock/discrepancies [get]
+func (h *StockAdminHandler) ListStockDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := h.stockService.Discrepancies(r.Context())
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, discrepancies)
+}
+
+// ReconcileStock godoc
+// @Summary Сверка остатков с журналом
+// @Description Приводит расходящиеся остатки к сумме движений журнала и возвращает найденные расхождения.
+// @Description Позиции с отрицательной суммой журнала не меняются и требуют ручной корректировки. Требуется право stock:write.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.StockDiscrepancy
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/stock/reconcile [post]
+func (h *StockAdminHandler) ReconcileStock(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := h.stockService.Reconcile(r.Context())
+	if err != nil {
+		writeStockError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, discrepancies)
+}
+
+// writeStockError переводит ошибки журнала остатков в HTTP-ответ
+func writeStockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidStockMovement):
+		writeProductError(w, http.StatusBadRequest, "Некорректное движение остатка", err.Error())
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeProductError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+	case errors.Is(err, errors.ErrInvalidCursor):
+		writeProductError(w, http.StatusBadRequest, "Некорректный курсор", err.Error())
+	case errors.Is(err, errors.ErrVariantRequired):
+		writeProductError(w, http.StatusBadRequest, "Не указан вариант товара", err.Error())
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeProductError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantNotFound):
+		writeProductError(w, http.StatusNotFound, "Вариант товара не найден", err.Error())
+	case errors.Is(err, errors.ErrWarehouseNotFound):
+		writeProductError(w, http.StatusNotFound, "Склад не найден", err.Error())
+	case errors.Is(err, errors.ErrInsufficientStock):
+		writeProductError(w, http.StatusConflict, "Недостаточно товара на складе", err.Error())
+	default:
+		log.Printf("Stock error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка изменения остатка", err.Error())
+	}
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type StockAlertHandler struct {
+	stockAlertService *service.StockAlertService
+}
+
+type StockAlertAdminHandler struct {
+	stockAlertService *service.StockAlertService
+}
+
+func NewStockAlertHandler(stockAlertService *service.StockAlertService) *StockAlertHandler {
+	return &StockAlertHandler{stockAlertService: stockAlertService}
+}
+
+func NewStockAlertAdminHandler(stockAlertService *service.StockAlertService) *StockAlertAdminHandler {
+	return &StockAlertAdminHandler{stockAlertService: stockAlertService}
+}
+
+// StockSubscriptionRequest represents the request body for subscribing to a product
+// @Description StockSubscriptionRequest задает вариант товара. Для товара с вариантами variant_id обязателен, для остальных тело можно не передавать.
+type StockSubscriptionRequest struct {
+	VariantID int `json:"variant_id,omitempty" example:"7"`
+}
+
+// LowStockThresholdRequest represents the request body for setting a low-stock threshold
+// @Description LowStockThresholdRequest задает порог низкого остатка товара. null возвращает порог по умолчанию из конфигурации.
+type LowStockThresholdRequest struct {
+	Threshold *int `json:"threshold" example:"5"`
+}
+
+// SubscribeToStock godoc
+// @Summary Подписка на поступление товара
+// @Description Ставит пользователя в очередь на уведомление о поступлении отсутствующего товара. Когда остаток станет больше нуля, отправляется событие product.back_in_stock, подписка закрывается. Повторная подписка возвращает существующую.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID товара"
+// @Param request body StockSubscriptionRequest false "Вариант товара"
+// @Success 201 {object} entity.StockSubscription
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /products/{id}/subscribe [post]
+func (h *StockAlertHandler) SubscribeToStock(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeProductError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID товара", err.Error())
+		return
+	}
+
+	var req StockSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	sub, err := h.stockAlertService.Subscribe(r.Context(), claims.UserID, productID, req.VariantID)
+	if err != nil {
+		writeStockAlertError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// SetLowStockThreshold godoc
+// @Summary Порог низкого остатка
+// @Description Задает порог низкого остатка товара. Когда остаток опускается ниже порога, отправляется событие product.low_stock (один раз, пока остаток не поднимется обратно). Требуется право stock:write.
+// @Tags admin-products
+// @Accept json
+// @Security BearerAuth
+// @Param id path int true "ID товара"
+// @Param request body LowStockThresholdRequest true "Порог"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/stock/threshold [put]
+func (h *StockAlertAdminHandler) SetLowStockThreshold(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID товара", err.Error())
+		return
+	}
+
+	var req LowStockThresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	if err := h.stockAlertService.SetThreshold(r.Context(), productID, req.Threshold); err != nil {
+		writeStockAlertError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLowStock godoc
+// @Summary Товары с низким остатком
+// @Description Возвращает товары, остаток которых ниже их порога (или порога по умолчанию), начиная с самых дефицитных. Требуется право products:read.
+// @Tags admin-products
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.LowStockProduct
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/stock/low [get]
+func (h *StockAlertAdminHandler) ListLowStock(w http.ResponseWriter, r *http.Request) {
+	products, err := h.stockAlertService.ListLowStock(r.Context())
+	if err != nil {
+		writeStockAlertError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, products)
+}
+
+// writeStockAlertError переводит ошибки порогов и подписок в HTTP-ответ
+func writeStockAlertError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeProductError(w, http.StatusNotFound, "Товар не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantNotFound):
+		writeProductError(w, http.StatusNotFound, "Вариант не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantRequired):
+		writeProductError(w, http.StatusBadRequest, "Укажите вариант товара", err.Error())
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeProductError(w, http.StatusBadRequest, "Порог не может быть отрицательным", err.Error())
+	case errors.Is(err, errors.ErrProductInStock):
+		writeProductError(w, http.StatusConflict, "Товар есть в наличии", err.Error())
+	default:
+		log.Printf("Stock alert error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе с остатками", err.Error())
+	}
+}
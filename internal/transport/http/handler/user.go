@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
 	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
@@ -13,10 +16,22 @@ import (
 
 type UserHandler struct {
 	userService *service.UserService
+	cartService *service.CartService
 }
 
-func NewUserHandler(userService *service.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *service.UserService, cartService *service.CartService) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		cartService: cartService,
+	}
+}
+
+type UserAdminHandler struct {
+	userService *service.UserService
+}
+
+func NewUserAdminHandler(userService *service.UserService) *UserAdminHandler {
+	return &UserAdminHandler{userService: userService}
 }
 
 type RegisterRequest struct {
@@ -31,8 +46,36 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	User  *entity.User `json:"user"`
+	Token        string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string       `json:"refresh_token" example:"3f0c7a52-7a4e-4a55-9d8a-5a1c2f0e9b11.Zk9x..."`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	User         *entity.User `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" example:"3f0c7a52-7a4e-4a55-9d8a-5a1c2f0e9b11.Zk9x..."`
+}
+
+type TokenResponse struct {
+	Token        string    `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string    `json:"refresh_token" example:"3f0c7a52-7a4e-4a55-9d8a-5a1c2f0e9b11.Zk9x..."`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// UsersResponse represents a paginated list of users
+// @Description UsersResponse содержит список пользователей и данные пагинации
+type UsersResponse struct {
+	Users    []*entity.User `json:"users"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// ChangeUserRoleRequest represents the request body for role assignment
+// @Description ChangeUserRoleRequest содержит новую роль пользователя
+type ChangeUserRoleRequest struct {
+	Role string `json:"role" example:"manager"`
 }
 
 // ErrorUserResponse представляет стандартную структуру ошибки для user-хендлеров
@@ -66,10 +109,10 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Email:    req.Email,
 		Password: req.Password,
 		Name:     req.Name,
-		Role:     "customer",
+		Role:     entity.RoleCustomer,
 	}
 
-	token, err := h.userService.Register(r.Context(), user)
+	tokens, err := h.userService.Register(r.Context(), user, r.UserAgent())
 	if err != nil {
 		switch err {
 		case errors.ErrUserExists:
@@ -84,21 +127,25 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         user,
 	})
 }
 
 // Login godoc
 // @Summary Авторизация пользователя
-// @Description Выполняет вход пользователя и возвращает JWT токен
+// @Description Выполняет вход пользователя и возвращает JWT токен. Если передан X-Cart-ID, гостевая корзина объединяется с корзиной пользователя.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Param X-Cart-ID header string false "ID гостевой корзины"
 // @Param request body LoginRequest true "Данные для входа"
 // @Success 200 {object} AuthResponse
 // @Failure 400 {object} ErrorUserResponse
 // @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
 // @Failure 500 {object} ErrorUserResponse
 // @Router /login [post]
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -108,11 +155,13 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, user, err := h.userService.Login(r.Context(), req.Email, req.Password)
+	tokens, user, err := h.userService.Login(r.Context(), req.Email, req.Password, r.UserAgent())
 	if err != nil {
 		switch err {
 		case errors.ErrInvalidCredentials:
 			writeUserError(w, http.StatusUnauthorized, "Неверный email или пароль", err.Error())
+		case errors.ErrUserDisabled:
+			writeUserError(w, http.StatusForbidden, "Учетная запись заблокирована", err.Error())
 		default:
 			log.Printf("Login error: %v", err)
 			writeUserError(w, http.StatusInternalServerError, "Ошибка при входе", err.Error())
@@ -120,13 +169,113 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if cartID := r.Header.Get(CartIDHeader); cartID != "" {
+		// Вход не должен падать из-за корзины — гость просто увидит свою старую корзину
+		if err := h.cartService.MergeGuestCart(r.Context(), cartID, user.ID); err != nil {
+			log.Printf("Merge guest cart error: %v", err)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		User:         user,
 	})
 }
 
+// RefreshToken godoc
+// @Summary Обновление токенов
+// @Description Обменивает refresh токен на новую пару токенов. Старый refresh токен перестает действовать; его повторное использование завершает сессию.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh токен"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /token/refresh [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	tokens, err := h.userService.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, errors.ErrInvalidToken):
+			writeUserError(w, http.StatusUnauthorized, "Недействительный refresh токен", err.Error())
+		case errors.Is(err, errors.ErrUserDisabled):
+			writeUserError(w, http.StatusForbidden, "Учетная запись заблокирована", err.Error())
+		default:
+			log.Printf("Refresh token error: %v", err)
+			writeUserError(w, http.StatusInternalServerError, "Ошибка при обновлении токена", err.Error())
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	})
+}
+
+// Logout godoc
+// @Summary Выход
+// @Description Завершает текущую сессию: access и refresh токены этой сессии перестают действовать
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /logout [post]
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeUserError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	if err := h.userService.Logout(r.Context(), claims.SessionID); err != nil {
+		log.Printf("Logout error: %v", err)
+		writeUserError(w, http.StatusInternalServerError, "Ошибка при выходе", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Выход со всех устройств
+// @Description Завершает все сессии текущего пользователя
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /logout/all [post]
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetUserFromContext(r.Context())
+	if claims == nil {
+		writeUserError(w, http.StatusUnauthorized, "Неавторизованный доступ", "JWT токен отсутствует или недействителен")
+		return
+	}
+
+	if err := h.userService.LogoutAll(r.Context(), claims.UserID); err != nil {
+		log.Printf("Logout all error: %v", err)
+		writeUserError(w, http.StatusInternalServerError, "Ошибка при выходе со всех устройств", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // Profile godoc
 // @Summary Получение профиля пользователя
 // @Description Возвращает информацию о текущем пользователе по JWT токену
@@ -155,3 +304,242 @@ func (h *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(user)
 }
+
+// ListUsers godoc
+// @Summary Список пользователей (админ)
+// @Description Возвращает пользователей с поиском по email и имени, фильтрами и пагинацией. Требуется право users:read.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Поиск по email или имени"
+// @Param role query string false "Фильтр по роли"
+// @Param disabled query bool false "Фильтр по блокировке"
+// @Param page query int false "Номер страницы" default(1)
+// @Param page_size query int false "Размер страницы" default(20)
+// @Success 200 {object} UsersResponse
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users [get]
+func (h *UserAdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := entity.UserFilter{
+		Query: strings.TrimSpace(query.Get("q")),
+		Role:  query.Get("role"),
+	}
+	if raw := query.Get("disabled"); raw != "" {
+		disabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeUserError(w, http.StatusBadRequest, "Некорректный параметр disabled", err.Error())
+			return
+		}
+		filter.Disabled = &disabled
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	users, total, err := h.userService.ListUsers(r.Context(), filter, page, pageSize)
+	if err != nil {
+		log.Printf("List users error: %v", err)
+		writeUserError(w, http.StatusInternalServerError, "Ошибка при получении пользователей", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UsersResponse{
+		Users:    users,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  total > 0 && (page*pageSize) < total,
+	})
+}
+
+// GetUser godoc
+// @Summary Получение пользователя (админ)
+// @Description Возвращает пользователя по ID. Требуется право users:read.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 404 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users/{id} [get]
+func (h *UserAdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.GetUser(r.Context(), id)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// ChangeUserRole godoc
+// @Summary Назначение роли пользователю (админ)
+// @Description Меняет роль пользователя и завершает его сессии, чтобы новые права применились сразу. Свою роль изменить нельзя. Требуется право users:write.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пользователя"
+// @Param request body ChangeUserRoleRequest true "Новая роль"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 404 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users/{id}/role [put]
+func (h *UserAdminHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangeUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeUserError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	claims := auth.GetUserFromContext(r.Context())
+	user, err := h.userService.SetRole(r.Context(), claims.UserID, id, req.Role)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// DisableUser godoc
+// @Summary Блокировка пользователя (админ)
+// @Description Блокирует учетную запись и завершает все ее сессии. Заблокировать себя нельзя. Требуется право users:write.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 404 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users/{id}/disable [post]
+func (h *UserAdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	claims := auth.GetUserFromContext(r.Context())
+	user, err := h.userService.DisableUser(r.Context(), claims.UserID, id)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// EnableUser godoc
+// @Summary Разблокировка пользователя (админ)
+// @Description Снимает блокировку с учетной записи. Требуется право users:write.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пользователя"
+// @Success 200 {object} entity.User
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 404 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users/{id}/enable [post]
+func (h *UserAdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.EnableUser(r.Context(), id)
+	if err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// RevokeUserSessions godoc
+// @Summary Принудительное завершение сессий (админ)
+// @Description Завершает все сессии пользователя: его access и refresh токены перестают действовать. Требуется право users:write.
+// @Tags admin-users
+// @Security BearerAuth
+// @Param id path int true "ID пользователя"
+// @Success 204
+// @Failure 400 {object} ErrorUserResponse
+// @Failure 401 {object} ErrorUserResponse
+// @Failure 403 {object} ErrorUserResponse
+// @Failure 404 {object} ErrorUserResponse
+// @Failure 500 {object} ErrorUserResponse
+// @Router /admin/users/{id}/sessions [delete]
+func (h *UserAdminHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := userIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeSessions(r.Context(), id); err != nil {
+		writeUserAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeUserError(w, http.StatusBadRequest, "Некорректный ID пользователя", err.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// writeUserAdminError переводит ошибки управления пользователями в HTTP-ответ
+func writeUserAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrUserNotFound):
+		writeUserError(w, http.StatusNotFound, "Пользователь не найден", err.Error())
+	case errors.Is(err, errors.ErrInvalidRole):
+		writeUserError(w, http.StatusBadRequest, "Неизвестная роль", err.Error())
+	case errors.Is(err, errors.ErrCannotModifySelf):
+		writeUserError(w, http.StatusBadRequest, "Нельзя изменить собственную учетную запись", err.Error())
+	default:
+		log.Printf("User admin error: %v", err)
+		writeUserError(w, http.StatusInternalServerError, "Ошибка при работе с пользователем", err.Error())
+	}
+}
@@ -3,6 +3,10 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/auth"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/pagination"
 )
 
 // ErrorResponse легаси залупа
@@ -38,3 +42,32 @@ func writeUserError(w http.ResponseWriter, status int, message, details string)
 		Details: details,
 	})
 }
+
+// writeOrderError sends JSON error response in unified format
+func writeOrderError(w http.ResponseWriter, status int, message, details string) {
+	writeJSON(w, status, ErrorOrderResponse{
+		Code:    status,
+		Message: message,
+		Details: details,
+	})
+}
+
+// cursorParams читает параметры keyset-пагинации. Режим курсоров включается,
+// если передан cursor или limit; иначе списки работают по page/page_size.
+func cursorParams(r *http.Request) (cursor string, limit int, ok bool) {
+	query := r.URL.Query()
+	if !query.Has("cursor") && !query.Has("limit") {
+		return "", 0, false
+	}
+
+	limit, _ = strconv.Atoi(query.Get("limit"))
+	return query.Get("cursor"), pagination.ClampLimit(limit), true
+}
+
+// actorID возвращает ID пользователя из JWT для записи в журналы; 0 — пользователь неизвестен
+func actorID(r *http.Request) int {
+	if claims := auth.GetUserFromContext(r.Context()); claims != nil {
+		return claims.UserID
+	}
+	return 0
+}
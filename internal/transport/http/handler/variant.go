@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+)
+
+// VariantRequest represents the request body for creating or updating a product variant
+// @Description VariantRequest содержит SKU, значения опций, цену (null — цена товара), начальный остаток и изображения варианта
+type VariantRequest struct {
+	SKU     string            `json:"sku" example:"SOFA-OSLO-GRY-220"`
+	Options map[string]string `json:"options"`
+	Price   *float64          `json:"price" example:"54990"`
+	Stock   int               `json:"stock" example:"5"`
+	Images  []string          `json:"images"`
+}
+
+// CreateVariant godoc
+// @Summary Добавление варианта товара
+// @Description Добавляет вариант (SKU) к товару. Остаток товара пересчитывается как сумма остатков вариантов. Требуется право products:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param request body VariantRequest true "Вариант"
+// @Success 201 {object} entity.ProductVariant
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/variants [post]
+func (h *ProductAdminHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return
+	}
+
+	var req VariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	variant := req.toVariant(productID)
+	if err := h.productService.CreateVariant(r.Context(), variant, actorID(r)); err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, variant)
+}
+
+// UpdateVariant godoc
+// @Summary Обновление варианта товара
+// @Description Обновляет SKU, опции, цену и изображения варианта. Поле stock игнорируется: остаток проводится через
+// @Description POST /admin/products/{id}/stock/movements. Требуется право products:write.
+// @Tags admin-products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param variant_id path int true "ID варианта"
+// @Param request body VariantRequest true "Вариант"
+// @Success 200 {object} entity.ProductVariant
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/variants/{variant_id} [put]
+func (h *ProductAdminHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := variantIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req VariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	variant := req.toVariant(productID)
+	variant.ID = variantID
+	if err := h.productService.UpdateVariant(r.Context(), variant); err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, variant)
+}
+
+// DeleteVariant godoc
+// @Summary Удаление варианта товара
+// @Description Удаляет вариант товара; в оформленных заказах остается его SKU. Требуется право products:write.
+// @Tags admin-products
+// @Security BearerAuth
+// @Param id path int true "ID продукта"
+// @Param variant_id path int true "ID варианта"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/products/{id}/variants/{variant_id} [delete]
+func (h *ProductAdminHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, variantID, ok := variantIDsFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.DeleteVariant(r.Context(), productID, variantID, actorID(r)); err != nil {
+		writeVariantError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (req VariantRequest) toVariant(productID int) *entity.ProductVariant {
+	return &entity.ProductVariant{
+		ProductID: productID,
+		SKU:       req.SKU,
+		Options:   req.Options,
+		Price:     req.Price,
+		Stock:     req.Stock,
+		Images:    req.Images,
+	}
+}
+
+// variantIDsFromPath читает ID продукта и варианта из пути; при ошибке ответ уже записан
+func variantIDsFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID продукта", err.Error())
+		return 0, 0, false
+	}
+	variantID, err := strconv.Atoi(r.PathValue("variant_id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID варианта", err.Error())
+		return 0, 0, false
+	}
+	return productID, variantID, true
+}
+
+// writeVariantError переводит ошибки вариантов и остатков в HTTP-ответ
+func writeVariantError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrInvalidVariant):
+		writeProductError(w, http.StatusBadRequest, "Некорректный вариант товара", err.Error())
+	case errors.Is(err, errors.ErrInvalidQuantity):
+		writeProductError(w, http.StatusBadRequest, "Некорректное количество", err.Error())
+	case errors.Is(err, errors.ErrVariantRequired):
+		writeProductError(w, http.StatusBadRequest, "Не указан вариант товара", err.Error())
+	case errors.Is(err, errors.ErrProductNotFound):
+		writeProductError(w, http.StatusNotFound, "Продукт не найден", err.Error())
+	case errors.Is(err, errors.ErrVariantNotFound):
+		writeProductError(w, http.StatusNotFound, "Вариант товара не найден", err.Error())
+	case errors.Is(err, errors.ErrSKUExists):
+		writeProductError(w, http.StatusConflict, "SKU уже существует", err.Error())
+	case errors.Is(err, errors.ErrInsufficientStock):
+		writeProductError(w, http.StatusConflict, "Недостаточно товара на основном складе", err.Error())
+	default:
+		log.Printf("Product variant error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при изменении варианта товара", err.Error())
+	}
+}
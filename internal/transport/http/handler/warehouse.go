@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/DenisOzindzheDev/furniture-shop/internal/common/errors"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/domain/entity"
+	"github.com/DenisOzindzheDev/furniture-shop/internal/service"
+)
+
+type WarehouseHandler struct {
+	warehouseService *service.WarehouseService
+}
+
+type WarehouseAdminHandler struct {
+	warehouseService *service.WarehouseService
+}
+
+func NewWarehouseHandler(warehouseService *service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{warehouseService: warehouseService}
+}
+
+func NewWarehouseAdminHandler(warehouseService *service.WarehouseService) *WarehouseAdminHandler {
+	return &WarehouseAdminHandler{warehouseService: warehouseService}
+}
+
+// WarehouseRequest represents the request body for creating or updating a warehouse
+// @Description WarehouseRequest содержит поля склада или шоурума. pickup разрешает самовывоз, priority задает порядок списания под доставку (меньше — раньше), active по умолчанию true.
+type WarehouseRequest struct {
+	Code     string `json:"code" example:"showroom-msk"`
+	Name     string `json:"name" example:"Шоурум на Ленинском"`
+	Type     string `json:"type" example:"showroom" enums:"warehouse,showroom"`
+	Address  string `json:"address" example:"Москва, Ленинский пр-т, 30"`
+	Pickup   bool   `json:"pickup" example:"true"`
+	Active   *bool  `json:"active,omitempty" example:"true"`
+	Priority int    `json:"priority" example:"10"`
+}
+
+// toEntity преобразует запрос в склад
+func (req WarehouseRequest) toEntity(id int) *entity.Warehouse {
+	w := &entity.Warehouse{
+		ID:       id,
+		Code:     req.Code,
+		Name:     req.Name,
+		Type:     entity.WarehouseType(req.Type),
+		Address:  req.Address,
+		Pickup:   req.Pickup,
+		Active:   true,
+		Priority: req.Priority,
+	}
+	if req.Active != nil {
+		w.Active = *req.Active
+	}
+	return w
+}
+
+// ListPickupPoints godoc
+// @Summary Пункты самовывоза
+// @Description Возвращает активные склады и шоурумы, из которых можно забрать заказ (delivery_method = pickup).
+// @Tags orders
+// @Produce json
+// @Success 200 {array} entity.Warehouse
+// @Failure 500 {object} ErrorOrderResponse
+// @Router /pickup-points [get]
+func (h *WarehouseHandler) ListPickupPoints(w http.ResponseWriter, r *http.Request) {
+	points, err := h.warehouseService.ListPickupPoints(r.Context())
+	if err != nil {
+		log.Printf("List pickup points error: %v", err)
+		writeOrderError(w, http.StatusInternalServerError, "Не удалось получить пункты самовывоза", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, points)
+}
+
+// ListWarehouses godoc
+// @Summary Склады и шоурумы
+// @Description Возвращает все склады и шоурумы, включая неактивные. Требуется право products:read.
+// @Tags admin-warehouses
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Warehouse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/warehouses [get]
+func (h *WarehouseAdminHandler) ListWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.warehouseService.ListWarehouses(r.Context())
+	if err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, warehouses)
+}
+
+// CreateWarehouse godoc
+// @Summary Создание склада
+// @Description Создает склад или шоурум. Остатки на нем заводятся движениями POST /admin/products/{id}/stock/movements с warehouse_id. Требуется право products:write.
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WarehouseRequest true "Склад"
+// @Success 201 {object} entity.Warehouse
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/warehouses [post]
+func (h *WarehouseAdminHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req WarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	warehouse := req.toEntity(0)
+	if err := h.warehouseService.CreateWarehouse(r.Context(), warehouse); err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, warehouse)
+}
+
+// UpdateWarehouse godoc
+// @Summary Обновление склада
+// @Description Обновляет склад или шоурум. Остатки неактивного склада сохраняются, но не продаются и не показываются в наличии. Требуется право products:write.
+// @Tags admin-warehouses
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID склада"
+// @Param request body WarehouseRequest true "Склад"
+// @Success 200 {object} entity.Warehouse
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/warehouses/{id} [put]
+func (h *WarehouseAdminHandler) UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID склада", err.Error())
+		return
+	}
+
+	var req WarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректное тело запроса", err.Error())
+		return
+	}
+
+	warehouse := req.toEntity(id)
+	if err := h.warehouseService.UpdateWarehouse(r.Context(), warehouse); err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, warehouse)
+}
+
+// DeleteWarehouse godoc
+// @Summary Удаление склада
+// @Description Удаляет склад без остатков и заказов; склад с историей можно только деактивировать. Требуется право products:write.
+// @Tags admin-warehouses
+// @Security BearerAuth
+// @Param id path int true "ID склада"
+// @Success 204
+// @Failure 400 {object} ErrorProductResponse
+// @Failure 401 {object} ErrorProductResponse
+// @Failure 403 {object} ErrorProductResponse
+// @Failure 404 {object} ErrorProductResponse
+// @Failure 409 {object} ErrorProductResponse
+// @Failure 500 {object} ErrorProductResponse
+// @Router /admin/warehouses/{id} [delete]
+func (h *WarehouseAdminHandler) DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeProductError(w, http.StatusBadRequest, "Некорректный ID склада", err.Error())
+		return
+	}
+
+	if err := h.warehouseService.DeleteWarehouse(r.Context(), id); err != nil {
+		writeWarehouseError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeWarehouseError переводит ошибки складов в HTTP-ответ
+func writeWarehouseError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ErrWarehouseNotFound):
+		writeProductError(w, http.StatusNotFound, "Склад не найден", err.Error())
+	case errors.Is(err, errors.ErrInvalidWarehouse):
+		writeProductError(w, http.StatusBadRequest, "Некорректный склад", "code (до 50 символов) и name обязательны, type — warehouse или showroom")
+	case errors.Is(err, errors.ErrWarehouseExists):
+		writeProductError(w, http.StatusConflict, "Склад с таким кодом уже существует", err.Error())
+	case errors.Is(err, errors.ErrWarehouseInUse):
+		writeProductError(w, http.StatusConflict, "На складе есть остатки или заказы", err.Error())
+	default:
+		log.Printf("Warehouse error: %v", err)
+		writeProductError(w, http.StatusInternalServerError, "Ошибка при работе со складом", err.Error())
+	}
+}
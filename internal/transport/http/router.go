@@ -17,15 +17,42 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func New(cfg *config.Config, db *sql.DB, redisClient *redis.Client, jwtManager *auth.JWTManager,
-	userService *service.UserService, productService *service.ProductService, pdfService *service.PDFService) http.Handler {
+func New(cfg *config.Config, db *sql.DB, redisClient *redis.Client, jwtManager *auth.JWTManager, sessions auth.SessionChecker, rbac *auth.RBAC,
+	idempotencyStore handler.IdempotencyStore,
+	userService *service.UserService, productService *service.ProductService, pdfService *service.PDFService,
+	orderService *service.OrderService, cartService *service.CartService, categoryService *service.CategoryService,
+	attributeService *service.AttributeService, importService *service.ImportService, exportService *service.ExportService,
+	stockService *service.StockService, warehouseService *service.WarehouseService, stockAlertService *service.StockAlertService,
+	paymentService *service.PaymentService, returnService *service.ReturnService, invoiceService *service.InvoiceService,
+	promotionService *service.PromotionService) http.Handler {
 
 	mux := http.NewServeMux()
 
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, cartService)
+	userAdminHandler := handler.NewUserAdminHandler(userService)
 	productHandler := handler.NewProductHandler(productService)
 	productAdminHandler := handler.NewProductAdminHandler(productService)
 	productPDFHandler := handler.NewProductPDFHandler(productService, pdfService)
+	orderHandler := handler.NewOrderHandler(orderService)
+	orderAdminHandler := handler.NewOrderAdminHandler(orderService)
+	cartHandler := handler.NewCartHandler(cartService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	categoryAdminHandler := handler.NewCategoryAdminHandler(categoryService)
+	attributeHandler := handler.NewAttributeHandler(attributeService)
+	attributeAdminHandler := handler.NewAttributeAdminHandler(attributeService)
+	importAdminHandler := handler.NewImportAdminHandler(importService)
+	exportAdminHandler := handler.NewExportAdminHandler(exportService)
+	stockAdminHandler := handler.NewStockAdminHandler(stockService)
+	warehouseHandler := handler.NewWarehouseHandler(warehouseService)
+	warehouseAdminHandler := handler.NewWarehouseAdminHandler(warehouseService)
+	stockAlertHandler := handler.NewStockAlertHandler(stockAlertService)
+	stockAlertAdminHandler := handler.NewStockAlertAdminHandler(stockAlertService)
+	paymentHandler := handler.NewPaymentHandler(paymentService)
+	paymentAdminHandler := handler.NewPaymentAdminHandler(paymentService)
+	returnHandler := handler.NewReturnHandler(returnService)
+	returnAdminHandler := handler.NewReturnAdminHandler(returnService)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceService, rbac)
+	promotionAdminHandler := handler.NewPromotionAdminHandler(promotionService)
 	healthHandler := handler.NewHealthHandler(db, redisClient, nil)
 
 	// Swagger
@@ -40,27 +67,123 @@ func New(cfg *config.Config, db *sql.DB, redisClient *redis.Client, jwtManager *
 	mux.HandleFunc("GET /api/health", healthHandler.HealthCheck)
 	mux.HandleFunc("POST /api/register", userHandler.Register)
 	mux.HandleFunc("POST /api/login", userHandler.Login)
+	mux.HandleFunc("POST /api/token/refresh", userHandler.RefreshToken)
 	mux.HandleFunc("GET /api/products", productHandler.ListProducts)
+	mux.HandleFunc("GET /api/products/search", productHandler.SearchProducts)
 	mux.HandleFunc("GET /api/products/{id}", productHandler.GetProduct)
 	mux.HandleFunc("GET /api/products/{id}/download", productPDFHandler.DownloadProductPDF)
 	mux.HandleFunc("GET /api/products/{id}/preview", productPDFHandler.PreviewProductPDF)
+	mux.HandleFunc("GET /api/categories", categoryHandler.GetCategoryTree)
+	mux.HandleFunc("GET /api/categories/{id}", categoryHandler.GetCategory)
+	mux.HandleFunc("GET /api/categories/{id}/attributes", attributeHandler.GetCategoryAttributes)
+	mux.HandleFunc("GET /api/pickup-points", warehouseHandler.ListPickupPoints)
+	mux.HandleFunc("POST /api/payments/webhook/{provider}", paymentHandler.PaymentWebhook)
 
 	// Auth middleware
-	authMiddleware := auth.AuthMiddleware(jwtManager)
+	authMiddleware := auth.AuthMiddleware(jwtManager, sessions)
+	// Оформление заказа и платежи повторяются клиентами при сбоях сети: Idempotency-Key защищает от дублей
+	idempotent := func(h http.HandlerFunc) http.Handler {
+		return handler.IdempotencyMiddleware(idempotencyStore, cfg.IdempotencyTTL)(h)
+	}
 	mux.Handle("GET /api/profile", authMiddleware(http.HandlerFunc(userHandler.Profile)))
+	mux.Handle("POST /api/logout", authMiddleware(http.HandlerFunc(userHandler.Logout)))
+	mux.Handle("POST /api/logout/all", authMiddleware(http.HandlerFunc(userHandler.LogoutAll)))
+	mux.Handle("POST /api/orders", authMiddleware(idempotent(orderHandler.CreateOrder)))
+	mux.Handle("GET /api/orders", authMiddleware(http.HandlerFunc(orderHandler.ListOrders)))
+	mux.Handle("GET /api/orders/{id}", authMiddleware(http.HandlerFunc(orderHandler.GetOrder)))
+	mux.Handle("POST /api/orders/{id}/pay", authMiddleware(idempotent(paymentHandler.CreatePayment)))
+	mux.Handle("GET /api/orders/{id}/invoice", authMiddleware(http.HandlerFunc(invoiceHandler.GetOrderInvoice)))
+	mux.Handle("POST /api/orders/{id}/cancel", authMiddleware(http.HandlerFunc(orderHandler.CancelOrder)))
+	mux.Handle("POST /api/orders/{id}/returns", authMiddleware(http.HandlerFunc(returnHandler.CreateReturn)))
+	mux.Handle("GET /api/orders/{id}/returns", authMiddleware(http.HandlerFunc(returnHandler.ListOrderReturns)))
+	mux.Handle("POST /api/products/{id}/subscribe", authMiddleware(http.HandlerFunc(stockAlertHandler.SubscribeToStock)))
+	mux.Handle("POST /api/cart/checkout", authMiddleware(idempotent(cartHandler.Checkout)))
 
-	// Admin middleware
-	adminMiddleware := auth.AuthMiddleware(jwtManager)
-	mux.Handle("POST /api/admin/products", adminMiddleware(http.HandlerFunc(productAdminHandler.CreateProduct)))
-	mux.Handle("PUT /api/admin/products/{id}", adminMiddleware(http.HandlerFunc(productAdminHandler.UpdateProduct)))
-	mux.Handle("DELETE /api/admin/products/{id}", adminMiddleware(http.HandlerFunc(productAdminHandler.DeleteProduct)))
-	mux.Handle("GET /api/admin/products", adminMiddleware(http.HandlerFunc(productAdminHandler.ListProducts)))
+	// Cart: гость по X-Cart-ID, пользователь по JWT
+	optionalAuthMiddleware := auth.OptionalAuthMiddleware(jwtManager, sessions)
+	mux.Handle("GET /api/cart", optionalAuthMiddleware(http.HandlerFunc(cartHandler.GetCart)))
+	mux.Handle("POST /api/cart/items", optionalAuthMiddleware(http.HandlerFunc(cartHandler.AddCartItem)))
+	mux.Handle("PUT /api/cart/items/{product_id}", optionalAuthMiddleware(http.HandlerFunc(cartHandler.UpdateCartItem)))
+	mux.Handle("DELETE /api/cart/items/{product_id}", optionalAuthMiddleware(http.HandlerFunc(cartHandler.RemoveCartItem)))
+	mux.Handle("PUT /api/cart/promo-code", optionalAuthMiddleware(http.HandlerFunc(cartHandler.ApplyPromoCode)))
+	mux.Handle("DELETE /api/cart/promo-code", optionalAuthMiddleware(http.HandlerFunc(cartHandler.RemovePromoCode)))
+
+	// Admin routes: каждый маршрут регистрируется вместе с требуемым правом
+	admin := func(perm auth.Permission, h http.HandlerFunc) http.Handler {
+		return authMiddleware(rbac.RequirePermission(perm)(h))
+	}
+	mux.Handle("POST /api/admin/products", admin(auth.PermProductsWrite, productAdminHandler.CreateProduct))
+	mux.Handle("PUT /api/admin/products/{id}", admin(auth.PermProductsWrite, productAdminHandler.UpdateProduct))
+	mux.Handle("DELETE /api/admin/products/{id}", admin(auth.PermProductsWrite, productAdminHandler.DeleteProduct))
+	mux.Handle("GET /api/admin/products", admin(auth.PermProductsRead, productAdminHandler.ListProducts))
+	mux.Handle("POST /api/admin/products/import", admin(auth.PermProductsWrite, importAdminHandler.ImportProducts))
+	mux.Handle("GET /api/admin/products/export", admin(auth.PermProductsRead, exportAdminHandler.ExportProducts))
+	mux.Handle("GET /api/admin/imports", admin(auth.PermProductsRead, importAdminHandler.ListImports))
+	mux.Handle("GET /api/admin/imports/{id}", admin(auth.PermProductsRead, importAdminHandler.GetImport))
+	mux.Handle("PUT /api/admin/products/{id}/stock", admin(auth.PermStockWrite, stockAdminHandler.UpdateStock))
+	mux.Handle("GET /api/admin/products/{id}/stock/movements", admin(auth.PermProductsRead, stockAdminHandler.ListStockMovements))
+	mux.Handle("POST /api/admin/products/{id}/stock/movements", admin(auth.PermStockWrite, stockAdminHandler.PostStockMovement))
+	mux.Handle("PUT /api/admin/products/{id}/stock/threshold", admin(auth.PermStockWrite, stockAlertAdminHandler.SetLowStockThreshold))
+	mux.Handle("POST /api/admin/products/{id}/variants", admin(auth.PermProductsWrite, productAdminHandler.CreateVariant))
+	mux.Handle("PUT /api/admin/products/{id}/variants/{variant_id}", admin(auth.PermProductsWrite, productAdminHandler.UpdateVariant))
+	mux.Handle("DELETE /api/admin/products/{id}/variants/{variant_id}", admin(auth.PermProductsWrite, productAdminHandler.DeleteVariant))
+	mux.Handle("GET /api/admin/products/{id}/images", admin(auth.PermProductsRead, productAdminHandler.ListProductImages))
+	mux.Handle("POST /api/admin/products/{id}/images", admin(auth.PermProductsWrite, productAdminHandler.UploadProductImages))
+	mux.Handle("PUT /api/admin/products/{id}/images/order", admin(auth.PermProductsWrite, productAdminHandler.ReorderProductImages))
+	mux.Handle("PUT /api/admin/products/{id}/images/{image_id}", admin(auth.PermProductsWrite, productAdminHandler.UpdateProductImage))
+	mux.Handle("POST /api/admin/products/{id}/images/{image_id}/primary", admin(auth.PermProductsWrite, productAdminHandler.SetPrimaryProductImage))
+	mux.Handle("DELETE /api/admin/products/{id}/images/{image_id}", admin(auth.PermProductsWrite, productAdminHandler.DeleteProductImage))
+	mux.Handle("GET /api/admin/stock/discrepancies", admin(auth.PermProductsRead, stockAdminHandler.ListStockDiscrepancies))
+	mux.Handle("POST /api/admin/stock/reconcile", admin(auth.PermStockWrite, stockAdminHandler.ReconcileStock))
+	mux.Handle("GET /api/admin/stock/low", admin(auth.PermProductsRead, stockAlertAdminHandler.ListLowStock))
+	mux.Handle("GET /api/admin/warehouses", admin(auth.PermProductsRead, warehouseAdminHandler.ListWarehouses))
+	mux.Handle("POST /api/admin/warehouses", admin(auth.PermProductsWrite, warehouseAdminHandler.CreateWarehouse))
+	mux.Handle("PUT /api/admin/warehouses/{id}", admin(auth.PermProductsWrite, warehouseAdminHandler.UpdateWarehouse))
+	mux.Handle("DELETE /api/admin/warehouses/{id}", admin(auth.PermProductsWrite, warehouseAdminHandler.DeleteWarehouse))
+	mux.Handle("POST /api/admin/categories", admin(auth.PermProductsWrite, categoryAdminHandler.CreateCategory))
+	mux.Handle("PUT /api/admin/categories/{id}", admin(auth.PermProductsWrite, categoryAdminHandler.UpdateCategory))
+	mux.Handle("DELETE /api/admin/categories/{id}", admin(auth.PermProductsWrite, categoryAdminHandler.DeleteCategory))
+	mux.Handle("PUT /api/admin/categories/{id}/attributes", admin(auth.PermProductsWrite, attributeAdminHandler.SetCategoryAttributes))
+	mux.Handle("GET /api/admin/attributes", admin(auth.PermProductsRead, attributeAdminHandler.ListAttributes))
+	mux.Handle("POST /api/admin/attributes", admin(auth.PermProductsWrite, attributeAdminHandler.CreateAttribute))
+	mux.Handle("PUT /api/admin/attributes/{id}", admin(auth.PermProductsWrite, attributeAdminHandler.UpdateAttribute))
+	mux.Handle("DELETE /api/admin/attributes/{id}", admin(auth.PermProductsWrite, attributeAdminHandler.DeleteAttribute))
+	mux.Handle("GET /api/admin/promotions", admin(auth.PermProductsRead, promotionAdminHandler.ListPromotions))
+	mux.Handle("POST /api/admin/promotions", admin(auth.PermProductsWrite, promotionAdminHandler.CreatePromotion))
+	mux.Handle("GET /api/admin/promotions/{id}", admin(auth.PermProductsRead, promotionAdminHandler.GetPromotion))
+	mux.Handle("PUT /api/admin/promotions/{id}", admin(auth.PermProductsWrite, promotionAdminHandler.UpdatePromotion))
+	mux.Handle("DELETE /api/admin/promotions/{id}", admin(auth.PermProductsWrite, promotionAdminHandler.DeletePromotion))
+	mux.Handle("GET /api/admin/promotions/{id}/codes", admin(auth.PermProductsRead, promotionAdminHandler.ListPromoCodes))
+	mux.Handle("POST /api/admin/promotions/{id}/codes", admin(auth.PermProductsWrite, promotionAdminHandler.CreatePromoCodes))
+	mux.Handle("GET /api/admin/orders", admin(auth.PermOrdersRead, orderAdminHandler.ListAllOrders))
+	mux.Handle("GET /api/admin/orders/export", admin(auth.PermOrdersRead, exportAdminHandler.ExportOrders))
+	mux.Handle("POST /api/admin/orders/status", admin(auth.PermOrdersWrite, orderAdminHandler.BulkChangeOrderStatus))
+	mux.Handle("GET /api/admin/orders/{id}", admin(auth.PermOrdersRead, orderAdminHandler.GetOrderDetails))
+	mux.Handle("POST /api/admin/orders/{id}/notes", admin(auth.PermOrdersWrite, orderAdminHandler.AddOrderNote))
+	mux.Handle("POST /api/admin/orders/{id}/status", admin(auth.PermOrdersWrite, orderAdminHandler.ChangeOrderStatus))
+	mux.Handle("POST /api/admin/orders/{id}/cancel", admin(auth.PermOrdersCancel, orderAdminHandler.CancelOrder))
+	mux.Handle("GET /api/admin/orders/{id}/status", admin(auth.PermOrdersRead, orderAdminHandler.GetOrderStatusHistory))
+	mux.Handle("GET /api/admin/orders/{id}/payments", admin(auth.PermOrdersRead, paymentAdminHandler.ListOrderPayments))
+	mux.Handle("POST /api/admin/orders/{id}/refund", authMiddleware(rbac.RequirePermission(auth.PermPaymentsRefund)(
+		idempotent(paymentAdminHandler.RefundOrderPayment))))
+	mux.Handle("GET /api/admin/returns", admin(auth.PermOrdersRead, returnAdminHandler.ListReturns))
+	mux.Handle("GET /api/admin/returns/{id}", admin(auth.PermOrdersRead, returnAdminHandler.GetReturn))
+	mux.Handle("POST /api/admin/returns/{id}/approve", authMiddleware(rbac.RequirePermission(auth.PermReturnsWrite)(
+		idempotent(returnAdminHandler.ApproveReturn))))
+	mux.Handle("POST /api/admin/returns/{id}/reject", admin(auth.PermReturnsWrite, returnAdminHandler.RejectReturn))
+	mux.Handle("GET /api/admin/users", admin(auth.PermUsersRead, userAdminHandler.ListUsers))
+	mux.Handle("GET /api/admin/users/{id}", admin(auth.PermUsersRead, userAdminHandler.GetUser))
+	mux.Handle("PUT /api/admin/users/{id}/role", admin(auth.PermUsersWrite, userAdminHandler.ChangeUserRole))
+	mux.Handle("POST /api/admin/users/{id}/disable", admin(auth.PermUsersWrite, userAdminHandler.DisableUser))
+	mux.Handle("POST /api/admin/users/{id}/enable", admin(auth.PermUsersWrite, userAdminHandler.EnableUser))
+	mux.Handle("DELETE /api/admin/users/{id}/sessions", admin(auth.PermUsersWrite, userAdminHandler.RevokeUserSessions))
 
 	// CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{handler.CartIDHeader, handler.IdempotentReplayHeader},
 		AllowCredentials: true,
 		Debug:            cfg.CorsDebug,
 	})
@@ -0,0 +1,16 @@
+-- migrations/000007_create_order_status_history.up.sql
+CREATE TABLE order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    changed_by INTEGER REFERENCES users(id),
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history(order_id);
+
+-- История для заказов, созданных до появления таблицы
+INSERT INTO order_status_history (order_id, to_status, changed_by, created_at)
+SELECT id, status, user_id, created_at FROM orders;
@@ -0,0 +1,8 @@
+-- migrations/000009_add_refresh_tokens_to_sessions.up.sql
+-- Сессия = одно устройство. Refresh токен хранится только в виде SHA-256 хэша и меняется при каждом обновлении.
+ALTER TABLE sessions ADD COLUMN refresh_token_hash VARCHAR(64);
+ALTER TABLE sessions ADD COLUMN user_agent VARCHAR(500);
+ALTER TABLE sessions ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Старые сессии без токена все равно нельзя обновить
+DELETE FROM sessions WHERE refresh_token_hash IS NULL;
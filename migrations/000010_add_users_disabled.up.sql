@@ -0,0 +1,5 @@
+-- migrations/000010_add_users_disabled.up.sql
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
+
+CREATE INDEX idx_users_role ON users(role);
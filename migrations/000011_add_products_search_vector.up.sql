@@ -0,0 +1,10 @@
+-- migrations/000011_add_products_search_vector.up.sql
+-- Полнотекстовый поиск: название важнее категории, категория важнее описания
+ALTER TABLE products ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(category, '')), 'B') ||
+        setweight(to_tsvector('russian', coalesce(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX idx_products_search_vector ON products USING GIN(search_vector);
@@ -0,0 +1,3 @@
+-- migrations/000012_add_order_items_product_index.up.sql
+-- Нужен для сортировки каталога по популярности
+CREATE INDEX idx_order_items_product_id ON order_items(product_id);
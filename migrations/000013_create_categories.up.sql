@@ -0,0 +1,61 @@
+-- migrations/000013_create_categories.up.sql
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(120) NOT NULL UNIQUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+-- Названия уникальны среди соседей без учета регистра
+CREATE UNIQUE INDEX idx_categories_parent_name ON categories(COALESCE(parent_id, 0), lower(name));
+
+-- Транслитерация для slug существующих категорий; в приложении используется такая же схема
+CREATE FUNCTION pg_temp.slugify(value TEXT) RETURNS TEXT AS $$
+    SELECT COALESCE(NULLIF(trim(BOTH '-' FROM regexp_replace(
+        translate(
+            replace(replace(replace(replace(replace(replace(replace(replace(replace(lower(value),
+                'щ', 'shch'), 'ш', 'sh'), 'ч', 'ch'), 'ж', 'zh'), 'ю', 'yu'), 'я', 'ya'), 'ц', 'ts'), 'х', 'kh'), 'ё', 'e'),
+            'абвгдезийклмнопрстуфыэъь', 'abvgdeziiklmnoprstufye'),
+        '[^a-z0-9]+', '-', 'g')), ''), 'category')
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Строковые категории становятся корневыми; "Гостиная" и "гостиная" сливаются в одну
+INSERT INTO categories (name, slug)
+SELECT name,
+       CASE WHEN row_number() OVER (PARTITION BY pg_temp.slugify(name) ORDER BY name) = 1
+            THEN pg_temp.slugify(name)
+            ELSE pg_temp.slugify(name) || '-' || row_number() OVER (PARTITION BY pg_temp.slugify(name) ORDER BY name)
+       END
+FROM (
+    SELECT DISTINCT ON (lower(trim(category))) trim(category) AS name
+    FROM products
+    WHERE trim(category) <> ''
+    ORDER BY lower(trim(category)), trim(category)
+) names;
+
+ALTER TABLE products ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE RESTRICT;
+
+-- products.category остается денормализованным названием категории для поиска и карточек
+UPDATE products p
+SET category_id = c.id, category = c.name
+FROM categories c
+WHERE c.parent_id IS NULL AND lower(c.name) = lower(trim(p.category));
+
+-- Товары с пустой категорией переносим в отдельную корневую категорию
+INSERT INTO categories (name, slug)
+SELECT 'Без категории', 'bez-kategorii'
+WHERE EXISTS (SELECT 1 FROM products WHERE category_id IS NULL)
+ON CONFLICT DO NOTHING;
+
+UPDATE products p
+SET category_id = c.id, category = c.name
+FROM categories c
+WHERE p.category_id IS NULL AND c.parent_id IS NULL AND lower(c.name) = 'без категории';
+
+ALTER TABLE products ALTER COLUMN category_id SET NOT NULL;
+
+CREATE INDEX idx_products_category_id ON products(category_id);
@@ -0,0 +1,24 @@
+-- migrations/000014_create_product_variants.up.sql
+-- Варианты исполнения товара: цвет, обивка, размер. У товара с вариантами
+-- products.stock хранит сумму остатков вариантов.
+CREATE TABLE product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    options JSONB NOT NULL DEFAULT '{}',
+    price DECIMAL(10,2) CHECK (price >= 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    images TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_variants_product_id ON product_variants(product_id);
+
+-- Позиция заказа ссылается на вариант; SKU сохраняется на момент покупки
+ALTER TABLE order_items ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL;
+ALTER TABLE order_items ADD COLUMN sku VARCHAR(64);
+
+ALTER TABLE cart_items ADD COLUMN variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE;
+ALTER TABLE cart_items DROP CONSTRAINT cart_items_user_id_product_id_key;
+CREATE UNIQUE INDEX idx_cart_items_user_product_variant ON cart_items(user_id, product_id, COALESCE(variant_id, 0));
@@ -0,0 +1,21 @@
+-- migrations/000015_create_product_images.up.sql
+-- Галерея изображений товара. products.image_url хранит URL главного изображения
+-- и обновляется вместе с галереей, чтобы списки товаров не ходили в product_images.
+CREATE TABLE product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    alt_text VARCHAR(255) NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_images_product_id ON product_images(product_id, position);
+CREATE UNIQUE INDEX idx_product_images_primary ON product_images(product_id) WHERE is_primary;
+
+-- Существующие изображения становятся главными изображениями галереи
+INSERT INTO product_images (product_id, url, alt_text, position, is_primary)
+SELECT id, image_url, name, 0, TRUE
+FROM products
+WHERE image_url IS NOT NULL AND image_url <> '';
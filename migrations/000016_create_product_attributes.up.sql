@@ -0,0 +1,47 @@
+-- migrations/000016_create_product_attributes.up.sql
+-- Типизированные характеристики товаров. Атрибут привязывается к категории
+-- и действует для всех ее подкатегорий.
+CREATE TABLE attributes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('number', 'enum')),
+    unit VARCHAR(20) NOT NULL DEFAULT '',
+    min_value NUMERIC,
+    max_value NUMERIC,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE category_attributes (
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    attribute_id INTEGER NOT NULL REFERENCES attributes(id) ON DELETE CASCADE,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (category_id, attribute_id)
+);
+
+-- Значение хранится в колонке своего типа: num_value для number, text_value для enum
+CREATE TABLE product_attribute_values (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    attribute_id INTEGER NOT NULL REFERENCES attributes(id) ON DELETE RESTRICT,
+    num_value NUMERIC,
+    text_value TEXT,
+    PRIMARY KEY (product_id, attribute_id),
+    CHECK (num_value IS NOT NULL OR text_value IS NOT NULL)
+);
+
+CREATE INDEX idx_product_attribute_values_num ON product_attribute_values(attribute_id, num_value);
+CREATE INDEX idx_product_attribute_values_text ON product_attribute_values(attribute_id, text_value);
+
+-- Базовый набор характеристик мебели; к категориям привязывается через админку
+INSERT INTO attributes (code, name, type, unit, min_value, max_value, options) VALUES
+('width', 'Ширина', 'number', 'см', 1, 1000, '{}'),
+('depth', 'Глубина', 'number', 'см', 1, 1000, '{}'),
+('height', 'Высота', 'number', 'см', 1, 1000, '{}'),
+('weight', 'Вес', 'number', 'кг', 0.1, 1000, '{}'),
+('frame_material', 'Материал каркаса', 'enum', '', NULL, NULL,
+    '{"Массив дуба","Массив сосны","Массив березы","ЛДСП","МДФ","Фанера","Металл"}'),
+('upholstery', 'Обивка', 'enum', '', NULL, NULL,
+    '{"Велюр","Рогожка","Шенилл","Микрофибра","Экокожа","Натуральная кожа","Жаккард"}');
@@ -0,0 +1,29 @@
+-- migrations/000017_create_import_jobs.up.sql
+-- Ключи товара для массового импорта: артикул и ID во внешней системе (ERP, PIM)
+ALTER TABLE products ADD COLUMN sku VARCHAR(64);
+ALTER TABLE products ADD COLUMN external_id VARCHAR(100);
+
+CREATE UNIQUE INDEX idx_products_sku ON products(sku) WHERE sku IS NOT NULL;
+CREATE UNIQUE INDEX idx_products_external_id ON products(external_id) WHERE external_id IS NOT NULL;
+
+-- Задачи импорта каталога. report хранит результат по каждой строке файла.
+CREATE TABLE import_jobs (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    format VARCHAR(10) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0,
+    updated_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    report JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_import_jobs_created_at ON import_jobs(created_at DESC);
@@ -0,0 +1,30 @@
+-- migrations/000018_create_stock_movements.up.sql
+-- Журнал движений остатков. Записи только добавляются: остаток товара (или варианта)
+-- равен сумме quantity его движений, balance — остаток сразу после движения.
+-- variant_id без внешнего ключа, чтобы история удаленного варианта сохранялась.
+CREATE TABLE stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER,
+    quantity INTEGER NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('receipt', 'sale', 'return', 'adjustment', 'write_off')),
+    balance INTEGER NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_movements_product ON stock_movements(product_id, id DESC);
+CREATE INDEX idx_stock_movements_variant ON stock_movements(variant_id) WHERE variant_id IS NOT NULL;
+
+-- Текущие остатки становятся начальными записями журнала
+INSERT INTO stock_movements (product_id, quantity, reason, balance, reference, comment)
+SELECT p.id, p.stock, 'adjustment', p.stock, 'opening_balance', 'Начальный остаток'
+FROM products p
+WHERE p.stock > 0 AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id);
+
+INSERT INTO stock_movements (product_id, variant_id, quantity, reason, balance, reference, comment)
+SELECT v.product_id, v.id, v.stock, 'adjustment', v.stock, 'opening_balance', 'Начальный остаток'
+FROM product_variants v
+WHERE v.stock > 0;
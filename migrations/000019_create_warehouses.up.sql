@@ -0,0 +1,58 @@
+-- migrations/000019_create_warehouses.up.sql
+-- Склады и шоурумы. priority задает порядок, в котором из них списывается товар под заказ с доставкой.
+CREATE TABLE warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'warehouse' CHECK (type IN ('warehouse', 'showroom')),
+    address TEXT NOT NULL DEFAULT '',
+    pickup BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO warehouses (code, name) VALUES ('main', 'Основной склад');
+
+-- Остатки по складам. products.stock и product_variants.stock хранят сумму по всем складам.
+CREATE TABLE warehouse_stock (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_warehouse_stock_item ON warehouse_stock(warehouse_id, product_id, COALESCE(variant_id, 0));
+CREATE INDEX idx_warehouse_stock_product ON warehouse_stock(product_id);
+
+-- Текущие остатки переносятся на основной склад
+INSERT INTO warehouse_stock (warehouse_id, product_id, stock)
+SELECT w.id, p.id, p.stock
+FROM products p, warehouses w
+WHERE w.code = 'main' AND p.stock > 0
+  AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id);
+
+INSERT INTO warehouse_stock (warehouse_id, product_id, variant_id, stock)
+SELECT w.id, v.product_id, v.id, v.stock
+FROM product_variants v, warehouses w
+WHERE w.code = 'main' AND v.stock > 0;
+
+ALTER TABLE stock_movements ADD COLUMN warehouse_id INTEGER REFERENCES warehouses(id) ON DELETE SET NULL;
+UPDATE stock_movements SET warehouse_id = (SELECT id FROM warehouses WHERE code = 'main');
+
+-- Способ получения заказа: доставка или самовывоз из пункта выдачи
+ALTER TABLE orders ADD COLUMN delivery_method VARCHAR(20) NOT NULL DEFAULT 'delivery' CHECK (delivery_method IN ('delivery', 'pickup'));
+ALTER TABLE orders ADD COLUMN pickup_warehouse_id INTEGER REFERENCES warehouses(id);
+
+-- С каких складов списаны позиции заказа; при отмене товар возвращается туда же
+CREATE TABLE order_allocations (
+    id SERIAL PRIMARY KEY,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX idx_order_allocations_item ON order_allocations(order_item_id);
@@ -0,0 +1,24 @@
+-- migrations/000020_create_stock_reservations.up.sql
+-- Резервы остатков под неоплаченные заказы. Пока резерв активен, товар остается на складе,
+-- но не продается другим покупателям. При оплате резерв превращается в списание (converted),
+-- по истечении срока или при отмене заказа — снимается (released).
+CREATE TABLE stock_reservations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id),
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'converted', 'released')),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_reservations_order ON stock_reservations(order_id);
+CREATE INDEX idx_stock_reservations_item ON stock_reservations(order_item_id);
+CREATE INDEX idx_stock_reservations_active ON stock_reservations(product_id, warehouse_id) WHERE status = 'active';
+CREATE INDEX idx_stock_reservations_expires ON stock_reservations(expires_at) WHERE status = 'active';
+
+ALTER TABLE orders ADD COLUMN reserved_until TIMESTAMP;
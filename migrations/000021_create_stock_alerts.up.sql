@@ -0,0 +1,20 @@
+-- migrations/000021_create_stock_alerts.up.sql
+-- Порог низкого остатка товара; NULL — действует порог из конфигурации (low_stock_threshold).
+-- low_stock_alerted помнит, что событие product.low_stock уже отправлено, чтобы не слать его при каждом движении.
+ALTER TABLE products ADD COLUMN low_stock_threshold INTEGER CHECK (low_stock_threshold >= 0);
+ALTER TABLE products ADD COLUMN low_stock_alerted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Подписки на поступление товара. notified_at заполняется, когда товар снова появился и уведомление отправлено.
+CREATE TABLE stock_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_stock_subscriptions_pending
+    ON stock_subscriptions(user_id, product_id, COALESCE(variant_id, 0))
+    WHERE notified_at IS NULL;
+CREATE INDEX idx_stock_subscriptions_product ON stock_subscriptions(product_id) WHERE notified_at IS NULL;
@@ -0,0 +1,20 @@
+-- migrations/000022_create_payment_intents.up.sql
+-- Платежи по заказам. Платеж создается у провайдера (provider), его статус меняют вебхуки;
+-- успешный платеж переводит заказ в paid. refunded_amount — сколько уже возвращено покупателю.
+CREATE TABLE payment_intents (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_payment_id VARCHAR(255),
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0 AND refunded_amount <= amount),
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'authorized', 'succeeded', 'cancelled', 'refunded')),
+    confirmation_url TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_payment_intents_provider_payment ON payment_intents(provider, provider_payment_id);
+CREATE INDEX idx_payment_intents_order ON payment_intents(order_id);
@@ -0,0 +1,34 @@
+-- migrations/000023_create_order_returns.up.sql
+-- Возвраты (RMA) доставленных заказов. Покупатель указывает причину и количество по позициям,
+-- поддержка одобряет возврат, решает, какие позиции вернуть на склад, и сколько денег вернуть.
+-- approved — возврат одобрен, товар оприходован, деньги еще возвращаются; completed — деньги возвращены.
+CREATE TABLE order_returns (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'approved', 'rejected', 'completed')),
+    reason VARCHAR(30) NOT NULL
+        CHECK (reason IN ('defective', 'damaged', 'wrong_item', 'not_as_described', 'changed_mind', 'other')),
+    comment TEXT,
+    refund_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (refund_amount >= 0),
+    restock_warehouse_id INTEGER REFERENCES warehouses(id),
+    resolved_by INTEGER REFERENCES users(id),
+    resolution_comment TEXT,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE order_return_items (
+    id SERIAL PRIMARY KEY,
+    return_id INTEGER NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+    order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (return_id, order_item_id)
+);
+
+CREATE INDEX idx_order_returns_order ON order_returns(order_id);
+CREATE INDEX idx_order_returns_status ON order_returns(status, created_at);
+CREATE INDEX idx_order_return_items_item ON order_return_items(order_item_id);
@@ -0,0 +1,10 @@
+-- migrations/000024_create_order_invoices.up.sql
+-- Счета по заказам. Счет выписывается один раз: номер и дата фиксируются при первом запросе,
+-- сгенерированный PDF хранится в S3 (file_url) под непредсказуемым ключом token.
+CREATE TABLE order_invoices (
+    order_id INTEGER PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+    number VARCHAR(50) NOT NULL UNIQUE,
+    token VARCHAR(64) NOT NULL,
+    file_url TEXT,
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,11 @@
+-- migrations/000025_create_order_notes.up.sql
+-- Внутренние заметки сотрудников к заказу; покупателю не показываются.
+CREATE TABLE order_notes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_notes_order ON order_notes(order_id, created_at);
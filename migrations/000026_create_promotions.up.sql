@@ -0,0 +1,72 @@
+-- migrations/000026_create_promotions.up.sql
+-- Акции и промокоды. Акция дает процентную или фиксированную скидку на товар, категорию (со всеми
+-- подкатегориями) или всю корзину. Акции без requires_code применяются автоматически, остальные — по промокоду.
+-- Использованием считается заказ со скидкой акции, кроме отмененных: отмена возвращает лимит.
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    value DECIMAL(10,2) NOT NULL CHECK (value > 0),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('cart', 'category', 'product')),
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    product_id INTEGER REFERENCES products(id) ON DELETE CASCADE,
+    min_total DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (min_total >= 0),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    requires_code BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (discount_type <> 'percent' OR value <= 100),
+    CHECK ((scope = 'category') = (category_id IS NOT NULL)),
+    CHECK ((scope = 'product') = (product_id IS NOT NULL)),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- Коды хранятся в верхнем регистре; usage_limit = 1 — одноразовый код
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    promotion_id INTEGER NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    usage_limit INTEGER CHECK (usage_limit > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_promo_codes_promotion ON promo_codes(promotion_id);
+
+-- Сумма позиций до скидок, итоговая скидка и примененный промокод. total — сумма к оплате.
+ALTER TABLE orders
+    ADD COLUMN subtotal DECIMAL(10,2),
+    ADD COLUMN discount_total DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN promo_code VARCHAR(64);
+
+UPDATE orders SET subtotal = total;
+
+ALTER TABLE orders ALTER COLUMN subtotal SET NOT NULL;
+
+-- Часть скидок заказа, приходящаяся на позицию (на все ее количество)
+ALTER TABLE order_items ADD COLUMN discount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- Скидки заказа по акциям. Название и код копируются, чтобы разбивка не менялась при правке или удалении акции.
+CREATE TABLE order_discounts (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    promotion_id INTEGER REFERENCES promotions(id) ON DELETE SET NULL,
+    promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(64),
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX idx_order_discounts_order ON order_discounts(order_id);
+CREATE INDEX idx_order_discounts_promotion ON order_discounts(promotion_id);
+CREATE INDEX idx_order_discounts_promo_code ON order_discounts(promo_code_id);
+
+-- Промокод, примененный к корзине пользователя. Гостевые корзины хранят код в Redis.
+CREATE TABLE cart_promo_codes (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);